@@ -2,6 +2,7 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -21,11 +22,15 @@ import (
 	"github.com/bluenviron/mediamtx/internal/auth"
 	"github.com/bluenviron/mediamtx/internal/conf"
 	"github.com/bluenviron/mediamtx/internal/defs"
+	"github.com/bluenviron/mediamtx/internal/events"
 	"github.com/bluenviron/mediamtx/internal/logger"
 	"github.com/bluenviron/mediamtx/internal/protocols/httpp"
 	"github.com/bluenviron/mediamtx/internal/record"
 	"github.com/bluenviron/mediamtx/internal/restrictnetwork"
+	"github.com/bluenviron/mediamtx/internal/servers/dash"
 	"github.com/bluenviron/mediamtx/internal/servers/hls"
+	"github.com/bluenviron/mediamtx/internal/servers/httpflv"
+	"github.com/bluenviron/mediamtx/internal/servers/httpts"
 	"github.com/bluenviron/mediamtx/internal/servers/rtmp"
 	"github.com/bluenviron/mediamtx/internal/servers/rtsp"
 	"github.com/bluenviron/mediamtx/internal/servers/srt"
@@ -113,6 +118,10 @@ func paramName(ctx *gin.Context) (string, bool) {
 type PathManager interface {
 	APIPathsList() (*defs.APIPathList, error)
 	APIPathsGet(string) (*defs.APIPath, error)
+	APIRecordingStart(string, defs.APIRecordingSessionStartReq) (*defs.APIRecordingSession, error)
+	APIRecordingStop(string, *uuid.UUID) (*defs.APIRecordingSessionList, error)
+	APIRecordingSessionsList() (*defs.APIRecordingSessionList, error)
+	APIRecordingSessionsGet(uuid.UUID) (*defs.APIRecordingSession, error)
 }
 
 // HLSServer contains methods used by the API and Metrics server.
@@ -144,6 +153,26 @@ type SRTServer interface {
 	APIConnsKick(uuid.UUID) error
 }
 
+// HTTPFLVServer contains methods used by the API and Metrics server.
+type HTTPFLVServer interface {
+	APIConnsList() (*defs.APIHTTPFLVConnList, error)
+	APIConnsGet(uuid.UUID) (*defs.APIHTTPFLVConn, error)
+	APIConnsKick(uuid.UUID) error
+}
+
+// HTTPTSServer contains methods used by the API and Metrics server.
+type HTTPTSServer interface {
+	APIConnsList() (*defs.APIHTTPTSConnList, error)
+	APIConnsGet(uuid.UUID) (*defs.APIHTTPTSConn, error)
+	APIConnsKick(uuid.UUID) error
+}
+
+// DASHServer contains methods used by the API and Metrics server.
+type DASHServer interface {
+	APIMuxersList() (*defs.APIDASHMuxerList, error)
+	APIMuxersGet(string) (*defs.APIDASHMuxer, error)
+}
+
 // WebRTCServer contains methods used by the API and Metrics server.
 type WebRTCServer interface {
 	APISessionsList() (*defs.APIWebRTCSessionList, error)
@@ -153,35 +182,46 @@ type WebRTCServer interface {
 
 type apiAuthManager interface {
 	Authenticate(req *auth.Request) error
+	FlushCache()
 }
 
 type apiParent interface {
 	logger.Writer
 	APIConfigSet(conf *conf.Conf)
+	APIConfigDiff(conf *conf.Conf) (*defs.APIConfigDiff, error)
 }
 
 // API is an API server.
 type API struct {
-	Address      string
-	ReadTimeout  conf.StringDuration
-	Conf         *conf.Conf
-	AuthManager  apiAuthManager
-	PathManager  PathManager
-	RTSPServer   RTSPServer
-	RTSPSServer  RTSPServer
-	RTMPServer   RTMPServer
-	RTMPSServer  RTMPServer
-	HLSServer    HLSServer
-	WebRTCServer WebRTCServer
-	SRTServer    SRTServer
-	Parent       apiParent
-
+	Address       string
+	ReadTimeout   conf.StringDuration
+	Conf          *conf.Conf
+	AuthManager   apiAuthManager
+	PathManager   PathManager
+	RTSPServer    RTSPServer
+	RTSPSServer   RTSPServer
+	RTMPServer    RTMPServer
+	RTMPSServer   RTMPServer
+	HLSServer     HLSServer
+	WebRTCServer  WebRTCServer
+	SRTServer     SRTServer
+	HTTPFLVServer HTTPFLVServer
+	HTTPTSServer  HTTPTSServer
+	DASHServer    DASHServer
+	EventBus      *events.Bus
+	TokenStore    *auth.TokenStore
+	Parent        apiParent
+
+	ctx        context.Context
+	ctxCancel  func()
 	httpServer *httpp.WrappedServer
 	mutex      sync.RWMutex
 }
 
 // Initialize initializes API.
 func (a *API) Initialize() error {
+	a.ctx, a.ctxCancel = context.WithCancel(context.Background())
+
 	router := gin.New()
 	router.SetTrustedProxies(nil) //nolint:errcheck
 
@@ -193,6 +233,8 @@ func (a *API) Initialize() error {
 	group.GET("/v3/config/pathdefaults/get", a.onConfigPathDefaultsGet)
 	group.PATCH("/v3/config/pathdefaults/patch", a.onConfigPathDefaultsPatch)
 
+	group.POST("/v3/config/validate", a.onConfigValidate)
+
 	group.GET("/v3/config/paths/list", a.onConfigPathsList)
 	group.GET("/v3/config/paths/get/*name", a.onConfigPathsGet)
 	group.POST("/v3/config/paths/add/*name", a.onConfigPathsAdd)
@@ -200,6 +242,8 @@ func (a *API) Initialize() error {
 	group.POST("/v3/config/paths/replace/*name", a.onConfigPathsReplace)
 	group.DELETE("/v3/config/paths/delete/*name", a.onConfigPathsDelete)
 
+	group.POST("/v3/auth/cache/flush", a.onAuthCacheFlush)
+
 	group.GET("/v3/paths/list", a.onPathsList)
 	group.GET("/v3/paths/get/*name", a.onPathsGet)
 
@@ -248,9 +292,40 @@ func (a *API) Initialize() error {
 		group.POST("/v3/srtconns/kick/:id", a.onSRTConnsKick)
 	}
 
+	if !interfaceIsEmpty(a.HTTPFLVServer) {
+		group.GET("/v3/httpflvconns/list", a.onHTTPFLVConnsList)
+		group.GET("/v3/httpflvconns/get/:id", a.onHTTPFLVConnsGet)
+		group.POST("/v3/httpflvconns/kick/:id", a.onHTTPFLVConnsKick)
+	}
+
+	if !interfaceIsEmpty(a.HTTPTSServer) {
+		group.GET("/v3/httptsconns/list", a.onHTTPTSConnsList)
+		group.GET("/v3/httptsconns/get/:id", a.onHTTPTSConnsGet)
+		group.POST("/v3/httptsconns/kick/:id", a.onHTTPTSConnsKick)
+	}
+
+	if !interfaceIsEmpty(a.DASHServer) {
+		group.GET("/v3/dashmuxers/list", a.onDASHMuxersList)
+		group.GET("/v3/dashmuxers/get/*name", a.onDASHMuxersGet)
+	}
+
 	group.GET("/v3/recordings/list", a.onRecordingsList)
 	group.GET("/v3/recordings/get/*name", a.onRecordingsGet)
 	group.DELETE("/v3/recordings/deletesegment", a.onRecordingDeleteSegment)
+	group.POST("/v3/recordings/start/*name", a.onRecordingStart)
+	group.POST("/v3/recordings/stop/*name", a.onRecordingStop)
+	group.GET("/v3/recordings/sessions/list", a.onRecordingSessionsList)
+	group.GET("/v3/recordings/sessions/get/:id", a.onRecordingSessionsGet)
+
+	if a.EventBus != nil {
+		group.GET("/v3/events", a.onEvents)
+	}
+
+	if a.TokenStore != nil {
+		group.GET("/v3/tokens/list", a.onTokensList)
+		group.POST("/v3/tokens/create", a.onTokensCreate)
+		group.DELETE("/v3/tokens/revoke/:id", a.onTokensRevoke)
+	}
 
 	network, address := restrictnetwork.Restrict("tcp", a.Address)
 
@@ -265,6 +340,7 @@ func (a *API) Initialize() error {
 		a,
 	)
 	if err != nil {
+		a.ctxCancel()
 		return err
 	}
 
@@ -276,12 +352,13 @@ func (a *API) Initialize() error {
 // Close closes the API.
 func (a *API) Close() {
 	a.Log(logger.Info, "listener is closing")
+	a.ctxCancel()
 	a.httpServer.Close()
 }
 
 // Log implements logger.Writer.
 func (a *API) Log(level logger.Level, format string, args ...interface{}) {
-	a.Parent.Log(level, "[API] "+format, args...)
+	a.Parent.Log(level, "%v"+format, append([]interface{}{logger.Component("API")}, args...)...)
 }
 
 func (a *API) writeError(ctx *gin.Context, status int, err error) {
@@ -297,13 +374,31 @@ func (a *API) writeError(ctx *gin.Context, status int, err error) {
 func (a *API) mwAuth(ctx *gin.Context) {
 	user, pass, hasCredentials := ctx.Request.BasicAuth()
 
-	err := a.AuthManager.Authenticate(&auth.Request{
-		User:   user,
-		Pass:   pass,
-		Query:  ctx.Request.URL.RawQuery,
-		IP:     net.ParseIP(ctx.ClientIP()),
-		Action: conf.AuthActionAPI,
-	})
+	req := &auth.Request{
+		User:     user,
+		Pass:     pass,
+		Query:    ctx.Request.URL.RawQuery,
+		IP:       net.ParseIP(ctx.ClientIP()),
+		Action:   conf.AuthActionAPI,
+		Token:    auth.TokenFromHeader(ctx.Request.Header),
+		Endpoint: ctx.Request.URL.Path,
+	}
+
+	if req.Token != "" {
+		// tokens can't manage tokens, otherwise a token could be used
+		// to create another token with more permissions than its own.
+		if strings.HasPrefix(req.Endpoint, "/v3/tokens/") {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, &defs.APIError{
+				Error: "API tokens cannot be used to manage tokens",
+			})
+			return
+		}
+
+		hasCredentials = true
+		req.Path = a.requestPath(ctx)
+	}
+
+	err := a.AuthManager.Authenticate(req)
 	if err != nil {
 		if !hasCredentials {
 			ctx.Header("WWW-Authenticate", `Basic realm="mediamtx"`)
@@ -392,6 +487,51 @@ func (a *API) onConfigPathDefaultsPatch(ctx *gin.Context) {
 	ctx.Status(http.StatusOK)
 }
 
+func (a *API) onConfigValidate(ctx *gin.Context) {
+	var partial map[string]interface{}
+	err := json.NewDecoder(ctx.Request.Body).Decode(&partial)
+	if err != nil {
+		a.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	a.mutex.RLock()
+	c := a.Conf
+	a.mutex.RUnlock()
+
+	newConf, err := c.Merge(partial)
+	if err == nil {
+		err = newConf.Validate()
+	}
+	if err != nil {
+		res := &defs.APIConfigValidation{
+			Valid: false,
+		}
+
+		for _, err := range conf.Errors(err) {
+			res.Errors = append(res.Errors, defs.APIConfigValidationError{
+				Field:   conf.ErrorField(err),
+				Message: err.Error(),
+			})
+		}
+
+		ctx.JSON(http.StatusOK, res)
+		return
+	}
+
+	diff, err := a.Parent.APIConfigDiff(newConf)
+	if err != nil {
+		a.writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, &defs.APIConfigValidation{
+		Valid:  true,
+		Errors: []defs.APIConfigValidationError{},
+		Diff:   diff,
+	})
+}
+
 func (a *API) onConfigPathsList(ctx *gin.Context) {
 	a.mutex.RLock()
 	c := a.Conf
@@ -589,6 +729,12 @@ func (a *API) onConfigPathsDelete(ctx *gin.Context) {
 	ctx.Status(http.StatusOK)
 }
 
+func (a *API) onAuthCacheFlush(ctx *gin.Context) {
+	a.AuthManager.FlushCache()
+
+	ctx.Status(http.StatusOK)
+}
+
 func (a *API) onPathsList(ctx *gin.Context) {
 	data, err := a.PathManager.APIPathsList()
 	if err != nil {
@@ -1089,6 +1235,160 @@ func (a *API) onSRTConnsKick(ctx *gin.Context) {
 	ctx.Status(http.StatusOK)
 }
 
+func (a *API) onHTTPFLVConnsList(ctx *gin.Context) {
+	data, err := a.HTTPFLVServer.APIConnsList()
+	if err != nil {
+		a.writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	data.ItemCount = len(data.Items)
+	pageCount, err := paginate(&data.Items, ctx.Query("itemsPerPage"), ctx.Query("page"))
+	if err != nil {
+		a.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	data.PageCount = pageCount
+
+	ctx.JSON(http.StatusOK, data)
+}
+
+func (a *API) onHTTPFLVConnsGet(ctx *gin.Context) {
+	uuid, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		a.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	data, err := a.HTTPFLVServer.APIConnsGet(uuid)
+	if err != nil {
+		if errors.Is(err, httpflv.ErrConnNotFound) {
+			a.writeError(ctx, http.StatusNotFound, err)
+		} else {
+			a.writeError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, data)
+}
+
+func (a *API) onHTTPFLVConnsKick(ctx *gin.Context) {
+	uuid, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		a.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	err = a.HTTPFLVServer.APIConnsKick(uuid)
+	if err != nil {
+		if errors.Is(err, httpflv.ErrConnNotFound) {
+			a.writeError(ctx, http.StatusNotFound, err)
+		} else {
+			a.writeError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+func (a *API) onHTTPTSConnsList(ctx *gin.Context) {
+	data, err := a.HTTPTSServer.APIConnsList()
+	if err != nil {
+		a.writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	data.ItemCount = len(data.Items)
+	pageCount, err := paginate(&data.Items, ctx.Query("itemsPerPage"), ctx.Query("page"))
+	if err != nil {
+		a.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	data.PageCount = pageCount
+
+	ctx.JSON(http.StatusOK, data)
+}
+
+func (a *API) onHTTPTSConnsGet(ctx *gin.Context) {
+	uuid, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		a.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	data, err := a.HTTPTSServer.APIConnsGet(uuid)
+	if err != nil {
+		if errors.Is(err, httpts.ErrConnNotFound) {
+			a.writeError(ctx, http.StatusNotFound, err)
+		} else {
+			a.writeError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, data)
+}
+
+func (a *API) onHTTPTSConnsKick(ctx *gin.Context) {
+	uuid, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		a.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	err = a.HTTPTSServer.APIConnsKick(uuid)
+	if err != nil {
+		if errors.Is(err, httpts.ErrConnNotFound) {
+			a.writeError(ctx, http.StatusNotFound, err)
+		} else {
+			a.writeError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+func (a *API) onDASHMuxersList(ctx *gin.Context) {
+	data, err := a.DASHServer.APIMuxersList()
+	if err != nil {
+		a.writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	data.ItemCount = len(data.Items)
+	pageCount, err := paginate(&data.Items, ctx.Query("itemsPerPage"), ctx.Query("page"))
+	if err != nil {
+		a.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	data.PageCount = pageCount
+
+	ctx.JSON(http.StatusOK, data)
+}
+
+func (a *API) onDASHMuxersGet(ctx *gin.Context) {
+	pathName, ok := paramName(ctx)
+	if !ok {
+		a.writeError(ctx, http.StatusBadRequest, fmt.Errorf("invalid name"))
+		return
+	}
+
+	data, err := a.DASHServer.APIMuxersGet(pathName)
+	if err != nil {
+		if errors.Is(err, dash.ErrMuxerNotFound) {
+			a.writeError(ctx, http.StatusNotFound, err)
+		} else {
+			a.writeError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, data)
+}
+
 func (a *API) onRecordingsList(ctx *gin.Context) {
 	a.mutex.RLock()
 	c := a.Conf
@@ -1173,6 +1473,119 @@ func (a *API) onRecordingDeleteSegment(ctx *gin.Context) {
 	ctx.Status(http.StatusOK)
 }
 
+func (a *API) onRecordingStart(ctx *gin.Context) {
+	pathName, ok := paramName(ctx)
+	if !ok {
+		a.writeError(ctx, http.StatusBadRequest, fmt.Errorf("invalid name"))
+		return
+	}
+
+	// overrides are optional
+	var req defs.APIRecordingSessionStartReq
+	if ctx.Request.ContentLength != 0 {
+		d := json.NewDecoder(ctx.Request.Body)
+		d.DisallowUnknownFields()
+		err := d.Decode(&req)
+		if err != nil {
+			a.writeError(ctx, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	if req.RecordPath != nil && *req.RecordPath == "" {
+		a.writeError(ctx, http.StatusBadRequest, fmt.Errorf("invalid 'recordPath'"))
+		return
+	}
+	if req.MaxDuration != nil && *req.MaxDuration <= 0 {
+		a.writeError(ctx, http.StatusBadRequest, fmt.Errorf("invalid 'maxDuration'"))
+		return
+	}
+
+	data, err := a.PathManager.APIRecordingStart(pathName, req)
+	if err != nil {
+		if errors.Is(err, conf.ErrPathNotFound) {
+			a.writeError(ctx, http.StatusNotFound, err)
+		} else {
+			a.writeError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, data)
+}
+
+func (a *API) onRecordingStop(ctx *gin.Context) {
+	pathName, ok := paramName(ctx)
+	if !ok {
+		a.writeError(ctx, http.StatusBadRequest, fmt.Errorf("invalid name"))
+		return
+	}
+
+	// when an ID is not provided, all sessions of the path are stopped
+	var id *uuid.UUID
+	if tmp := ctx.Query("id"); tmp != "" {
+		v, err := uuid.Parse(tmp)
+		if err != nil {
+			a.writeError(ctx, http.StatusBadRequest, err)
+			return
+		}
+		id = &v
+	}
+
+	data, err := a.PathManager.APIRecordingStop(pathName, id)
+	if err != nil {
+		if errors.Is(err, conf.ErrPathNotFound) || errors.Is(err, defs.ErrRecordingSessionNotFound) {
+			a.writeError(ctx, http.StatusNotFound, err)
+		} else {
+			a.writeError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	data.ItemCount = len(data.Items)
+	data.PageCount = 1
+
+	ctx.JSON(http.StatusOK, data)
+}
+
+func (a *API) onRecordingSessionsList(ctx *gin.Context) {
+	data, err := a.PathManager.APIRecordingSessionsList()
+	if err != nil {
+		a.writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	data.ItemCount = len(data.Items)
+	pageCount, err := paginate(&data.Items, ctx.Query("itemsPerPage"), ctx.Query("page"))
+	if err != nil {
+		a.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	data.PageCount = pageCount
+
+	ctx.JSON(http.StatusOK, data)
+}
+
+func (a *API) onRecordingSessionsGet(ctx *gin.Context) {
+	uuid, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		a.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	data, err := a.PathManager.APIRecordingSessionsGet(uuid)
+	if err != nil {
+		if errors.Is(err, defs.ErrRecordingSessionNotFound) {
+			a.writeError(ctx, http.StatusNotFound, err)
+		} else {
+			a.writeError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, data)
+}
+
 // ReloadConf is called by core.
 func (a *API) ReloadConf(conf *conf.Conf) {
 	a.mutex.Lock()
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
 	"io"
@@ -13,6 +14,8 @@ import (
 
 	"github.com/bluenviron/mediamtx/internal/auth"
 	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/defs"
+	"github.com/bluenviron/mediamtx/internal/events"
 	"github.com/bluenviron/mediamtx/internal/logger"
 	"github.com/bluenviron/mediamtx/internal/test"
 	"github.com/stretchr/testify/require"
@@ -25,6 +28,10 @@ func (testParent) Log(_ logger.Level, _ string, _ ...interface{}) {
 
 func (testParent) APIConfigSet(_ *conf.Conf) {}
 
+func (testParent) APIConfigDiff(_ *conf.Conf) (*defs.APIConfigDiff, error) {
+	return &defs.APIConfigDiff{}, nil
+}
+
 func tempConf(t *testing.T, cnt string) *conf.Conf {
 	fi, err := test.CreateTempFile([]byte(cnt))
 	require.NoError(t, err)
@@ -116,11 +123,12 @@ func TestConfigAuth(t *testing.T) {
 		AuthManager: &test.AuthManager{
 			Func: func(req *auth.Request) error {
 				require.Equal(t, &auth.Request{
-					User:   "myuser",
-					Pass:   "mypass",
-					IP:     req.IP,
-					Action: "api",
-					Query:  "key=val",
+					User:     "myuser",
+					Pass:     "mypass",
+					IP:       req.IP,
+					Action:   "api",
+					Query:    "key=val",
+					Endpoint: "/v3/config/global/get",
 				}, req)
 				return nil
 			},
@@ -364,6 +372,41 @@ func TestConfigPathsGet(t *testing.T) {
 	require.Equal(t, "myuser", out["readUser"])
 }
 
+func TestConfigPathsGetTemplate(t *testing.T) {
+	cnf := tempConf(t, "api: yes\n"+
+		"pathTemplates:\n"+
+		"  ipcam:\n"+
+		"    source: rtsp://{{ip}}:554/stream\n"+
+		"    sourceOnDemand: yes\n"+
+		"paths:\n"+
+		"  my/path:\n"+
+		"    template: ipcam\n"+
+		"    vars:\n"+
+		"      ip: 10.0.0.5\n")
+
+	api := API{
+		Address:     "localhost:9997",
+		ReadTimeout: conf.StringDuration(10 * time.Second),
+		Conf:        cnf,
+		AuthManager: test.NilAuthManager,
+		Parent:      &testParent{},
+	}
+	err := api.Initialize()
+	require.NoError(t, err)
+	defer api.Close()
+
+	tr := &http.Transport{}
+	defer tr.CloseIdleConnections()
+	hc := &http.Client{Transport: tr}
+
+	var out map[string]interface{}
+	httpRequest(t, hc, http.MethodGet, "http://localhost:9997/v3/config/paths/get/my/path", nil, &out)
+	require.Equal(t, "ipcam", out["template"])
+	require.Equal(t, map[string]interface{}{"ip": "10.0.0.5"}, out["vars"])
+	require.Equal(t, "rtsp://10.0.0.5:554/stream", out["source"])
+	require.Equal(t, true, out["sourceOnDemand"])
+}
+
 func TestConfigPathsAdd(t *testing.T) {
 	cnf := tempConf(t, "api: yes\n")
 
@@ -717,3 +760,34 @@ func TestRecordingsDeleteSegment(t *testing.T) {
 	defer res.Body.Close()
 	require.Equal(t, http.StatusOK, res.StatusCode)
 }
+
+func TestEventsKeepAlive(t *testing.T) {
+	eventsKeepAlivePeriod = 100 * time.Millisecond
+
+	cnf := tempConf(t, "api: yes\n")
+
+	api := API{
+		Address:     "localhost:9997",
+		ReadTimeout: conf.StringDuration(10 * time.Second),
+		Conf:        cnf,
+		AuthManager: test.NilAuthManager,
+		EventBus:    &events.Bus{},
+		Parent:      &testParent{},
+	}
+	err := api.Initialize()
+	require.NoError(t, err)
+	defer api.Close()
+
+	tr := &http.Transport{}
+	defer tr.CloseIdleConnections()
+	hc := &http.Client{Transport: tr}
+
+	res, err := hc.Get("http://localhost:9997/v3/events")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	line, err := bufio.NewReader(res.Body).ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, ":\n", line)
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/bluenviron/mediamtx/internal/events"
+	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/bluenviron/mediamtx/internal/protocols/httpp"
+	"github.com/bluenviron/mediamtx/internal/protocols/websocket"
+)
+
+// period of SSE comments that prevent proxies from closing idle connections.
+var eventsKeepAlivePeriod = 15 * time.Second
+
+func parseEventsFilter(ctx *gin.Context) (events.Filter, error) {
+	var f events.Filter
+
+	for _, entry := range ctx.QueryArray("path") {
+		for _, p := range strings.Split(entry, ",") {
+			if p != "" {
+				f.Paths = append(f.Paths, p)
+			}
+		}
+	}
+
+	for _, entry := range ctx.QueryArray("type") {
+		for _, t := range strings.Split(entry, ",") {
+			if t == "" {
+				continue
+			}
+
+			if !events.IsValidType(events.Type(t)) {
+				return events.Filter{}, fmt.Errorf("invalid event type: '%s'", t)
+			}
+
+			f.Types = append(f.Types, events.Type(t))
+		}
+	}
+
+	return f, nil
+}
+
+func (a *API) onEvents(ctx *gin.Context) {
+	filter, err := parseEventsFilter(ctx)
+	if err != nil {
+		a.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	if ctx.GetHeader("Upgrade") != "" {
+		a.onEventsWebSocket(ctx, filter)
+		return
+	}
+
+	a.onEventsSSE(ctx, filter)
+}
+
+func (a *API) onEventsSSE(ctx *gin.Context, filter events.Filter) {
+	sub := a.EventBus.Subscribe(filter)
+	defer a.EventBus.Unsubscribe(sub)
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Status(http.StatusOK)
+	ctx.Writer.Flush()
+
+	a.Log(logger.Debug, "event listener %v connected (SSE)", httpp.RemoteAddr(ctx))
+
+	keepAliveTicker := time.NewTicker(eventsKeepAlivePeriod)
+	defer keepAliveTicker.Stop()
+
+	for {
+		select {
+		case e := <-sub.Events():
+			byts, err := json.Marshal(e)
+			if err != nil {
+				return
+			}
+
+			_, err = fmt.Fprintf(ctx.Writer, "event: %s\ndata: %s\n\n", e.Type, byts)
+			if err != nil {
+				return
+			}
+			ctx.Writer.Flush()
+
+		case <-keepAliveTicker.C:
+			_, err := io.WriteString(ctx.Writer, ":\n\n")
+			if err != nil {
+				return
+			}
+			ctx.Writer.Flush()
+
+		case <-ctx.Request.Context().Done():
+			a.Log(logger.Debug, "event listener %v disconnected", httpp.RemoteAddr(ctx))
+			return
+
+		case <-a.ctx.Done():
+			return
+		}
+	}
+}
+
+func (a *API) onEventsWebSocket(ctx *gin.Context, filter events.Filter) {
+	wc, err := websocket.NewServerConn(ctx.Writer, ctx.Request)
+	if err != nil {
+		a.Log(logger.Warn, "unable to upgrade connection: %v", err)
+		return
+	}
+	defer wc.Close()
+
+	sub := a.EventBus.Subscribe(filter)
+	defer a.EventBus.Unsubscribe(sub)
+
+	a.Log(logger.Debug, "event listener %v connected (WebSocket)", wc.RemoteAddr())
+
+	// ping frames are sent periodically by the connection itself.
+
+	// read incoming messages in order to detect when the client disconnects.
+	readDone := make(chan struct{})
+	go func() {
+		defer close(readDone)
+		for {
+			var in interface{}
+			err := wc.ReadJSON(&in)
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case e := <-sub.Events():
+			err := wc.WriteJSON(e)
+			if err != nil {
+				return
+			}
+
+		case <-readDone:
+			a.Log(logger.Debug, "event listener %v disconnected", wc.RemoteAddr())
+			return
+
+		case <-a.ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/bluenviron/mediamtx/internal/auth"
+	"github.com/bluenviron/mediamtx/internal/defs"
+)
+
+func tokenEntry(t *auth.Token) *defs.APIToken {
+	return &defs.APIToken{
+		ID:          t.ID,
+		Name:        t.Name,
+		Permissions: t.Permissions,
+		Created:     t.Created,
+		ExpiresAt:   t.ExpiresAt,
+	}
+}
+
+// requestPath returns the path a request refers to,
+// in order to check path restrictions of API tokens.
+func (a *API) requestPath(ctx *gin.Context) string {
+	if name, ok := paramName(ctx); ok {
+		return name
+	}
+
+	if ctx.Request.URL.Path == "/v3/recordings/deletesegment" {
+		return ctx.Query("path")
+	}
+
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		return ""
+	}
+
+	route := ctx.FullPath()
+
+	switch {
+	case strings.HasPrefix(route, "/v3/rtspsessions/"):
+		if data, err := a.RTSPServer.APISessionsGet(id); err == nil {
+			return data.Path
+		}
+
+	case strings.HasPrefix(route, "/v3/rtspssessions/"):
+		if data, err := a.RTSPSServer.APISessionsGet(id); err == nil {
+			return data.Path
+		}
+
+	case strings.HasPrefix(route, "/v3/rtmpconns/"):
+		if data, err := a.RTMPServer.APIConnsGet(id); err == nil {
+			return data.Path
+		}
+
+	case strings.HasPrefix(route, "/v3/rtmpsconns/"):
+		if data, err := a.RTMPSServer.APIConnsGet(id); err == nil {
+			return data.Path
+		}
+
+	case strings.HasPrefix(route, "/v3/webrtcsessions/"):
+		if data, err := a.WebRTCServer.APISessionsGet(id); err == nil {
+			return data.Path
+		}
+
+	case strings.HasPrefix(route, "/v3/srtconns/"):
+		if data, err := a.SRTServer.APIConnsGet(id); err == nil {
+			return data.Path
+		}
+
+	case strings.HasPrefix(route, "/v3/httpflvconns/"):
+		if data, err := a.HTTPFLVServer.APIConnsGet(id); err == nil {
+			return data.Path
+		}
+
+	case strings.HasPrefix(route, "/v3/httptsconns/"):
+		if data, err := a.HTTPTSServer.APIConnsGet(id); err == nil {
+			return data.Path
+		}
+
+	case strings.HasPrefix(route, "/v3/recordings/sessions/"):
+		if data, err := a.PathManager.APIRecordingSessionsGet(id); err == nil {
+			return data.Path
+		}
+	}
+
+	return ""
+}
+
+func (a *API) onTokensList(ctx *gin.Context) {
+	tokens := a.TokenStore.List()
+
+	data := &defs.APITokenList{
+		Items: make([]*defs.APIToken, len(tokens)),
+	}
+
+	for i, t := range tokens {
+		data.Items[i] = tokenEntry(t)
+	}
+
+	data.ItemCount = len(data.Items)
+	pageCount, err := paginate(&data.Items, ctx.Query("itemsPerPage"), ctx.Query("page"))
+	if err != nil {
+		a.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	data.PageCount = pageCount
+
+	ctx.JSON(http.StatusOK, data)
+}
+
+func (a *API) onTokensCreate(ctx *gin.Context) {
+	var req defs.APITokenCreateReq
+	err := json.NewDecoder(ctx.Request.Body).Decode(&req)
+	if err != nil {
+		a.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	if req.Name == "" {
+		a.writeError(ctx, http.StatusBadRequest, fmt.Errorf("name is required"))
+		return
+	}
+
+	t, secret, err := a.TokenStore.Create(req.Name, req.Permissions, req.ExpiresAt)
+	if err != nil {
+		a.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, &defs.APITokenCreateRes{
+		APIToken: *tokenEntry(t),
+		Token:    secret,
+	})
+}
+
+func (a *API) onTokensRevoke(ctx *gin.Context) {
+	uuid, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		a.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	err = a.TokenStore.Revoke(uuid)
+	if err != nil {
+		if errors.Is(err, auth.ErrTokenNotFound) {
+			a.writeError(ctx, http.StatusNotFound, err)
+		} else {
+			a.writeError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
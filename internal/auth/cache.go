@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+)
+
+type cacheKey struct {
+	user     string
+	passHash [sha256.Size]byte
+	ip       string
+	action   conf.AuthAction
+	path     string
+	query    string
+}
+
+func newCacheKey(req *Request) cacheKey {
+	return cacheKey{
+		user:     req.User,
+		passHash: sha256.Sum256([]byte(req.Pass)),
+		ip:       req.IP.String(),
+		action:   req.Action,
+		path:     req.Path,
+		query:    req.Query,
+	}
+}
+
+type cacheEntry struct {
+	key      cacheKey
+	err      error
+	deadline time.Time
+}
+
+// cache stores results of authentication requests.
+type cache struct {
+	ttl         time.Duration
+	negativeTTL time.Duration
+	maxSize     int
+
+	mutex   sync.Mutex
+	entries map[cacheKey]*list.Element
+	order   *list.List
+}
+
+func newCache(ttl time.Duration, negativeTTL time.Duration, maxSize int) *cache {
+	return &cache{
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		maxSize:     maxSize,
+		entries:     make(map[cacheKey]*list.Element),
+		order:       list.New(),
+	}
+}
+
+// get returns a stored result, or nil if there's no valid result.
+func (c *cache) get(key cacheKey) *cacheEntry {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+
+	entry := el.Value.(*cacheEntry)
+
+	if !time.Now().Before(entry.deadline) {
+		c.order.Remove(el)
+		delete(c.entries, key)
+		return nil
+	}
+
+	return entry
+}
+
+// set stores a result. maxDeadline, when not zero, limits the lifetime of the entry.
+func (c *cache) set(key cacheKey, err error, maxDeadline time.Time) {
+	ttl := c.ttl
+	if err != nil {
+		ttl = c.negativeTTL
+	}
+
+	if ttl <= 0 || c.maxSize <= 0 {
+		return
+	}
+
+	deadline := time.Now().Add(ttl)
+	if !maxDeadline.IsZero() && maxDeadline.Before(deadline) {
+		deadline = maxDeadline
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		c.order.Remove(el)
+		delete(c.entries, key)
+	}
+
+	for c.order.Len() >= c.maxSize {
+		oldest := c.order.Front()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+
+	c.entries[key] = c.order.PushBack(&cacheEntry{
+		key:      key,
+		err:      err,
+		deadline: deadline,
+	})
+}
+
+func (c *cache) flush() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.entries = make(map[cacheKey]*list.Element)
+	c.order.Init()
+}
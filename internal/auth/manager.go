@@ -4,6 +4,7 @@ package auth
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -36,11 +37,14 @@ type Protocol string
 
 // protocols.
 const (
-	ProtocolRTSP   Protocol = "rtsp"
-	ProtocolRTMP   Protocol = "rtmp"
-	ProtocolHLS    Protocol = "hls"
-	ProtocolWebRTC Protocol = "webrtc"
-	ProtocolSRT    Protocol = "srt"
+	ProtocolRTSP    Protocol = "rtsp"
+	ProtocolRTMP    Protocol = "rtmp"
+	ProtocolHLS     Protocol = "hls"
+	ProtocolWebRTC  Protocol = "webrtc"
+	ProtocolSRT     Protocol = "srt"
+	ProtocolHTTPFLV Protocol = "httpflv"
+	ProtocolHTTPTS  Protocol = "httpts"
+	ProtocolDASH    Protocol = "dash"
 )
 
 // Request is an authentication request.
@@ -58,11 +62,30 @@ type Request struct {
 	RTSPRequest *base.Request
 	RTSPBaseURL *base.URL
 	RTSPNonce   string
+
+	// only for ActionAPI, ActionMetrics, ActionPprof, ActionPlayback
+	Token    string
+	Endpoint string
+}
+
+// transientError is an error that does not depend on the request,
+// and therefore is not cached.
+type transientError struct {
+	err error
+}
+
+// Error implements the error interface.
+func (e transientError) Error() string {
+	return e.err.Error()
 }
 
 // Error is a authentication error.
 type Error struct {
 	Message string
+
+	// the request doesn't contain credentials,
+	// therefore the client is just being asked to provide them.
+	AskCredentials bool
 }
 
 // Error implements the error interface.
@@ -70,23 +93,27 @@ func (e Error) Error() string {
 	return "authentication failed: " + e.Message
 }
 
+func matchesPath(permPath string, path string) bool {
+	switch {
+	case permPath == "":
+		return true
+
+	case strings.HasPrefix(permPath, "~"):
+		regexp, err := regexp.Compile(permPath[1:])
+		return err == nil && regexp.MatchString(path)
+
+	default:
+		return permPath == path
+	}
+}
+
 func matchesPermission(perms []conf.AuthInternalUserPermission, req *Request) bool {
 	for _, perm := range perms {
 		if perm.Action == req.Action {
 			if perm.Action == conf.AuthActionPublish ||
 				perm.Action == conf.AuthActionRead ||
 				perm.Action == conf.AuthActionPlayback {
-				switch {
-				case perm.Path == "":
-					return true
-
-				case strings.HasPrefix(perm.Path, "~"):
-					regexp, err := regexp.Compile(perm.Path[1:])
-					if err == nil && regexp.MatchString(req.Path) {
-						return true
-					}
-
-				case perm.Path == req.Path:
+				if matchesPath(perm.Path, req.Path) {
 					return true
 				}
 			} else {
@@ -105,18 +132,30 @@ type customClaims struct {
 
 // Manager is the authentication manager.
 type Manager struct {
-	Method          conf.AuthMethod
-	InternalUsers   []conf.AuthInternalUser
-	HTTPAddress     string
-	HTTPExclude     []conf.AuthInternalUserPermission
-	JWTJWKS         string
-	ReadTimeout     time.Duration
-	RTSPAuthMethods []headers.AuthMethod
+	Method           conf.AuthMethod
+	InternalUsers    []conf.AuthInternalUser
+	HTTPAddress      string
+	HTTPExclude      []conf.AuthInternalUserPermission
+	JWTJWKS          string
+	ReadTimeout      time.Duration
+	RTSPAuthMethods  []headers.AuthMethod
+	Tokens           *TokenStore
+	CacheTTL         time.Duration
+	CacheNegativeTTL time.Duration
+	CacheSize        int
 
 	mutex          sync.RWMutex
 	jwtHTTPClient  *http.Client
 	jwtLastRefresh time.Time
 	jwtKeyFunc     keyfunc.Keyfunc
+	cache          *cache
+}
+
+// Initialize initializes Manager.
+func (m *Manager) Initialize() {
+	if m.CacheTTL > 0 || m.CacheNegativeTTL > 0 {
+		m.cache = newCache(m.CacheTTL, m.CacheNegativeTTL, m.CacheSize)
+	}
 }
 
 // ReloadInternalUsers reloads InternalUsers.
@@ -126,15 +165,34 @@ func (m *Manager) ReloadInternalUsers(u []conf.AuthInternalUser) {
 	m.InternalUsers = u
 }
 
+// FlushCache removes all cached authentication results.
+func (m *Manager) FlushCache() {
+	if m.cache != nil {
+		m.cache.flush()
+	}
+}
+
 // Authenticate authenticates a request.
 func (m *Manager) Authenticate(req *Request) error {
 	err := m.authenticateInner(req)
 	if err != nil {
-		return Error{Message: err.Error()}
+		return Error{
+			Message:        err.Error(),
+			AskCredentials: !hasCredentials(req),
+		}
 	}
 	return nil
 }
 
+func hasCredentials(req *Request) bool {
+	if req.User != "" || req.Pass != "" || req.Token != "" {
+		return true
+	}
+
+	v, _ := url.ParseQuery(req.Query)
+	return v.Get("jwt") != ""
+}
+
 func (m *Manager) authenticateInner(req *Request) error {
 	// if this is a RTSP request, fill username and password
 	var rtspAuthHeader headers.Authorization
@@ -155,16 +213,49 @@ func (m *Manager) authenticateInner(req *Request) error {
 		}
 	}
 
+	// API tokens take precedence over the authentication method
+	if req.Token != "" && m.Tokens != nil {
+		return m.Tokens.authenticate(req)
+	}
+
 	switch m.Method {
 	case conf.AuthMethodInternal:
 		return m.authenticateInternal(req, &rtspAuthHeader)
 
-	case conf.AuthMethodHTTP:
-		return m.authenticateHTTP(req)
-
 	default:
-		return m.authenticateJWT(req)
+		return m.authenticateExternal(req)
+	}
+}
+
+// authenticateExternal authenticates a request with the HTTP or JWT method,
+// caching results when enabled.
+func (m *Manager) authenticateExternal(req *Request) error {
+	if m.cache == nil {
+		_, err := m.authenticateExternalInner(req)
+		return err
 	}
+
+	key := newCacheKey(req)
+
+	if entry := m.cache.get(key); entry != nil {
+		return entry.err
+	}
+
+	deadline, err := m.authenticateExternalInner(req)
+
+	var terr transientError
+	if !errors.As(err, &terr) {
+		m.cache.set(key, err, deadline)
+	}
+
+	return err
+}
+
+func (m *Manager) authenticateExternalInner(req *Request) (time.Time, error) {
+	if m.Method == conf.AuthMethodHTTP {
+		return time.Time{}, m.authenticateHTTP(req)
+	}
+	return m.authenticateJWT(req)
 }
 
 func (m *Manager) authenticateInternal(req *Request, rtspAuthHeader *headers.Authorization) error {
@@ -245,47 +336,59 @@ func (m *Manager) authenticateHTTP(req *Request) error {
 
 	res, err := http.Post(m.HTTPAddress, "application/json", bytes.NewReader(enc))
 	if err != nil {
-		return fmt.Errorf("HTTP request failed: %w", err)
+		return transientError{fmt.Errorf("HTTP request failed: %w", err)}
 	}
 	defer res.Body.Close()
 
 	if res.StatusCode < 200 || res.StatusCode > 299 {
-		if resBody, err := io.ReadAll(res.Body); err == nil && len(resBody) != 0 {
-			return fmt.Errorf("server replied with code %d: %s", res.StatusCode, string(resBody))
+		err = fmt.Errorf("server replied with code %d", res.StatusCode)
+		if resBody, err2 := io.ReadAll(res.Body); err2 == nil && len(resBody) != 0 {
+			err = fmt.Errorf("server replied with code %d: %s", res.StatusCode, string(resBody))
 		}
 
-		return fmt.Errorf("server replied with code %d", res.StatusCode)
+		// server errors do not depend on the request and must not be cached
+		if res.StatusCode >= 500 {
+			return transientError{err}
+		}
+
+		return err
 	}
 
 	return nil
 }
 
-func (m *Manager) authenticateJWT(req *Request) error {
+// authenticateJWT authenticates a request with a JWT.
+// It returns the expiration time of the JWT, if any.
+func (m *Manager) authenticateJWT(req *Request) (time.Time, error) {
 	keyfunc, err := m.pullJWTJWKS()
 	if err != nil {
-		return err
+		return time.Time{}, transientError{err}
 	}
 
 	v, err := url.ParseQuery(req.Query)
 	if err != nil {
-		return err
+		return time.Time{}, err
 	}
 
 	if len(v["jwt"]) != 1 {
-		return fmt.Errorf("JWT not provided")
+		return time.Time{}, fmt.Errorf("JWT not provided")
 	}
 
 	var customClaims customClaims
 	_, err = jwt.ParseWithClaims(v["jwt"][0], &customClaims, keyfunc)
 	if err != nil {
-		return err
+		return time.Time{}, err
 	}
 
 	if !matchesPermission(customClaims.MediaMTXPermissions, req) {
-		return fmt.Errorf("user doesn't have permission to perform action")
+		return time.Time{}, fmt.Errorf("user doesn't have permission to perform action")
 	}
 
-	return nil
+	if customClaims.ExpiresAt != nil {
+		return customClaims.ExpiresAt.Time, nil
+	}
+
+	return time.Time{}, nil
 }
 
 func (m *Manager) pullJWTJWKS() (jwt.Keyfunc, error) {
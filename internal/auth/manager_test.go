@@ -289,6 +289,97 @@ func TestAuthHTTPExclude(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestAuthHTTPCache(t *testing.T) {
+	calls := 0
+
+	httpServ := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+
+			var in struct {
+				User string `json:"user"`
+			}
+			err := json.NewDecoder(r.Body).Decode(&in)
+			require.NoError(t, err)
+
+			switch in.User {
+			case "testpublisher":
+			case "unavailable":
+				w.WriteHeader(http.StatusServiceUnavailable)
+			default:
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		}),
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:9120")
+	require.NoError(t, err)
+
+	go httpServ.Serve(ln)
+	defer httpServ.Shutdown(context.Background())
+
+	m := Manager{
+		Method:           conf.AuthMethodHTTP,
+		HTTPAddress:      "http://127.0.0.1:9120/auth",
+		CacheTTL:         time.Minute,
+		CacheNegativeTTL: 200 * time.Millisecond,
+		CacheSize:        2,
+	}
+	m.Initialize()
+
+	req := func(user string, path string) *Request {
+		return &Request{
+			User:     user,
+			Pass:     "testpass",
+			IP:       net.ParseIP("127.0.0.1"),
+			Action:   conf.AuthActionPublish,
+			Path:     path,
+			Protocol: ProtocolRTSP,
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		err = m.Authenticate(req("testpublisher", "teststream"))
+		require.NoError(t, err)
+	}
+	require.Equal(t, 1, calls)
+
+	for i := 0; i < 2; i++ {
+		err = m.Authenticate(req("invalid", "teststream"))
+		require.Error(t, err)
+	}
+	require.Equal(t, 2, calls)
+
+	// negative results expire earlier
+	time.Sleep(300 * time.Millisecond)
+
+	err = m.Authenticate(req("invalid", "teststream"))
+	require.Error(t, err)
+	require.Equal(t, 3, calls)
+
+	// oldest entries are removed when the cache is full
+	err = m.Authenticate(req("testpublisher", "otherstream"))
+	require.NoError(t, err)
+	require.Equal(t, 4, calls)
+
+	err = m.Authenticate(req("testpublisher", "teststream"))
+	require.NoError(t, err)
+	require.Equal(t, 5, calls)
+
+	m.FlushCache()
+
+	err = m.Authenticate(req("testpublisher", "otherstream"))
+	require.NoError(t, err)
+	require.Equal(t, 6, calls)
+
+	// server errors are not cached
+	for i := 0; i < 2; i++ {
+		err = m.Authenticate(req("unavailable", "teststream"))
+		require.EqualError(t, err, "authentication failed: server replied with code 503")
+	}
+	require.Equal(t, 8, calls)
+}
+
 func TestAuthJWT(t *testing.T) {
 	// taken from
 	// https://github.com/MicahParks/jwkset/blob/master/examples/http_server/main.go
@@ -0,0 +1,283 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/google/uuid"
+)
+
+// TokenPrefix is the prefix of all API tokens.
+const TokenPrefix = "mtx_"
+
+// ErrTokenNotFound is returned when a token is not found.
+var ErrTokenNotFound = errors.New("token not found")
+
+func hashToken(secret string) string {
+	h := sha256.Sum256([]byte(secret))
+	return "sha256:" + base64.StdEncoding.EncodeToString(h[:])
+}
+
+func matchesEndpoint(pattern string, endpoint string) bool {
+	if !strings.Contains(pattern, "*") {
+		return pattern == endpoint
+	}
+
+	re, err := regexp.Compile("^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*") + "$")
+	return err == nil && re.MatchString(endpoint)
+}
+
+// TokenFromHeader returns the API token contained in the Authorization header, if any.
+func TokenFromHeader(h http.Header) string {
+	v := h.Get("Authorization")
+
+	if !strings.HasPrefix(v, "Bearer ") {
+		return ""
+	}
+
+	v = strings.TrimSpace(v[len("Bearer "):])
+
+	if !strings.HasPrefix(v, TokenPrefix) {
+		return ""
+	}
+
+	return v
+}
+
+// TokenPermission is a permission of an API token.
+type TokenPermission struct {
+	// api, metrics or playback.
+	Action conf.AuthAction `json:"action"`
+
+	// HTTP endpoint. An empty value means any endpoint.
+	// Asterisks can be used as wildcards.
+	Endpoint string `json:"endpoint"`
+
+	// path. An empty value means any path.
+	// Regular expressions can be used by using a tilde as prefix.
+	Path string `json:"path"`
+}
+
+func (p TokenPermission) validate() error {
+	switch p.Action {
+	case conf.AuthActionAPI, conf.AuthActionMetrics, conf.AuthActionPlayback:
+	default:
+		return fmt.Errorf("action '%s' is not supported by tokens", p.Action)
+	}
+
+	if strings.HasPrefix(p.Path, "~") {
+		_, err := regexp.Compile(p.Path[1:])
+		if err != nil {
+			return fmt.Errorf("invalid regular expression: %s", p.Path[1:])
+		}
+	}
+
+	return nil
+}
+
+func (p TokenPermission) matches(req *Request) bool {
+	return p.Action == req.Action &&
+		(p.Endpoint == "" || matchesEndpoint(p.Endpoint, req.Endpoint)) &&
+		(p.Path == "" || matchesPath(p.Path, req.Path))
+}
+
+// Token is an API token.
+type Token struct {
+	ID          uuid.UUID         `json:"id"`
+	Name        string            `json:"name"`
+	Hash        string            `json:"hash"`
+	Permissions []TokenPermission `json:"permissions"`
+	Created     time.Time         `json:"created"`
+	ExpiresAt   *time.Time        `json:"expiresAt"`
+}
+
+func (t *Token) expired(now time.Time) bool {
+	return t.ExpiresAt != nil && !now.Before(*t.ExpiresAt)
+}
+
+// TokenStore stores API tokens into a file.
+// Only hashes of tokens are stored.
+type TokenStore struct {
+	FilePath string
+
+	mutex  sync.RWMutex
+	tokens []*Token
+}
+
+// Initialize initializes TokenStore.
+func (s *TokenStore) Initialize() error {
+	byts, err := os.ReadFile(s.FilePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var tokens []*Token
+	err = json.Unmarshal(byts, &tokens)
+	if err != nil {
+		return fmt.Errorf("unable to load tokens: %w", err)
+	}
+
+	s.tokens = tokens
+
+	return nil
+}
+
+func (s *TokenStore) save() error {
+	byts, err := json.MarshalIndent(s.tokens, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(s.FilePath)
+
+	err = os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file and then rename it, in order to never leave a partial file on disk.
+	tmp, err := os.CreateTemp(dir, filepath.Base(s.FilePath)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(byts)
+	tmp.Close()
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.FilePath)
+}
+
+// Create creates a token. The returned secret is not stored and cannot be retrieved later.
+func (s *TokenStore) Create(
+	name string,
+	permissions []TokenPermission,
+	expiresAt *time.Time,
+) (*Token, string, error) {
+	if len(permissions) == 0 {
+		return nil, "", fmt.Errorf("at least one permission is required")
+	}
+
+	for _, perm := range permissions {
+		err := perm.validate()
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	var buf [32]byte
+	_, err := rand.Read(buf[:])
+	if err != nil {
+		return nil, "", err
+	}
+
+	secret := TokenPrefix + base64.RawURLEncoding.EncodeToString(buf[:])
+
+	t := &Token{
+		ID:          uuid.New(),
+		Name:        name,
+		Hash:        hashToken(secret),
+		Permissions: permissions,
+		Created:     time.Now(),
+		ExpiresAt:   expiresAt,
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.tokens = append(s.tokens, t)
+
+	err = s.save()
+	if err != nil {
+		s.tokens = s.tokens[:len(s.tokens)-1]
+		return nil, "", err
+	}
+
+	return t, secret, nil
+}
+
+// List returns all tokens.
+func (s *TokenStore) List() []*Token {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	out := make([]*Token, len(s.tokens))
+	copy(out, s.tokens)
+	return out
+}
+
+// Revoke deletes a token.
+func (s *TokenStore) Revoke(id uuid.UUID) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i, t := range s.tokens {
+		if t.ID == id {
+			prev := s.tokens
+			s.tokens = append(append([]*Token(nil), s.tokens[:i]...), s.tokens[i+1:]...)
+
+			err := s.save()
+			if err != nil {
+				s.tokens = prev
+				return err
+			}
+
+			return nil
+		}
+	}
+
+	return ErrTokenNotFound
+}
+
+// get returns the token that corresponds to a secret.
+func (s *TokenStore) get(secret string) (*Token, error) {
+	hash := hashToken(secret)
+	now := time.Now()
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for _, t := range s.tokens {
+		if t.Hash == hash {
+			if t.expired(now) {
+				return nil, fmt.Errorf("token is expired")
+			}
+			return t, nil
+		}
+	}
+
+	return nil, ErrTokenNotFound
+}
+
+func (s *TokenStore) authenticate(req *Request) error {
+	t, err := s.get(req.Token)
+	if err != nil {
+		return err
+	}
+
+	for _, perm := range t.Permissions {
+		if perm.matches(req) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("token doesn't have permission to perform action")
+}
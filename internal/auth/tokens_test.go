@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenFromHeader(t *testing.T) {
+	for _, ca := range []struct {
+		name   string
+		header string
+		token  string
+	}{
+		{
+			"token",
+			"Bearer mtx_abc",
+			"mtx_abc",
+		},
+		{
+			"other bearer",
+			"Bearer eyJhbGciOiJSUzI1NiJ9",
+			"",
+		},
+		{
+			"basic",
+			"Basic bXl1c2VyOm15cGFzcw==",
+			"",
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			h := http.Header{}
+			h.Set("Authorization", ca.header)
+			require.Equal(t, ca.token, TokenFromHeader(h))
+		})
+	}
+}
+
+func TestAuthToken(t *testing.T) {
+	dir, err := os.MkdirTemp("", "mediamtx-tokens")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	fpath := filepath.Join(dir, "tokens.json")
+
+	s := &TokenStore{FilePath: fpath}
+	err = s.Initialize()
+	require.NoError(t, err)
+
+	_, _, err = s.Create("invalid", []TokenPermission{{Action: conf.AuthActionPublish}}, nil)
+	require.EqualError(t, err, "action 'publish' is not supported by tokens")
+
+	tok, secret, err := s.Create("kicker", []TokenPermission{
+		{
+			Action:   conf.AuthActionAPI,
+			Endpoint: "/v3/*/kick/*",
+			Path:     "~^cam",
+		},
+		{
+			Action:   conf.AuthActionAPI,
+			Endpoint: "/v3/paths/*",
+		},
+	}, nil)
+	require.NoError(t, err)
+
+	expired := time.Now().Add(-time.Second)
+	_, expiredSecret, err := s.Create("expired", []TokenPermission{{Action: conf.AuthActionMetrics}}, &expired)
+	require.NoError(t, err)
+
+	byts, err := os.ReadFile(fpath)
+	require.NoError(t, err)
+	require.NotContains(t, string(byts), secret)
+
+	// tokens must be loaded from file
+	s = &TokenStore{FilePath: fpath}
+	err = s.Initialize()
+	require.NoError(t, err)
+	require.Len(t, s.List(), 2)
+
+	m := Manager{
+		Method: conf.AuthMethodInternal,
+		Tokens: s,
+	}
+
+	for _, ca := range []struct {
+		name string
+		req  Request
+		ok   bool
+	}{
+		{
+			"kick allowed",
+			Request{Token: secret, Action: conf.AuthActionAPI, Endpoint: "/v3/rtspsessions/kick/abc", Path: "cam1"},
+			true,
+		},
+		{
+			"kick wrong path",
+			Request{Token: secret, Action: conf.AuthActionAPI, Endpoint: "/v3/rtspsessions/kick/abc", Path: "other"},
+			false,
+		},
+		{
+			"paths allowed",
+			Request{Token: secret, Action: conf.AuthActionAPI, Endpoint: "/v3/paths/list"},
+			true,
+		},
+		{
+			"config denied",
+			Request{Token: secret, Action: conf.AuthActionAPI, Endpoint: "/v3/config/global/get"},
+			false,
+		},
+		{
+			"wrong action",
+			Request{Token: secret, Action: conf.AuthActionMetrics, Endpoint: "/metrics"},
+			false,
+		},
+		{
+			"expired",
+			Request{Token: expiredSecret, Action: conf.AuthActionMetrics, Endpoint: "/metrics"},
+			false,
+		},
+		{
+			"unknown",
+			Request{Token: "mtx_unknown", Action: conf.AuthActionAPI, Endpoint: "/v3/paths/list"},
+			false,
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			err := m.Authenticate(&ca.req)
+			if ca.ok {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+
+	err = s.Revoke(tok.ID)
+	require.NoError(t, err)
+
+	err = m.Authenticate(&Request{Token: secret, Action: conf.AuthActionAPI, Endpoint: "/v3/paths/list"})
+	require.Error(t, err)
+
+	err = s.Revoke(tok.ID)
+	require.Equal(t, ErrTokenNotFound, err)
+}
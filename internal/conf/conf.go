@@ -122,7 +122,9 @@ func anyPathHasDeprecatedCredentials(paths map[string]*OptionalPath) bool {
 // Conf is a configuration.
 type Conf struct {
 	// General
+	Include             []string        `json:"include"`
 	LogLevel            LogLevel        `json:"logLevel"`
+	LogFormat           LogFormat       `json:"logFormat"`
 	LogDestinations     LogDestinations `json:"logDestinations"`
 	LogFile             string          `json:"logFile"`
 	ReadTimeout         StringDuration  `json:"readTimeout"`
@@ -132,11 +134,17 @@ type Conf struct {
 	UDPMaxPayloadSize   int             `json:"udpMaxPayloadSize"`
 	Metrics             bool            `json:"metrics"`
 	MetricsAddress      string          `json:"metricsAddress"`
+	OTLP                bool            `json:"otlp"`
+	OTLPAddress         string          `json:"otlpAddress"`
+	OTLPInterval        StringDuration  `json:"otlpInterval"`
 	PPROF               bool            `json:"pprof"`
 	PPROFAddress        string          `json:"pprofAddress"`
 	RunOnConnect        string          `json:"runOnConnect"`
 	RunOnConnectRestart bool            `json:"runOnConnectRestart"`
 	RunOnDisconnect     string          `json:"runOnDisconnect"`
+	WebhookTimeout      StringDuration  `json:"webhookTimeout"`
+	WebhookRetries      int             `json:"webhookRetries"`
+	WebhookSecret       string          `json:"webhookSecret"`
 
 	// Authentication
 	AuthMethod                AuthMethod                   `json:"authMethod"`
@@ -145,15 +153,33 @@ type Conf struct {
 	ExternalAuthenticationURL *string                      `json:"externalAuthenticationURL,omitempty"` // deprecated
 	AuthHTTPExclude           []AuthInternalUserPermission `json:"authHTTPExclude"`
 	AuthJWTJWKS               string                       `json:"authJWTJWKS"`
+	AuthCacheTTL              StringDuration               `json:"authCacheTTL"`
+	AuthCacheNegativeTTL      StringDuration               `json:"authCacheNegativeTTL"`
+	AuthCacheSize             int                          `json:"authCacheSize"`
 
 	// API
-	API        bool   `json:"api"`
-	APIAddress string `json:"apiAddress"`
+	API              bool   `json:"api"`
+	APIAddress       string `json:"apiAddress"`
+	APIPersistConfig bool   `json:"apiPersistConfig"`
+	APITokensFile    string `json:"apiTokensFile"`
 
 	// Playback
 	Playback        bool   `json:"playback"`
 	PlaybackAddress string `json:"playbackAddress"`
 
+	// Record
+	RecordMinFreeSpace StringSize `json:"recordMinFreeSpace"`
+
+	// Record upload
+	RecordUploadEndpoint        string         `json:"recordUploadEndpoint"`
+	RecordUploadRegion          string         `json:"recordUploadRegion"`
+	RecordUploadBucket          string         `json:"recordUploadBucket"`
+	RecordUploadAccessKeyID     string         `json:"recordUploadAccessKeyID"`
+	RecordUploadSecretAccessKey string         `json:"recordUploadSecretAccessKey"`
+	RecordUploadPartSize        StringSize     `json:"recordUploadPartSize"`
+	RecordUploadQueuePath       string         `json:"recordUploadQueuePath"`
+	RecordUploadTimeout         StringDuration `json:"recordUploadTimeout"`
+
 	// RTSP server
 	RTSP              bool             `json:"rtsp"`
 	RTSPDisable       *bool            `json:"rtspDisable,omitempty"` // deprecated
@@ -221,6 +247,33 @@ type Conf struct {
 	SRT        bool   `json:"srt"`
 	SRTAddress string `json:"srtAddress"`
 
+	// HTTP-FLV server
+	HTTPFLV               bool       `json:"httpflv"`
+	HTTPFLVAddress        string     `json:"httpflvAddress"`
+	HTTPFLVEncryption     bool       `json:"httpflvEncryption"`
+	HTTPFLVServerKey      string     `json:"httpflvServerKey"`
+	HTTPFLVServerCert     string     `json:"httpflvServerCert"`
+	HTTPFLVAllowOrigin    string     `json:"httpflvAllowOrigin"`
+	HTTPFLVTrustedProxies IPNetworks `json:"httpflvTrustedProxies"`
+
+	// HTTP-TS server
+	HTTPTS               bool       `json:"httpts"`
+	HTTPTSAddress        string     `json:"httptsAddress"`
+	HTTPTSEncryption     bool       `json:"httptsEncryption"`
+	HTTPTSServerKey      string     `json:"httptsServerKey"`
+	HTTPTSServerCert     string     `json:"httptsServerCert"`
+	HTTPTSAllowOrigin    string     `json:"httptsAllowOrigin"`
+	HTTPTSTrustedProxies IPNetworks `json:"httptsTrustedProxies"`
+
+	// DASH server
+	DASH            bool   `json:"dash"`
+	DASHAddress     string `json:"dashAddress"`
+	DASHEncryption  bool   `json:"dashEncryption"`
+	DASHServerKey   string `json:"dashServerKey"`
+	DASHServerCert  string `json:"dashServerCert"`
+	DASHAlwaysRemux bool   `json:"dashAlwaysRemux"`
+	DASHLowLatency  bool   `json:"dashLowLatency"`
+
 	// Record (deprecated)
 	Record                *bool           `json:"record,omitempty"`                // deprecated
 	RecordPath            *string         `json:"recordPath,omitempty"`            // deprecated
@@ -232,6 +285,9 @@ type Conf struct {
 	// Path defaults
 	PathDefaults Path `json:"pathDefaults"`
 
+	// Path templates
+	PathTemplates map[string]*OptionalPath `json:"pathTemplates"`
+
 	// Paths
 	OptionalPaths map[string]*OptionalPath `json:"paths"`
 	Paths         map[string]*Path         `json:"-"` // filled by Check()
@@ -239,7 +295,9 @@ type Conf struct {
 
 func (conf *Conf) setDefaults() {
 	// General
+	conf.Include = []string{}
 	conf.LogLevel = LogLevel(logger.Info)
+	conf.LogFormat = LogFormat(logger.FormatText)
 	conf.LogDestinations = LogDestinations{logger.DestinationStdout}
 	conf.LogFile = "mediamtx.log"
 	conf.ReadTimeout = 10 * StringDuration(time.Second)
@@ -247,7 +305,11 @@ func (conf *Conf) setDefaults() {
 	conf.WriteQueueSize = 512
 	conf.UDPMaxPayloadSize = 1472
 	conf.MetricsAddress = ":9998"
+	conf.OTLPAddress = "http://localhost:4318"
+	conf.OTLPInterval = 10 * StringDuration(time.Second)
 	conf.PPROFAddress = ":9999"
+	conf.WebhookTimeout = 10 * StringDuration(time.Second)
+	conf.WebhookRetries = 3
 
 	// Authentication
 	conf.AuthInternalUsers = []AuthInternalUser{
@@ -295,12 +357,20 @@ func (conf *Conf) setDefaults() {
 		},
 	}
 
+	conf.AuthCacheSize = 1000
+
 	// API
 	conf.APIAddress = ":9997"
 
 	// Playback server
 	conf.PlaybackAddress = ":9996"
 
+	// Record upload
+	conf.RecordUploadRegion = "us-east-1"
+	conf.RecordUploadPartSize = 16 * 1024 * 1024
+	conf.RecordUploadQueuePath = "./recordings_upload_queue.json"
+	conf.RecordUploadTimeout = 5 * StringDuration(time.Minute)
+
 	// RTSP server
 	conf.RTSP = true
 	conf.Protocols = Protocols{
@@ -354,6 +424,23 @@ func (conf *Conf) setDefaults() {
 	conf.SRT = true
 	conf.SRTAddress = ":8890"
 
+	// HTTP-FLV server
+	conf.HTTPFLVAddress = ":8080"
+	conf.HTTPFLVServerKey = "server.key"
+	conf.HTTPFLVServerCert = "server.crt"
+	conf.HTTPFLVAllowOrigin = "*"
+
+	// HTTP-TS server
+	conf.HTTPTSAddress = ":8892"
+	conf.HTTPTSServerKey = "server.key"
+	conf.HTTPTSServerCert = "server.crt"
+	conf.HTTPTSAllowOrigin = "*"
+
+	// DASH server
+	conf.DASHAddress = ":8891"
+	conf.DASHServerKey = "server.key"
+	conf.DASHServerCert = "server.crt"
+
 	conf.PathDefaults.setDefaults()
 }
 
@@ -396,31 +483,52 @@ func (conf *Conf) loadFromFile(fpath string, defaultConfPaths []string) (string,
 		}
 	}
 
-	byts, err := os.ReadFile(fpath)
+	byts, err := readFile(fpath)
 	if err != nil {
 		return "", err
 	}
 
-	if key, ok := os.LookupEnv("RTSP_CONFKEY"); ok { // legacy format
-		byts, err = decrypt.Decrypt(key, byts)
+	err = yaml.Load(byts, conf)
+	if err != nil {
+		return "", err
+	}
+
+	files, err := IncludedFiles(fpath, conf.Include)
+	if err != nil {
+		return "", err
+	}
+
+	if len(files) != 0 {
+		err = conf.loadIncluded(fpath, files)
 		if err != nil {
 			return "", err
 		}
 	}
 
-	if key, ok := os.LookupEnv("MTX_CONFKEY"); ok {
+	return fpath, nil
+}
+
+func readFile(fpath string) ([]byte, error) {
+	byts, err := os.ReadFile(fpath)
+	if err != nil {
+		return nil, err
+	}
+
+	if key, ok := os.LookupEnv("RTSP_CONFKEY"); ok { // legacy format
 		byts, err = decrypt.Decrypt(key, byts)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 	}
 
-	err = yaml.Load(byts, conf)
-	if err != nil {
-		return "", err
+	if key, ok := os.LookupEnv("MTX_CONFKEY"); ok {
+		byts, err = decrypt.Decrypt(key, byts)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return fpath, nil
+	return byts, nil
 }
 
 // Clone clones the configuration.
@@ -441,16 +549,33 @@ func (conf Conf) Clone() *Conf {
 
 // Validate checks the configuration for errors.
 func (conf *Conf) Validate() error {
+	var errs fieldErrors
+
 	// General
 
 	if conf.ReadBufferCount != nil {
 		conf.WriteQueueSize = *conf.ReadBufferCount
 	}
 	if (conf.WriteQueueSize & (conf.WriteQueueSize - 1)) != 0 {
-		return fmt.Errorf("'writeQueueSize' must be a power of two")
+		errs.add(newFieldError("writeQueueSize", "'writeQueueSize' must be a power of two"))
 	}
 	if conf.UDPMaxPayloadSize > 1472 {
-		return fmt.Errorf("'udpMaxPayloadSize' must be less than 1472")
+		errs.add(newFieldError("udpMaxPayloadSize", "'udpMaxPayloadSize' must be less than 1472"))
+	}
+	if conf.OTLP {
+		if !strings.HasPrefix(conf.OTLPAddress, "http://") &&
+			!strings.HasPrefix(conf.OTLPAddress, "https://") {
+			errs.add(newFieldError("otlpAddress", "'otlpAddress' must be a HTTP URL"))
+		}
+		if conf.OTLPInterval <= 0 {
+			errs.add(newFieldError("otlpInterval", "'otlpInterval' must be greater than zero"))
+		}
+	}
+	if conf.WebhookTimeout <= 0 {
+		errs.add(newFieldError("webhookTimeout", "'webhookTimeout' must be greater than zero"))
+	}
+	if conf.WebhookRetries < 0 {
+		errs.add(newFieldError("webhookRetries", "'webhookRetries' must be positive"))
 	}
 
 	// Authentication
@@ -462,12 +587,12 @@ func (conf *Conf) Validate() error {
 	if conf.AuthHTTPAddress != "" &&
 		!strings.HasPrefix(conf.AuthHTTPAddress, "http://") &&
 		!strings.HasPrefix(conf.AuthHTTPAddress, "https://") {
-		return fmt.Errorf("'externalAuthenticationURL' must be a HTTP URL")
+		errs.add(newFieldError("authHTTPAddress", "'externalAuthenticationURL' must be a HTTP URL"))
 	}
 	if conf.AuthJWTJWKS != "" &&
 		!strings.HasPrefix(conf.AuthJWTJWKS, "http://") &&
 		!strings.HasPrefix(conf.AuthJWTJWKS, "https://") {
-		return fmt.Errorf("'authJWTJWKS' must be a HTTP URL")
+		errs.add(newFieldError("authJWTJWKS", "'authJWTJWKS' must be a HTTP URL"))
 	}
 	deprecatedCredentialsMode := false
 	if credentialIsNotEmpty(conf.PathDefaults.PublishUser) ||
@@ -506,15 +631,45 @@ func (conf *Conf) Validate() error {
 		}
 		deprecatedCredentialsMode = true
 	}
+	if conf.AuthCacheTTL < 0 {
+		errs.add(newFieldError("authCacheTTL", "'authCacheTTL' must be positive"))
+	}
+	if conf.AuthCacheNegativeTTL < 0 {
+		errs.add(newFieldError("authCacheNegativeTTL", "'authCacheNegativeTTL' must be positive"))
+	}
+	if conf.AuthCacheSize < 0 {
+		errs.add(newFieldError("authCacheSize", "'authCacheSize' must be positive"))
+	}
 	switch conf.AuthMethod {
 	case AuthMethodHTTP:
 		if conf.AuthHTTPAddress == "" {
-			return fmt.Errorf("'authHTTPAddress' is empty")
+			errs.add(newFieldError("authHTTPAddress", "'authHTTPAddress' is empty"))
 		}
 
 	case AuthMethodJWT:
 		if conf.AuthJWTJWKS == "" {
-			return fmt.Errorf("'authJWTJWKS' is empty")
+			errs.add(newFieldError("authJWTJWKS", "'authJWTJWKS' is empty"))
+		}
+	}
+
+	// Record upload
+
+	if conf.RecordUploadEndpoint != "" {
+		if !strings.HasPrefix(conf.RecordUploadEndpoint, "http://") &&
+			!strings.HasPrefix(conf.RecordUploadEndpoint, "https://") {
+			errs.add(newFieldError("recordUploadEndpoint", "'recordUploadEndpoint' must be a HTTP URL"))
+		}
+		if conf.RecordUploadBucket == "" {
+			errs.add(newFieldError("recordUploadBucket", "'recordUploadBucket' is empty"))
+		}
+		if conf.RecordUploadPartSize < 5*1024*1024 {
+			errs.add(newFieldError("recordUploadPartSize", "'recordUploadPartSize' must be at least 5MiB"))
+		}
+		if conf.RecordUploadQueuePath == "" {
+			errs.add(newFieldError("recordUploadQueuePath", "'recordUploadQueuePath' is empty"))
+		}
+		if conf.RecordUploadTimeout <= 0 {
+			errs.add(newFieldError("recordUploadTimeout", "'recordUploadTimeout' must be greater than zero"))
 		}
 	}
 
@@ -525,10 +680,11 @@ func (conf *Conf) Validate() error {
 	}
 	if conf.Encryption == EncryptionStrict {
 		if _, ok := conf.Protocols[Protocol(gortsplib.TransportUDP)]; ok {
-			return fmt.Errorf("strict encryption can't be used with the UDP transport protocol")
+			errs.add(newFieldError("encryption", "strict encryption can't be used with the UDP transport protocol"))
 		}
 		if _, ok := conf.Protocols[Protocol(gortsplib.TransportUDPMulticast)]; ok {
-			return fmt.Errorf("strict encryption can't be used with the UDP-multicast transport protocol")
+			errs.add(newFieldError("encryption",
+				"strict encryption can't be used with the UDP-multicast transport protocol"))
 		}
 	}
 	if conf.AuthMethods != nil {
@@ -536,11 +692,14 @@ func (conf *Conf) Validate() error {
 	}
 	if contains(conf.RTSPAuthMethods, headers.AuthDigestMD5) {
 		if conf.AuthMethod != AuthMethodInternal {
-			return fmt.Errorf("when RTSP digest is enabled, the only supported auth method is 'internal'")
+			errs.add(newFieldError("rtspAuthMethods",
+				"when RTSP digest is enabled, the only supported auth method is 'internal'"))
 		}
 		for _, user := range conf.AuthInternalUsers {
 			if user.User.IsHashed() || user.Pass.IsHashed() {
-				return fmt.Errorf("when RTSP digest is enabled, hashed credentials cannot be used")
+				errs.add(newFieldError("rtspAuthMethods",
+					"when RTSP digest is enabled, hashed credentials cannot be used"))
+				break
 			}
 		}
 	}
@@ -591,21 +750,32 @@ func (conf *Conf) Validate() error {
 		if !strings.HasPrefix(server.URL, "stun:") &&
 			!strings.HasPrefix(server.URL, "turn:") &&
 			!strings.HasPrefix(server.URL, "turns:") {
-			return fmt.Errorf("invalid ICE server: '%s'", server.URL)
+			errs.add(newFieldError("webrtcICEServers2", "invalid ICE server: '%s'", server.URL))
 		}
 	}
 	if conf.WebRTCLocalUDPAddress == "" &&
 		conf.WebRTCLocalTCPAddress == "" &&
 		len(conf.WebRTCICEServers2) == 0 {
-		return fmt.Errorf("at least one between 'webrtcLocalUDPAddress'," +
-			" 'webrtcLocalTCPAddress' or 'webrtcICEServers2' must be filled")
+		errs.add(newFieldError("webrtcLocalUDPAddress", "at least one between 'webrtcLocalUDPAddress',"+
+			" 'webrtcLocalTCPAddress' or 'webrtcICEServers2' must be filled"))
 	}
 	if conf.WebRTCLocalUDPAddress != "" || conf.WebRTCLocalTCPAddress != "" {
 		if !conf.WebRTCIPsFromInterfaces && len(conf.WebRTCAdditionalHosts) == 0 {
-			return fmt.Errorf("at least one between 'webrtcIPsFromInterfaces' or 'webrtcAdditionalHosts' must be filled")
+			errs.add(newFieldError("webrtcIPsFromInterfaces",
+				"at least one between 'webrtcIPsFromInterfaces' or 'webrtcAdditionalHosts' must be filled"))
 		}
 	}
 
+	// DASH
+
+	if conf.DASH && conf.HLSSegmentCount < 2 {
+		errs.add(newFieldError("hlsSegmentCount", "when DASH is enabled, 'hlsSegmentCount' must be greater than 1"))
+	}
+	if conf.DASH && conf.DASHLowLatency && conf.HLSPartDuration >= conf.HLSSegmentDuration {
+		errs.add(newFieldError("dashLowLatency",
+			"when DASH low-latency mode is enabled, 'hlsPartDuration' must be less than 'hlsSegmentDuration'"))
+	}
+
 	// Record (deprecated)
 	if conf.Record != nil {
 		conf.PathDefaults.Record = *conf.Record
@@ -630,12 +800,18 @@ func (conf *Conf) Validate() error {
 	for name := range conf.OptionalPaths {
 		if name == "all" || name == "all_others" || name == "~^.*$" {
 			if hasAllOthers {
-				return fmt.Errorf("all_others, all and '~^.*$' are aliases")
+				errs.add(newFieldError("paths", "all_others, all and '~^.*$' are aliases"))
+				break
 			}
 			hasAllOthers = true
 		}
 	}
 
+	err := validatePathTemplates(conf.PathTemplates)
+	if err != nil {
+		errs.add(err)
+	}
+
 	conf.Paths = make(map[string]*Path)
 
 	for _, name := range sortedKeys(conf.OptionalPaths) {
@@ -647,16 +823,22 @@ func (conf *Conf) Validate() error {
 			conf.OptionalPaths[name] = optional
 		}
 
-		pconf := newPath(&conf.PathDefaults, optional)
+		pconf, err := newPath(&conf.PathDefaults, conf.PathTemplates, optional)
+		if err != nil {
+			errs.add(wrapPathError(name, fmt.Errorf("path '%s': %w", name, err)))
+			continue
+		}
 		conf.Paths[name] = pconf
 
-		err := pconf.validate(conf, name, deprecatedCredentialsMode)
+		err = pconf.validate(conf, name, deprecatedCredentialsMode)
 		if err != nil {
-			return err
+			for _, err := range Errors(err) {
+				errs.add(wrapPathError(name, err))
+			}
 		}
 	}
 
-	return nil
+	return errs.err()
 }
 
 // UnmarshalJSON implements json.Unmarshaler.
@@ -670,16 +852,33 @@ func (conf *Conf) UnmarshalJSON(b []byte) error {
 }
 
 // Global returns the global part of Conf.
+// Secrets are redacted.
 func (conf *Conf) Global() *Global {
 	g := &Global{
 		Values: newGlobalValues(),
 	}
 	copyStructFields(g.Values, conf)
+
+	for _, name := range secretGlobalFields {
+		v := reflect.ValueOf(g.Values).Elem().FieldByName(name)
+		if v.String() != "" {
+			v.SetString(redactedSecret)
+		}
+	}
+
 	return g
 }
 
 // PatchGlobal patches the global configuration.
+// Redacted secrets are left unchanged.
 func (conf *Conf) PatchGlobal(optional *OptionalGlobal) {
+	for _, name := range secretGlobalFields {
+		v := reflect.ValueOf(optional.Values).Elem().FieldByName(name)
+		if !v.IsNil() && v.Elem().String() == redactedSecret {
+			v.Set(reflect.Zero(v.Type()))
+		}
+	}
+
 	copyStructFields(conf, optional.Values)
 }
 
@@ -688,6 +887,63 @@ func (conf *Conf) PatchPathDefaults(optional *OptionalPath) {
 	copyStructFields(&conf.PathDefaults, optional.Values)
 }
 
+// Merge returns a copy of the configuration with a partial configuration applied.
+// Global parameters are replaced, path defaults are replaced one by one,
+// path templates and paths are replaced one by one.
+// The returned configuration is not validated.
+func (conf Conf) Merge(partial map[string]interface{}) (*Conf, error) {
+	enc, err := json.Marshal(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	var merged map[string]interface{}
+	err = json.Unmarshal(enc, &merged)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, val := range partial {
+		switch key {
+		case "pathDefaults", "pathTemplates", "paths":
+			if val == nil {
+				continue
+			}
+
+			src, ok := val.(map[string]interface{})
+			if !ok {
+				return nil, newFieldError(key, "'%s' must be a map", key)
+			}
+
+			dest, _ := merged[key].(map[string]interface{})
+			if dest == nil {
+				dest = make(map[string]interface{})
+				merged[key] = dest
+			}
+
+			for k, v := range src {
+				dest[k] = v
+			}
+
+		default:
+			merged[key] = val
+		}
+	}
+
+	enc, err = json.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+
+	var dest Conf
+	err = json.Unmarshal(enc, &dest)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dest, nil
+}
+
 // AddPath adds a path.
 func (conf *Conf) AddPath(name string, p *OptionalPath) error {
 	if _, ok := conf.OptionalPaths[name]; ok {
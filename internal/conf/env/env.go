@@ -135,6 +135,9 @@ func loadEnvInternal(env map[string]string, prefix string, prv reflect.Value) er
 			}
 
 			// initialize only if there's at least one key
+			if prv.IsNil() {
+				prv.Set(reflect.New(rt))
+			}
 			if prv.Elem().IsNil() {
 				prv.Elem().Set(reflect.MakeMap(rt))
 			}
@@ -142,6 +145,23 @@ func loadEnvInternal(env map[string]string, prefix string, prv reflect.Value) er
 			mapKeyLower := strings.ToLower(mapKey)
 			nv := prv.Elem().MapIndex(reflect.ValueOf(mapKeyLower))
 			zero := reflect.Value{}
+
+			// map of values
+			if rt.Elem().Kind() != reflect.Pointer {
+				v := reflect.New(rt.Elem())
+				if nv != zero {
+					v.Elem().Set(nv)
+				}
+
+				err := loadEnvInternal(env, prefix+"_"+mapKey, v)
+				if err != nil {
+					return err
+				}
+
+				prv.Elem().SetMapIndex(reflect.ValueOf(mapKeyLower), v.Elem())
+				continue
+			}
+
 			if nv == zero {
 				nv = reflect.New(rt.Elem().Elem())
 				prv.Elem().SetMapIndex(reflect.ValueOf(mapKeyLower), nv)
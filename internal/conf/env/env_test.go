@@ -85,6 +85,8 @@ type testStruct struct {
 	MyDurationOpt            *myDuration          `json:"myDurationOpt"`
 	MyDurationOptUnset       *myDuration          `json:"myDurationOptUnset"`
 	MyMap                    map[string]*mapEntry `json:"myMap"`
+	MyMapString              map[string]string    `json:"myMapString"`
+	MyMapStringOpt           *map[string]string   `json:"myMapStringOpt"`
 	MySliceFloat             []float64            `json:"mySliceFloat"`
 	MySliceString            []string             `json:"mySliceString"`
 	MySliceStringEmpty       []string             `json:"mySliceStringEmpty"`
@@ -114,6 +116,8 @@ func TestLoad(t *testing.T) {
 		"MYPREFIX_MYMAP_MYKEY":                    "",
 		"MYPREFIX_MYMAP_MYKEY2_MYVALUE":           "asd",
 		"MYPREFIX_MYMAP_MYKEY2_MYSTRUCT_MYPARAM":  "456",
+		"MYPREFIX_MYMAPSTRING_MYKEY":              "val",
+		"MYPREFIX_MYMAPSTRINGOPT_MYKEY":           "val2",
 		"MYPREFIX_MYSLICEFLOAT":                   "0.5,0.5",
 		"MYPREFIX_MYSLICESTRING":                  "val1,val2",
 		"MYPREFIX_MYSLICESTRINGEMPTY":             "",
@@ -162,7 +166,9 @@ func TestLoad(t *testing.T) {
 				},
 			},
 		},
-		MySliceFloat: []float64{0.5, 0.5},
+		MyMapString:    map[string]string{"mykey": "val"},
+		MyMapStringOpt: &map[string]string{"mykey": "val2"},
+		MySliceFloat:   []float64{0.5, 0.5},
 		MySliceString: []string{
 			"val1",
 			"val2",
@@ -0,0 +1,108 @@
+package conf
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// FieldError is an error related to a configuration field.
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+// Error implements the error interface.
+func (e *FieldError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+func newFieldError(field string, format string, args ...interface{}) error {
+	return &FieldError{
+		Field: field,
+		Err:   fmt.Errorf(format, args...),
+	}
+}
+
+// fieldErrors is a list of errors found while validating a configuration.
+type fieldErrors []error
+
+// Error implements the error interface.
+func (e fieldErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap returns the wrapped errors.
+func (e fieldErrors) Unwrap() []error {
+	return e
+}
+
+func (e *fieldErrors) add(err error) {
+	*e = append(*e, Errors(err)...)
+}
+
+func (e fieldErrors) err() error {
+	switch len(e) {
+	case 0:
+		return nil
+	case 1:
+		return e[0]
+	}
+	return e
+}
+
+// Errors returns the errors contained into an error returned by Load or Validate.
+func Errors(err error) []error {
+	var fe fieldErrors
+	if errors.As(err, &fe) {
+		return fe
+	}
+	return []error{err}
+}
+
+// wrapPathError prefixes the field of an error with the path name.
+func wrapPathError(name string, err error) error {
+	field := "paths." + name
+
+	var fe *FieldError
+	if errors.As(err, &fe) {
+		field += "." + fe.Field
+	}
+
+	return &FieldError{
+		Field: field,
+		Err:   err,
+	}
+}
+
+// ErrorField returns the field an error returned by Load or Validate is related to.
+// Fields of paths are in the format "paths.name.field".
+// It returns an empty string if the field is not known.
+func ErrorField(err error) string {
+	var fe *FieldError
+	if errors.As(err, &fe) {
+		return fe.Field
+	}
+
+	var te *json.UnmarshalTypeError
+	if errors.As(err, &te) {
+		return te.Field
+	}
+
+	// errors of json.Decoder.DisallowUnknownFields() are not typed
+	if msg := err.Error(); strings.HasPrefix(msg, `json: unknown field "`) {
+		return strings.TrimSuffix(strings.TrimPrefix(msg, `json: unknown field "`), `"`)
+	}
+
+	return ""
+}
@@ -5,6 +5,14 @@ import (
 	"reflect"
 )
 
+// redactedSecret replaces secrets in the global configuration returned by the API.
+const redactedSecret = "xxxxx"
+
+// global fields that contain secrets.
+var secretGlobalFields = []string{
+	"RecordUploadSecretAccessKey",
+}
+
 var globalValuesType = func() reflect.Type {
 	var fields []reflect.StructField
 	rt := reflect.TypeOf(Conf{})
@@ -14,7 +22,8 @@ var globalValuesType = func() reflect.Type {
 		f := rt.Field(i)
 		j := f.Tag.Get("json")
 
-		if j != "-" && j != "pathDefaults" && j != "paths" {
+		// 'include' is not exposed, since it can't be changed at runtime.
+		if j != "-" && j != "include" && j != "pathDefaults" && j != "pathTemplates" && j != "paths" {
 			fields = append(fields, reflect.StructField{
 				Name: f.Name,
 				Type: f.Type,
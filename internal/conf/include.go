@@ -0,0 +1,192 @@
+package conf
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/bluenviron/mediamtx/internal/conf/yaml"
+)
+
+// IncludePatterns returns the glob patterns of the files that are merged
+// into the configuration file placed in fpath:
+// the entries of 'include', relative to the directory of the configuration file,
+// and every .yml file inside a directory that has the same name of the
+// configuration file and the .d extension (i.e. mediamtx.d/*.yml).
+// Patterns are absolute.
+func IncludePatterns(fpath string, include []string) []string {
+	abs, _ := filepath.Abs(fpath)
+	dir := filepath.Dir(abs)
+
+	patterns := make([]string, 0, len(include)+1)
+
+	for _, pattern := range include {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(dir, pattern)
+		}
+		patterns = append(patterns, pattern)
+	}
+
+	base := strings.TrimSuffix(filepath.Base(abs), filepath.Ext(abs))
+	patterns = append(patterns, filepath.Join(dir, base+".d", "*.yml"))
+
+	return patterns
+}
+
+func hasMeta(pattern string) bool {
+	return strings.ContainsAny(pattern, `*?[\`)
+}
+
+// IncludedFiles returns the files that are merged into the configuration file placed in fpath.
+func IncludedFiles(fpath string, include []string) ([]string, error) {
+	abs, _ := filepath.Abs(fpath)
+
+	var files []string
+	found := make(map[string]struct{})
+
+	for i, pattern := range IncludePatterns(fpath, include) {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid include pattern '%s': %w", pattern, err)
+		}
+
+		// files that are explicitly included must exist
+		if len(matches) == 0 && i < len(include) && !hasMeta(pattern) {
+			return nil, fmt.Errorf("included file '%s' not found", pattern)
+		}
+
+		for _, match := range matches {
+			if match == abs {
+				continue
+			}
+			if _, ok := found[match]; ok {
+				continue
+			}
+			found[match] = struct{}{}
+			files = append(files, match)
+		}
+	}
+
+	return files, nil
+}
+
+// mergeIncluded merges the content of an included file into the content of the configuration.
+// Global parameters override existing ones, path defaults and path templates are overridden one by one,
+// paths are added and can't be defined twice.
+func mergeIncluded(
+	dest map[string]interface{},
+	src map[string]interface{},
+	srcPath string,
+	pathOrigins map[string]string,
+) error {
+	for key, val := range src {
+		switch key {
+		case "include":
+			return fmt.Errorf("%s: 'include' can't be used in included files", srcPath)
+
+		case "pathDefaults", "pathTemplates":
+			srcDefaults, ok := val.(map[string]interface{})
+			if !ok {
+				if val == nil {
+					continue
+				}
+				return fmt.Errorf("%s: '%s' must be a map", srcPath, key)
+			}
+
+			destDefaults, _ := dest[key].(map[string]interface{})
+			if destDefaults == nil {
+				destDefaults = make(map[string]interface{})
+				dest[key] = destDefaults
+			}
+
+			for k, v := range srcDefaults {
+				destDefaults[k] = v
+			}
+
+		case "paths":
+			srcPaths, ok := val.(map[string]interface{})
+			if !ok {
+				if val == nil {
+					continue
+				}
+				return fmt.Errorf("%s: 'paths' must be a map", srcPath)
+			}
+
+			destPaths, _ := dest[key].(map[string]interface{})
+			if destPaths == nil {
+				destPaths = make(map[string]interface{})
+				dest[key] = destPaths
+			}
+
+			for name, pathConf := range srcPaths {
+				if origin, ok := pathOrigins[name]; ok {
+					return fmt.Errorf("path '%s' is defined in both %s and %s", name, origin, srcPath)
+				}
+				pathOrigins[name] = srcPath
+				destPaths[name] = pathConf
+			}
+
+		default:
+			dest[key] = val
+		}
+	}
+
+	return nil
+}
+
+func readConfFile(fpath string) (map[string]interface{}, error) {
+	byts, err := readFile(fpath)
+	if err != nil {
+		return nil, err
+	}
+
+	var m map[string]interface{}
+	err = yaml.Load(byts, &m)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fpath, err)
+	}
+
+	return m, nil
+}
+
+// loadIncluded loads the configuration file placed in fpath and merges
+// the given included files into it.
+func (conf *Conf) loadIncluded(fpath string, files []string) error {
+	merged, err := readConfFile(fpath)
+	if err != nil {
+		return err
+	}
+
+	if merged == nil {
+		merged = make(map[string]interface{})
+	}
+
+	pathOrigins := make(map[string]string)
+	if paths, ok := merged["paths"].(map[string]interface{}); ok {
+		for name := range paths {
+			pathOrigins[name] = fpath
+		}
+	}
+
+	for _, file := range files {
+		var m map[string]interface{}
+		m, err = readConfFile(file)
+		if err != nil {
+			return err
+		}
+
+		err = mergeIncluded(merged, m, file, pathOrigins)
+		if err != nil {
+			return err
+		}
+	}
+
+	byts, err := json.Marshal(merged)
+	if err != nil {
+		return err
+	}
+
+	*conf = Conf{}
+	return json.Unmarshal(byts, conf)
+}
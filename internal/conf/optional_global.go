@@ -16,7 +16,8 @@ var optionalGlobalValuesType = func() reflect.Type {
 		f := rt.Field(i)
 		j := f.Tag.Get("json")
 
-		if j != "-" && j != "pathDefaults" && j != "paths" {
+		// 'include' is not exposed, since it can't be changed at runtime.
+		if j != "-" && j != "include" && j != "pathDefaults" && j != "pathTemplates" && j != "paths" {
 			if !strings.Contains(j, ",omitempty") {
 				j += ",omitempty"
 			}
@@ -7,6 +7,7 @@ import (
 	gourl "net/url"
 	"reflect"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -45,6 +46,109 @@ func srtCheckPassphrase(passphrase string) error {
 	}
 }
 
+func validateUDPOutput(u string) error {
+	pu, err := gourl.Parse(u)
+	if err != nil || pu.Scheme != "udp" || pu.Port() == "" {
+		return fmt.Errorf("'%s' is not a valid UDP URL", u)
+	}
+
+	q := pu.Query()
+
+	if v := q.Get("ttl"); v != "" {
+		ttl, err := strconv.Atoi(v)
+		if err != nil || ttl < 0 || ttl > 255 {
+			return fmt.Errorf("invalid ttl: '%s'", v)
+		}
+	}
+
+	if v := q.Get("pkt_size"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil || size <= 0 || (size%188) != 0 {
+			return fmt.Errorf("invalid pkt_size: '%s' (must be a multiple of 188)", v)
+		}
+	}
+
+	return nil
+}
+
+func checkSourceURL(source string) error {
+	switch {
+	case strings.HasPrefix(source, "rtsp://") ||
+		strings.HasPrefix(source, "rtsps://"):
+		_, err := base.ParseURL(source)
+		if err != nil {
+			return fmt.Errorf("'%s' is not a valid URL", source)
+		}
+
+	case strings.HasPrefix(source, "rtmp://") ||
+		strings.HasPrefix(source, "rtmps://"):
+		u, err := gourl.Parse(source)
+		if err != nil {
+			return fmt.Errorf("'%s' is not a valid URL", source)
+		}
+
+		if u.User != nil {
+			pass, _ := u.User.Password()
+			user := u.User.Username()
+			if user != "" && pass == "" ||
+				user == "" && pass != "" {
+				return fmt.Errorf("username and password must be both provided")
+			}
+		}
+
+	case strings.HasPrefix(source, "http://") ||
+		strings.HasPrefix(source, "https://"):
+		u, err := gourl.Parse(source)
+		if err != nil {
+			return fmt.Errorf("'%s' is not a valid URL", source)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("'%s' is not a valid URL", source)
+		}
+
+		if u.User != nil {
+			pass, _ := u.User.Password()
+			user := u.User.Username()
+			if user != "" && pass == "" ||
+				user == "" && pass != "" {
+				return fmt.Errorf("username and password must be both provided")
+			}
+		}
+
+	case strings.HasPrefix(source, "udp://"):
+		_, _, err := net.SplitHostPort(source[len("udp://"):])
+		if err != nil {
+			return fmt.Errorf("'%s' is not a valid UDP URL", source)
+		}
+
+	case strings.HasPrefix(source, "sdp://"):
+
+	case strings.HasPrefix(source, "file://"):
+		if source == "file://" {
+			return fmt.Errorf("'%s' is not a valid file URL", source)
+		}
+
+	case strings.HasPrefix(source, "srt://"):
+
+		_, err := gourl.Parse(source)
+		if err != nil {
+			return fmt.Errorf("'%s' is not a valid URL", source)
+		}
+
+	case strings.HasPrefix(source, "whep://") ||
+		strings.HasPrefix(source, "wheps://"):
+		_, err := gourl.Parse(source)
+		if err != nil {
+			return fmt.Errorf("'%s' is not a valid URL", source)
+		}
+
+	default:
+		return fmt.Errorf("invalid source: '%s'", source)
+	}
+
+	return nil
+}
+
 // FindPathConf returns the configuration corresponding to the given path name.
 func FindPathConf(pathConfs map[string]*Path, name string) (string, *Path, []string, error) {
 	err := isValidPathName(name)
@@ -86,14 +190,20 @@ type Path struct {
 	Name   string         `json:"name"` // filled by Check()
 
 	// General
-	Source                     string         `json:"source"`
-	SourceFingerprint          string         `json:"sourceFingerprint"`
-	SourceOnDemand             bool           `json:"sourceOnDemand"`
-	SourceOnDemandStartTimeout StringDuration `json:"sourceOnDemandStartTimeout"`
-	SourceOnDemandCloseAfter   StringDuration `json:"sourceOnDemandCloseAfter"`
-	MaxReaders                 int            `json:"maxReaders"`
-	SRTReadPassphrase          string         `json:"srtReadPassphrase"`
-	Fallback                   string         `json:"fallback"`
+	Template                   string            `json:"template"`
+	Vars                       map[string]string `json:"vars"`
+	Source                     string            `json:"source"`
+	Sources                    []string          `json:"sources"`
+	SourceSwitchTimeout        StringDuration    `json:"sourceSwitchTimeout"`
+	SourceFingerprint          string            `json:"sourceFingerprint"`
+	SourceSDP                  string            `json:"sourceSDP"`
+	SourceFileLoop             bool              `json:"sourceFileLoop"`
+	SourceOnDemand             bool              `json:"sourceOnDemand"`
+	SourceOnDemandStartTimeout StringDuration    `json:"sourceOnDemandStartTimeout"`
+	SourceOnDemandCloseAfter   StringDuration    `json:"sourceOnDemandCloseAfter"`
+	MaxReaders                 int               `json:"maxReaders"`
+	SRTReadPassphrase          string            `json:"srtReadPassphrase"`
+	Fallback                   string            `json:"fallback"`
 
 	// Record
 	Record                bool           `json:"record"`
@@ -103,6 +213,17 @@ type Path struct {
 	RecordPartDuration    StringDuration `json:"recordPartDuration"`
 	RecordSegmentDuration StringDuration `json:"recordSegmentDuration"`
 	RecordDeleteAfter     StringDuration `json:"recordDeleteAfter"`
+	RecordMaxSize         StringSize     `json:"recordMaxSize"`
+	RecordPreRoll         StringDuration `json:"recordPreRoll"`
+
+	// Record upload
+	RecordUpload            bool   `json:"recordUpload"`
+	RecordUploadPrefix      string `json:"recordUploadPrefix"`
+	RecordUploadDeleteLocal bool   `json:"recordUploadDeleteLocal"`
+
+	// Push
+	Push      []string `json:"push"`
+	UDPOutput string   `json:"udpOutput"`
 
 	// Authentication (deprecated)
 	PublishUser *Credential `json:"publishUser,omitempty"` // deprecated
@@ -183,7 +304,11 @@ type Path struct {
 
 func (pconf *Path) setDefaults() {
 	// General
+	pconf.Vars = map[string]string{}
 	pconf.Source = "publisher"
+	pconf.Sources = []string{}
+	pconf.SourceSwitchTimeout = 10 * StringDuration(time.Second)
+	pconf.SourceFileLoop = true
 	pconf.SourceOnDemandStartTimeout = 10 * StringDuration(time.Second)
 	pconf.SourceOnDemandCloseAfter = 10 * StringDuration(time.Second)
 
@@ -194,6 +319,12 @@ func (pconf *Path) setDefaults() {
 	pconf.RecordSegmentDuration = 3600 * StringDuration(time.Second)
 	pconf.RecordDeleteAfter = 24 * 3600 * StringDuration(time.Second)
 
+	// Record upload
+	pconf.RecordUploadPrefix = "%path/"
+
+	// Push
+	pconf.Push = []string{}
+
 	// Publisher source
 	pconf.OverridePublisher = true
 
@@ -223,11 +354,42 @@ func (pconf *Path) setDefaults() {
 	pconf.RunOnDemandCloseAfter = 10 * StringDuration(time.Second)
 }
 
-func newPath(defaults *Path, partial *OptionalPath) *Path {
+func newPath(defaults *Path, templates map[string]*OptionalPath, partial *OptionalPath) (*Path, error) {
 	pconf := &Path{}
 	copyStructFields(pconf, defaults)
+	vars := mergeVars(nil, pconf.Vars)
+
+	templateName := defaults.Template
+	if v := partialTemplate(partial); v != nil {
+		templateName = *v
+	}
+
+	if templateName != "" {
+		template, ok := templates[templateName]
+		if !ok {
+			return nil, newFieldError("template", "template '%s' not found", templateName)
+		}
+
+		if template != nil {
+			copyStructFields(pconf, template.Values)
+			vars = mergeVars(vars, pconf.Vars)
+		}
+	}
+
 	copyStructFields(pconf, partial.Values)
-	return pconf
+	pconf.Vars = mergeVars(vars, pconf.Vars)
+	pconf.Template = templateName
+
+	// expand variables only in paths that use them, in order not to alter
+	// commands of other paths, that may contain {{...}} for other purposes.
+	if pconf.Template != "" || len(pconf.Vars) != 0 {
+		err := pconf.expandVars()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return pconf, nil
 }
 
 // Clone clones the configuration.
@@ -273,107 +435,133 @@ func (pconf *Path) validate(
 		pconf.Regexp = regexp
 	}
 
-	// General
-
-	if pconf.Source != "publisher" && pconf.Source != "redirect" &&
-		pconf.Regexp != nil && !pconf.SourceOnDemand {
-		return fmt.Errorf("a path with a regular expression (or path 'all') and a static source" +
-			" must have 'sourceOnDemand' set to true")
-	}
-	switch {
-	case pconf.Source == "publisher":
+	var errs fieldErrors
 
-	case strings.HasPrefix(pconf.Source, "rtsp://") ||
-		strings.HasPrefix(pconf.Source, "rtsps://"):
-		_, err := base.ParseURL(pconf.Source)
-		if err != nil {
-			return fmt.Errorf("'%s' is not a valid URL", pconf.Source)
-		}
+	// General
 
-	case strings.HasPrefix(pconf.Source, "rtmp://") ||
-		strings.HasPrefix(pconf.Source, "rtmps://"):
-		u, err := gourl.Parse(pconf.Source)
-		if err != nil {
-			return fmt.Errorf("'%s' is not a valid URL", pconf.Source)
+	if len(pconf.Sources) != 0 {
+		if pconf.Source != "publisher" {
+			errs.add(newFieldError("sources", "'source' and 'sources' can't be used together"))
 		}
 
-		if u.User != nil {
-			pass, _ := u.User.Password()
-			user := u.User.Username()
-			if user != "" && pass == "" ||
-				user == "" && pass != "" {
-				return fmt.Errorf("username and password must be both provided")
+		for _, source := range pconf.Sources {
+			err := checkSourceURL(source)
+			if err != nil {
+				errs.add(&FieldError{Field: "sources", Err: err})
 			}
 		}
 
-	case strings.HasPrefix(pconf.Source, "http://") ||
-		strings.HasPrefix(pconf.Source, "https://"):
-		u, err := gourl.Parse(pconf.Source)
-		if err != nil {
-			return fmt.Errorf("'%s' is not a valid URL", pconf.Source)
-		}
-		if u.Scheme != "http" && u.Scheme != "https" {
-			return fmt.Errorf("'%s' is not a valid URL", pconf.Source)
+		if pconf.SourceSwitchTimeout <= 0 {
+			errs.add(newFieldError("sourceSwitchTimeout", "'sourceSwitchTimeout' must be greater than zero"))
 		}
+	}
 
-		if u.User != nil {
-			pass, _ := u.User.Password()
-			user := u.User.Username()
-			if user != "" && pass == "" ||
-				user == "" && pass != "" {
-				return fmt.Errorf("username and password must be both provided")
-			}
-		}
+	source := pconf.PrimarySource()
 
-	case strings.HasPrefix(pconf.Source, "udp://"):
-		_, _, err := net.SplitHostPort(pconf.Source[len("udp://"):])
-		if err != nil {
-			return fmt.Errorf("'%s' is not a valid UDP URL", pconf.Source)
-		}
+	if source != "publisher" && source != "redirect" &&
+		pconf.Regexp != nil && !pconf.SourceOnDemand {
+		errs.add(newFieldError("sourceOnDemand", "a path with a regular expression (or path 'all') and a static source"+
+			" must have 'sourceOnDemand' set to true"))
+	}
+	switch {
+	case len(pconf.Sources) != 0:
+		// entries of 'sources' have already been checked
 
-	case strings.HasPrefix(pconf.Source, "srt://"):
+	case source == "publisher":
 
-		_, err := gourl.Parse(pconf.Source)
-		if err != nil {
-			return fmt.Errorf("'%s' is not a valid URL", pconf.Source)
-		}
+	case source == "redirect":
 
-	case strings.HasPrefix(pconf.Source, "whep://") ||
-		strings.HasPrefix(pconf.Source, "wheps://"):
-		_, err := gourl.Parse(pconf.Source)
-		if err != nil {
-			return fmt.Errorf("'%s' is not a valid URL", pconf.Source)
-		}
-
-	case pconf.Source == "redirect":
-
-	case pconf.Source == "rpiCamera":
+	case source == "rpiCamera":
 
 	default:
-		return fmt.Errorf("invalid source: '%s'", pconf.Source)
+		err := checkSourceURL(source)
+		if err != nil {
+			errs.add(&FieldError{Field: "source", Err: err})
+		}
+	}
+	if strings.HasPrefix(source, "sdp://") {
+		if source == "sdp://" && pconf.SourceSDP == "" {
+			errs.add(newFieldError("sourceSDP", "'sourceSDP' is required when source is 'sdp://'"))
+		}
+		if source != "sdp://" && pconf.SourceSDP != "" {
+			errs.add(newFieldError("sourceSDP", "'sourceSDP' can't be used together with a SDP file"))
+		}
+	} else if pconf.SourceSDP != "" {
+		errs.add(newFieldError("sourceSDP", "'sourceSDP' can be used only when source is 'sdp://'"))
 	}
 	if pconf.SourceOnDemand {
-		if pconf.Source == "publisher" {
-			return fmt.Errorf("'sourceOnDemand' is useless when source is 'publisher'")
+		if source == "publisher" {
+			errs.add(newFieldError("sourceOnDemand", "'sourceOnDemand' is useless when source is 'publisher'"))
 		}
 	}
 	if pconf.SRTReadPassphrase != "" {
 		err := srtCheckPassphrase(pconf.SRTReadPassphrase)
 		if err != nil {
-			return fmt.Errorf("invalid 'readRTPassphrase': %w", err)
+			errs.add(newFieldError("srtReadPassphrase", "invalid 'readRTPassphrase': %w", err))
 		}
 	}
 	if pconf.Fallback != "" {
 		if strings.HasPrefix(pconf.Fallback, "/") {
 			err := isValidPathName(pconf.Fallback[1:])
 			if err != nil {
-				return fmt.Errorf("'%s': %w", pconf.Fallback, err)
+				errs.add(newFieldError("fallback", "'%s': %w", pconf.Fallback, err))
 			}
 		} else {
 			_, err := base.ParseURL(pconf.Fallback)
 			if err != nil {
-				return fmt.Errorf("'%s' is not a valid RTSP URL", pconf.Fallback)
+				errs.add(newFieldError("fallback", "'%s' is not a valid RTSP URL", pconf.Fallback))
+			}
+		}
+	}
+
+	// Record
+
+	if pconf.RecordPreRoll < 0 {
+		errs.add(newFieldError("recordPreRoll", "'recordPreRoll' must be positive"))
+	}
+
+	// Record upload
+
+	if pconf.RecordUpload && conf.RecordUploadEndpoint == "" {
+		errs.add(newFieldError("recordUpload", "'recordUpload' requires 'recordUploadEndpoint' to be set"))
+	}
+
+	// Push
+
+	for _, target := range pconf.Push {
+		switch {
+		case strings.HasPrefix(target, "rtsp://") ||
+			strings.HasPrefix(target, "rtsps://"):
+			_, err := base.ParseURL(target)
+			if err != nil {
+				errs.add(newFieldError("push", "'%s' is not a valid URL", target))
+			}
+
+		case strings.HasPrefix(target, "rtmp://") ||
+			strings.HasPrefix(target, "rtmps://") ||
+			strings.HasPrefix(target, "srt://") ||
+			strings.HasPrefix(target, "whip://") ||
+			strings.HasPrefix(target, "whips://"):
+			_, err := gourl.Parse(target)
+			if err != nil {
+				errs.add(newFieldError("push", "'%s' is not a valid URL", target))
+			}
+
+		case strings.HasPrefix(target, "udp://"):
+			err := validateUDPOutput(target)
+			if err != nil {
+				errs.add(newFieldError("push", "%v", err))
 			}
+
+		default:
+			errs.add(newFieldError("push", "invalid push target: '%s'", target))
+		}
+	}
+
+	if pconf.UDPOutput != "" {
+		err := validateUDPOutput(pconf.UDPOutput)
+		if err != nil {
+			errs.add(newFieldError("udpOutput", "%v", err))
 		}
 	}
 
@@ -452,12 +640,13 @@ func (pconf *Path) validate(
 	}
 	if pconf.SRTPublishPassphrase != "" {
 		if pconf.Source != "publisher" {
-			return fmt.Errorf("'srtPublishPassphase' can only be used when source is 'publisher'")
+			errs.add(newFieldError("srtPublishPassphrase",
+				"'srtPublishPassphase' can only be used when source is 'publisher'"))
 		}
 
 		err := srtCheckPassphrase(pconf.SRTPublishPassphrase)
 		if err != nil {
-			return fmt.Errorf("invalid 'srtPublishPassphrase': %w", err)
+			errs.add(newFieldError("srtPublishPassphrase", "invalid 'srtPublishPassphrase': %w", err))
 		}
 	}
 
@@ -474,12 +663,9 @@ func (pconf *Path) validate(
 
 	if pconf.Source == "redirect" {
 		if pconf.SourceRedirect == "" {
-			return fmt.Errorf("source redirect must be filled")
-		}
-
-		_, err := base.ParseURL(pconf.SourceRedirect)
-		if err != nil {
-			return fmt.Errorf("'%s' is not a valid RTSP URL", pconf.SourceRedirect)
+			errs.add(newFieldError("sourceRedirect", "source redirect must be filled"))
+		} else if _, err := base.ParseURL(pconf.SourceRedirect); err != nil {
+			errs.add(newFieldError("sourceRedirect", "'%s' is not a valid RTSP URL", pconf.SourceRedirect))
 		}
 	}
 
@@ -489,61 +675,64 @@ func (pconf *Path) validate(
 		for otherName, otherPath := range conf.Paths {
 			if otherPath != pconf && otherPath != nil &&
 				otherPath.Source == "rpiCamera" && otherPath.RPICameraCamID == pconf.RPICameraCamID {
-				return fmt.Errorf("'rpiCamera' with same camera ID %d is used as source in two paths, '%s' and '%s'",
-					pconf.RPICameraCamID, name, otherName)
+				errs.add(newFieldError("rpiCameraCamID",
+					"'rpiCamera' with same camera ID %d is used as source in two paths, '%s' and '%s'",
+					pconf.RPICameraCamID, name, otherName))
+				break
 			}
 		}
 	}
 	switch pconf.RPICameraExposure {
 	case "normal", "short", "long", "custom":
 	default:
-		return fmt.Errorf("invalid 'rpiCameraExposure' value")
+		errs.add(newFieldError("rpiCameraExposure", "invalid 'rpiCameraExposure' value"))
 	}
 	switch pconf.RPICameraAWB {
 	case "auto", "incandescent", "tungsten", "fluorescent", "indoor", "daylight", "cloudy", "custom":
 	default:
-		return fmt.Errorf("invalid 'rpiCameraAWB' value")
+		errs.add(newFieldError("rpiCameraAWB", "invalid 'rpiCameraAWB' value"))
 	}
 	if len(pconf.RPICameraAWBGains) != 2 {
-		return fmt.Errorf("invalid 'rpiCameraAWBGains' value")
+		errs.add(newFieldError("rpiCameraAWBGains", "invalid 'rpiCameraAWBGains' value"))
 	}
 	switch pconf.RPICameraDenoise {
 	case "off", "cdn_off", "cdn_fast", "cdn_hq":
 	default:
-		return fmt.Errorf("invalid 'rpiCameraDenoise' value")
+		errs.add(newFieldError("rpiCameraDenoise", "invalid 'rpiCameraDenoise' value"))
 	}
 	switch pconf.RPICameraMetering {
 	case "centre", "spot", "matrix", "custom":
 	default:
-		return fmt.Errorf("invalid 'rpiCameraMetering' value")
+		errs.add(newFieldError("rpiCameraMetering", "invalid 'rpiCameraMetering' value"))
 	}
 	switch pconf.RPICameraAfMode {
 	case "auto", "manual", "continuous":
 	default:
-		return fmt.Errorf("invalid 'rpiCameraAfMode' value")
+		errs.add(newFieldError("rpiCameraAfMode", "invalid 'rpiCameraAfMode' value"))
 	}
 	switch pconf.RPICameraAfRange {
 	case "normal", "macro", "full":
 	default:
-		return fmt.Errorf("invalid 'rpiCameraAfRange' value")
+		errs.add(newFieldError("rpiCameraAfRange", "invalid 'rpiCameraAfRange' value"))
 	}
 	switch pconf.RPICameraAfSpeed {
 	case "normal", "fast":
 	default:
-		return fmt.Errorf("invalid 'rpiCameraAfSpeed' value")
+		errs.add(newFieldError("rpiCameraAfSpeed", "invalid 'rpiCameraAfSpeed' value"))
 	}
 
 	// Hooks
 
 	if pconf.RunOnInit != "" && pconf.Regexp != nil {
-		return fmt.Errorf("a path with a regular expression (or path 'all')" +
-			" does not support option 'runOnInit'; use another path")
+		errs.add(newFieldError("runOnInit", "a path with a regular expression (or path 'all')"+
+			" does not support option 'runOnInit'; use another path"))
 	}
 	if (pconf.RunOnDemand != "" || pconf.RunOnUnDemand != "") && pconf.Source != "publisher" {
-		return fmt.Errorf("'runOnDemand' and 'runOnUnDemand' can be used only when source is 'publisher'")
+		errs.add(newFieldError("runOnDemand",
+			"'runOnDemand' and 'runOnUnDemand' can be used only when source is 'publisher'"))
 	}
 
-	return nil
+	return errs.err()
 }
 
 // Equal checks whether two Paths are equal.
@@ -551,19 +740,32 @@ func (pconf *Path) Equal(other *Path) bool {
 	return reflect.DeepEqual(pconf, other)
 }
 
+// PrimarySource returns the source of the path,
+// that is the first entry of 'sources' when it is filled.
+func (pconf Path) PrimarySource() string {
+	if len(pconf.Sources) != 0 {
+		return pconf.Sources[0]
+	}
+	return pconf.Source
+}
+
 // HasStaticSource checks whether the path has a static source.
 func (pconf Path) HasStaticSource() bool {
-	return strings.HasPrefix(pconf.Source, "rtsp://") ||
-		strings.HasPrefix(pconf.Source, "rtsps://") ||
-		strings.HasPrefix(pconf.Source, "rtmp://") ||
-		strings.HasPrefix(pconf.Source, "rtmps://") ||
-		strings.HasPrefix(pconf.Source, "http://") ||
-		strings.HasPrefix(pconf.Source, "https://") ||
-		strings.HasPrefix(pconf.Source, "udp://") ||
-		strings.HasPrefix(pconf.Source, "srt://") ||
-		strings.HasPrefix(pconf.Source, "whep://") ||
-		strings.HasPrefix(pconf.Source, "wheps://") ||
-		pconf.Source == "rpiCamera"
+	source := pconf.PrimarySource()
+
+	return strings.HasPrefix(source, "rtsp://") ||
+		strings.HasPrefix(source, "rtsps://") ||
+		strings.HasPrefix(source, "rtmp://") ||
+		strings.HasPrefix(source, "rtmps://") ||
+		strings.HasPrefix(source, "http://") ||
+		strings.HasPrefix(source, "https://") ||
+		strings.HasPrefix(source, "udp://") ||
+		strings.HasPrefix(source, "sdp://") ||
+		strings.HasPrefix(source, "file://") ||
+		strings.HasPrefix(source, "srt://") ||
+		strings.HasPrefix(source, "whep://") ||
+		strings.HasPrefix(source, "wheps://") ||
+		source == "rpiCamera"
 }
 
 // HasOnDemandStaticSource checks whether the path has a on demand static source.
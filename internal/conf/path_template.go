@@ -0,0 +1,102 @@
+package conf
+
+import (
+	"reflect"
+	"regexp"
+)
+
+var reVar = regexp.MustCompile(`\{\{\s*([a-zA-Z0-9_]+)\s*\}\}`)
+
+func partialTemplate(partial *OptionalPath) *string {
+	return reflect.ValueOf(partial.Values).Elem().FieldByName("Template").Interface().(*string)
+}
+
+func mergeVars(dest map[string]string, src map[string]string) map[string]string {
+	if dest == nil {
+		dest = make(map[string]string)
+	}
+	for k, v := range src {
+		dest[k] = v
+	}
+	return dest
+}
+
+func expandVars(field string, in string, vars map[string]string) (string, error) {
+	var err error
+
+	out := reVar.ReplaceAllStringFunc(in, func(match string) string {
+		name := reVar.FindStringSubmatch(match)[1]
+
+		v, ok := vars[name]
+		if !ok {
+			if err == nil {
+				err = newFieldError(field, "variable '%s' is not defined", name)
+			}
+			return match
+		}
+
+		return v
+	})
+
+	return out, err
+}
+
+// expandVars replaces {{name}} with the value of variable 'name'
+// in the source and in commands.
+func (pconf *Path) expandVars() error {
+	fields := []struct {
+		name  string
+		value *string
+	}{
+		{"source", &pconf.Source},
+		{"recordPath", &pconf.RecordPath},
+		{"runOnInit", &pconf.RunOnInit},
+		{"runOnDemand", &pconf.RunOnDemand},
+		{"runOnUnDemand", &pconf.RunOnUnDemand},
+		{"runOnReady", &pconf.RunOnReady},
+		{"runOnNotReady", &pconf.RunOnNotReady},
+		{"runOnRead", &pconf.RunOnRead},
+		{"runOnUnread", &pconf.RunOnUnread},
+		{"runOnRecordSegmentCreate", &pconf.RunOnRecordSegmentCreate},
+		{"runOnRecordSegmentComplete", &pconf.RunOnRecordSegmentComplete},
+	}
+
+	for _, field := range fields {
+		v, err := expandVars(field.name, *field.value, pconf.Vars)
+		if err != nil {
+			return err
+		}
+		*field.value = v
+	}
+
+	// do not edit the slice of defaults or templates
+	sources := make([]string, len(pconf.Sources))
+	for i, source := range pconf.Sources {
+		v, err := expandVars("sources", source, pconf.Vars)
+		if err != nil {
+			return err
+		}
+		sources[i] = v
+	}
+	pconf.Sources = sources
+
+	return nil
+}
+
+func validatePathTemplates(templates map[string]*OptionalPath) error {
+	var errs fieldErrors
+
+	for _, name := range sortedKeys(templates) {
+		template := templates[name]
+		if template == nil {
+			continue
+		}
+
+		if partialTemplate(template) != nil {
+			errs.add(newFieldError("pathTemplates."+name+".template",
+				"template '%s': 'template' can't be used inside templates", name))
+		}
+	}
+
+	return errs.err()
+}
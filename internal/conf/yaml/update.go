@@ -0,0 +1,216 @@
+package yaml
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// convertNumbers converts JSON numbers into integers when possible,
+// in order to avoid writing them as floats or strings.
+func convertNumbers(i interface{}) interface{} {
+	switch x := i.(type) {
+	case map[string]interface{}:
+		for k, v := range x {
+			x[k] = convertNumbers(v)
+		}
+
+	case []interface{}:
+		for j, v := range x {
+			x[j] = convertNumbers(v)
+		}
+
+	case json.Number:
+		if v, err := x.Int64(); err == nil {
+			return v
+		}
+		v, _ := x.Float64()
+		return v
+	}
+
+	return i
+}
+
+func toGeneric(v interface{}) (map[string]interface{}, error) {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	d := json.NewDecoder(bytes.NewReader(buf))
+	d.UseNumber()
+
+	var out map[string]interface{}
+	err = d.Decode(&out)
+	if err != nil {
+		return nil, err
+	}
+
+	convertNumbers(out)
+
+	return out, nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	out := make([]string, 0, len(m))
+	for key := range m {
+		out = append(out, key)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func findKey(node *yamlv3.Node, key string) int {
+	for i := 0; i < len(node.Content)-1; i += 2 {
+		if node.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func encodeValue(v interface{}) (*yamlv3.Node, error) {
+	var n yamlv3.Node
+	err := n.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+func updateMapping(node *yamlv3.Node, prev map[string]interface{}, cur map[string]interface{}) error {
+	for _, key := range sortedKeys(cur) {
+		curVal := cur[key]
+		prevVal, inPrev := prev[key]
+
+		if inPrev && reflect.DeepEqual(prevVal, curVal) {
+			continue
+		}
+
+		i := findKey(node, key)
+
+		// maps are updated recursively, in order to write changed keys only.
+		if curMap, ok := curVal.(map[string]interface{}); ok {
+			prevMap, ok := prevVal.(map[string]interface{})
+			if !ok {
+				prevMap = map[string]interface{}{}
+			}
+
+			var valNode *yamlv3.Node
+
+			if i < 0 {
+				valNode = &yamlv3.Node{Kind: yamlv3.MappingNode, Tag: "!!map"}
+				node.Content = append(node.Content,
+					&yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!str", Value: key},
+					valNode)
+			} else {
+				valNode = node.Content[i+1]
+				if valNode.Kind != yamlv3.MappingNode {
+					*valNode = yamlv3.Node{
+						Kind:        yamlv3.MappingNode,
+						Tag:         "!!map",
+						HeadComment: valNode.HeadComment,
+						LineComment: valNode.LineComment,
+						FootComment: valNode.FootComment,
+					}
+				}
+			}
+
+			err := updateMapping(valNode, prevMap, curMap)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		valNode, err := encodeValue(curVal)
+		if err != nil {
+			return err
+		}
+
+		if i < 0 {
+			node.Content = append(node.Content,
+				&yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!str", Value: key},
+				valNode)
+		} else {
+			// keep comments of the existing value
+			oldNode := node.Content[i+1]
+			valNode.HeadComment = oldNode.HeadComment
+			valNode.LineComment = oldNode.LineComment
+			valNode.FootComment = oldNode.FootComment
+			node.Content[i+1] = valNode
+		}
+	}
+
+	// remove keys that have been deleted, like removed paths.
+	for _, key := range sortedKeys(prev) {
+		if _, ok := cur[key]; !ok {
+			if i := findKey(node, key); i >= 0 {
+				node.Content = append(node.Content[:i], node.Content[i+2:]...)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Update applies the differences between prev and cur to a YAML document.
+// Keys that are not touched by the differences, and comments, are preserved.
+func Update(buf []byte, prev interface{}, cur interface{}) ([]byte, error) {
+	prevMap, err := toGeneric(prev)
+	if err != nil {
+		return nil, err
+	}
+
+	curMap, err := toGeneric(cur)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc yamlv3.Node
+	err = yamlv3.Unmarshal(buf, &doc)
+	if err != nil {
+		return nil, err
+	}
+
+	// empty document
+	if doc.Kind == 0 {
+		doc = yamlv3.Node{
+			Kind: yamlv3.DocumentNode,
+			Content: []*yamlv3.Node{{
+				Kind: yamlv3.MappingNode,
+				Tag:  "!!map",
+			}},
+		}
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yamlv3.MappingNode {
+		return nil, fmt.Errorf("root element is not a map")
+	}
+
+	err = updateMapping(root, prevMap, curMap)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	enc := yamlv3.NewEncoder(&out)
+	enc.SetIndent(2)
+
+	err = enc.Encode(&doc)
+	if err != nil {
+		return nil, err
+	}
+
+	err = enc.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
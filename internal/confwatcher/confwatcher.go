@@ -2,8 +2,12 @@
 package confwatcher
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
@@ -19,6 +23,12 @@ type ConfWatcher struct {
 	inner       *fsnotify.Watcher
 	watchedPath string
 
+	ignoredMutex sync.Mutex
+	ignoredHash  []byte
+
+	includedMutex    sync.Mutex
+	includedPatterns []string
+
 	// in
 	terminate chan struct{}
 
@@ -77,15 +87,51 @@ outer:
 	for {
 		select {
 		case event := <-w.inner.Events:
+			eventAbsPath, _ := filepath.Abs(event.Name)
+			includedDirCreated := (event.Op&fsnotify.Create) == fsnotify.Create &&
+				w.isIncludedDirOrParent(eventAbsPath)
+
+			// a directory of included files was created: watch it
+			if includedDirCreated {
+				w.addIncludedDirs()
+			}
+
 			if time.Since(lastCalled) < minInterval {
 				continue
 			}
 
 			currentWatchedPath, _ := filepath.EvalSymlinks(w.watchedPath)
-			eventPath, _ := filepath.Abs(event.Name)
-			eventPath, _ = filepath.EvalSymlinks(eventPath)
+			eventPath, _ := filepath.EvalSymlinks(eventAbsPath)
+
+			if includedDirCreated {
+				// files may have been moved into the directory together with it
+				if !w.hasIncludedFiles() {
+					continue
+				}
+
+				time.Sleep(additionalWait)
+				lastCalled = time.Now()
+
+				select {
+				case w.signal <- struct{}{}:
+				case <-w.terminate:
+					break outer
+				}
+			} else if w.isIncluded(eventAbsPath) {
+				// included files can be added, changed or removed
+				if (event.Op & fsnotify.Chmod) == fsnotify.Chmod {
+					continue
+				}
+
+				time.Sleep(additionalWait)
+				lastCalled = time.Now()
 
-			if currentWatchedPath == "" {
+				select {
+				case w.signal <- struct{}{}:
+				case <-w.terminate:
+					break outer
+				}
+			} else if currentWatchedPath == "" {
 				// watched file was removed; wait for write event to trigger reload
 				previousWatchedPath = ""
 			} else if currentWatchedPath != previousWatchedPath ||
@@ -96,6 +142,10 @@ outer:
 				time.Sleep(additionalWait)
 				previousWatchedPath = currentWatchedPath
 
+				if w.isIgnored() {
+					continue
+				}
+
 				lastCalled = time.Now()
 
 				select {
@@ -117,6 +167,114 @@ outer:
 	w.inner.Close() //nolint:errcheck
 }
 
+// IgnoreContent makes the watcher ignore changes that result in the given file content.
+// It allows to write the configuration file without triggering a reload.
+func (w *ConfWatcher) IgnoreContent(byts []byte) {
+	h := sha256.Sum256(byts)
+
+	w.ignoredMutex.Lock()
+	defer w.ignoredMutex.Unlock()
+	w.ignoredHash = h[:]
+}
+
+func (w *ConfWatcher) isIgnored() bool {
+	w.ignoredMutex.Lock()
+	defer w.ignoredMutex.Unlock()
+
+	if w.ignoredHash == nil {
+		return false
+	}
+
+	byts, err := os.ReadFile(w.watchedPath)
+	if err != nil {
+		return false
+	}
+
+	h := sha256.Sum256(byts)
+	return bytes.Equal(h[:], w.ignoredHash)
+}
+
+// WatchIncluded makes the watcher watch also files that match the given glob patterns.
+// It replaces patterns passed previously.
+func (w *ConfWatcher) WatchIncluded(patterns []string) {
+	w.includedMutex.Lock()
+	w.includedPatterns = patterns
+	w.includedMutex.Unlock()
+
+	w.addIncludedDirs()
+}
+
+// includedDirs returns the directories of included patterns that do not contain wildcards.
+func (w *ConfWatcher) includedDirs() []string {
+	w.includedMutex.Lock()
+	defer w.includedMutex.Unlock()
+
+	var dirs []string
+
+	for _, pattern := range w.includedPatterns {
+		dir := filepath.Dir(pattern)
+		if !strings.ContainsAny(dir, `*?[\`) {
+			dirs = append(dirs, dir)
+		}
+	}
+
+	return dirs
+}
+
+// addIncludedDirs watches directories of included patterns.
+// When a directory doesn't exist, its nearest existing parent is watched,
+// in order to detect the creation of the directory.
+func (w *ConfWatcher) addIncludedDirs() {
+	for _, dir := range w.includedDirs() {
+		for {
+			if w.inner.Add(dir) == nil {
+				break
+			}
+
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				break
+			}
+			dir = parent
+		}
+	}
+}
+
+func (w *ConfWatcher) isIncludedDirOrParent(fpath string) bool {
+	for _, dir := range w.includedDirs() {
+		if dir == fpath || strings.HasPrefix(dir, fpath+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *ConfWatcher) hasIncludedFiles() bool {
+	w.includedMutex.Lock()
+	defer w.includedMutex.Unlock()
+
+	for _, pattern := range w.includedPatterns {
+		if matches, _ := filepath.Glob(pattern); len(matches) != 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (w *ConfWatcher) isIncluded(fpath string) bool {
+	w.includedMutex.Lock()
+	defer w.includedMutex.Unlock()
+
+	for _, pattern := range w.includedPatterns {
+		if ok, _ := filepath.Match(pattern, fpath); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
 // Watch returns a channel that is called after the configuration file has changed.
 func (w *ConfWatcher) Watch() chan struct{} {
 	return w.signal
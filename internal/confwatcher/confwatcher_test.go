@@ -2,6 +2,7 @@ package confwatcher
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -39,6 +40,37 @@ func TestWrite(t *testing.T) {
 	}
 }
 
+func TestIgnoreContent(t *testing.T) {
+	fpath, err := test.CreateTempFile([]byte("{}"))
+	require.NoError(t, err)
+
+	w, err := New(fpath)
+	require.NoError(t, err)
+	defer w.Close()
+
+	w.IgnoreContent([]byte("paths: {}"))
+
+	err = os.WriteFile(fpath, []byte("paths: {}"), 0o644)
+	require.NoError(t, err)
+
+	select {
+	case <-w.Watch():
+		t.Errorf("should not happen")
+		return
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	err = os.WriteFile(fpath, []byte("paths: {mypath: {}}"), 0o644)
+	require.NoError(t, err)
+
+	select {
+	case <-w.Watch():
+	case <-time.After(500 * time.Millisecond):
+		t.Errorf("timed out")
+		return
+	}
+}
+
 func TestWriteMultipleTimes(t *testing.T) {
 	fpath, err := test.CreateTempFile([]byte("{}"))
 	require.NoError(t, err)
@@ -139,3 +171,78 @@ func TestSymlinkDeleteCreate(t *testing.T) {
 		return
 	}
 }
+
+func TestWatchIncluded(t *testing.T) {
+	dir, err := os.MkdirTemp("", "mediamtx-confwatcher")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	fpath := filepath.Join(dir, "mediamtx.yml")
+	err = os.WriteFile(fpath, []byte("{}"), 0o644)
+	require.NoError(t, err)
+
+	err = os.Mkdir(filepath.Join(dir, "mediamtx.d"), 0o755)
+	require.NoError(t, err)
+
+	w, err := New(fpath)
+	require.NoError(t, err)
+	defer w.Close()
+
+	w.WatchIncluded([]string{filepath.Join(dir, "mediamtx.d", "*.yml")})
+
+	err = os.WriteFile(filepath.Join(dir, "mediamtx.d", "other.txt"), []byte("{}"), 0o644)
+	require.NoError(t, err)
+
+	select {
+	case <-w.Watch():
+		t.Errorf("should not happen")
+		return
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	err = os.WriteFile(filepath.Join(dir, "mediamtx.d", "cam1.yml"), []byte("{}"), 0o644)
+	require.NoError(t, err)
+
+	select {
+	case <-w.Watch():
+	case <-time.After(500 * time.Millisecond):
+		t.Errorf("timed out")
+		return
+	}
+}
+
+func TestWatchIncludedDirCreatedLater(t *testing.T) {
+	dir, err := os.MkdirTemp("", "mediamtx-confwatcher")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	fpath := filepath.Join(dir, "mediamtx.yml")
+	err = os.WriteFile(fpath, []byte("{}"), 0o644)
+	require.NoError(t, err)
+
+	w, err := New(fpath)
+	require.NoError(t, err)
+	defer w.Close()
+
+	w.WatchIncluded([]string{filepath.Join(dir, "conf", "mediamtx.d", "*.yml")})
+
+	err = os.MkdirAll(filepath.Join(dir, "conf", "mediamtx.d"), 0o755)
+	require.NoError(t, err)
+
+	select {
+	case <-w.Watch():
+		t.Errorf("should not happen")
+		return
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	err = os.WriteFile(filepath.Join(dir, "conf", "mediamtx.d", "cam1.yml"), []byte("{}"), 0o644)
+	require.NoError(t, err)
+
+	select {
+	case <-w.Watch():
+	case <-time.After(500 * time.Millisecond):
+		t.Errorf("timed out")
+		return
+	}
+}
@@ -3,6 +3,7 @@ package core
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
@@ -19,15 +20,22 @@ import (
 	"github.com/bluenviron/mediamtx/internal/api"
 	"github.com/bluenviron/mediamtx/internal/auth"
 	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/conf/yaml"
 	"github.com/bluenviron/mediamtx/internal/confwatcher"
+	"github.com/bluenviron/mediamtx/internal/defs"
+	"github.com/bluenviron/mediamtx/internal/events"
 	"github.com/bluenviron/mediamtx/internal/externalcmd"
 	"github.com/bluenviron/mediamtx/internal/logger"
 	"github.com/bluenviron/mediamtx/internal/metrics"
+	"github.com/bluenviron/mediamtx/internal/otlp"
 	"github.com/bluenviron/mediamtx/internal/playback"
 	"github.com/bluenviron/mediamtx/internal/pprof"
 	"github.com/bluenviron/mediamtx/internal/record"
 	"github.com/bluenviron/mediamtx/internal/rlimit"
+	"github.com/bluenviron/mediamtx/internal/servers/dash"
 	"github.com/bluenviron/mediamtx/internal/servers/hls"
+	"github.com/bluenviron/mediamtx/internal/servers/httpflv"
+	"github.com/bluenviron/mediamtx/internal/servers/httpts"
 	"github.com/bluenviron/mediamtx/internal/servers/rtmp"
 	"github.com/bluenviron/mediamtx/internal/servers/rtsp"
 	"github.com/bluenviron/mediamtx/internal/servers/srt"
@@ -44,15 +52,17 @@ var defaultConfPaths = []string{
 	"/etc/mediamtx/mediamtx.yml",
 }
 
-func gatherCleanerEntries(paths map[string]*conf.Path) []record.CleanerEntry {
+func gatherCleanerEntries(paths map[string]*conf.Path, minFreeSpace conf.StringSize) []record.CleanerEntry {
 	out := make(map[record.CleanerEntry]struct{})
 
 	for _, pa := range paths {
-		if pa.Record && pa.RecordDeleteAfter != 0 {
+		// when a minimum free space is set, segments of all paths are candidates for removal.
+		if pa.Record && (pa.RecordDeleteAfter != 0 || pa.RecordMaxSize != 0 || minFreeSpace != 0) {
 			entry := record.CleanerEntry{
 				Path:        pa.RecordPath,
 				Format:      pa.RecordFormat,
 				DeleteAfter: time.Duration(pa.RecordDeleteAfter),
+				MaxSize:     uint64(pa.RecordMaxSize),
 			}
 			out[entry] = struct{}{}
 		}
@@ -70,15 +80,49 @@ func gatherCleanerEntries(paths map[string]*conf.Path) []record.CleanerEntry {
 		if out2[i].Path != out2[j].Path {
 			return out2[i].Path < out2[j].Path
 		}
-		return out2[i].DeleteAfter < out2[j].DeleteAfter
+		if out2[i].DeleteAfter != out2[j].DeleteAfter {
+			return out2[i].DeleteAfter < out2[j].DeleteAfter
+		}
+		return out2[i].MaxSize < out2[j].MaxSize
 	})
 
 	return out2
 }
 
+type coreAPIConfigDiffReq struct {
+	conf *conf.Conf
+	res  chan *defs.APIConfigDiff
+}
+
 var cli struct {
-	Version  bool   `help:"print version"`
-	Confpath string `arg:"" default:""`
+	Version bool `help:"print version"`
+	Run     struct {
+		Confpath string `arg:"" default:""`
+	} `cmd:"" default:"withargs" help:"run the server (default command)"`
+	Check struct {
+		Confpath string `arg:"" default:""`
+	} `cmd:"" help:"check a config file for errors and exit"`
+}
+
+// checkConf loads and validates a configuration file.
+func checkConf(confPath string) (string, error) {
+	_, confPath, err := conf.Load(confPath, defaultConfPaths)
+	if err != nil {
+		var errs []error
+		for _, err := range conf.Errors(err) {
+			if field := conf.ErrorField(err); field != "" {
+				err = fmt.Errorf("%s: %w", field, err)
+			}
+			errs = append(errs, err)
+		}
+		return "", errors.Join(errs...)
+	}
+
+	if confPath == "" {
+		return "", fmt.Errorf("configuration file not found")
+	}
+
+	return confPath, nil
 }
 
 // Core is an instance of MediaMTX.
@@ -89,10 +133,13 @@ type Core struct {
 	conf            *conf.Conf
 	logger          *logger.Logger
 	externalCmdPool *externalcmd.Pool
+	eventBus        *events.Bus
 	authManager     *auth.Manager
 	metrics         *metrics.Metrics
+	otlpExporter    *otlp.Exporter
 	pprof           *pprof.PPROF
 	recordCleaner   *record.Cleaner
+	recordUploader  *record.Uploader
 	playbackServer  *playback.Server
 	pathManager     *pathManager
 	rtspServer      *rtsp.Server
@@ -102,11 +149,15 @@ type Core struct {
 	hlsServer       *hls.Server
 	webRTCServer    *webrtc.Server
 	srtServer       *srt.Server
+	httpFLVServer   *httpflv.Server
+	httpTSServer    *httpts.Server
+	dashServer      *dash.Server
 	api             *api.API
 	confWatcher     *confwatcher.ConfWatcher
 
 	// in
-	chAPIConfigSet chan *conf.Conf
+	chAPIConfigSet  chan *conf.Conf
+	chAPIConfigDiff chan coreAPIConfigDiffReq
 
 	// out
 	done chan struct{}
@@ -130,7 +181,7 @@ func New(args []string) (*Core, bool) {
 		panic(err)
 	}
 
-	_, err = parser.Parse(args)
+	kctx, err := parser.Parse(args)
 	parser.FatalIfErrorf(err)
 
 	if cli.Version {
@@ -138,16 +189,30 @@ func New(args []string) (*Core, bool) {
 		os.Exit(0)
 	}
 
+	if strings.HasPrefix(kctx.Command(), "check") {
+		confPath, err := checkConf(cli.Check.Confpath)
+		if err != nil {
+			for _, line := range strings.Split(err.Error(), "\n") {
+				fmt.Printf("ERR: %s\n", line)
+			}
+			os.Exit(1)
+		}
+
+		fmt.Printf("configuration file %s is valid\n", confPath)
+		os.Exit(0)
+	}
+
 	ctx, ctxCancel := context.WithCancel(context.Background())
 
 	p := &Core{
-		ctx:            ctx,
-		ctxCancel:      ctxCancel,
-		chAPIConfigSet: make(chan *conf.Conf),
-		done:           make(chan struct{}),
+		ctx:             ctx,
+		ctxCancel:       ctxCancel,
+		chAPIConfigSet:  make(chan *conf.Conf),
+		chAPIConfigDiff: make(chan coreAPIConfigDiffReq),
+		done:            make(chan struct{}),
 	}
 
-	p.conf, p.confPath, err = conf.Load(cli.Confpath, defaultConfPaths)
+	p.conf, p.confPath, err = conf.Load(cli.Run.Confpath, defaultConfPaths)
 	if err != nil {
 		fmt.Printf("ERR: %s\n", err)
 		return nil, false
@@ -219,12 +284,24 @@ outer:
 		case newConf := <-p.chAPIConfigSet:
 			p.Log(logger.Info, "reloading configuration (API request)")
 
+			prevConf := p.conf
+
 			err := p.reloadConf(newConf, true)
 			if err != nil {
 				p.Log(logger.Error, "%s", err)
 				break outer
 			}
 
+			if newConf.APIPersistConfig {
+				err = p.persistConf(prevConf, newConf)
+				if err != nil {
+					p.Log(logger.Error, "unable to persist configuration: %v", err)
+				}
+			}
+
+		case req := <-p.chAPIConfigDiff:
+			req.res <- p.apiConfigDiff(req.conf)
+
 		case <-interrupt:
 			p.Log(logger.Info, "shutting down gracefully")
 			break outer
@@ -245,6 +322,7 @@ func (p *Core) createResources(initial bool) error {
 	if p.logger == nil {
 		p.logger, err = logger.New(
 			logger.Level(p.conf.LogLevel),
+			logger.Format(p.conf.LogFormat),
 			p.conf.LogDestinations,
 			p.conf.LogFile,
 		)
@@ -259,6 +337,11 @@ func (p *Core) createResources(initial bool) error {
 		if p.confPath != "" {
 			a, _ := filepath.Abs(p.confPath)
 			p.Log(logger.Info, "configuration loaded from %s", a)
+
+			files, _ := conf.IncludedFiles(p.confPath, p.conf.Include)
+			for _, f := range files {
+				p.Log(logger.Info, "configuration included from %s", f)
+			}
 		} else {
 			list := make([]string, len(defaultConfPaths))
 			for i, pa := range defaultConfPaths {
@@ -278,24 +361,54 @@ func (p *Core) createResources(initial bool) error {
 		gin.SetMode(gin.ReleaseMode)
 
 		p.externalCmdPool = externalcmd.NewPool()
+
+		p.eventBus = &events.Bus{}
 	}
 
+	p.externalCmdPool.SetWebhookConf(externalcmd.WebhookConf{
+		Timeout: time.Duration(p.conf.WebhookTimeout),
+		Retries: p.conf.WebhookRetries,
+		Secret:  p.conf.WebhookSecret,
+	})
+
 	if p.authManager == nil {
+		var tokens *auth.TokenStore
+
+		if p.conf.APITokensFile != "" {
+			tokens = &auth.TokenStore{
+				FilePath: p.conf.APITokensFile,
+			}
+			err = tokens.Initialize()
+			if err != nil {
+				return err
+			}
+		}
+
 		p.authManager = &auth.Manager{
-			Method:          p.conf.AuthMethod,
-			InternalUsers:   p.conf.AuthInternalUsers,
-			HTTPAddress:     p.conf.AuthHTTPAddress,
-			HTTPExclude:     p.conf.AuthHTTPExclude,
-			JWTJWKS:         p.conf.AuthJWTJWKS,
-			ReadTimeout:     time.Duration(p.conf.ReadTimeout),
-			RTSPAuthMethods: p.conf.RTSPAuthMethods,
+			Method:           p.conf.AuthMethod,
+			InternalUsers:    p.conf.AuthInternalUsers,
+			HTTPAddress:      p.conf.AuthHTTPAddress,
+			HTTPExclude:      p.conf.AuthHTTPExclude,
+			JWTJWKS:          p.conf.AuthJWTJWKS,
+			ReadTimeout:      time.Duration(p.conf.ReadTimeout),
+			RTSPAuthMethods:  p.conf.RTSPAuthMethods,
+			Tokens:           tokens,
+			CacheTTL:         time.Duration(p.conf.AuthCacheTTL),
+			CacheNegativeTTL: time.Duration(p.conf.AuthCacheNegativeTTL),
+			CacheSize:        p.conf.AuthCacheSize,
 		}
+		p.authManager.Initialize()
 	}
 
-	if p.conf.Metrics &&
+	if (p.conf.Metrics || p.conf.OTLP) &&
 		p.metrics == nil {
 		i := &metrics.Metrics{
-			Address:     p.conf.MetricsAddress,
+			Address: func() string {
+				if p.conf.Metrics {
+					return p.conf.MetricsAddress
+				}
+				return ""
+			}(),
 			ReadTimeout: p.conf.ReadTimeout,
 			AuthManager: p.authManager,
 			Parent:      p,
@@ -307,6 +420,22 @@ func (p *Core) createResources(initial bool) error {
 		p.metrics = i
 	}
 
+	if p.conf.OTLP &&
+		p.otlpExporter == nil {
+		i := &otlp.Exporter{
+			Address:     p.conf.OTLPAddress,
+			Interval:    p.conf.OTLPInterval,
+			ReadTimeout: p.conf.ReadTimeout,
+			Metrics:     p.metrics,
+			Parent:      p,
+		}
+		err := i.Initialize()
+		if err != nil {
+			return err
+		}
+		p.otlpExporter = i
+	}
+
 	if p.conf.PPROF &&
 		p.pprof == nil {
 		i := &pprof.PPROF{
@@ -322,14 +451,39 @@ func (p *Core) createResources(initial bool) error {
 		p.pprof = i
 	}
 
-	cleanerEntries := gatherCleanerEntries(p.conf.Paths)
+	cleanerEntries := gatherCleanerEntries(p.conf.Paths, p.conf.RecordMinFreeSpace)
 	if len(cleanerEntries) != 0 &&
 		p.recordCleaner == nil {
 		p.recordCleaner = &record.Cleaner{
-			Entries: cleanerEntries,
-			Parent:  p,
+			Entries:      cleanerEntries,
+			MinFreeSpace: uint64(p.conf.RecordMinFreeSpace),
+			Parent:       p,
 		}
 		p.recordCleaner.Initialize()
+
+		if p.metrics != nil {
+			p.metrics.SetRecordCleaner(p.recordCleaner)
+		}
+	}
+
+	if p.conf.RecordUploadEndpoint != "" &&
+		p.recordUploader == nil {
+		i := &record.Uploader{
+			Endpoint:        p.conf.RecordUploadEndpoint,
+			Region:          p.conf.RecordUploadRegion,
+			Bucket:          p.conf.RecordUploadBucket,
+			AccessKeyID:     p.conf.RecordUploadAccessKeyID,
+			SecretAccessKey: p.conf.RecordUploadSecretAccessKey,
+			PartSize:        uint64(p.conf.RecordUploadPartSize),
+			QueuePath:       p.conf.RecordUploadQueuePath,
+			Timeout:         time.Duration(p.conf.RecordUploadTimeout),
+			Parent:          p,
+		}
+		err := i.Initialize()
+		if err != nil {
+			return err
+		}
+		p.recordUploader = i
 	}
 
 	if p.conf.Playback &&
@@ -359,6 +513,9 @@ func (p *Core) createResources(initial bool) error {
 			udpMaxPayloadSize: p.conf.UDPMaxPayloadSize,
 			pathConfs:         p.conf.Paths,
 			externalCmdPool:   p.externalCmdPool,
+			recordUploader:    p.recordUploader,
+			eventBus:          p.eventBus,
+			otlpExporter:      p.otlpExporter,
 			parent:            p,
 		}
 		p.pathManager.initialize()
@@ -606,22 +763,113 @@ func (p *Core) createResources(initial bool) error {
 		}
 	}
 
+	if p.conf.HTTPFLV &&
+		p.httpFLVServer == nil {
+		i := &httpflv.Server{
+			Address:         p.conf.HTTPFLVAddress,
+			Encryption:      p.conf.HTTPFLVEncryption,
+			ServerKey:       p.conf.HTTPFLVServerKey,
+			ServerCert:      p.conf.HTTPFLVServerCert,
+			AllowOrigin:     p.conf.HTTPFLVAllowOrigin,
+			TrustedProxies:  p.conf.HTTPFLVTrustedProxies,
+			ReadTimeout:     p.conf.ReadTimeout,
+			WriteTimeout:    p.conf.WriteTimeout,
+			WriteQueueSize:  p.conf.WriteQueueSize,
+			ExternalCmdPool: p.externalCmdPool,
+			PathManager:     p.pathManager,
+			Parent:          p,
+		}
+		err := i.Initialize()
+		if err != nil {
+			return err
+		}
+		p.httpFLVServer = i
+
+		if p.metrics != nil {
+			p.metrics.SetHTTPFLVServer(p.httpFLVServer)
+		}
+	}
+
+	if p.conf.HTTPTS &&
+		p.httpTSServer == nil {
+		i := &httpts.Server{
+			Address:         p.conf.HTTPTSAddress,
+			Encryption:      p.conf.HTTPTSEncryption,
+			ServerKey:       p.conf.HTTPTSServerKey,
+			ServerCert:      p.conf.HTTPTSServerCert,
+			AllowOrigin:     p.conf.HTTPTSAllowOrigin,
+			TrustedProxies:  p.conf.HTTPTSTrustedProxies,
+			ReadTimeout:     p.conf.ReadTimeout,
+			WriteTimeout:    p.conf.WriteTimeout,
+			WriteQueueSize:  p.conf.WriteQueueSize,
+			ExternalCmdPool: p.externalCmdPool,
+			PathManager:     p.pathManager,
+			Parent:          p,
+		}
+		err := i.Initialize()
+		if err != nil {
+			return err
+		}
+		p.httpTSServer = i
+
+		if p.metrics != nil {
+			p.metrics.SetHTTPTSServer(p.httpTSServer)
+		}
+	}
+
+	if p.conf.DASH &&
+		p.dashServer == nil {
+		i := &dash.Server{
+			Address:         p.conf.DASHAddress,
+			Encryption:      p.conf.DASHEncryption,
+			ServerKey:       p.conf.DASHServerKey,
+			ServerCert:      p.conf.DASHServerCert,
+			AlwaysRemux:     p.conf.DASHAlwaysRemux,
+			LowLatency:      p.conf.DASHLowLatency,
+			SegmentCount:    p.conf.HLSSegmentCount,
+			SegmentDuration: p.conf.HLSSegmentDuration,
+			PartDuration:    p.conf.HLSPartDuration,
+			AllowOrigin:     p.conf.HLSAllowOrigin,
+			TrustedProxies:  p.conf.HLSTrustedProxies,
+			ReadTimeout:     p.conf.ReadTimeout,
+			WriteQueueSize:  p.conf.WriteQueueSize,
+			PathManager:     p.pathManager,
+			Parent:          p,
+		}
+		err := i.Initialize()
+		if err != nil {
+			return err
+		}
+		p.dashServer = i
+
+		p.pathManager.setDASHServer(p.dashServer)
+
+		if p.metrics != nil {
+			p.metrics.SetDASHServer(p.dashServer)
+		}
+	}
+
 	if p.conf.API &&
 		p.api == nil {
 		i := &api.API{
-			Address:      p.conf.APIAddress,
-			ReadTimeout:  p.conf.ReadTimeout,
-			Conf:         p.conf,
-			AuthManager:  p.authManager,
-			PathManager:  p.pathManager,
-			RTSPServer:   p.rtspServer,
-			RTSPSServer:  p.rtspsServer,
-			RTMPServer:   p.rtmpServer,
-			RTMPSServer:  p.rtmpsServer,
-			HLSServer:    p.hlsServer,
-			WebRTCServer: p.webRTCServer,
-			SRTServer:    p.srtServer,
-			Parent:       p,
+			Address:       p.conf.APIAddress,
+			ReadTimeout:   p.conf.ReadTimeout,
+			Conf:          p.conf,
+			AuthManager:   p.authManager,
+			PathManager:   p.pathManager,
+			RTSPServer:    p.rtspServer,
+			RTSPSServer:   p.rtspsServer,
+			RTMPServer:    p.rtmpServer,
+			RTMPSServer:   p.rtmpsServer,
+			HLSServer:     p.hlsServer,
+			WebRTCServer:  p.webRTCServer,
+			SRTServer:     p.srtServer,
+			HTTPFLVServer: p.httpFLVServer,
+			HTTPTSServer:  p.httpTSServer,
+			DASHServer:    p.dashServer,
+			EventBus:      p.eventBus,
+			TokenStore:    p.authManager.Tokens,
+			Parent:        p,
 		}
 		err := i.Initialize()
 		if err != nil {
@@ -637,219 +885,340 @@ func (p *Core) createResources(initial bool) error {
 		}
 	}
 
+	if p.confWatcher != nil {
+		p.confWatcher.WatchIncluded(conf.IncludePatterns(p.confPath, p.conf.Include))
+	}
+
 	return nil
 }
 
+// confDiff contains the components that must be closed when switching
+// from a configuration to another.
+type confDiff struct {
+	closeLogger          bool
+	closeAuthManager     bool
+	closeMetrics         bool
+	closeOTLPExporter    bool
+	closePPROF           bool
+	closeRecorderCleaner bool
+	closeRecordUploader  bool
+	closePlaybackServer  bool
+	closePathManager     bool
+	closeRTSPServer      bool
+	closeRTSPSServer     bool
+	closeRTMPServer      bool
+	closeRTMPSServer     bool
+	closeHLSServer       bool
+	closeWebRTCServer    bool
+	closeSRTServer       bool
+	closeHTTPFLVServer   bool
+	closeHTTPTSServer    bool
+	closeDASHServer      bool
+	closeAPI             bool
+}
+
+// newConfDiff computes the components that must be closed when switching from oldConf to newConf.
+// If newConf is nil, all components are closed.
+func newConfDiff(oldConf *conf.Conf, newConf *conf.Conf) *confDiff {
+	d := &confDiff{}
+
+	d.closeLogger = newConf == nil ||
+		newConf.LogLevel != oldConf.LogLevel ||
+		newConf.LogFormat != oldConf.LogFormat ||
+		!reflect.DeepEqual(newConf.LogDestinations, oldConf.LogDestinations) ||
+		newConf.LogFile != oldConf.LogFile
+
+	d.closeAuthManager = newConf == nil ||
+		newConf.AuthMethod != oldConf.AuthMethod ||
+		newConf.AuthHTTPAddress != oldConf.AuthHTTPAddress ||
+		!reflect.DeepEqual(newConf.AuthHTTPExclude, oldConf.AuthHTTPExclude) ||
+		newConf.AuthJWTJWKS != oldConf.AuthJWTJWKS ||
+		newConf.ReadTimeout != oldConf.ReadTimeout ||
+		!reflect.DeepEqual(newConf.RTSPAuthMethods, oldConf.RTSPAuthMethods) ||
+		newConf.APITokensFile != oldConf.APITokensFile ||
+		newConf.AuthCacheTTL != oldConf.AuthCacheTTL ||
+		newConf.AuthCacheNegativeTTL != oldConf.AuthCacheNegativeTTL ||
+		newConf.AuthCacheSize != oldConf.AuthCacheSize
+
+	d.closeMetrics = newConf == nil ||
+		newConf.Metrics != oldConf.Metrics ||
+		newConf.MetricsAddress != oldConf.MetricsAddress ||
+		newConf.OTLP != oldConf.OTLP ||
+		newConf.ReadTimeout != oldConf.ReadTimeout ||
+		d.closeAuthManager ||
+		d.closeLogger
+
+	d.closeOTLPExporter = newConf == nil ||
+		newConf.OTLP != oldConf.OTLP ||
+		newConf.OTLPAddress != oldConf.OTLPAddress ||
+		newConf.OTLPInterval != oldConf.OTLPInterval ||
+		newConf.ReadTimeout != oldConf.ReadTimeout ||
+		d.closeMetrics ||
+		d.closeLogger
+
+	d.closePPROF = newConf == nil ||
+		newConf.PPROF != oldConf.PPROF ||
+		newConf.PPROFAddress != oldConf.PPROFAddress ||
+		newConf.ReadTimeout != oldConf.ReadTimeout ||
+		d.closeAuthManager ||
+		d.closeLogger
+
+	d.closeRecorderCleaner = newConf == nil ||
+		newConf.RecordMinFreeSpace != oldConf.RecordMinFreeSpace ||
+		!reflect.DeepEqual(gatherCleanerEntries(newConf.Paths, newConf.RecordMinFreeSpace),
+			gatherCleanerEntries(oldConf.Paths, oldConf.RecordMinFreeSpace)) ||
+		d.closeMetrics ||
+		d.closeLogger
+
+	d.closeRecordUploader = newConf == nil ||
+		newConf.RecordUploadEndpoint != oldConf.RecordUploadEndpoint ||
+		newConf.RecordUploadRegion != oldConf.RecordUploadRegion ||
+		newConf.RecordUploadBucket != oldConf.RecordUploadBucket ||
+		newConf.RecordUploadAccessKeyID != oldConf.RecordUploadAccessKeyID ||
+		newConf.RecordUploadSecretAccessKey != oldConf.RecordUploadSecretAccessKey ||
+		newConf.RecordUploadPartSize != oldConf.RecordUploadPartSize ||
+		newConf.RecordUploadQueuePath != oldConf.RecordUploadQueuePath ||
+		newConf.RecordUploadTimeout != oldConf.RecordUploadTimeout ||
+		d.closeLogger
+
+	d.closePlaybackServer = newConf == nil ||
+		newConf.Playback != oldConf.Playback ||
+		newConf.PlaybackAddress != oldConf.PlaybackAddress ||
+		newConf.ReadTimeout != oldConf.ReadTimeout ||
+		d.closeAuthManager ||
+		d.closeLogger
+
+	d.closePathManager = newConf == nil ||
+		newConf.LogLevel != oldConf.LogLevel ||
+		newConf.RTSPAddress != oldConf.RTSPAddress ||
+		!reflect.DeepEqual(newConf.RTSPAuthMethods, oldConf.RTSPAuthMethods) ||
+		newConf.ReadTimeout != oldConf.ReadTimeout ||
+		newConf.WriteTimeout != oldConf.WriteTimeout ||
+		newConf.WriteQueueSize != oldConf.WriteQueueSize ||
+		newConf.UDPMaxPayloadSize != oldConf.UDPMaxPayloadSize ||
+		d.closeRecordUploader ||
+		d.closeMetrics ||
+		d.closeOTLPExporter ||
+		d.closeAuthManager ||
+		d.closeLogger
+
+	d.closeRTSPServer = newConf == nil ||
+		newConf.RTSP != oldConf.RTSP ||
+		newConf.Encryption != oldConf.Encryption ||
+		newConf.RTSPAddress != oldConf.RTSPAddress ||
+		!reflect.DeepEqual(newConf.RTSPAuthMethods, oldConf.RTSPAuthMethods) ||
+		newConf.ReadTimeout != oldConf.ReadTimeout ||
+		newConf.WriteTimeout != oldConf.WriteTimeout ||
+		newConf.WriteQueueSize != oldConf.WriteQueueSize ||
+		!reflect.DeepEqual(newConf.Protocols, oldConf.Protocols) ||
+		newConf.RTPAddress != oldConf.RTPAddress ||
+		newConf.RTCPAddress != oldConf.RTCPAddress ||
+		newConf.MulticastIPRange != oldConf.MulticastIPRange ||
+		newConf.MulticastRTPPort != oldConf.MulticastRTPPort ||
+		newConf.MulticastRTCPPort != oldConf.MulticastRTCPPort ||
+		newConf.RTSPAddress != oldConf.RTSPAddress ||
+		!reflect.DeepEqual(newConf.Protocols, oldConf.Protocols) ||
+		newConf.RunOnConnect != oldConf.RunOnConnect ||
+		newConf.RunOnConnectRestart != oldConf.RunOnConnectRestart ||
+		newConf.RunOnDisconnect != oldConf.RunOnDisconnect ||
+		d.closeMetrics ||
+		d.closePathManager ||
+		d.closeLogger
+
+	d.closeRTSPSServer = newConf == nil ||
+		newConf.RTSP != oldConf.RTSP ||
+		newConf.Encryption != oldConf.Encryption ||
+		newConf.RTSPSAddress != oldConf.RTSPSAddress ||
+		!reflect.DeepEqual(newConf.RTSPAuthMethods, oldConf.RTSPAuthMethods) ||
+		newConf.ReadTimeout != oldConf.ReadTimeout ||
+		newConf.WriteTimeout != oldConf.WriteTimeout ||
+		newConf.WriteQueueSize != oldConf.WriteQueueSize ||
+		newConf.ServerCert != oldConf.ServerCert ||
+		newConf.ServerKey != oldConf.ServerKey ||
+		newConf.RTSPAddress != oldConf.RTSPAddress ||
+		!reflect.DeepEqual(newConf.Protocols, oldConf.Protocols) ||
+		newConf.RunOnConnect != oldConf.RunOnConnect ||
+		newConf.RunOnConnectRestart != oldConf.RunOnConnectRestart ||
+		newConf.RunOnDisconnect != oldConf.RunOnDisconnect ||
+		d.closeMetrics ||
+		d.closePathManager ||
+		d.closeLogger
+
+	d.closeRTMPServer = newConf == nil ||
+		newConf.RTMP != oldConf.RTMP ||
+		newConf.RTMPEncryption != oldConf.RTMPEncryption ||
+		newConf.RTMPAddress != oldConf.RTMPAddress ||
+		newConf.ReadTimeout != oldConf.ReadTimeout ||
+		newConf.WriteTimeout != oldConf.WriteTimeout ||
+		newConf.WriteQueueSize != oldConf.WriteQueueSize ||
+		newConf.RTSPAddress != oldConf.RTSPAddress ||
+		newConf.RunOnConnect != oldConf.RunOnConnect ||
+		newConf.RunOnConnectRestart != oldConf.RunOnConnectRestart ||
+		newConf.RunOnDisconnect != oldConf.RunOnDisconnect ||
+		d.closeMetrics ||
+		d.closePathManager ||
+		d.closeLogger
+
+	d.closeRTMPSServer = newConf == nil ||
+		newConf.RTMP != oldConf.RTMP ||
+		newConf.RTMPEncryption != oldConf.RTMPEncryption ||
+		newConf.RTMPSAddress != oldConf.RTMPSAddress ||
+		newConf.ReadTimeout != oldConf.ReadTimeout ||
+		newConf.WriteTimeout != oldConf.WriteTimeout ||
+		newConf.WriteQueueSize != oldConf.WriteQueueSize ||
+		newConf.RTMPServerCert != oldConf.RTMPServerCert ||
+		newConf.RTMPServerKey != oldConf.RTMPServerKey ||
+		newConf.RTSPAddress != oldConf.RTSPAddress ||
+		newConf.RunOnConnect != oldConf.RunOnConnect ||
+		newConf.RunOnConnectRestart != oldConf.RunOnConnectRestart ||
+		newConf.RunOnDisconnect != oldConf.RunOnDisconnect ||
+		d.closeMetrics ||
+		d.closePathManager ||
+		d.closeLogger
+
+	d.closeHLSServer = newConf == nil ||
+		newConf.HLS != oldConf.HLS ||
+		newConf.HLSAddress != oldConf.HLSAddress ||
+		newConf.HLSEncryption != oldConf.HLSEncryption ||
+		newConf.HLSServerKey != oldConf.HLSServerKey ||
+		newConf.HLSServerCert != oldConf.HLSServerCert ||
+		newConf.HLSAlwaysRemux != oldConf.HLSAlwaysRemux ||
+		newConf.HLSVariant != oldConf.HLSVariant ||
+		newConf.HLSSegmentCount != oldConf.HLSSegmentCount ||
+		newConf.HLSSegmentDuration != oldConf.HLSSegmentDuration ||
+		newConf.HLSPartDuration != oldConf.HLSPartDuration ||
+		newConf.HLSSegmentMaxSize != oldConf.HLSSegmentMaxSize ||
+		newConf.HLSAllowOrigin != oldConf.HLSAllowOrigin ||
+		!reflect.DeepEqual(newConf.HLSTrustedProxies, oldConf.HLSTrustedProxies) ||
+		newConf.HLSDirectory != oldConf.HLSDirectory ||
+		newConf.ReadTimeout != oldConf.ReadTimeout ||
+		newConf.WriteQueueSize != oldConf.WriteQueueSize ||
+		d.closePathManager ||
+		d.closeMetrics ||
+		d.closeLogger
+
+	d.closeWebRTCServer = newConf == nil ||
+		newConf.WebRTC != oldConf.WebRTC ||
+		newConf.WebRTCAddress != oldConf.WebRTCAddress ||
+		newConf.WebRTCEncryption != oldConf.WebRTCEncryption ||
+		newConf.WebRTCServerKey != oldConf.WebRTCServerKey ||
+		newConf.WebRTCServerCert != oldConf.WebRTCServerCert ||
+		newConf.WebRTCAllowOrigin != oldConf.WebRTCAllowOrigin ||
+		!reflect.DeepEqual(newConf.WebRTCTrustedProxies, oldConf.WebRTCTrustedProxies) ||
+		newConf.ReadTimeout != oldConf.ReadTimeout ||
+		newConf.WriteQueueSize != oldConf.WriteQueueSize ||
+		newConf.WebRTCLocalUDPAddress != oldConf.WebRTCLocalUDPAddress ||
+		newConf.WebRTCLocalTCPAddress != oldConf.WebRTCLocalTCPAddress ||
+		newConf.WebRTCIPsFromInterfaces != oldConf.WebRTCIPsFromInterfaces ||
+		!reflect.DeepEqual(newConf.WebRTCIPsFromInterfacesList, oldConf.WebRTCIPsFromInterfacesList) ||
+		!reflect.DeepEqual(newConf.WebRTCAdditionalHosts, oldConf.WebRTCAdditionalHosts) ||
+		!reflect.DeepEqual(newConf.WebRTCICEServers2, oldConf.WebRTCICEServers2) ||
+		d.closeMetrics ||
+		d.closePathManager ||
+		d.closeLogger
+
+	d.closeSRTServer = newConf == nil ||
+		newConf.SRT != oldConf.SRT ||
+		newConf.SRTAddress != oldConf.SRTAddress ||
+		newConf.RTSPAddress != oldConf.RTSPAddress ||
+		newConf.ReadTimeout != oldConf.ReadTimeout ||
+		newConf.WriteTimeout != oldConf.WriteTimeout ||
+		newConf.WriteQueueSize != oldConf.WriteQueueSize ||
+		newConf.UDPMaxPayloadSize != oldConf.UDPMaxPayloadSize ||
+		newConf.RunOnConnect != oldConf.RunOnConnect ||
+		newConf.RunOnConnectRestart != oldConf.RunOnConnectRestart ||
+		newConf.RunOnDisconnect != oldConf.RunOnDisconnect ||
+		d.closePathManager ||
+		d.closeLogger
+
+	d.closeHTTPFLVServer = newConf == nil ||
+		newConf.HTTPFLV != oldConf.HTTPFLV ||
+		newConf.HTTPFLVAddress != oldConf.HTTPFLVAddress ||
+		newConf.HTTPFLVEncryption != oldConf.HTTPFLVEncryption ||
+		newConf.HTTPFLVServerKey != oldConf.HTTPFLVServerKey ||
+		newConf.HTTPFLVServerCert != oldConf.HTTPFLVServerCert ||
+		newConf.HTTPFLVAllowOrigin != oldConf.HTTPFLVAllowOrigin ||
+		!reflect.DeepEqual(newConf.HTTPFLVTrustedProxies, oldConf.HTTPFLVTrustedProxies) ||
+		newConf.ReadTimeout != oldConf.ReadTimeout ||
+		newConf.WriteTimeout != oldConf.WriteTimeout ||
+		newConf.WriteQueueSize != oldConf.WriteQueueSize ||
+		d.closePathManager ||
+		d.closeLogger
+
+	d.closeHTTPTSServer = newConf == nil ||
+		newConf.HTTPTS != oldConf.HTTPTS ||
+		newConf.HTTPTSAddress != oldConf.HTTPTSAddress ||
+		newConf.HTTPTSEncryption != oldConf.HTTPTSEncryption ||
+		newConf.HTTPTSServerKey != oldConf.HTTPTSServerKey ||
+		newConf.HTTPTSServerCert != oldConf.HTTPTSServerCert ||
+		newConf.HTTPTSAllowOrigin != oldConf.HTTPTSAllowOrigin ||
+		!reflect.DeepEqual(newConf.HTTPTSTrustedProxies, oldConf.HTTPTSTrustedProxies) ||
+		newConf.ReadTimeout != oldConf.ReadTimeout ||
+		newConf.WriteTimeout != oldConf.WriteTimeout ||
+		newConf.WriteQueueSize != oldConf.WriteQueueSize ||
+		d.closePathManager ||
+		d.closeLogger
+
+	d.closeDASHServer = newConf == nil ||
+		newConf.DASH != oldConf.DASH ||
+		newConf.DASHAddress != oldConf.DASHAddress ||
+		newConf.DASHEncryption != oldConf.DASHEncryption ||
+		newConf.DASHServerKey != oldConf.DASHServerKey ||
+		newConf.DASHServerCert != oldConf.DASHServerCert ||
+		newConf.DASHAlwaysRemux != oldConf.DASHAlwaysRemux ||
+		newConf.DASHLowLatency != oldConf.DASHLowLatency ||
+		newConf.HLSSegmentCount != oldConf.HLSSegmentCount ||
+		newConf.HLSSegmentDuration != oldConf.HLSSegmentDuration ||
+		newConf.HLSPartDuration != oldConf.HLSPartDuration ||
+		newConf.HLSAllowOrigin != oldConf.HLSAllowOrigin ||
+		!reflect.DeepEqual(newConf.HLSTrustedProxies, oldConf.HLSTrustedProxies) ||
+		newConf.ReadTimeout != oldConf.ReadTimeout ||
+		newConf.WriteQueueSize != oldConf.WriteQueueSize ||
+		d.closePathManager ||
+		d.closeLogger
+
+	d.closeAPI = newConf == nil ||
+		newConf.API != oldConf.API ||
+		newConf.APIAddress != oldConf.APIAddress ||
+		newConf.ReadTimeout != oldConf.ReadTimeout ||
+		d.closeAuthManager ||
+		d.closePathManager ||
+		d.closeRTSPServer ||
+		d.closeRTSPSServer ||
+		d.closeRTMPServer ||
+		d.closeHLSServer ||
+		d.closeWebRTCServer ||
+		d.closeSRTServer ||
+		d.closeHTTPFLVServer ||
+		d.closeHTTPTSServer ||
+		d.closeDASHServer ||
+		d.closeLogger
+
+	return d
+}
+
 func (p *Core) closeResources(newConf *conf.Conf, calledByAPI bool) {
-	closeLogger := newConf == nil ||
-		newConf.LogLevel != p.conf.LogLevel ||
-		!reflect.DeepEqual(newConf.LogDestinations, p.conf.LogDestinations) ||
-		newConf.LogFile != p.conf.LogFile
-
-	closeAuthManager := newConf == nil ||
-		newConf.AuthMethod != p.conf.AuthMethod ||
-		newConf.AuthHTTPAddress != p.conf.AuthHTTPAddress ||
-		!reflect.DeepEqual(newConf.AuthHTTPExclude, p.conf.AuthHTTPExclude) ||
-		newConf.AuthJWTJWKS != p.conf.AuthJWTJWKS ||
-		newConf.ReadTimeout != p.conf.ReadTimeout ||
-		!reflect.DeepEqual(newConf.RTSPAuthMethods, p.conf.RTSPAuthMethods)
-	if !closeAuthManager && !reflect.DeepEqual(newConf.AuthInternalUsers, p.conf.AuthInternalUsers) {
+	d := newConfDiff(p.conf, newConf)
+
+	if !d.closeAuthManager && !reflect.DeepEqual(newConf.AuthInternalUsers, p.conf.AuthInternalUsers) {
 		p.authManager.ReloadInternalUsers(newConf.AuthInternalUsers)
 	}
 
-	closeMetrics := newConf == nil ||
-		newConf.Metrics != p.conf.Metrics ||
-		newConf.MetricsAddress != p.conf.MetricsAddress ||
-		newConf.ReadTimeout != p.conf.ReadTimeout ||
-		closeAuthManager ||
-		closeLogger
-
-	closePPROF := newConf == nil ||
-		newConf.PPROF != p.conf.PPROF ||
-		newConf.PPROFAddress != p.conf.PPROFAddress ||
-		newConf.ReadTimeout != p.conf.ReadTimeout ||
-		closeAuthManager ||
-		closeLogger
-
-	closeRecorderCleaner := newConf == nil ||
-		!reflect.DeepEqual(gatherCleanerEntries(newConf.Paths), gatherCleanerEntries(p.conf.Paths)) ||
-		closeLogger
-
-	closePlaybackServer := newConf == nil ||
-		newConf.Playback != p.conf.Playback ||
-		newConf.PlaybackAddress != p.conf.PlaybackAddress ||
-		newConf.ReadTimeout != p.conf.ReadTimeout ||
-		closeAuthManager ||
-		closeLogger
-	if !closePlaybackServer && p.playbackServer != nil && !reflect.DeepEqual(newConf.Paths, p.conf.Paths) {
+	if !d.closePlaybackServer && p.playbackServer != nil && !reflect.DeepEqual(newConf.Paths, p.conf.Paths) {
 		p.playbackServer.ReloadPathConfs(newConf.Paths)
 	}
 
-	closePathManager := newConf == nil ||
-		newConf.LogLevel != p.conf.LogLevel ||
-		newConf.RTSPAddress != p.conf.RTSPAddress ||
-		!reflect.DeepEqual(newConf.RTSPAuthMethods, p.conf.RTSPAuthMethods) ||
-		newConf.ReadTimeout != p.conf.ReadTimeout ||
-		newConf.WriteTimeout != p.conf.WriteTimeout ||
-		newConf.WriteQueueSize != p.conf.WriteQueueSize ||
-		newConf.UDPMaxPayloadSize != p.conf.UDPMaxPayloadSize ||
-		closeMetrics ||
-		closeAuthManager ||
-		closeLogger
-	if !closePathManager && !reflect.DeepEqual(newConf.Paths, p.conf.Paths) {
+	if !d.closePathManager && !reflect.DeepEqual(newConf.Paths, p.conf.Paths) {
 		p.pathManager.ReloadPathConfs(newConf.Paths)
 	}
 
-	closeRTSPServer := newConf == nil ||
-		newConf.RTSP != p.conf.RTSP ||
-		newConf.Encryption != p.conf.Encryption ||
-		newConf.RTSPAddress != p.conf.RTSPAddress ||
-		!reflect.DeepEqual(newConf.RTSPAuthMethods, p.conf.RTSPAuthMethods) ||
-		newConf.ReadTimeout != p.conf.ReadTimeout ||
-		newConf.WriteTimeout != p.conf.WriteTimeout ||
-		newConf.WriteQueueSize != p.conf.WriteQueueSize ||
-		!reflect.DeepEqual(newConf.Protocols, p.conf.Protocols) ||
-		newConf.RTPAddress != p.conf.RTPAddress ||
-		newConf.RTCPAddress != p.conf.RTCPAddress ||
-		newConf.MulticastIPRange != p.conf.MulticastIPRange ||
-		newConf.MulticastRTPPort != p.conf.MulticastRTPPort ||
-		newConf.MulticastRTCPPort != p.conf.MulticastRTCPPort ||
-		newConf.RTSPAddress != p.conf.RTSPAddress ||
-		!reflect.DeepEqual(newConf.Protocols, p.conf.Protocols) ||
-		newConf.RunOnConnect != p.conf.RunOnConnect ||
-		newConf.RunOnConnectRestart != p.conf.RunOnConnectRestart ||
-		newConf.RunOnDisconnect != p.conf.RunOnDisconnect ||
-		closeMetrics ||
-		closePathManager ||
-		closeLogger
-
-	closeRTSPSServer := newConf == nil ||
-		newConf.RTSP != p.conf.RTSP ||
-		newConf.Encryption != p.conf.Encryption ||
-		newConf.RTSPSAddress != p.conf.RTSPSAddress ||
-		!reflect.DeepEqual(newConf.RTSPAuthMethods, p.conf.RTSPAuthMethods) ||
-		newConf.ReadTimeout != p.conf.ReadTimeout ||
-		newConf.WriteTimeout != p.conf.WriteTimeout ||
-		newConf.WriteQueueSize != p.conf.WriteQueueSize ||
-		newConf.ServerCert != p.conf.ServerCert ||
-		newConf.ServerKey != p.conf.ServerKey ||
-		newConf.RTSPAddress != p.conf.RTSPAddress ||
-		!reflect.DeepEqual(newConf.Protocols, p.conf.Protocols) ||
-		newConf.RunOnConnect != p.conf.RunOnConnect ||
-		newConf.RunOnConnectRestart != p.conf.RunOnConnectRestart ||
-		newConf.RunOnDisconnect != p.conf.RunOnDisconnect ||
-		closeMetrics ||
-		closePathManager ||
-		closeLogger
-
-	closeRTMPServer := newConf == nil ||
-		newConf.RTMP != p.conf.RTMP ||
-		newConf.RTMPEncryption != p.conf.RTMPEncryption ||
-		newConf.RTMPAddress != p.conf.RTMPAddress ||
-		newConf.ReadTimeout != p.conf.ReadTimeout ||
-		newConf.WriteTimeout != p.conf.WriteTimeout ||
-		newConf.WriteQueueSize != p.conf.WriteQueueSize ||
-		newConf.RTSPAddress != p.conf.RTSPAddress ||
-		newConf.RunOnConnect != p.conf.RunOnConnect ||
-		newConf.RunOnConnectRestart != p.conf.RunOnConnectRestart ||
-		newConf.RunOnDisconnect != p.conf.RunOnDisconnect ||
-		closeMetrics ||
-		closePathManager ||
-		closeLogger
-
-	closeRTMPSServer := newConf == nil ||
-		newConf.RTMP != p.conf.RTMP ||
-		newConf.RTMPEncryption != p.conf.RTMPEncryption ||
-		newConf.RTMPSAddress != p.conf.RTMPSAddress ||
-		newConf.ReadTimeout != p.conf.ReadTimeout ||
-		newConf.WriteTimeout != p.conf.WriteTimeout ||
-		newConf.WriteQueueSize != p.conf.WriteQueueSize ||
-		newConf.RTMPServerCert != p.conf.RTMPServerCert ||
-		newConf.RTMPServerKey != p.conf.RTMPServerKey ||
-		newConf.RTSPAddress != p.conf.RTSPAddress ||
-		newConf.RunOnConnect != p.conf.RunOnConnect ||
-		newConf.RunOnConnectRestart != p.conf.RunOnConnectRestart ||
-		newConf.RunOnDisconnect != p.conf.RunOnDisconnect ||
-		closeMetrics ||
-		closePathManager ||
-		closeLogger
-
-	closeHLSServer := newConf == nil ||
-		newConf.HLS != p.conf.HLS ||
-		newConf.HLSAddress != p.conf.HLSAddress ||
-		newConf.HLSEncryption != p.conf.HLSEncryption ||
-		newConf.HLSServerKey != p.conf.HLSServerKey ||
-		newConf.HLSServerCert != p.conf.HLSServerCert ||
-		newConf.HLSAlwaysRemux != p.conf.HLSAlwaysRemux ||
-		newConf.HLSVariant != p.conf.HLSVariant ||
-		newConf.HLSSegmentCount != p.conf.HLSSegmentCount ||
-		newConf.HLSSegmentDuration != p.conf.HLSSegmentDuration ||
-		newConf.HLSPartDuration != p.conf.HLSPartDuration ||
-		newConf.HLSSegmentMaxSize != p.conf.HLSSegmentMaxSize ||
-		newConf.HLSAllowOrigin != p.conf.HLSAllowOrigin ||
-		!reflect.DeepEqual(newConf.HLSTrustedProxies, p.conf.HLSTrustedProxies) ||
-		newConf.HLSDirectory != p.conf.HLSDirectory ||
-		newConf.ReadTimeout != p.conf.ReadTimeout ||
-		newConf.WriteQueueSize != p.conf.WriteQueueSize ||
-		closePathManager ||
-		closeMetrics ||
-		closeLogger
-
-	closeWebRTCServer := newConf == nil ||
-		newConf.WebRTC != p.conf.WebRTC ||
-		newConf.WebRTCAddress != p.conf.WebRTCAddress ||
-		newConf.WebRTCEncryption != p.conf.WebRTCEncryption ||
-		newConf.WebRTCServerKey != p.conf.WebRTCServerKey ||
-		newConf.WebRTCServerCert != p.conf.WebRTCServerCert ||
-		newConf.WebRTCAllowOrigin != p.conf.WebRTCAllowOrigin ||
-		!reflect.DeepEqual(newConf.WebRTCTrustedProxies, p.conf.WebRTCTrustedProxies) ||
-		newConf.ReadTimeout != p.conf.ReadTimeout ||
-		newConf.WriteQueueSize != p.conf.WriteQueueSize ||
-		newConf.WebRTCLocalUDPAddress != p.conf.WebRTCLocalUDPAddress ||
-		newConf.WebRTCLocalTCPAddress != p.conf.WebRTCLocalTCPAddress ||
-		newConf.WebRTCIPsFromInterfaces != p.conf.WebRTCIPsFromInterfaces ||
-		!reflect.DeepEqual(newConf.WebRTCIPsFromInterfacesList, p.conf.WebRTCIPsFromInterfacesList) ||
-		!reflect.DeepEqual(newConf.WebRTCAdditionalHosts, p.conf.WebRTCAdditionalHosts) ||
-		!reflect.DeepEqual(newConf.WebRTCICEServers2, p.conf.WebRTCICEServers2) ||
-		closeMetrics ||
-		closePathManager ||
-		closeLogger
-
-	closeSRTServer := newConf == nil ||
-		newConf.SRT != p.conf.SRT ||
-		newConf.SRTAddress != p.conf.SRTAddress ||
-		newConf.RTSPAddress != p.conf.RTSPAddress ||
-		newConf.ReadTimeout != p.conf.ReadTimeout ||
-		newConf.WriteTimeout != p.conf.WriteTimeout ||
-		newConf.WriteQueueSize != p.conf.WriteQueueSize ||
-		newConf.UDPMaxPayloadSize != p.conf.UDPMaxPayloadSize ||
-		newConf.RunOnConnect != p.conf.RunOnConnect ||
-		newConf.RunOnConnectRestart != p.conf.RunOnConnectRestart ||
-		newConf.RunOnDisconnect != p.conf.RunOnDisconnect ||
-		closePathManager ||
-		closeLogger
-
-	closeAPI := newConf == nil ||
-		newConf.API != p.conf.API ||
-		newConf.APIAddress != p.conf.APIAddress ||
-		newConf.ReadTimeout != p.conf.ReadTimeout ||
-		closeAuthManager ||
-		closePathManager ||
-		closeRTSPServer ||
-		closeRTSPSServer ||
-		closeRTMPServer ||
-		closeHLSServer ||
-		closeWebRTCServer ||
-		closeSRTServer ||
-		closeLogger
-
 	if newConf == nil && p.confWatcher != nil {
 		p.confWatcher.Close()
 		p.confWatcher = nil
 	}
 
 	if p.api != nil {
-		if closeAPI {
+		if d.closeAPI {
 			p.api.Close()
 			p.api = nil
 		} else if !calledByAPI { // avoid a loop
@@ -857,7 +1226,36 @@ func (p *Core) closeResources(newConf *conf.Conf, calledByAPI bool) {
 		}
 	}
 
-	if closeSRTServer && p.srtServer != nil {
+	if d.closeDASHServer && p.dashServer != nil {
+		if p.metrics != nil {
+			p.metrics.SetDASHServer(nil)
+		}
+
+		p.pathManager.setDASHServer(nil)
+
+		p.dashServer.Close()
+		p.dashServer = nil
+	}
+
+	if d.closeHTTPTSServer && p.httpTSServer != nil {
+		if p.metrics != nil {
+			p.metrics.SetHTTPTSServer(nil)
+		}
+
+		p.httpTSServer.Close()
+		p.httpTSServer = nil
+	}
+
+	if d.closeHTTPFLVServer && p.httpFLVServer != nil {
+		if p.metrics != nil {
+			p.metrics.SetHTTPFLVServer(nil)
+		}
+
+		p.httpFLVServer.Close()
+		p.httpFLVServer = nil
+	}
+
+	if d.closeSRTServer && p.srtServer != nil {
 		if p.metrics != nil {
 			p.metrics.SetSRTServer(nil)
 		}
@@ -866,7 +1264,7 @@ func (p *Core) closeResources(newConf *conf.Conf, calledByAPI bool) {
 		p.srtServer = nil
 	}
 
-	if closeWebRTCServer && p.webRTCServer != nil {
+	if d.closeWebRTCServer && p.webRTCServer != nil {
 		if p.metrics != nil {
 			p.metrics.SetWebRTCServer(nil)
 		}
@@ -875,7 +1273,7 @@ func (p *Core) closeResources(newConf *conf.Conf, calledByAPI bool) {
 		p.webRTCServer = nil
 	}
 
-	if closeHLSServer && p.hlsServer != nil {
+	if d.closeHLSServer && p.hlsServer != nil {
 		if p.metrics != nil {
 			p.metrics.SetHLSServer(nil)
 		}
@@ -886,7 +1284,7 @@ func (p *Core) closeResources(newConf *conf.Conf, calledByAPI bool) {
 		p.hlsServer = nil
 	}
 
-	if closeRTMPSServer && p.rtmpsServer != nil {
+	if d.closeRTMPSServer && p.rtmpsServer != nil {
 		if p.metrics != nil {
 			p.metrics.SetRTMPSServer(nil)
 		}
@@ -895,7 +1293,7 @@ func (p *Core) closeResources(newConf *conf.Conf, calledByAPI bool) {
 		p.rtmpsServer = nil
 	}
 
-	if closeRTMPServer && p.rtmpServer != nil {
+	if d.closeRTMPServer && p.rtmpServer != nil {
 		if p.metrics != nil {
 			p.metrics.SetRTMPServer(nil)
 		}
@@ -904,7 +1302,7 @@ func (p *Core) closeResources(newConf *conf.Conf, calledByAPI bool) {
 		p.rtmpServer = nil
 	}
 
-	if closeRTSPSServer && p.rtspsServer != nil {
+	if d.closeRTSPSServer && p.rtspsServer != nil {
 		if p.metrics != nil {
 			p.metrics.SetRTSPSServer(nil)
 		}
@@ -913,7 +1311,7 @@ func (p *Core) closeResources(newConf *conf.Conf, calledByAPI bool) {
 		p.rtspsServer = nil
 	}
 
-	if closeRTSPServer && p.rtspServer != nil {
+	if d.closeRTSPServer && p.rtspServer != nil {
 		if p.metrics != nil {
 			p.metrics.SetRTSPServer(nil)
 		}
@@ -922,7 +1320,7 @@ func (p *Core) closeResources(newConf *conf.Conf, calledByAPI bool) {
 		p.rtspServer = nil
 	}
 
-	if closePathManager && p.pathManager != nil {
+	if d.closePathManager && p.pathManager != nil {
 		if p.metrics != nil {
 			p.metrics.SetPathManager(nil)
 		}
@@ -931,27 +1329,41 @@ func (p *Core) closeResources(newConf *conf.Conf, calledByAPI bool) {
 		p.pathManager = nil
 	}
 
-	if closePlaybackServer && p.playbackServer != nil {
+	if d.closePlaybackServer && p.playbackServer != nil {
 		p.playbackServer.Close()
 		p.playbackServer = nil
 	}
 
-	if closeRecorderCleaner && p.recordCleaner != nil {
+	if d.closeRecordUploader && p.recordUploader != nil {
+		p.recordUploader.Close()
+		p.recordUploader = nil
+	}
+
+	if d.closeRecorderCleaner && p.recordCleaner != nil {
+		if p.metrics != nil {
+			p.metrics.SetRecordCleaner(nil)
+		}
+
 		p.recordCleaner.Close()
 		p.recordCleaner = nil
 	}
 
-	if closePPROF && p.pprof != nil {
+	if d.closePPROF && p.pprof != nil {
 		p.pprof.Close()
 		p.pprof = nil
 	}
 
-	if closeMetrics && p.metrics != nil {
+	if d.closeOTLPExporter && p.otlpExporter != nil {
+		p.otlpExporter.Close()
+		p.otlpExporter = nil
+	}
+
+	if d.closeMetrics && p.metrics != nil {
 		p.metrics.Close()
 		p.metrics = nil
 	}
 
-	if closeAuthManager && p.authManager != nil {
+	if d.closeAuthManager && p.authManager != nil {
 		p.authManager = nil
 	}
 
@@ -960,7 +1372,7 @@ func (p *Core) closeResources(newConf *conf.Conf, calledByAPI bool) {
 		p.externalCmdPool.Close()
 	}
 
-	if closeLogger && p.logger != nil {
+	if d.closeLogger && p.logger != nil {
 		p.logger.Close()
 		p.logger = nil
 	}
@@ -969,7 +1381,172 @@ func (p *Core) closeResources(newConf *conf.Conf, calledByAPI bool) {
 func (p *Core) reloadConf(newConf *conf.Conf, calledByAPI bool) error {
 	p.closeResources(newConf, calledByAPI)
 	p.conf = newConf
-	return p.createResources(false)
+
+	err := p.createResources(false)
+	if err != nil {
+		return err
+	}
+
+	p.eventBus.Publish(&events.Event{
+		Type: events.TypeConfigReloaded,
+	})
+
+	return nil
+}
+
+// apiConfigDiff returns the running components and the paths
+// that would be affected by switching to newConf.
+func (p *Core) apiConfigDiff(newConf *conf.Conf) *defs.APIConfigDiff {
+	d := newConfDiff(p.conf, newConf)
+
+	out := &defs.APIConfigDiff{
+		RestartedComponents: []string{},
+		AddedPaths:          []string{},
+		RemovedPaths:        []string{},
+		ReloadedPaths:       []string{},
+		RestartedPaths:      []string{},
+	}
+
+	for _, c := range []struct {
+		name    string
+		close   bool
+		running bool
+	}{
+		{"logger", d.closeLogger, p.logger != nil},
+		{"authManager", d.closeAuthManager, p.authManager != nil},
+		{"metrics", d.closeMetrics, p.metrics != nil},
+		{"otlpExporter", d.closeOTLPExporter, p.otlpExporter != nil},
+		{"pprof", d.closePPROF, p.pprof != nil},
+		{"recordCleaner", d.closeRecorderCleaner, p.recordCleaner != nil},
+		{"recordUploader", d.closeRecordUploader, p.recordUploader != nil},
+		{"playbackServer", d.closePlaybackServer, p.playbackServer != nil},
+		{"pathManager", d.closePathManager, p.pathManager != nil},
+		{"rtspServer", d.closeRTSPServer, p.rtspServer != nil},
+		{"rtspsServer", d.closeRTSPSServer, p.rtspsServer != nil},
+		{"rtmpServer", d.closeRTMPServer, p.rtmpServer != nil},
+		{"rtmpsServer", d.closeRTMPSServer, p.rtmpsServer != nil},
+		{"hlsServer", d.closeHLSServer, p.hlsServer != nil},
+		{"webrtcServer", d.closeWebRTCServer, p.webRTCServer != nil},
+		{"srtServer", d.closeSRTServer, p.srtServer != nil},
+		{"httpflvServer", d.closeHTTPFLVServer, p.httpFLVServer != nil},
+		{"httptsServer", d.closeHTTPTSServer, p.httpTSServer != nil},
+		{"dashServer", d.closeDASHServer, p.dashServer != nil},
+		{"api", d.closeAPI, p.api != nil},
+	} {
+		if c.close && c.running {
+			out.RestartedComponents = append(out.RestartedComponents, c.name)
+		}
+	}
+
+	for name, oldPath := range p.conf.Paths {
+		newPath, ok := newConf.Paths[name]
+
+		switch {
+		case !ok:
+			out.RemovedPaths = append(out.RemovedPaths, name)
+
+		case d.closePathManager:
+			out.RestartedPaths = append(out.RestartedPaths, name)
+
+		case newPath.Equal(oldPath):
+
+		case pathConfCanBeUpdated(oldPath, newPath):
+			out.ReloadedPaths = append(out.ReloadedPaths, name)
+
+		default:
+			out.RestartedPaths = append(out.RestartedPaths, name)
+		}
+	}
+
+	for name := range newConf.Paths {
+		if _, ok := p.conf.Paths[name]; !ok {
+			out.AddedPaths = append(out.AddedPaths, name)
+		}
+	}
+
+	sort.Strings(out.AddedPaths)
+	sort.Strings(out.RemovedPaths)
+	sort.Strings(out.ReloadedPaths)
+	sort.Strings(out.RestartedPaths)
+
+	return out
+}
+
+// persistConf writes changes performed through the API into the configuration file.
+func (p *Core) persistConf(prevConf *conf.Conf, newConf *conf.Conf) error {
+	if p.confPath == "" {
+		return fmt.Errorf("configuration file not found")
+	}
+
+	_, ok1 := os.LookupEnv("RTSP_CONFKEY")
+	_, ok2 := os.LookupEnv("MTX_CONFKEY")
+	if ok1 || ok2 {
+		return fmt.Errorf("encrypted configuration files are not supported")
+	}
+
+	// changes can't be assigned to the right file
+	files, err := conf.IncludedFiles(p.confPath, prevConf.Include)
+	if err != nil {
+		return err
+	}
+	if len(files) != 0 {
+		return fmt.Errorf("configurations split into multiple files are not supported")
+	}
+
+	// in case of symlinks, replace the target
+	fpath, err := filepath.EvalSymlinks(p.confPath)
+	if err != nil {
+		return err
+	}
+
+	fi, err := os.Stat(fpath)
+	if err != nil {
+		return err
+	}
+
+	byts, err := os.ReadFile(fpath)
+	if err != nil {
+		return err
+	}
+
+	byts, err = yaml.Update(byts, prevConf, newConf)
+	if err != nil {
+		return err
+	}
+
+	// do not reload the configuration after writing it
+	if p.confWatcher != nil {
+		p.confWatcher.IgnoreContent(byts)
+	}
+
+	// write to a temporary file and then rename it, in order to never leave a partial file on disk.
+	tmp, err := os.CreateTemp(filepath.Dir(fpath), filepath.Base(fpath)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(byts)
+	tmp.Close()
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	err = os.Chmod(tmp.Name(), fi.Mode())
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	err = os.Rename(tmp.Name(), fpath)
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	p.Log(logger.Info, "configuration saved to %s", p.confPath)
+
+	return nil
 }
 
 // APIConfigSet is called by api.
@@ -979,3 +1556,19 @@ func (p *Core) APIConfigSet(conf *conf.Conf) {
 	case <-p.ctx.Done():
 	}
 }
+
+// APIConfigDiff is called by api.
+func (p *Core) APIConfigDiff(conf *conf.Conf) (*defs.APIConfigDiff, error) {
+	req := coreAPIConfigDiffReq{
+		conf: conf,
+		res:  make(chan *defs.APIConfigDiff),
+	}
+
+	select {
+	case p.chAPIConfigDiff <- req:
+		return <-req.res, nil
+
+	case <-p.ctx.Done():
+		return nil, fmt.Errorf("terminated")
+	}
+}
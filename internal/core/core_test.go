@@ -94,6 +94,35 @@ func TestCoreErrors(t *testing.T) {
 	}
 }
 
+func TestCoreCheckConf(t *testing.T) {
+	tmpf, err := test.CreateTempFile([]byte("paths:\n" +
+		"  mypath:\n"))
+	require.NoError(t, err)
+	defer os.Remove(tmpf)
+
+	confPath, err := checkConf(tmpf)
+	require.NoError(t, err)
+	require.Equal(t, tmpf, confPath)
+
+	err = os.WriteFile(tmpf, []byte("paths:\n"+
+		"  mypath:\n"+
+		"    sourceOnDemand: yes\n"), 0o644)
+	require.NoError(t, err)
+
+	_, err = checkConf(tmpf)
+	require.EqualError(t, err, "paths.mypath.sourceOnDemand: 'sourceOnDemand' is useless when source is 'publisher'")
+
+	err = os.WriteFile(tmpf, []byte("writeQueueSize: 1001\n"+
+		"paths:\n"+
+		"  mypath:\n"+
+		"    sourceOnDemand: yes\n"), 0o644)
+	require.NoError(t, err)
+
+	_, err = checkConf(tmpf)
+	require.EqualError(t, err, "writeQueueSize: 'writeQueueSize' must be a power of two\n"+
+		"paths.mypath.sourceOnDemand: 'sourceOnDemand' is useless when source is 'publisher'")
+}
+
 func TestCoreHotReloading(t *testing.T) {
 	confPath := filepath.Join(os.TempDir(), "rtsp-conf")
 
@@ -4,9 +4,11 @@ import (
 	"bufio"
 	"context"
 	"crypto/tls"
+	"encoding/json"
 	"io"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
 	"sync"
@@ -238,21 +240,75 @@ webrtc_sessions_bytes_sent 0
 			`^paths\{name=".*?",state="ready"\} 1`+"\n"+
 				`paths_bytes_received\{name=".*?",state="ready"\} [0-9]+`+"\n"+
 				`paths_bytes_sent\{name=".*?",state="ready"\} [0-9]+`+"\n"+
+				`paths_tracks_bitrate\{name=".*?",track="0",codec="H264"\} [0-9.]+`+"\n"+
+				`paths_tracks_frame_rate\{name=".*?",track="0",codec="H264"\} [0-9.]+`+"\n"+
+				`paths_tracks_keyframe_interval\{name=".*?",track="0",codec="H264"\} [0-9.]+`+"\n"+
+				`paths_tracks_width\{name=".*?",track="0",codec="H264"\} [0-9]+`+"\n"+
+				`paths_tracks_height\{name=".*?",track="0",codec="H264"\} [0-9]+`+"\n"+
+				`paths_tracks_timestamp_discontinuities\{name=".*?",track="0",codec="H264"\} 0`+"\n"+
+				`paths_tracks_rtp_packets_received\{name=".*?",track="0",codec="H264"\} [0-9]+`+"\n"+
+				`paths_tracks_rtp_packets_lost\{name=".*?",track="0",codec="H264"\} 0`+"\n"+
+				`paths_tracks_jitter\{name=".*?",track="0",codec="H264"\} [0-9.e-]+`+"\n"+
 				`paths\{name=".*?",state="ready"\} 1`+"\n"+
 				`paths_bytes_received\{name=".*?",state="ready"\} [0-9]+`+"\n"+
 				`paths_bytes_sent\{name=".*?",state="ready"\} [0-9]+`+"\n"+
+				`paths_tracks_bitrate\{name=".*?",track="0",codec="H264"\} [0-9.]+`+"\n"+
+				`paths_tracks_frame_rate\{name=".*?",track="0",codec="H264"\} [0-9.]+`+"\n"+
+				`paths_tracks_keyframe_interval\{name=".*?",track="0",codec="H264"\} [0-9.]+`+"\n"+
+				`paths_tracks_width\{name=".*?",track="0",codec="H264"\} [0-9]+`+"\n"+
+				`paths_tracks_height\{name=".*?",track="0",codec="H264"\} [0-9]+`+"\n"+
+				`paths_tracks_timestamp_discontinuities\{name=".*?",track="0",codec="H264"\} 0`+"\n"+
+				`paths_tracks_rtp_packets_received\{name=".*?",track="0",codec="H264"\} [0-9]+`+"\n"+
+				`paths_tracks_rtp_packets_lost\{name=".*?",track="0",codec="H264"\} 0`+"\n"+
+				`paths_tracks_jitter\{name=".*?",track="0",codec="H264"\} [0-9.e-]+`+"\n"+
 				`paths\{name=".*?",state="ready"\} 1`+"\n"+
 				`paths_bytes_received\{name=".*?",state="ready"\} [0-9]+`+"\n"+
 				`paths_bytes_sent\{name=".*?",state="ready"\} [0-9]+`+"\n"+
+				`paths_tracks_bitrate\{name=".*?",track="0",codec="H264"\} [0-9.]+`+"\n"+
+				`paths_tracks_frame_rate\{name=".*?",track="0",codec="H264"\} [0-9.]+`+"\n"+
+				`paths_tracks_keyframe_interval\{name=".*?",track="0",codec="H264"\} [0-9.]+`+"\n"+
+				`paths_tracks_width\{name=".*?",track="0",codec="H264"\} [0-9]+`+"\n"+
+				`paths_tracks_height\{name=".*?",track="0",codec="H264"\} [0-9]+`+"\n"+
+				`paths_tracks_timestamp_discontinuities\{name=".*?",track="0",codec="H264"\} 0`+"\n"+
+				`paths_tracks_rtp_packets_received\{name=".*?",track="0",codec="H264"\} [0-9]+`+"\n"+
+				`paths_tracks_rtp_packets_lost\{name=".*?",track="0",codec="H264"\} 0`+"\n"+
+				`paths_tracks_jitter\{name=".*?",track="0",codec="H264"\} [0-9.e-]+`+"\n"+
 				`paths\{name=".*?",state="ready"\} 1`+"\n"+
 				`paths_bytes_received\{name=".*?",state="ready"\} [0-9]+`+"\n"+
 				`paths_bytes_sent\{name=".*?",state="ready"\} [0-9]+`+"\n"+
+				`paths_tracks_bitrate\{name=".*?",track="0",codec="H264"\} [0-9.]+`+"\n"+
+				`paths_tracks_frame_rate\{name=".*?",track="0",codec="H264"\} [0-9.]+`+"\n"+
+				`paths_tracks_keyframe_interval\{name=".*?",track="0",codec="H264"\} [0-9.]+`+"\n"+
+				`paths_tracks_width\{name=".*?",track="0",codec="H264"\} [0-9]+`+"\n"+
+				`paths_tracks_height\{name=".*?",track="0",codec="H264"\} [0-9]+`+"\n"+
+				`paths_tracks_timestamp_discontinuities\{name=".*?",track="0",codec="H264"\} 0`+"\n"+
+				`paths_tracks_rtp_packets_received\{name=".*?",track="0",codec="H264"\} [0-9]+`+"\n"+
+				`paths_tracks_rtp_packets_lost\{name=".*?",track="0",codec="H264"\} 0`+"\n"+
+				`paths_tracks_jitter\{name=".*?",track="0",codec="H264"\} [0-9.e-]+`+"\n"+
 				`paths\{name=".*?",state="ready"\} 1`+"\n"+
 				`paths_bytes_received\{name=".*?",state="ready"\} [0-9]+`+"\n"+
 				`paths_bytes_sent\{name=".*?",state="ready"\} [0-9]+`+"\n"+
+				`paths_tracks_bitrate\{name=".*?",track="0",codec="H264"\} [0-9.]+`+"\n"+
+				`paths_tracks_frame_rate\{name=".*?",track="0",codec="H264"\} [0-9.]+`+"\n"+
+				`paths_tracks_keyframe_interval\{name=".*?",track="0",codec="H264"\} [0-9.]+`+"\n"+
+				`paths_tracks_width\{name=".*?",track="0",codec="H264"\} [0-9]+`+"\n"+
+				`paths_tracks_height\{name=".*?",track="0",codec="H264"\} [0-9]+`+"\n"+
+				`paths_tracks_timestamp_discontinuities\{name=".*?",track="0",codec="H264"\} 0`+"\n"+
+				`paths_tracks_rtp_packets_received\{name=".*?",track="0",codec="H264"\} [0-9]+`+"\n"+
+				`paths_tracks_rtp_packets_lost\{name=".*?",track="0",codec="H264"\} 0`+"\n"+
+				`paths_tracks_jitter\{name=".*?",track="0",codec="H264"\} [0-9.e-]+`+"\n"+
 				`paths\{name=".*?",state="ready"\} 1`+"\n"+
 				`paths_bytes_received\{name=".*?",state="ready"\} [0-9]+`+"\n"+
 				`paths_bytes_sent\{name=".*?",state="ready"\} [0-9]+`+"\n"+
+				`paths_tracks_bitrate\{name=".*?",track="0",codec="H264"\} [0-9.]+`+"\n"+
+				`paths_tracks_frame_rate\{name=".*?",track="0",codec="H264"\} [0-9.]+`+"\n"+
+				`paths_tracks_keyframe_interval\{name=".*?",track="0",codec="H264"\} [0-9.]+`+"\n"+
+				`paths_tracks_width\{name=".*?",track="0",codec="H264"\} [0-9]+`+"\n"+
+				`paths_tracks_height\{name=".*?",track="0",codec="H264"\} [0-9]+`+"\n"+
+				`paths_tracks_timestamp_discontinuities\{name=".*?",track="0",codec="H264"\} 0`+"\n"+
+				`paths_tracks_rtp_packets_received\{name=".*?",track="0",codec="H264"\} [0-9]+`+"\n"+
+				`paths_tracks_rtp_packets_lost\{name=".*?",track="0",codec="H264"\} 0`+"\n"+
+				`paths_tracks_jitter\{name=".*?",track="0",codec="H264"\} [0-9.e-]+`+"\n"+
 				`hls_muxers\{name=".*?"\} 1`+"\n"+
 				`hls_muxers_bytes_sent\{name=".*?"\} 0`+"\n"+
 				`hls_muxers\{name=".*?"\} 1`+"\n"+
@@ -361,3 +417,84 @@ webrtc_sessions_bytes_sent 0
 		require.Equal(t, "paths 0\n", string(bo))
 	})
 }
+
+func TestOTLP(t *testing.T) {
+	var mutex sync.Mutex
+	var metricNames []string
+	var spanNames []string
+	traceIDs := make(map[string]struct{})
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg struct {
+			ResourceMetrics []struct {
+				ScopeMetrics []struct {
+					Metrics []struct {
+						Name string `json:"name"`
+					} `json:"metrics"`
+				} `json:"scopeMetrics"`
+			} `json:"resourceMetrics"`
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []struct {
+						TraceID string `json:"traceId"`
+						Name    string `json:"name"`
+					} `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+		err := json.NewDecoder(r.Body).Decode(&msg)
+		require.NoError(t, err)
+
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		switch r.URL.Path {
+		case "/v1/metrics":
+			for _, m := range msg.ResourceMetrics[0].ScopeMetrics[0].Metrics {
+				metricNames = append(metricNames, m.Name)
+			}
+
+		case "/v1/traces":
+			for _, s := range msg.ResourceSpans[0].ScopeSpans[0].Spans {
+				spanNames = append(spanNames, s.Name)
+				traceIDs[s.TraceID] = struct{}{}
+			}
+		}
+	}))
+	defer ts.Close()
+
+	p, ok := newInstance("otlp: yes\n" +
+		"otlpAddress: " + ts.URL + "\n" +
+		"otlpInterval: 100ms\n" +
+		"paths:\n" +
+		"  all_others:\n")
+	require.Equal(t, true, ok)
+	defer p.Close()
+
+	source := gortsplib.Client{}
+	err := source.StartRecording(
+		"rtsp://localhost:8554/mypath",
+		&description.Session{Medias: []*description.Media{test.UniqueMediaH264()}})
+	require.NoError(t, err)
+	source.Close()
+
+	for i := 0; ; i++ {
+		time.Sleep(100 * time.Millisecond)
+
+		mutex.Lock()
+		done := len(spanNames) == 5
+		mutex.Unlock()
+
+		if done || i == 50 {
+			break
+		}
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	require.Contains(t, metricNames, "paths")
+	require.Contains(t, metricNames, "rtsp_sessions")
+	require.Equal(t, []string{"path lookup", "auth", "publisher add", "teardown", "session"}, spanNames)
+	require.Equal(t, 1, len(traceIDs))
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"reflect"
 	"strconv"
 	"strings"
 	"sync"
@@ -11,12 +12,16 @@ import (
 
 	"github.com/bluenviron/gortsplib/v4/pkg/base"
 	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	"github.com/google/uuid"
 
 	"github.com/bluenviron/mediamtx/internal/conf"
 	"github.com/bluenviron/mediamtx/internal/defs"
+	"github.com/bluenviron/mediamtx/internal/events"
 	"github.com/bluenviron/mediamtx/internal/externalcmd"
 	"github.com/bluenviron/mediamtx/internal/hooks"
 	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/bluenviron/mediamtx/internal/otlp"
+	"github.com/bluenviron/mediamtx/internal/push"
 	"github.com/bluenviron/mediamtx/internal/record"
 	"github.com/bluenviron/mediamtx/internal/stream"
 )
@@ -32,6 +37,7 @@ type pathParent interface {
 	pathReady(*path)
 	pathNotReady(*path)
 	closePath(*path)
+	recordSessionStopped(*defs.APIRecordingSession)
 }
 
 type pathOnDemandState int
@@ -63,6 +69,30 @@ type pathAPIPathsGetReq struct {
 	res  chan pathAPIPathsGetRes
 }
 
+type pathAPIRecordingStartRes struct {
+	data *defs.APIRecordingSession
+	err  error
+}
+
+type pathAPIRecordingStartReq struct {
+	overrides defs.APIRecordingSessionStartReq
+	res       chan pathAPIRecordingStartRes
+}
+
+type pathAPIRecordingStopRes struct {
+	data []*defs.APIRecordingSession
+	err  error
+}
+
+type pathAPIRecordingStopReq struct {
+	id  *uuid.UUID
+	res chan pathAPIRecordingStopRes
+}
+
+type pathAPIRecordingSessionsListReq struct {
+	res chan []*defs.APIRecordingSession
+}
+
 type path struct {
 	parentCtx         context.Context
 	logLevel          conf.LogLevel
@@ -77,6 +107,9 @@ type path struct {
 	matches           []string
 	wg                *sync.WaitGroup
 	externalCmdPool   *externalcmd.Pool
+	recordUploader    *record.Uploader
+	eventBus          *events.Bus
+	otlpExporter      *otlp.Exporter
 	parent            pathParent
 
 	ctx                            context.Context
@@ -86,6 +119,9 @@ type path struct {
 	publisherQuery                 string
 	stream                         *stream.Stream
 	recordAgent                    *record.Agent
+	preRollBuffer                  *record.PreRollBuffer
+	recordSessions                 map[uuid.UUID]*recordSession
+	pushAgent                      *push.Agent
 	readyTime                      time.Time
 	onUnDemandHook                 func(string)
 	onNotReadyHook                 func()
@@ -111,6 +147,10 @@ type path struct {
 	chAddReader               chan defs.PathAddReaderReq
 	chRemoveReader            chan defs.PathRemoveReaderReq
 	chAPIPathsGet             chan pathAPIPathsGetReq
+	chAPIRecordingStart       chan pathAPIRecordingStartReq
+	chAPIRecordingStop        chan pathAPIRecordingStopReq
+	chAPIRecordingSessions    chan pathAPIRecordingSessionsListReq
+	chRecordSessionExpired    chan *recordSession
 
 	// out
 	done chan struct{}
@@ -122,6 +162,7 @@ func (pa *path) initialize() {
 	pa.ctx = ctx
 	pa.ctxCancel = ctxCancel
 	pa.readers = make(map[defs.Reader]struct{})
+	pa.recordSessions = make(map[uuid.UUID]*recordSession)
 	pa.onDemandStaticSourceReadyTimer = emptyTimer()
 	pa.onDemandStaticSourceCloseTimer = emptyTimer()
 	pa.onDemandPublisherReadyTimer = emptyTimer()
@@ -137,6 +178,10 @@ func (pa *path) initialize() {
 	pa.chAddReader = make(chan defs.PathAddReaderReq)
 	pa.chRemoveReader = make(chan defs.PathRemoveReaderReq)
 	pa.chAPIPathsGet = make(chan pathAPIPathsGetReq)
+	pa.chAPIRecordingStart = make(chan pathAPIRecordingStartReq)
+	pa.chAPIRecordingStop = make(chan pathAPIRecordingStopReq)
+	pa.chAPIRecordingSessions = make(chan pathAPIRecordingSessionsListReq)
+	pa.chRecordSessionExpired = make(chan *recordSession)
 	pa.done = make(chan struct{})
 
 	pa.Log(logger.Debug, "created")
@@ -155,7 +200,7 @@ func (pa *path) wait() {
 
 // Log implements logger.Writer.
 func (pa *path) Log(level logger.Level, format string, args ...interface{}) {
-	pa.parent.Log(level, "[path "+pa.name+"] "+format, args...)
+	pa.parent.Log(level, "%v"+format, append([]interface{}{logger.Path(pa.name)}, args...)...)
 }
 
 func (pa *path) Name() string {
@@ -166,24 +211,33 @@ func (pa *path) run() {
 	defer close(pa.done)
 	defer pa.wg.Done()
 
-	if pa.conf.Source == "redirect" {
+	if pa.conf.PrimarySource() == "redirect" {
 		pa.source = &sourceRedirect{}
 	} else if pa.conf.HasStaticSource() {
-		resolvedSource := pa.conf.Source
-		if len(pa.matches) > 1 {
-			for i, ma := range pa.matches[1:] {
-				resolvedSource = strings.ReplaceAll(resolvedSource, "$G"+strconv.FormatInt(int64(i+1), 10), ma)
+		resolve := func(source string) string {
+			if len(pa.matches) > 1 {
+				for i, ma := range pa.matches[1:] {
+					source = strings.ReplaceAll(source, "$G"+strconv.FormatInt(int64(i+1), 10), ma)
+				}
 			}
+			return source
+		}
+
+		resolvedSources := make([]string, len(pa.conf.Sources))
+		for i, source := range pa.conf.Sources {
+			resolvedSources[i] = resolve(source)
 		}
 
 		pa.source = &staticSourceHandler{
-			conf:           pa.conf,
-			logLevel:       pa.logLevel,
-			readTimeout:    pa.readTimeout,
-			writeTimeout:   pa.writeTimeout,
-			writeQueueSize: pa.writeQueueSize,
-			resolvedSource: resolvedSource,
-			parent:         pa,
+			conf:              pa.conf,
+			logLevel:          pa.logLevel,
+			readTimeout:       pa.readTimeout,
+			writeTimeout:      pa.writeTimeout,
+			writeQueueSize:    pa.writeQueueSize,
+			udpMaxPayloadSize: pa.udpMaxPayloadSize,
+			resolvedSource:    resolve(pa.conf.PrimarySource()),
+			resolvedSources:   resolvedSources,
+			parent:            pa,
 		}
 		pa.source.(*staticSourceHandler).initialize()
 
@@ -225,13 +279,19 @@ func (pa *path) run() {
 		pa.setNotReady()
 	}
 
+	for _, s := range pa.recordSessions {
+		pa.stopRecordSession(s)
+	}
+
 	if pa.source != nil {
 		if source, ok := pa.source.(*staticSourceHandler); ok {
 			if !pa.conf.SourceOnDemand || pa.onDemandStaticSourceState != pathOnDemandStateInitial {
 				source.close("path is closing")
 			}
 		} else if source, ok := pa.source.(defs.Publisher); ok {
+			span := pa.startTeardownSpan(source.APISourceDescribe())
 			source.Close()
+			span.End(nil)
 		}
 	}
 
@@ -322,6 +382,26 @@ func (pa *path) runInner() error {
 		case req := <-pa.chAPIPathsGet:
 			pa.doAPIPathsGet(req)
 
+		case req := <-pa.chAPIRecordingStart:
+			pa.doAPIRecordingStart(req)
+
+		case req := <-pa.chAPIRecordingStop:
+			pa.doAPIRecordingStop(req)
+
+			if pa.shouldClose() {
+				return fmt.Errorf("not in use")
+			}
+
+		case req := <-pa.chAPIRecordingSessions:
+			pa.doAPIRecordingSessionsList(req)
+
+		case s := <-pa.chRecordSessionExpired:
+			pa.doRecordSessionExpired(s)
+
+			if pa.shouldClose() {
+				return fmt.Errorf("not in use")
+			}
+
 		case <-pa.ctx.Done():
 			return fmt.Errorf("terminated")
 		}
@@ -366,6 +446,9 @@ func (pa *path) doOnDemandPublisherCloseTimer() {
 }
 
 func (pa *path) doReloadConf(newConf *conf.Path) {
+	pushChanged := !reflect.DeepEqual(pa.conf.Push, newConf.Push) || pa.conf.UDPOutput != newConf.UDPOutput
+	preRollChanged := pa.conf.RecordPreRoll != newConf.RecordPreRoll
+
 	pa.confMutex.Lock()
 	pa.conf = newConf
 	pa.confMutex.Unlock()
@@ -374,6 +457,18 @@ func (pa *path) doReloadConf(newConf *conf.Path) {
 		pa.source.(*staticSourceHandler).reloadConf(newConf)
 	}
 
+	// the buffer is only read when a recording starts, therefore it can be replaced at any time.
+	if preRollChanged {
+		if pa.preRollBuffer != nil {
+			pa.preRollBuffer.Close()
+			pa.preRollBuffer = nil
+		}
+
+		if pa.stream != nil && pa.conf.RecordPreRoll != 0 {
+			pa.startPreRollBuffer()
+		}
+	}
+
 	if pa.conf.Record {
 		if pa.stream != nil && pa.recordAgent == nil {
 			pa.startRecording()
@@ -382,6 +477,17 @@ func (pa *path) doReloadConf(newConf *conf.Path) {
 		pa.recordAgent.Close()
 		pa.recordAgent = nil
 	}
+
+	if pushChanged {
+		if pa.pushAgent != nil {
+			pa.pushAgent.Close()
+			pa.pushAgent = nil
+		}
+
+		if pa.stream != nil && len(pa.pushTargets()) != 0 {
+			pa.startPushing()
+		}
+	}
 }
 
 func (pa *path) doSourceStaticSetReady(req defs.PathSourceStaticSetReadyReq) {
@@ -473,7 +579,7 @@ func (pa *path) doRemovePublisher(req defs.PathRemovePublisherReq) {
 }
 
 func (pa *path) doAddPublisher(req defs.PathAddPublisherReq) {
-	if pa.conf.Source != "publisher" {
+	if pa.conf.PrimarySource() != "publisher" {
 		req.Res <- defs.PathAddPublisherRes{
 			Err: fmt.Errorf("can't publish to path '%s' since 'source' is not 'publisher'", pa.name),
 		}
@@ -494,6 +600,13 @@ func (pa *path) doAddPublisher(req defs.PathAddPublisherReq) {
 	pa.source = req.Author
 	pa.publisherQuery = req.AccessRequest.Query
 
+	desc := req.Author.APISourceDescribe()
+	pa.eventBus.Publish(&events.Event{
+		Type:   events.TypePublisherAdded,
+		Path:   pa.name,
+		Source: &desc,
+	})
+
 	req.Res <- defs.PathAddPublisherRes{Path: pa}
 }
 
@@ -601,6 +714,12 @@ func (pa *path) doAPIPathsGet(req pathAPIPathsGetReq) {
 				}
 				return defs.MediasToCodecs(pa.stream.Desc().Medias)
 			}(),
+			TrackStats: func() []defs.APIPathTrackStats {
+				if pa.stream == nil {
+					return []defs.APIPathTrackStats{}
+				}
+				return defs.FormatStatsToAPI(pa.stream.Stats())
+			}(),
 			BytesReceived: func() uint64 {
 				if pa.stream == nil {
 					return 0
@@ -620,10 +739,103 @@ func (pa *path) doAPIPathsGet(req pathAPIPathsGetReq) {
 				}
 				return ret
 			}(),
+			Push: func() []defs.APIPathPushTarget {
+				if pa.pushAgent == nil {
+					return []defs.APIPathPushTarget{}
+				}
+				return pa.pushAgent.APITargets()
+			}(),
 		},
 	}
 }
 
+func (pa *path) doAPIRecordingStart(req pathAPIRecordingStartReq) {
+	s := &recordSession{
+		recordPath: pa.conf.RecordPath,
+		format:     pa.conf.RecordFormat,
+		parent:     pa,
+	}
+
+	if req.overrides.RecordPath != nil {
+		s.recordPath = *req.overrides.RecordPath
+	}
+	if req.overrides.Format != nil {
+		s.format = *req.overrides.Format
+	}
+	if req.overrides.MaxDuration != nil {
+		s.maxDuration = time.Duration(*req.overrides.MaxDuration)
+	}
+
+	s.initialize()
+	pa.recordSessions[s.id] = s
+
+	if pa.stream != nil {
+		s.start(pa.stream)
+	}
+
+	pa.Log(logger.Info, "recording session %s started", s.id)
+
+	req.res <- pathAPIRecordingStartRes{data: s.apiItem()}
+}
+
+func (pa *path) doAPIRecordingStop(req pathAPIRecordingStopReq) {
+	var sessions []*recordSession
+
+	if req.id != nil {
+		s, ok := pa.recordSessions[*req.id]
+		if !ok {
+			req.res <- pathAPIRecordingStopRes{err: defs.ErrRecordingSessionNotFound}
+			return
+		}
+		sessions = []*recordSession{s}
+	} else {
+		for _, s := range pa.recordSessions {
+			sessions = append(sessions, s)
+		}
+	}
+
+	data := []*defs.APIRecordingSession{}
+
+	for _, s := range sessions {
+		data = append(data, pa.stopRecordSession(s))
+	}
+
+	req.res <- pathAPIRecordingStopRes{data: data}
+}
+
+func (pa *path) doAPIRecordingSessionsList(req pathAPIRecordingSessionsListReq) {
+	data := []*defs.APIRecordingSession{}
+
+	for _, s := range pa.recordSessions {
+		data = append(data, s.apiItem())
+	}
+
+	req.res <- data
+}
+
+func (pa *path) doRecordSessionExpired(s *recordSession) {
+	if _, ok := pa.recordSessions[s.id]; !ok {
+		return
+	}
+
+	pa.Log(logger.Info, "recording session %s reached its maximum duration", s.id)
+	pa.stopRecordSession(s)
+}
+
+// stopRecordSession stops a session and hands it over to the parent,
+// that keeps it for recordSessionRetention.
+func (pa *path) stopRecordSession(s *recordSession) *defs.APIRecordingSession {
+	s.stop()
+	delete(pa.recordSessions, s.id)
+
+	item := s.apiItem()
+	pa.parent.recordSessionStopped(item)
+
+	pa.Log(logger.Info, "recording session %s stopped", s.id)
+
+	return item
+}
+
 func (pa *path) SafeConf() *conf.Path {
 	pa.confMutex.RLock()
 	defer pa.confMutex.RUnlock()
@@ -651,6 +863,7 @@ func (pa *path) shouldClose() bool {
 	return pa.conf.Regexp != nil &&
 		pa.source == nil &&
 		len(pa.readers) == 0 &&
+		len(pa.recordSessions) == 0 &&
 		len(pa.describeRequestsOnHold) == 0 &&
 		len(pa.readerAddRequestsOnHold) == 0
 }
@@ -728,10 +941,22 @@ func (pa *path) setReady(desc *description.Session, allocateEncoder bool) error
 		return err
 	}
 
+	if pa.conf.RecordPreRoll != 0 {
+		pa.startPreRollBuffer()
+	}
+
 	if pa.conf.Record {
 		pa.startRecording()
 	}
 
+	if len(pa.pushTargets()) != 0 {
+		pa.startPushing()
+	}
+
+	for _, s := range pa.recordSessions {
+		s.start(pa.stream)
+	}
+
 	pa.readyTime = time.Now()
 
 	pa.onNotReadyHook = hooks.OnReady(hooks.OnReadyParams{
@@ -745,6 +970,13 @@ func (pa *path) setReady(desc *description.Session, allocateEncoder bool) error
 
 	pa.parent.pathReady(pa)
 
+	sourceDesc := pa.source.APISourceDescribe()
+	pa.eventBus.Publish(&events.Event{
+		Type:   events.TypePathReady,
+		Path:   pa.name,
+		Source: &sourceDesc,
+	})
+
 	return nil
 }
 
@@ -765,6 +997,11 @@ func (pa *path) consumeOnHoldRequests() {
 func (pa *path) setNotReady() {
 	pa.parent.pathNotReady(pa)
 
+	pa.eventBus.Publish(&events.Event{
+		Type: events.TypePathNotReady,
+		Path: pa.name,
+	})
+
 	for r := range pa.readers {
 		pa.executeRemoveReader(r)
 		r.Close()
@@ -777,12 +1014,36 @@ func (pa *path) setNotReady() {
 		pa.recordAgent = nil
 	}
 
+	if pa.pushAgent != nil {
+		pa.pushAgent.Close()
+		pa.pushAgent = nil
+	}
+
+	for _, s := range pa.recordSessions {
+		s.pause()
+	}
+
+	if pa.preRollBuffer != nil {
+		pa.preRollBuffer.Close()
+		pa.preRollBuffer = nil
+	}
+
 	if pa.stream != nil {
 		pa.stream.Close()
 		pa.stream = nil
 	}
 }
 
+func (pa *path) startPreRollBuffer() {
+	pa.preRollBuffer = &record.PreRollBuffer{
+		Duration:       time.Duration(pa.conf.RecordPreRoll),
+		WriteQueueSize: pa.writeQueueSize,
+		Stream:         pa.stream,
+		Parent:         pa,
+	}
+	pa.preRollBuffer.Initialize()
+}
+
 func (pa *path) startRecording() {
 	pa.recordAgent = &record.Agent{
 		WriteQueueSize:  pa.writeQueueSize,
@@ -792,51 +1053,133 @@ func (pa *path) startRecording() {
 		SegmentDuration: time.Duration(pa.conf.RecordSegmentDuration),
 		PathName:        pa.name,
 		Stream:          pa.stream,
-		OnSegmentCreate: func(segmentPath string) {
-			if pa.conf.RunOnRecordSegmentCreate != "" {
-				env := pa.ExternalCmdEnv()
-				env["MTX_SEGMENT_PATH"] = segmentPath
-
-				pa.Log(logger.Info, "runOnRecordSegmentCreate command launched")
-				externalcmd.NewCmd(
-					pa.externalCmdPool,
-					pa.conf.RunOnRecordSegmentCreate,
-					false,
-					env,
-					nil)
-			}
-		},
+		PreRoll:         pa.preRollBuffer,
+		OnSegmentCreate: pa.onRecordSegmentCreate,
 		OnSegmentComplete: func(segmentPath string) {
-			if pa.conf.RunOnRecordSegmentComplete != "" {
-				env := pa.ExternalCmdEnv()
-				env["MTX_SEGMENT_PATH"] = segmentPath
-
-				pa.Log(logger.Info, "runOnRecordSegmentComplete command launched")
-				externalcmd.NewCmd(
-					pa.externalCmdPool,
-					pa.conf.RunOnRecordSegmentComplete,
-					false,
-					env,
-					nil)
-			}
+			pa.onRecordSegmentComplete(pa.conf.RecordPath, pa.conf.RecordFormat, segmentPath)
 		},
 		Parent: pa,
 	}
 	pa.recordAgent.Initialize()
 }
 
+func (pa *path) onRecordSegmentCreate(segmentPath string) {
+	pa.eventBus.Publish(&events.Event{
+		Type:        events.TypeRecordingSegmentCreated,
+		Path:        pa.name,
+		SegmentPath: segmentPath,
+	})
+
+	if pa.conf.RunOnRecordSegmentCreate != "" {
+		env := pa.ExternalCmdEnv()
+		env["MTX_SEGMENT_PATH"] = segmentPath
+
+		pa.Log(logger.Info, "runOnRecordSegmentCreate command launched")
+		externalcmd.NewCmd(
+			pa.externalCmdPool,
+			pa.conf.RunOnRecordSegmentCreate,
+			false,
+			env,
+			nil)
+	}
+}
+
+func (pa *path) onRecordSegmentComplete(recordPath string, recordFormat conf.RecordFormat, segmentPath string) {
+	pa.eventBus.Publish(&events.Event{
+		Type:        events.TypeRecordingSegmentCompleted,
+		Path:        pa.name,
+		SegmentPath: segmentPath,
+	})
+
+	if pa.conf.RunOnRecordSegmentComplete != "" {
+		env := pa.ExternalCmdEnv()
+		env["MTX_SEGMENT_PATH"] = segmentPath
+
+		pa.Log(logger.Info, "runOnRecordSegmentComplete command launched")
+		externalcmd.NewCmd(
+			pa.externalCmdPool,
+			pa.conf.RunOnRecordSegmentComplete,
+			false,
+			env,
+			nil)
+	}
+
+	if pa.conf.RecordUpload && pa.recordUploader != nil {
+		pa.recordUploader.Enqueue(
+			segmentPath,
+			record.UploadKey(
+				pa.conf.RecordUploadPrefix,
+				recordPath,
+				recordFormat,
+				pa.name,
+				segmentPath),
+			pa.conf.RecordUploadDeleteLocal)
+	}
+}
+
+// pushTargets returns push targets, including the UDP output.
+func (pa *path) pushTargets() []string {
+	targets := pa.conf.Push
+	if pa.conf.UDPOutput != "" {
+		targets = append(append([]string(nil), targets...), pa.conf.UDPOutput)
+	}
+	return targets
+}
+
+func (pa *path) startPushing() {
+	pa.pushAgent = &push.Agent{
+		WriteQueueSize:    pa.writeQueueSize,
+		ReadTimeout:       time.Duration(pa.readTimeout),
+		WriteTimeout:      time.Duration(pa.writeTimeout),
+		UDPMaxPayloadSize: pa.udpMaxPayloadSize,
+		Targets:           pa.pushTargets(),
+		Stream:            pa.stream,
+		Parent:            pa,
+	}
+	pa.pushAgent.Initialize()
+}
+
 func (pa *path) executeRemoveReader(r defs.Reader) {
+	desc := r.APIReaderDescribe()
+	span := pa.startTeardownSpan(desc)
+	defer span.End(nil)
+
 	delete(pa.readers, r)
+
+	pa.eventBus.Publish(&events.Event{
+		Type:   events.TypeReaderRemoved,
+		Path:   pa.name,
+		Reader: &desc,
+	})
 }
 
 func (pa *path) executeRemovePublisher() {
+	desc := pa.source.APISourceDescribe()
+	span := pa.startTeardownSpan(desc)
+	defer span.End(nil)
+
 	if pa.stream != nil {
 		pa.setNotReady()
 	}
 
+	pa.eventBus.Publish(&events.Event{
+		Type:   events.TypePublisherRemoved,
+		Path:   pa.name,
+		Source: &desc,
+	})
+
 	pa.source = nil
 }
 
+// startTeardownSpan starts the span that describes the teardown of a publisher or reader session.
+func (pa *path) startTeardownSpan(desc defs.APIPathSourceOrReader) *otlp.Span {
+	id, err := uuid.Parse(desc.ID)
+	if err != nil {
+		return nil
+	}
+	return pa.otlpExporter.StartTeardown(id, otlp.Attribute{Key: "path", Value: pa.name})
+}
+
 func (pa *path) addReaderPost(req defs.PathAddReaderReq) {
 	if _, ok := pa.readers[req.Author]; ok {
 		req.Res <- defs.PathAddReaderRes{
@@ -853,6 +1196,13 @@ func (pa *path) addReaderPost(req defs.PathAddReaderReq) {
 
 	pa.readers[req.Author] = struct{}{}
 
+	desc := req.Author.APIReaderDescribe()
+	pa.eventBus.Publish(&events.Event{
+		Type:   events.TypeReaderAdded,
+		Path:   pa.name,
+		Reader: &desc,
+	})
+
 	if pa.conf.HasOnDemandStaticSource() {
 		if pa.onDemandStaticSourceState == pathOnDemandStateClosing {
 			pa.onDemandStaticSourceState = pathOnDemandStateReady
@@ -1003,3 +1353,59 @@ func (pa *path) APIPathsGet(req pathAPIPathsGetReq) (*defs.APIPath, error) {
 		return nil, fmt.Errorf("terminated")
 	}
 }
+
+// APIRecordingStart is called by api.
+func (pa *path) APIRecordingStart(overrides defs.APIRecordingSessionStartReq) (*defs.APIRecordingSession, error) {
+	req := pathAPIRecordingStartReq{
+		overrides: overrides,
+		res:       make(chan pathAPIRecordingStartRes),
+	}
+
+	select {
+	case pa.chAPIRecordingStart <- req:
+		res := <-req.res
+		return res.data, res.err
+
+	case <-pa.ctx.Done():
+		return nil, fmt.Errorf("terminated")
+	}
+}
+
+// APIRecordingStop is called by api.
+func (pa *path) APIRecordingStop(id *uuid.UUID) ([]*defs.APIRecordingSession, error) {
+	req := pathAPIRecordingStopReq{
+		id:  id,
+		res: make(chan pathAPIRecordingStopRes),
+	}
+
+	select {
+	case pa.chAPIRecordingStop <- req:
+		res := <-req.res
+		return res.data, res.err
+
+	case <-pa.ctx.Done():
+		return nil, fmt.Errorf("terminated")
+	}
+}
+
+// APIRecordingSessionsList is called by api.
+func (pa *path) APIRecordingSessionsList() ([]*defs.APIRecordingSession, error) {
+	req := pathAPIRecordingSessionsListReq{
+		res: make(chan []*defs.APIRecordingSession),
+	}
+
+	select {
+	case pa.chAPIRecordingSessions <- req:
+		return <-req.res, nil
+
+	case <-pa.ctx.Done():
+		return nil, fmt.Errorf("terminated")
+	}
+}
+
+func (pa *path) recordSessionExpired(s *recordSession) {
+	select {
+	case pa.chRecordSessionExpired <- s:
+	case <-pa.ctx.Done():
+	}
+}
@@ -2,15 +2,21 @@ package core
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sort"
 	"sync"
 
+	"github.com/google/uuid"
+
 	"github.com/bluenviron/mediamtx/internal/auth"
 	"github.com/bluenviron/mediamtx/internal/conf"
 	"github.com/bluenviron/mediamtx/internal/defs"
+	"github.com/bluenviron/mediamtx/internal/events"
 	"github.com/bluenviron/mediamtx/internal/externalcmd"
 	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/bluenviron/mediamtx/internal/otlp"
+	"github.com/bluenviron/mediamtx/internal/record"
 	"github.com/bluenviron/mediamtx/internal/stream"
 )
 
@@ -19,6 +25,11 @@ func pathConfCanBeUpdated(oldPathConf *conf.Path, newPathConf *conf.Path) bool {
 
 	clone.Record = newPathConf.Record
 
+	clone.Push = newPathConf.Push
+	clone.UDPOutput = newPathConf.UDPOutput
+
+	clone.SourceFileLoop = newPathConf.SourceFileLoop
+
 	clone.RPICameraBrightness = newPathConf.RPICameraBrightness
 	clone.RPICameraContrast = newPathConf.RPICameraContrast
 	clone.RPICameraSaturation = newPathConf.RPICameraSaturation
@@ -43,6 +54,11 @@ type pathManagerHLSServer interface {
 	PathNotReady(defs.Path)
 }
 
+type pathManagerDASHServer interface {
+	PathReady(defs.Path)
+	PathNotReady(defs.Path)
+}
+
 type pathManagerParent interface {
 	logger.Writer
 }
@@ -57,27 +73,34 @@ type pathManager struct {
 	udpMaxPayloadSize int
 	pathConfs         map[string]*conf.Path
 	externalCmdPool   *externalcmd.Pool
+	recordUploader    *record.Uploader
+	eventBus          *events.Bus
+	otlpExporter      *otlp.Exporter
 	parent            pathManagerParent
 
 	ctx         context.Context
 	ctxCancel   func()
 	wg          sync.WaitGroup
 	hlsManager  pathManagerHLSServer
+	dashServer  pathManagerDASHServer
 	paths       map[string]*path
 	pathsByConf map[string]map[*path]struct{}
 
+	stoppedRecordSessions stoppedRecordSessions
+
 	// in
-	chReloadConf   chan map[string]*conf.Path
-	chSetHLSServer chan pathManagerHLSServer
-	chClosePath    chan *path
-	chPathReady    chan *path
-	chPathNotReady chan *path
-	chFindPathConf chan defs.PathFindPathConfReq
-	chDescribe     chan defs.PathDescribeReq
-	chAddReader    chan defs.PathAddReaderReq
-	chAddPublisher chan defs.PathAddPublisherReq
-	chAPIPathsList chan pathAPIPathsListReq
-	chAPIPathsGet  chan pathAPIPathsGetReq
+	chReloadConf    chan map[string]*conf.Path
+	chSetHLSServer  chan pathManagerHLSServer
+	chSetDASHServer chan pathManagerDASHServer
+	chClosePath     chan *path
+	chPathReady     chan *path
+	chPathNotReady  chan *path
+	chFindPathConf  chan defs.PathFindPathConfReq
+	chDescribe      chan defs.PathDescribeReq
+	chAddReader     chan defs.PathAddReaderReq
+	chAddPublisher  chan defs.PathAddPublisherReq
+	chAPIPathsList  chan pathAPIPathsListReq
+	chAPIPathsGet   chan pathAPIPathsGetReq
 }
 
 func (pm *pathManager) initialize() {
@@ -89,6 +112,7 @@ func (pm *pathManager) initialize() {
 	pm.pathsByConf = make(map[string]map[*path]struct{})
 	pm.chReloadConf = make(chan map[string]*conf.Path)
 	pm.chSetHLSServer = make(chan pathManagerHLSServer)
+	pm.chSetDASHServer = make(chan pathManagerDASHServer)
 	pm.chClosePath = make(chan *path)
 	pm.chPathReady = make(chan *path)
 	pm.chPathNotReady = make(chan *path)
@@ -134,6 +158,9 @@ outer:
 		case m := <-pm.chSetHLSServer:
 			pm.doSetHLSServer(m)
 
+		case s := <-pm.chSetDASHServer:
+			pm.doSetDASHServer(s)
+
 		case pa := <-pm.chClosePath:
 			pm.doClosePath(pa)
 
@@ -210,6 +237,10 @@ func (pm *pathManager) doSetHLSServer(m pathManagerHLSServer) {
 	pm.hlsManager = m
 }
 
+func (pm *pathManager) doSetDASHServer(s pathManagerDASHServer) {
+	pm.dashServer = s
+}
+
 func (pm *pathManager) doClosePath(pa *path) {
 	if pmpa, ok := pm.paths[pa.name]; !ok || pmpa != pa {
 		return
@@ -221,12 +252,18 @@ func (pm *pathManager) doPathReady(pa *path) {
 	if pm.hlsManager != nil {
 		pm.hlsManager.PathReady(pa)
 	}
+	if pm.dashServer != nil {
+		pm.dashServer.PathReady(pa)
+	}
 }
 
 func (pm *pathManager) doPathNotReady(pa *path) {
 	if pm.hlsManager != nil {
 		pm.hlsManager.PathNotReady(pa)
 	}
+	if pm.dashServer != nil {
+		pm.dashServer.PathNotReady(pa)
+	}
 }
 
 func (pm *pathManager) doFindPathConf(req defs.PathFindPathConfReq) {
@@ -236,7 +273,7 @@ func (pm *pathManager) doFindPathConf(req defs.PathFindPathConfReq) {
 		return
 	}
 
-	err = pm.authManager.Authenticate(req.AccessRequest.ToAuthRequest())
+	err = pm.authenticate(&req.AccessRequest)
 	if err != nil {
 		req.Res <- defs.PathFindPathConfRes{Err: err}
 		return
@@ -252,7 +289,7 @@ func (pm *pathManager) doDescribe(req defs.PathDescribeReq) {
 		return
 	}
 
-	err = pm.authManager.Authenticate(req.AccessRequest.ToAuthRequest())
+	err = pm.authenticate(&req.AccessRequest)
 	if err != nil {
 		req.Res <- defs.PathDescribeRes{Err: err}
 		return
@@ -267,15 +304,23 @@ func (pm *pathManager) doDescribe(req defs.PathDescribeReq) {
 }
 
 func (pm *pathManager) doAddReader(req defs.PathAddReaderReq) {
+	desc := req.Author.APIReaderDescribe()
+
+	span := pm.startSpan(desc, req.AccessRequest.Name, "path lookup")
 	pathConfName, pathConf, pathMatches, err := conf.FindPathConf(pm.pathConfs, req.AccessRequest.Name)
+	span.End(err)
 	if err != nil {
+		pm.endSessionTrace(desc, err)
 		req.Res <- defs.PathAddReaderRes{Err: err}
 		return
 	}
 
 	if !req.AccessRequest.SkipAuth {
-		err = pm.authManager.Authenticate(req.AccessRequest.ToAuthRequest())
+		span = pm.startSpan(desc, req.AccessRequest.Name, "auth")
+		err = pm.authenticate(&req.AccessRequest)
+		span.End(err)
 		if err != nil {
+			pm.endSessionTrace(desc, err)
 			req.Res <- defs.PathAddReaderRes{Err: err}
 			return
 		}
@@ -290,15 +335,23 @@ func (pm *pathManager) doAddReader(req defs.PathAddReaderReq) {
 }
 
 func (pm *pathManager) doAddPublisher(req defs.PathAddPublisherReq) {
+	desc := req.Author.APISourceDescribe()
+
+	span := pm.startSpan(desc, req.AccessRequest.Name, "path lookup")
 	pathConfName, pathConf, pathMatches, err := conf.FindPathConf(pm.pathConfs, req.AccessRequest.Name)
+	span.End(err)
 	if err != nil {
+		pm.endSessionTrace(desc, err)
 		req.Res <- defs.PathAddPublisherRes{Err: err}
 		return
 	}
 
 	if !req.AccessRequest.SkipAuth {
-		err = pm.authManager.Authenticate(req.AccessRequest.ToAuthRequest())
+		span = pm.startSpan(desc, req.AccessRequest.Name, "auth")
+		err = pm.authenticate(&req.AccessRequest)
+		span.End(err)
 		if err != nil {
+			pm.endSessionTrace(desc, err)
 			req.Res <- defs.PathAddPublisherRes{Err: err}
 			return
 		}
@@ -332,6 +385,46 @@ func (pm *pathManager) doAPIPathsGet(req pathAPIPathsGetReq) {
 	req.res <- pathAPIPathsGetRes{path: path}
 }
 
+func (pm *pathManager) authenticate(req *defs.PathAccessRequest) error {
+	authReq := req.ToAuthRequest()
+
+	err := pm.authManager.Authenticate(authReq)
+	if err != nil {
+		// do not publish requests without credentials, that are part of regular logins.
+		var terr auth.Error
+		if !errors.As(err, &terr) || !terr.AskCredentials {
+			pm.eventBus.Publish(&events.Event{
+				Type:   events.TypeAuthFailed,
+				Path:   req.Name,
+				User:   authReq.User,
+				IP:     req.IP,
+				Action: string(authReq.Action),
+				Error:  err.Error(),
+			})
+		}
+		return err
+	}
+
+	return nil
+}
+
+// startSpan starts a span of the trace of a publisher or reader session.
+func (pm *pathManager) startSpan(desc defs.APIPathSourceOrReader, pathName string, name string) *otlp.Span {
+	id, err := uuid.Parse(desc.ID)
+	if err != nil {
+		return nil
+	}
+	return pm.otlpExporter.StartSpan(id, name, otlp.Attribute{Key: "path", Value: pathName})
+}
+
+// endSessionTrace ends the trace of a publisher or reader session.
+func (pm *pathManager) endSessionTrace(desc defs.APIPathSourceOrReader, err error) {
+	id, err2 := uuid.Parse(desc.ID)
+	if err2 == nil {
+		pm.otlpExporter.EndSession(id, err)
+	}
+}
+
 func (pm *pathManager) createPath(
 	pathConfName string,
 	pathConf *conf.Path,
@@ -352,6 +445,9 @@ func (pm *pathManager) createPath(
 		matches:           matches,
 		wg:                &pm.wg,
 		externalCmdPool:   pm.externalCmdPool,
+		recordUploader:    pm.recordUploader,
+		eventBus:          pm.eventBus,
+		otlpExporter:      pm.otlpExporter,
 		parent:            pm,
 	}
 	pa.initialize()
@@ -398,6 +494,11 @@ func (pm *pathManager) pathNotReady(pa *path) {
 	}
 }
 
+// recordSessionStopped is called by path.
+func (pm *pathManager) recordSessionStopped(item *defs.APIRecordingSession) {
+	pm.stoppedRecordSessions.add(item)
+}
+
 // closePath is called by path.
 func (pm *pathManager) closePath(pa *path) {
 	select {
@@ -453,7 +554,15 @@ func (pm *pathManager) AddPublisher(req defs.PathAddPublisherReq) (defs.Path, er
 			return nil, res.Err
 		}
 
-		return res.Path.(*path).addPublisher(req)
+		desc := req.Author.APISourceDescribe()
+		span := pm.startSpan(desc, req.AccessRequest.Name, "publisher add")
+		pa, err := res.Path.(*path).addPublisher(req)
+		span.End(err)
+		if err != nil {
+			pm.endSessionTrace(desc, err)
+		}
+
+		return pa, err
 
 	case <-pm.ctx.Done():
 		return nil, fmt.Errorf("terminated")
@@ -470,7 +579,15 @@ func (pm *pathManager) AddReader(req defs.PathAddReaderReq) (defs.Path, *stream.
 			return nil, nil, res.Err
 		}
 
-		return res.Path.(*path).addReader(req)
+		desc := req.Author.APIReaderDescribe()
+		span := pm.startSpan(desc, req.AccessRequest.Name, "reader add")
+		pa, stream, err := res.Path.(*path).addReader(req)
+		span.End(err)
+		if err != nil {
+			pm.endSessionTrace(desc, err)
+		}
+
+		return pa, stream, err
 
 	case <-pm.ctx.Done():
 		return nil, nil, fmt.Errorf("terminated")
@@ -485,6 +602,14 @@ func (pm *pathManager) setHLSServer(s pathManagerHLSServer) {
 	}
 }
 
+// setDASHServer is called by core.
+func (pm *pathManager) setDASHServer(s pathManagerDASHServer) {
+	select {
+	case pm.chSetDASHServer <- s:
+	case <-pm.ctx.Done():
+	}
+}
+
 // APIPathsList is called by api.
 func (pm *pathManager) APIPathsList() (*defs.APIPathList, error) {
 	req := pathAPIPathsListReq{
@@ -538,3 +663,104 @@ func (pm *pathManager) APIPathsGet(name string) (*defs.APIPath, error) {
 		return nil, fmt.Errorf("terminated")
 	}
 }
+
+func (pm *pathManager) apiGetPath(name string) (*path, error) {
+	req := pathAPIPathsGetReq{
+		name: name,
+		res:  make(chan pathAPIPathsGetRes),
+	}
+
+	select {
+	case pm.chAPIPathsGet <- req:
+		res := <-req.res
+		return res.path, res.err
+
+	case <-pm.ctx.Done():
+		return nil, fmt.Errorf("terminated")
+	}
+}
+
+// APIRecordingStart is called by api.
+func (pm *pathManager) APIRecordingStart(
+	name string,
+	overrides defs.APIRecordingSessionStartReq,
+) (*defs.APIRecordingSession, error) {
+	pa, err := pm.apiGetPath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return pa.APIRecordingStart(overrides)
+}
+
+// APIRecordingStop is called by api.
+func (pm *pathManager) APIRecordingStop(name string, id *uuid.UUID) (*defs.APIRecordingSessionList, error) {
+	// stopping a stopped session has no effect.
+	if id != nil {
+		if item := pm.stoppedRecordSessions.get(*id); item != nil && item.Path == name {
+			return &defs.APIRecordingSessionList{Items: []*defs.APIRecordingSession{item}}, nil
+		}
+	}
+
+	pa, err := pm.apiGetPath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := pa.APIRecordingStop(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &defs.APIRecordingSessionList{Items: items}, nil
+}
+
+// APIRecordingSessionsList is called by api.
+func (pm *pathManager) APIRecordingSessionsList() (*defs.APIRecordingSessionList, error) {
+	req := pathAPIPathsListReq{
+		res: make(chan pathAPIPathsListRes),
+	}
+
+	select {
+	case pm.chAPIPathsList <- req:
+		res := <-req.res
+
+		data := &defs.APIRecordingSessionList{
+			Items: []*defs.APIRecordingSession{},
+		}
+
+		for _, pa := range res.paths {
+			items, err := pa.APIRecordingSessionsList()
+			if err == nil {
+				data.Items = append(data.Items, items...)
+			}
+		}
+
+		data.Items = append(data.Items, pm.stoppedRecordSessions.list()...)
+
+		sort.Slice(data.Items, func(i, j int) bool {
+			return data.Items[i].Created.Before(data.Items[j].Created)
+		})
+
+		return data, nil
+
+	case <-pm.ctx.Done():
+		return nil, fmt.Errorf("terminated")
+	}
+}
+
+// APIRecordingSessionsGet is called by api.
+func (pm *pathManager) APIRecordingSessionsGet(id uuid.UUID) (*defs.APIRecordingSession, error) {
+	data, err := pm.APIRecordingSessionsList()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range data.Items {
+		if item.ID == id {
+			return item, nil
+		}
+	}
+
+	return nil, defs.ErrRecordingSessionNotFound
+}
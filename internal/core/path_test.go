@@ -3,7 +3,12 @@ package core
 import (
 	"bufio"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"net/url"
@@ -321,6 +326,85 @@ func TestPathRunOnReady(t *testing.T) {
 	require.Equal(t, "test query=value\n", string(byts))
 }
 
+func TestPathRunOnReadyWebhook(t *testing.T) {
+	type webhookReq struct {
+		path      string
+		body      []byte
+		signature string
+	}
+
+	received := make(chan webhookReq, 2)
+
+	// the first request fails, in order to test retries
+	failed := false
+
+	httpServ := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, http.MethodPost, r.Method)
+			require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+			if !failed {
+				failed = true
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+
+			received <- webhookReq{
+				path:      r.URL.String(),
+				body:      body,
+				signature: r.Header.Get("X-MTX-Signature"),
+			}
+		}),
+	}
+
+	ln, err := net.Listen("tcp", "localhost:9120")
+	require.NoError(t, err)
+
+	go httpServ.Serve(ln)
+	defer httpServ.Shutdown(context.Background())
+
+	p, ok := newInstance("rtmp: no\n" +
+		"hls: no\n" +
+		"webrtc: no\n" +
+		"webhookRetries: 1\n" +
+		"webhookSecret: mysecret\n" +
+		"paths:\n" +
+		"  test:\n" +
+		"    runOnReady: http://localhost:9120/ready?path=$MTX_PATH\n" +
+		"    runOnNotReady: http://localhost:9120/notready?path=$MTX_PATH\n")
+	require.Equal(t, true, ok)
+	defer p.Close()
+
+	func() {
+		c := gortsplib.Client{}
+
+		err := c.StartRecording(
+			"rtsp://localhost:8554/test?query=value",
+			&description.Session{Medias: []*description.Media{test.UniqueMediaH264()}})
+		require.NoError(t, err)
+		defer c.Close()
+
+		req := <-received
+		require.Equal(t, "/ready?path=test", req.path)
+
+		var body map[string]string
+		err = json.Unmarshal(req.body, &body)
+		require.NoError(t, err)
+		require.Equal(t, "test", body["MTX_PATH"])
+		require.Equal(t, "query=value", body["MTX_QUERY"])
+
+		mac := hmac.New(sha256.New, []byte("mysecret"))
+		mac.Write(req.body)
+		require.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), req.signature)
+	}()
+
+	req := <-received
+	require.Equal(t, "/notready?path=test", req.path)
+}
+
 func TestPathRunOnRead(t *testing.T) {
 	for _, ca := range []string{"rtsp", "rtmp", "srt", "webrtc"} {
 		t.Run(ca, func(t *testing.T) {
@@ -0,0 +1,206 @@
+package core
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/defs"
+	"github.com/bluenviron/mediamtx/internal/record"
+	"github.com/bluenviron/mediamtx/internal/stream"
+)
+
+// stopped sessions are kept for this duration, in order to allow to query them.
+const recordSessionRetention = 10 * time.Minute
+
+// stoppedRecordSessions stores stopped recording sessions until recordSessionRetention expires.
+// It is not tied to paths, in order not to keep paths alive and not to lose sessions when paths are closed.
+type stoppedRecordSessions struct {
+	mutex sync.Mutex
+	items []*defs.APIRecordingSession
+}
+
+// removeExpired must be called with the mutex locked.
+func (r *stoppedRecordSessions) removeExpired() {
+	now := time.Now()
+	n := 0
+
+	for _, item := range r.items {
+		if now.Sub(*item.Stopped) < recordSessionRetention {
+			r.items[n] = item
+			n++
+		}
+	}
+
+	for i := n; i < len(r.items); i++ {
+		r.items[i] = nil
+	}
+	r.items = r.items[:n]
+}
+
+func (r *stoppedRecordSessions) add(item *defs.APIRecordingSession) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.removeExpired()
+	r.items = append(r.items, item)
+}
+
+func (r *stoppedRecordSessions) list() []*defs.APIRecordingSession {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.removeExpired()
+	out := make([]*defs.APIRecordingSession, len(r.items))
+	copy(out, r.items)
+	return out
+}
+
+func (r *stoppedRecordSessions) get(id uuid.UUID) *defs.APIRecordingSession {
+	for _, item := range r.list() {
+		if item.ID == id {
+			return item
+		}
+	}
+	return nil
+}
+
+// recordSession is a recording started with the API.
+// It records the stream of a path until it is stopped or its maximum duration is reached,
+// and it survives to restarts of the stream.
+type recordSession struct {
+	id          uuid.UUID
+	created     time.Time
+	recordPath  string
+	format      conf.RecordFormat
+	maxDuration time.Duration
+	parent      *path
+
+	mutex          sync.RWMutex
+	agent          *record.Agent
+	bytesWritten   uint64
+	currentSegment string
+	stopped        *time.Time
+	expireTimer    *time.Timer
+}
+
+func (s *recordSession) initialize() {
+	s.id = uuid.New()
+	s.created = time.Now()
+
+	if s.maxDuration != 0 {
+		s.expireTimer = time.AfterFunc(s.maxDuration, func() {
+			s.parent.recordSessionExpired(s)
+		})
+	}
+}
+
+// start starts recording the given stream.
+func (s *recordSession) start(strm *stream.Stream) {
+	agent := &record.Agent{
+		WriteQueueSize:  s.parent.writeQueueSize,
+		PathFormat:      s.recordPath,
+		Format:          s.format,
+		PartDuration:    time.Duration(s.parent.conf.RecordPartDuration),
+		SegmentDuration: time.Duration(s.parent.conf.RecordSegmentDuration),
+		PathName:        s.parent.name,
+		Stream:          strm,
+		PreRoll:         s.parent.preRollBuffer,
+		OnSegmentCreate: func(segmentPath string) {
+			s.mutex.Lock()
+			s.currentSegment = segmentPath
+			s.mutex.Unlock()
+
+			s.parent.onRecordSegmentCreate(segmentPath)
+		},
+		OnSegmentComplete: func(segmentPath string) {
+			s.mutex.Lock()
+			if s.currentSegment == segmentPath {
+				s.currentSegment = ""
+			}
+			s.mutex.Unlock()
+
+			s.parent.onRecordSegmentComplete(s.recordPath, s.format, segmentPath)
+		},
+		Parent: s.parent,
+	}
+	agent.Initialize()
+
+	s.mutex.Lock()
+	s.agent = agent
+	s.mutex.Unlock()
+}
+
+// pause stops recording until start() is called again.
+func (s *recordSession) pause() {
+	s.mutex.RLock()
+	agent := s.agent
+	s.mutex.RUnlock()
+
+	if agent == nil {
+		return
+	}
+
+	agent.Close()
+
+	s.mutex.Lock()
+	s.bytesWritten += agent.BytesWritten()
+	s.agent = nil
+	s.mutex.Unlock()
+}
+
+// stop stops the session.
+func (s *recordSession) stop() {
+	if s.expireTimer != nil {
+		s.expireTimer.Stop()
+	}
+
+	s.pause()
+
+	now := time.Now()
+
+	s.mutex.Lock()
+	s.stopped = &now
+	s.mutex.Unlock()
+}
+
+func (s *recordSession) apiItem() *defs.APIRecordingSession {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	bytesWritten := s.bytesWritten
+
+	var state defs.APIRecordingSessionState
+	switch {
+	case s.stopped != nil:
+		state = defs.APIRecordingSessionStateStopped
+
+	case s.agent != nil:
+		state = defs.APIRecordingSessionStateRecording
+		bytesWritten += s.agent.BytesWritten()
+
+	default:
+		state = defs.APIRecordingSessionStateWaiting
+	}
+
+	return &defs.APIRecordingSession{
+		ID:           s.id,
+		Created:      s.created,
+		Path:         s.parent.name,
+		State:        state,
+		Format:       s.format,
+		RecordPath:   s.recordPath,
+		MaxDuration:  conf.StringDuration(s.maxDuration),
+		Stopped:      s.stopped,
+		BytesWritten: bytesWritten,
+		CurrentSegment: func() *string {
+			if s.currentSegment == "" {
+				return nil
+			}
+			v := s.currentSegment
+			return &v
+		}(),
+	}
+}
@@ -0,0 +1,554 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	"github.com/bluenviron/gortsplib/v4/pkg/sdp"
+
+	"github.com/bluenviron/mediamtx/internal/asyncwriter"
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/defs"
+	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/bluenviron/mediamtx/internal/stream"
+	"github.com/bluenviron/mediamtx/internal/unit"
+)
+
+const (
+	// gap between the last timestamp of a source and the first timestamp of the next one.
+	staticSourceFailoverPTSGap = 100 * time.Millisecond
+)
+
+func cloneDescription(desc *description.Session) (*description.Session, error) {
+	byts, err := desc.Marshal(false)
+	if err != nil {
+		return nil, err
+	}
+
+	var sd sdp.SessionDescription
+	err = sd.Unmarshal(byts)
+	if err != nil {
+		return nil, err
+	}
+
+	var desc2 description.Session
+	err = desc2.Unmarshal(&sd)
+	if err != nil {
+		return nil, err
+	}
+
+	return &desc2, nil
+}
+
+func descriptionsAreCompatible(desc1 *description.Session, desc2 *description.Session) bool {
+	if len(desc1.Medias) != len(desc2.Medias) {
+		return false
+	}
+
+	for i, medi := range desc1.Medias {
+		medi2 := desc2.Medias[i]
+
+		if medi.Type != medi2.Type || len(medi.Formats) != len(medi2.Formats) {
+			return false
+		}
+
+		for j, forma := range medi.Formats {
+			if forma.Codec() != medi2.Formats[j].Codec() {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+type staticSourceFailoverSetReadyReq struct {
+	entry *staticSourceFailoverEntry
+	req   defs.PathSourceStaticSetReadyReq
+}
+
+type staticSourceFailoverSetNotReadyReq struct {
+	entry *staticSourceFailoverEntry
+	req   defs.PathSourceStaticSetNotReadyReq
+}
+
+type staticSourceFailoverEntryError struct {
+	entry *staticSourceFailoverEntry
+	err   error
+}
+
+// staticSourceFailoverEntry is a source of staticSourceFailover.
+type staticSourceFailoverEntry struct {
+	f        *staticSourceFailover
+	index    int
+	instance defs.StaticSource
+
+	running      bool
+	ctx          context.Context
+	ctxCancel    func()
+	reloadConf   chan *conf.Path
+	stream       *stream.Stream
+	writer       *asyncwriter.Writer
+	lastData     int64
+	gotData      int32
+	ptsOffset    time.Duration // protected by staticSourceFailover.mutex
+	ptsOffsetSet bool          // protected by staticSourceFailover.mutex
+
+	done chan struct{}
+}
+
+// Log implements logger.Writer.
+func (e *staticSourceFailoverEntry) Log(level logger.Level, format string, args ...interface{}) {
+	index := strconv.FormatInt(int64(e.index+1), 10)
+	e.f.Log(level, "%v"+format, append([]interface{}{logger.Attr{
+		Text:   "[source " + index + "] ",
+		Fields: []logger.Field{{Key: "source", Value: index}},
+	}}, args...)...)
+}
+
+// SetReady implements defs.StaticSourceParent.
+func (e *staticSourceFailoverEntry) SetReady(req defs.PathSourceStaticSetReadyReq) defs.PathSourceStaticSetReadyRes {
+	req.Res = make(chan defs.PathSourceStaticSetReadyRes)
+	select {
+	case e.f.chEntrySetReady <- staticSourceFailoverSetReadyReq{entry: e, req: req}:
+		return <-req.Res
+
+	case <-e.ctx.Done():
+		return defs.PathSourceStaticSetReadyRes{Err: fmt.Errorf("terminated")}
+	}
+}
+
+// SetNotReady implements defs.StaticSourceParent.
+func (e *staticSourceFailoverEntry) SetNotReady(req defs.PathSourceStaticSetNotReadyReq) {
+	req.Res = make(chan struct{})
+	select {
+	case e.f.chEntrySetNotReady <- staticSourceFailoverSetNotReadyReq{entry: e, req: req}:
+		<-req.Res
+
+	case <-e.ctx.Done():
+	}
+}
+
+func (e *staticSourceFailoverEntry) run(pathConf *conf.Path) {
+	defer close(e.done)
+
+	err := e.instance.Run(defs.StaticSourceRunParams{
+		Context:    e.ctx,
+		Conf:       pathConf,
+		ReloadConf: e.reloadConf,
+	})
+	if err == nil {
+		err = fmt.Errorf("terminated")
+	}
+
+	select {
+	case e.f.chEntryError <- staticSourceFailoverEntryError{entry: e, err: err}:
+	case <-e.ctx.Done():
+	}
+}
+
+// staticSourceFailover is a static source that switches between multiple sources.
+// Each source writes into a private stream, whose data is forwarded to the path stream
+// when the source is active. This allows readers to stay attached during a switch.
+type staticSourceFailover struct {
+	resolvedSources   []string
+	writeQueueSize    int
+	udpMaxPayloadSize int
+	newInstance       func(string, defs.StaticSourceParent) defs.StaticSource
+	parent            defs.StaticSourceParent
+
+	retryPause       time.Duration
+	entries          []*staticSourceFailoverEntry
+	pathConf         *conf.Path
+	probeTimer       *time.Timer
+	restartTimer     *time.Timer
+	primaryDataSince time.Time
+
+	mutex       sync.RWMutex
+	active      int
+	outerStream *stream.Stream
+	lastPTS     time.Duration
+	hasLastPTS  bool
+
+	chEntrySetReady    chan staticSourceFailoverSetReadyReq
+	chEntrySetNotReady chan staticSourceFailoverSetNotReadyReq
+	chEntryError       chan staticSourceFailoverEntryError
+	chEntryData        chan *staticSourceFailoverEntry
+}
+
+func (f *staticSourceFailover) initialize() {
+	if f.retryPause == 0 {
+		f.retryPause = staticSourceHandlerRetryPause
+	}
+
+	f.chEntrySetReady = make(chan staticSourceFailoverSetReadyReq)
+	f.chEntrySetNotReady = make(chan staticSourceFailoverSetNotReadyReq)
+	f.chEntryError = make(chan staticSourceFailoverEntryError)
+	f.chEntryData = make(chan *staticSourceFailoverEntry, len(f.resolvedSources))
+
+	for i, resolvedSource := range f.resolvedSources {
+		e := &staticSourceFailoverEntry{
+			f:     f,
+			index: i,
+		}
+		e.instance = f.newInstance(resolvedSource, e)
+		f.entries = append(f.entries, e)
+	}
+}
+
+// Log implements logger.Writer.
+func (f *staticSourceFailover) Log(level logger.Level, format string, args ...interface{}) {
+	f.parent.Log(level, format, args...)
+}
+
+// APISourceDescribe implements defs.StaticSource.
+func (f *staticSourceFailover) APISourceDescribe() defs.APIPathSourceOrReader {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	return f.entries[f.active].instance.APISourceDescribe()
+}
+
+// Run implements defs.StaticSource.
+func (f *staticSourceFailover) Run(params defs.StaticSourceRunParams) error {
+	f.pathConf = params.Conf
+	switchTimeout := time.Duration(params.Conf.SourceSwitchTimeout)
+
+	f.probeTimer = emptyTimer()
+	f.restartTimer = emptyTimer()
+
+	defer func() {
+		f.probeTimer.Stop()
+		f.restartTimer.Stop()
+	}()
+
+	checkPeriod := switchTimeout / 10
+	if checkPeriod < time.Millisecond {
+		checkPeriod = time.Millisecond
+	}
+
+	checkTicker := time.NewTicker(checkPeriod)
+	defer checkTicker.Stop()
+
+	defer f.stopAll()
+
+	f.setActive(0)
+	f.startEntry(f.entries[0])
+
+	for {
+		select {
+		case req := <-f.chEntrySetReady:
+			res := f.onEntrySetReady(req.entry, req.req)
+			req.req.Res <- res
+			if res.Err != nil && req.entry.index == f.active {
+				return res.Err
+			}
+
+		case req := <-f.chEntrySetNotReady:
+			f.detachEntry(req.entry)
+			close(req.req.Res)
+
+		case entryErr := <-f.chEntryError:
+			f.stopEntry(entryErr.entry)
+
+			if entryErr.entry.index == f.active {
+				f.switchToNext(entryErr.err.Error())
+			} else {
+				entryErr.entry.Log(logger.Debug, "primary source is not available: %v", entryErr.err)
+				f.scheduleProbe()
+			}
+
+		case e := <-f.chEntryData:
+			if e.index == 0 && f.active != 0 && e.running && e.stream != nil {
+				e.Log(logger.Debug, "primary source is sending data")
+				f.primaryDataSince = time.Now()
+			}
+
+		case <-checkTicker.C:
+			e := f.entries[f.active]
+			if e.running && time.Since(time.Unix(0, atomic.LoadInt64(&e.lastData))) > switchTimeout {
+				f.stopEntry(e)
+				f.switchToNext(fmt.Sprintf("no data received in %v", switchTimeout))
+			} else if f.active != 0 {
+				f.checkPrimary(switchTimeout)
+			}
+
+		case <-f.probeTimer.C:
+			if f.active != 0 && !f.entries[0].running {
+				f.startEntry(f.entries[0])
+			}
+
+		case <-f.restartTimer.C:
+			if !f.entries[f.active].running {
+				f.startEntry(f.entries[f.active])
+			}
+
+		case newConf := <-params.ReloadConf:
+			f.pathConf = newConf
+
+			for _, e := range f.entries {
+				if e.running {
+					cReloadConf := e.reloadConf
+					cCtx := e.ctx
+					go func() {
+						select {
+						case cReloadConf <- newConf:
+						case <-cCtx.Done():
+						}
+					}()
+				}
+			}
+
+		case <-params.Context.Done():
+			return nil
+		}
+	}
+}
+
+func (f *staticSourceFailover) setActive(index int) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.active = index
+	f.entries[index].ptsOffsetSet = false
+}
+
+func (f *staticSourceFailover) startEntry(e *staticSourceFailoverEntry) {
+	e.running = true
+	e.ctx, e.ctxCancel = context.WithCancel(context.Background())
+	e.reloadConf = make(chan *conf.Path)
+	e.done = make(chan struct{})
+	atomic.StoreInt64(&e.lastData, time.Now().UnixNano())
+
+	go e.run(f.pathConf)
+}
+
+func (f *staticSourceFailover) stopEntry(e *staticSourceFailoverEntry) {
+	e.running = false
+	e.ctxCancel()
+	<-e.done
+
+	f.detachEntry(e)
+}
+
+func (f *staticSourceFailover) stopAll() {
+	for _, e := range f.entries {
+		if e.running {
+			f.stopEntry(e)
+		}
+	}
+
+	f.mutex.Lock()
+	outerStream := f.outerStream
+	f.outerStream = nil
+	f.mutex.Unlock()
+
+	if outerStream != nil {
+		f.parent.SetNotReady(defs.PathSourceStaticSetNotReadyReq{})
+	}
+}
+
+func (f *staticSourceFailover) scheduleProbe() {
+	f.probeTimer.Stop()
+	f.probeTimer = time.NewTimer(f.retryPause)
+}
+
+func (f *staticSourceFailover) switchTo(index int) {
+	if cur := f.entries[f.active]; cur.running && f.active != index {
+		f.stopEntry(cur)
+	}
+
+	f.setActive(index)
+
+	e := f.entries[index]
+
+	if !e.running {
+		f.startEntry(e)
+	} else if e.stream != nil {
+		err := f.attachEntry(e)
+		if err != nil {
+			e.Log(logger.Error, err.Error())
+		}
+	}
+
+	if index != 0 && !f.entries[0].running {
+		f.scheduleProbe()
+	}
+}
+
+// checkPrimary switches back to the primary source
+// once it has been sending data continuously for switchTimeout.
+func (f *staticSourceFailover) checkPrimary(switchTimeout time.Duration) {
+	e := f.entries[0]
+
+	if !e.running || e.stream == nil || f.primaryDataSince.IsZero() {
+		return
+	}
+
+	if time.Since(time.Unix(0, atomic.LoadInt64(&e.lastData))) > switchTimeout {
+		e.Log(logger.Debug, "primary source stopped sending data")
+		f.stopEntry(e)
+		f.scheduleProbe()
+		return
+	}
+
+	if time.Since(f.primaryDataSince) >= switchTimeout {
+		e.Log(logger.Info, "primary source has been available for %v, switching back", switchTimeout)
+		f.switchTo(0)
+	}
+}
+
+func (f *staticSourceFailover) switchToNext(reason string) {
+	next := (f.active + 1) % len(f.entries)
+
+	f.entries[f.active].Log(logger.Warn, "%s, switching to source %d", reason, next+1)
+
+	// all sources have been tried: wait before starting again
+	if next == 0 && !f.entries[0].running {
+		f.setActive(0)
+		f.restartTimer.Stop()
+		f.restartTimer = time.NewTimer(f.retryPause)
+		return
+	}
+
+	f.switchTo(next)
+}
+
+func (f *staticSourceFailover) onEntrySetReady(
+	e *staticSourceFailoverEntry,
+	req defs.PathSourceStaticSetReadyReq,
+) defs.PathSourceStaticSetReadyRes {
+	strm, err := stream.New(
+		f.udpMaxPayloadSize,
+		req.Desc,
+		req.GenerateRTPPackets,
+		logger.NewLimitedLogger(e),
+	)
+	if err != nil {
+		return defs.PathSourceStaticSetReadyRes{Err: err}
+	}
+
+	e.stream = strm
+	e.writer = asyncwriter.New(f.writeQueueSize, e)
+	atomic.StoreInt32(&e.gotData, 0)
+
+	for i, medi := range req.Desc.Medias {
+		for j, forma := range medi.Formats {
+			ci := i
+			cj := j
+			strm.AddReader(e.writer, medi, forma, func(u unit.Unit) error {
+				f.forward(e, ci, cj, u)
+				return nil
+			})
+		}
+	}
+
+	e.writer.Start()
+
+	if e.index == f.active {
+		err := f.attachEntry(e)
+		if err != nil {
+			f.detachEntry(e)
+			return defs.PathSourceStaticSetReadyRes{Err: err}
+		}
+	}
+
+	return defs.PathSourceStaticSetReadyRes{Stream: strm}
+}
+
+// attachEntry links the private stream of a source to the path stream.
+func (f *staticSourceFailover) attachEntry(e *staticSourceFailoverEntry) error {
+	f.mutex.RLock()
+	outerStream := f.outerStream
+	f.mutex.RUnlock()
+
+	if outerStream != nil && !descriptionsAreCompatible(outerStream.Desc(), e.stream.Desc()) {
+		e.Log(logger.Warn, "tracks are not compatible with the ones of the previous source, readers will be disconnected")
+
+		f.mutex.Lock()
+		f.outerStream = nil
+		f.mutex.Unlock()
+
+		f.parent.SetNotReady(defs.PathSourceStaticSetNotReadyReq{})
+		outerStream = nil
+	}
+
+	if outerStream == nil {
+		desc, err := cloneDescription(e.stream.Desc())
+		if err != nil {
+			return err
+		}
+
+		res := f.parent.SetReady(defs.PathSourceStaticSetReadyReq{
+			Desc:               desc,
+			GenerateRTPPackets: true,
+		})
+		if res.Err != nil {
+			return res.Err
+		}
+
+		f.mutex.Lock()
+		f.outerStream = res.Stream
+		f.hasLastPTS = false
+		f.mutex.Unlock()
+	}
+
+	return nil
+}
+
+func (f *staticSourceFailover) detachEntry(e *staticSourceFailoverEntry) {
+	if e.index == 0 {
+		f.primaryDataSince = time.Time{}
+	}
+
+	if e.stream != nil {
+		e.stream.RemoveReader(e.writer)
+		e.writer.Stop()
+		e.stream.Close()
+		e.stream = nil
+	}
+}
+
+// forward is called by the writer of a source.
+func (f *staticSourceFailover) forward(e *staticSourceFailoverEntry, mediaIndex int, formatIndex int, u unit.Unit) {
+	atomic.StoreInt64(&e.lastData, time.Now().UnixNano())
+
+	if atomic.CompareAndSwapInt32(&e.gotData, 0, 1) {
+		select {
+		case f.chEntryData <- e:
+		default:
+		}
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.outerStream == nil || f.entries[f.active] != e {
+		return
+	}
+
+	// make timestamps of the new source follow the ones of the previous source
+	if !e.ptsOffsetSet {
+		if f.hasLastPTS {
+			e.ptsOffset = f.lastPTS + staticSourceFailoverPTSGap - u.GetPTS()
+		} else {
+			e.ptsOffset = 0
+		}
+		e.ptsOffsetSet = true
+	}
+
+	pts := u.GetPTS() + e.ptsOffset
+	u.SetPTS(pts)
+
+	if !f.hasLastPTS || pts > f.lastPTS {
+		f.lastPTS = pts
+		f.hasLastPTS = true
+	}
+
+	medi := f.outerStream.Desc().Medias[mediaIndex]
+	f.outerStream.WriteUnit(medi, medi.Formats[formatIndex], u)
+}
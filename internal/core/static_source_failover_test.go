@@ -0,0 +1,194 @@
+package core
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	"github.com/bluenviron/gortsplib/v4/pkg/format"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bluenviron/mediamtx/internal/asyncwriter"
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/defs"
+	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/bluenviron/mediamtx/internal/stream"
+	"github.com/bluenviron/mediamtx/internal/test"
+	"github.com/bluenviron/mediamtx/internal/unit"
+)
+
+type testFailoverSource struct {
+	name     string
+	parent   defs.StaticSourceParent
+	disabled int32
+}
+
+func (s *testFailoverSource) Log(level logger.Level, format string, args ...interface{}) {
+	s.parent.Log(level, format, args...)
+}
+
+func (s *testFailoverSource) Run(params defs.StaticSourceRunParams) error {
+	medi := &description.Media{
+		Type: description.MediaTypeVideo,
+		Formats: []format.Format{&format.H264{
+			PayloadTyp:        96,
+			SPS:               test.FormatH264.SPS,
+			PPS:               test.FormatH264.PPS,
+			PacketizationMode: 1,
+		}},
+	}
+
+	res := s.parent.SetReady(defs.PathSourceStaticSetReadyReq{
+		Desc:               &description.Session{Medias: []*description.Media{medi}},
+		GenerateRTPPackets: true,
+	})
+	if res.Err != nil {
+		return res.Err
+	}
+
+	defer s.parent.SetNotReady(defs.PathSourceStaticSetNotReadyReq{})
+
+	// timestamps start from zero at every run
+	pts := time.Duration(0)
+
+	for {
+		select {
+		case <-time.After(10 * time.Millisecond):
+			if atomic.LoadInt32(&s.disabled) == 0 {
+				res.Stream.WriteUnit(medi, medi.Formats[0], &unit.H264{
+					Base: unit.Base{
+						PTS: pts,
+					},
+					AU: [][]byte{{5}},
+				})
+				pts += 10 * time.Millisecond
+			}
+
+		case <-params.Context.Done():
+			return nil
+		}
+	}
+}
+
+func (s *testFailoverSource) APISourceDescribe() defs.APIPathSourceOrReader {
+	return defs.APIPathSourceOrReader{Type: s.name}
+}
+
+type testFailoverParent struct {
+	setReadyCount    int32
+	setNotReadyCount int32
+	ready            chan *stream.Stream
+}
+
+func (p *testFailoverParent) Log(logger.Level, string, ...interface{}) {
+}
+
+func (p *testFailoverParent) SetReady(req defs.PathSourceStaticSetReadyReq) defs.PathSourceStaticSetReadyRes {
+	atomic.AddInt32(&p.setReadyCount, 1)
+
+	strm, err := stream.New(1472, req.Desc, req.GenerateRTPPackets, test.NilLogger)
+	if err != nil {
+		return defs.PathSourceStaticSetReadyRes{Err: err}
+	}
+
+	p.ready <- strm
+	return defs.PathSourceStaticSetReadyRes{Stream: strm}
+}
+
+func (p *testFailoverParent) SetNotReady(defs.PathSourceStaticSetNotReadyReq) {
+	atomic.AddInt32(&p.setNotReadyCount, 1)
+}
+
+func TestStaticSourceFailover(t *testing.T) {
+	var sources []*testFailoverSource
+
+	p := &testFailoverParent{
+		ready: make(chan *stream.Stream, 1),
+	}
+
+	f := &staticSourceFailover{
+		resolvedSources:   []string{"primary", "backup"},
+		writeQueueSize:    512,
+		udpMaxPayloadSize: 1472,
+		newInstance: func(resolvedSource string, parent defs.StaticSourceParent) defs.StaticSource {
+			s := &testFailoverSource{
+				name:   resolvedSource,
+				parent: parent,
+			}
+			sources = append(sources, s)
+			return s
+		},
+		parent:     p,
+		retryPause: 10 * time.Millisecond,
+	}
+	f.initialize()
+
+	ctx, ctxCancel := context.WithCancel(context.Background())
+	defer ctxCancel()
+
+	runDone := make(chan error)
+	go func() {
+		runDone <- f.Run(defs.StaticSourceRunParams{
+			Context:    ctx,
+			Conf:       &conf.Path{SourceSwitchTimeout: conf.StringDuration(200 * time.Millisecond)},
+			ReloadConf: make(chan *conf.Path),
+		})
+	}()
+
+	strm := <-p.ready
+	defer strm.Close()
+
+	received := make(chan time.Duration, 1024)
+
+	writer := asyncwriter.New(512, test.NilLogger)
+	strm.AddReader(writer, strm.Desc().Medias[0], strm.Desc().Medias[0].Formats[0], func(u unit.Unit) error {
+		received <- u.GetPTS()
+		return nil
+	})
+	writer.Start()
+	defer writer.Stop()
+
+	prevPTS := time.Duration(-1)
+
+	waitForSource := func(name string) {
+		for {
+			pts := <-received
+			require.Greater(t, pts, prevPTS)
+			prevPTS = pts
+
+			if f.APISourceDescribe().Type == name {
+				// make sure that data of the new source is received
+				for i := 0; i < 5; i++ {
+					pts := <-received
+					require.Greater(t, pts, prevPTS)
+					prevPTS = pts
+				}
+				return
+			}
+		}
+	}
+
+	waitForSource("primary")
+
+	// primary stops delivering data
+	atomic.StoreInt32(&sources[0].disabled, 1)
+
+	waitForSource("backup")
+
+	// primary is healthy again
+	atomic.StoreInt32(&sources[0].disabled, 0)
+	enabled := time.Now()
+
+	waitForSource("primary")
+
+	// primary must be healthy for the switch timeout before switching back
+	require.GreaterOrEqual(t, time.Since(enabled), 200*time.Millisecond)
+
+	ctxCancel()
+	require.NoError(t, <-runDone)
+
+	require.Equal(t, int32(1), atomic.LoadInt32(&p.setReadyCount))
+	require.Equal(t, int32(1), atomic.LoadInt32(&p.setNotReadyCount))
+}
@@ -9,9 +9,11 @@ import (
 	"github.com/bluenviron/mediamtx/internal/conf"
 	"github.com/bluenviron/mediamtx/internal/defs"
 	"github.com/bluenviron/mediamtx/internal/logger"
+	filesource "github.com/bluenviron/mediamtx/internal/staticsources/file"
 	hlssource "github.com/bluenviron/mediamtx/internal/staticsources/hls"
 	rpicamerasource "github.com/bluenviron/mediamtx/internal/staticsources/rpicamera"
 	rtmpsource "github.com/bluenviron/mediamtx/internal/staticsources/rtmp"
+	rtpsource "github.com/bluenviron/mediamtx/internal/staticsources/rtp"
 	rtspsource "github.com/bluenviron/mediamtx/internal/staticsources/rtsp"
 	srtsource "github.com/bluenviron/mediamtx/internal/staticsources/srt"
 	udpsource "github.com/bluenviron/mediamtx/internal/staticsources/udp"
@@ -30,13 +32,15 @@ type staticSourceHandlerParent interface {
 
 // staticSourceHandler is a static source handler.
 type staticSourceHandler struct {
-	conf           *conf.Path
-	logLevel       conf.LogLevel
-	readTimeout    conf.StringDuration
-	writeTimeout   conf.StringDuration
-	writeQueueSize int
-	resolvedSource string
-	parent         staticSourceHandlerParent
+	conf              *conf.Path
+	logLevel          conf.LogLevel
+	readTimeout       conf.StringDuration
+	writeTimeout      conf.StringDuration
+	writeQueueSize    int
+	udpMaxPayloadSize int
+	resolvedSource    string
+	resolvedSources   []string
+	parent            staticSourceHandlerParent
 
 	ctx       context.Context
 	ctxCancel func()
@@ -57,62 +61,95 @@ func (s *staticSourceHandler) initialize() {
 	s.chInstanceSetReady = make(chan defs.PathSourceStaticSetReadyReq)
 	s.chInstanceSetNotReady = make(chan defs.PathSourceStaticSetNotReadyReq)
 
+	if len(s.resolvedSources) > 1 {
+		s.instance = &staticSourceFailover{
+			resolvedSources:   s.resolvedSources,
+			writeQueueSize:    s.writeQueueSize,
+			udpMaxPayloadSize: s.udpMaxPayloadSize,
+			newInstance:       s.newInstance,
+			parent:            s,
+		}
+		s.instance.(*staticSourceFailover).initialize()
+	} else {
+		s.instance = s.newInstance(s.resolvedSource, s)
+	}
+}
+
+func (s *staticSourceHandler) newInstance(
+	resolvedSource string,
+	parent defs.StaticSourceParent,
+) defs.StaticSource {
 	switch {
-	case strings.HasPrefix(s.resolvedSource, "rtsp://") ||
-		strings.HasPrefix(s.resolvedSource, "rtsps://"):
-		s.instance = &rtspsource.Source{
-			ResolvedSource: s.resolvedSource,
+	case strings.HasPrefix(resolvedSource, "rtsp://") ||
+		strings.HasPrefix(resolvedSource, "rtsps://"):
+		return &rtspsource.Source{
+			ResolvedSource: resolvedSource,
 			ReadTimeout:    s.readTimeout,
 			WriteTimeout:   s.writeTimeout,
 			WriteQueueSize: s.writeQueueSize,
-			Parent:         s,
+			Parent:         parent,
 		}
 
-	case strings.HasPrefix(s.resolvedSource, "rtmp://") ||
-		strings.HasPrefix(s.resolvedSource, "rtmps://"):
-		s.instance = &rtmpsource.Source{
-			ResolvedSource: s.resolvedSource,
+	case strings.HasPrefix(resolvedSource, "rtmp://") ||
+		strings.HasPrefix(resolvedSource, "rtmps://"):
+		return &rtmpsource.Source{
+			ResolvedSource: resolvedSource,
 			ReadTimeout:    s.readTimeout,
 			WriteTimeout:   s.writeTimeout,
-			Parent:         s,
+			Parent:         parent,
+		}
+
+	case strings.HasPrefix(resolvedSource, "http://") ||
+		strings.HasPrefix(resolvedSource, "https://"):
+		return &hlssource.Source{
+			ResolvedSource: resolvedSource,
+			ReadTimeout:    s.readTimeout,
+			Parent:         parent,
 		}
 
-	case strings.HasPrefix(s.resolvedSource, "http://") ||
-		strings.HasPrefix(s.resolvedSource, "https://"):
-		s.instance = &hlssource.Source{
-			ResolvedSource: s.resolvedSource,
+	case strings.HasPrefix(resolvedSource, "udp://"):
+		return &udpsource.Source{
+			ResolvedSource: resolvedSource,
 			ReadTimeout:    s.readTimeout,
-			Parent:         s,
+			Parent:         parent,
 		}
 
-	case strings.HasPrefix(s.resolvedSource, "udp://"):
-		s.instance = &udpsource.Source{
-			ResolvedSource: s.resolvedSource,
+	case strings.HasPrefix(resolvedSource, "sdp://"):
+		return &rtpsource.Source{
+			ResolvedSource: resolvedSource,
 			ReadTimeout:    s.readTimeout,
-			Parent:         s,
+			Parent:         parent,
 		}
 
-	case strings.HasPrefix(s.resolvedSource, "srt://"):
-		s.instance = &srtsource.Source{
-			ResolvedSource: s.resolvedSource,
+	case strings.HasPrefix(resolvedSource, "file://"):
+		return &filesource.Source{
+			ResolvedSource: resolvedSource,
+			Parent:         parent,
+		}
+
+	case strings.HasPrefix(resolvedSource, "srt://"):
+		return &srtsource.Source{
+			ResolvedSource: resolvedSource,
 			ReadTimeout:    s.readTimeout,
-			Parent:         s,
+			Parent:         parent,
 		}
 
-	case strings.HasPrefix(s.resolvedSource, "whep://") ||
-		strings.HasPrefix(s.resolvedSource, "wheps://"):
-		s.instance = &webrtcsource.Source{
-			ResolvedSource: s.resolvedSource,
+	case strings.HasPrefix(resolvedSource, "whep://") ||
+		strings.HasPrefix(resolvedSource, "wheps://"):
+		return &webrtcsource.Source{
+			ResolvedSource: resolvedSource,
 			ReadTimeout:    s.readTimeout,
-			Parent:         s,
+			Parent:         parent,
 		}
 
-	case s.resolvedSource == "rpiCamera":
-		s.instance = &rpicamerasource.Source{
+	case resolvedSource == "rpiCamera":
+		return &rpicamerasource.Source{
 			LogLevel: s.logLevel,
-			Parent:   s,
+			Parent:   parent,
 		}
 	}
+
+	return nil
 }
 
 func (s *staticSourceHandler) close(reason string) {
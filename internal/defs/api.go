@@ -1,10 +1,12 @@
 package defs
 
 import (
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
 
+	"github.com/bluenviron/mediamtx/internal/auth"
 	"github.com/bluenviron/mediamtx/internal/conf"
 )
 
@@ -20,12 +22,67 @@ type APIPathConfList struct {
 	Items     []*conf.Path `json:"items"`
 }
 
+// APIConfigValidationError is an error found while validating a configuration.
+type APIConfigValidationError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// APIConfigDiff contains the effects of applying a configuration.
+type APIConfigDiff struct {
+	RestartedComponents []string `json:"restartedComponents"`
+	AddedPaths          []string `json:"addedPaths"`
+	RemovedPaths        []string `json:"removedPaths"`
+	ReloadedPaths       []string `json:"reloadedPaths"`
+	RestartedPaths      []string `json:"restartedPaths"`
+}
+
+// APIConfigValidation is the result of a configuration validation.
+type APIConfigValidation struct {
+	Valid  bool                       `json:"valid"`
+	Errors []APIConfigValidationError `json:"errors"`
+	Diff   *APIConfigDiff             `json:"diff"`
+}
+
 // APIPathSourceOrReader is a source or a reader.
 type APIPathSourceOrReader struct {
 	Type string `json:"type"`
 	ID   string `json:"id"`
 }
 
+// APIPathPushTargetState is the state of a push target.
+type APIPathPushTargetState string
+
+// states.
+const (
+	APIPathPushTargetStateConnecting APIPathPushTargetState = "connecting"
+	APIPathPushTargetStatePublishing APIPathPushTargetState = "publishing"
+	APIPathPushTargetStateWaiting    APIPathPushTargetState = "waiting"
+)
+
+// APIPathPushTarget is a push target.
+type APIPathPushTarget struct {
+	URL       string                 `json:"url"`
+	State     APIPathPushTargetState `json:"state"`
+	LastError string                 `json:"lastError"`
+	BytesSent uint64                 `json:"bytesSent"`
+}
+
+// APIPathTrackStats contains statistics of a track.
+type APIPathTrackStats struct {
+	Codec                    string     `json:"codec"`
+	Bitrate                  float64    `json:"bitrate"`
+	FrameRate                float64    `json:"frameRate"`
+	KeyFrameInterval         float64    `json:"keyFrameInterval"`
+	Width                    int        `json:"width"`
+	Height                   int        `json:"height"`
+	TimestampDiscontinuities uint64     `json:"timestampDiscontinuities"`
+	RTPPacketsReceived       uint64     `json:"rtpPacketsReceived"`
+	RTPPacketsLost           uint64     `json:"rtpPacketsLost"`
+	Jitter                   float64    `json:"jitter"`
+	LastUnit                 *time.Time `json:"lastUnit"`
+}
+
 // APIPath is a path.
 type APIPath struct {
 	Name          string                  `json:"name"`
@@ -34,9 +91,11 @@ type APIPath struct {
 	Ready         bool                    `json:"ready"`
 	ReadyTime     *time.Time              `json:"readyTime"`
 	Tracks        []string                `json:"tracks"`
+	TrackStats    []APIPathTrackStats     `json:"trackStats"`
 	BytesReceived uint64                  `json:"bytesReceived"`
 	BytesSent     uint64                  `json:"bytesSent"`
 	Readers       []APIPathSourceOrReader `json:"readers"`
+	Push          []APIPathPushTarget     `json:"push"`
 }
 
 // APIPathList is a list of paths.
@@ -61,6 +120,65 @@ type APIHLSMuxerList struct {
 	Items     []*APIHLSMuxer `json:"items"`
 }
 
+// APIDASHMuxer is a MPEG-DASH muxer.
+type APIDASHMuxer struct {
+	Path        string    `json:"path"`
+	Created     time.Time `json:"created"`
+	LastRequest time.Time `json:"lastRequest"`
+	BytesSent   uint64    `json:"bytesSent"`
+}
+
+// APIDASHMuxerList is a list of MPEG-DASH muxers.
+type APIDASHMuxerList struct {
+	ItemCount int             `json:"itemCount"`
+	PageCount int             `json:"pageCount"`
+	Items     []*APIDASHMuxer `json:"items"`
+}
+
+// APIHTTPFLVConnTransport is the transport of a HTTP-FLV connection.
+type APIHTTPFLVConnTransport string
+
+// transports.
+const (
+	APIHTTPFLVConnTransportHTTP      APIHTTPFLVConnTransport = "http"
+	APIHTTPFLVConnTransportWebSocket APIHTTPFLVConnTransport = "webSocket"
+)
+
+// APIHTTPFLVConn is a HTTP-FLV connection.
+type APIHTTPFLVConn struct {
+	ID         uuid.UUID               `json:"id"`
+	Created    time.Time               `json:"created"`
+	RemoteAddr string                  `json:"remoteAddr"`
+	Transport  APIHTTPFLVConnTransport `json:"transport"`
+	Path       string                  `json:"path"`
+	Query      string                  `json:"query"`
+	BytesSent  uint64                  `json:"bytesSent"`
+}
+
+// APIHTTPFLVConnList is a list of HTTP-FLV connections.
+type APIHTTPFLVConnList struct {
+	ItemCount int               `json:"itemCount"`
+	PageCount int               `json:"pageCount"`
+	Items     []*APIHTTPFLVConn `json:"items"`
+}
+
+// APIHTTPTSConn is a HTTP-TS connection.
+type APIHTTPTSConn struct {
+	ID         uuid.UUID `json:"id"`
+	Created    time.Time `json:"created"`
+	RemoteAddr string    `json:"remoteAddr"`
+	Path       string    `json:"path"`
+	Query      string    `json:"query"`
+	BytesSent  uint64    `json:"bytesSent"`
+}
+
+// APIHTTPTSConnList is a list of HTTP-TS connections.
+type APIHTTPTSConnList struct {
+	ItemCount int              `json:"itemCount"`
+	PageCount int              `json:"pageCount"`
+	Items     []*APIHTTPTSConn `json:"items"`
+}
+
 // APIRTMPConnState is the state of a RTMP connection.
 type APIRTMPConnState string
 
@@ -332,3 +450,73 @@ type APIRecordingList struct {
 	PageCount int             `json:"pageCount"`
 	Items     []*APIRecording `json:"items"`
 }
+
+// ErrRecordingSessionNotFound is returned when a recording session is not found.
+var ErrRecordingSessionNotFound = errors.New("recording session not found")
+
+// APIRecordingSessionState is the state of a recording session.
+type APIRecordingSessionState string
+
+// states.
+const (
+	APIRecordingSessionStateRecording APIRecordingSessionState = "recording"
+	APIRecordingSessionStateWaiting   APIRecordingSessionState = "waiting"
+	APIRecordingSessionStateStopped   APIRecordingSessionState = "stopped"
+)
+
+// APIRecordingSessionStartReq contains overrides of a recording session.
+type APIRecordingSessionStartReq struct {
+	Format      *conf.RecordFormat   `json:"format"`
+	MaxDuration *conf.StringDuration `json:"maxDuration"`
+	RecordPath  *string              `json:"recordPath"`
+}
+
+// APIRecordingSession is a recording session started with the API.
+type APIRecordingSession struct {
+	ID             uuid.UUID                `json:"id"`
+	Created        time.Time                `json:"created"`
+	Path           string                   `json:"path"`
+	State          APIRecordingSessionState `json:"state"`
+	Format         conf.RecordFormat        `json:"format"`
+	RecordPath     string                   `json:"recordPath"`
+	MaxDuration    conf.StringDuration      `json:"maxDuration"`
+	Stopped        *time.Time               `json:"stopped"`
+	BytesWritten   uint64                   `json:"bytesWritten"`
+	CurrentSegment *string                  `json:"currentSegment"`
+}
+
+// APIRecordingSessionList is a list of recording sessions.
+type APIRecordingSessionList struct {
+	ItemCount int                    `json:"itemCount"`
+	PageCount int                    `json:"pageCount"`
+	Items     []*APIRecordingSession `json:"items"`
+}
+
+// APITokenCreateReq is a request to create an API token.
+type APITokenCreateReq struct {
+	Name        string                 `json:"name"`
+	Permissions []auth.TokenPermission `json:"permissions"`
+	ExpiresAt   *time.Time             `json:"expiresAt"`
+}
+
+// APIToken is an API token.
+type APIToken struct {
+	ID          uuid.UUID              `json:"id"`
+	Name        string                 `json:"name"`
+	Permissions []auth.TokenPermission `json:"permissions"`
+	Created     time.Time              `json:"created"`
+	ExpiresAt   *time.Time             `json:"expiresAt"`
+}
+
+// APITokenCreateRes is the response to the creation of an API token.
+type APITokenCreateRes struct {
+	APIToken
+	Token string `json:"token"`
+}
+
+// APITokenList is a list of API tokens.
+type APITokenList struct {
+	ItemCount int         `json:"itemCount"`
+	PageCount int         `json:"pageCount"`
+	Items     []*APIToken `json:"items"`
+}
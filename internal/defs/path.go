@@ -3,6 +3,7 @@ package defs
 import (
 	"fmt"
 	"net"
+	"time"
 
 	"github.com/bluenviron/gortsplib/v4/pkg/base"
 	"github.com/bluenviron/gortsplib/v4/pkg/description"
@@ -177,3 +178,32 @@ type PathSourceStaticSetReadyReq struct {
 type PathSourceStaticSetNotReadyReq struct {
 	Res chan struct{}
 }
+
+// FormatStatsToAPI converts format statistics into track statistics.
+func FormatStatsToAPI(stats []stream.FormatStats) []APIPathTrackStats {
+	ret := make([]APIPathTrackStats, len(stats))
+
+	for i, st := range stats {
+		ret[i] = APIPathTrackStats{
+			Codec:                    st.Codec,
+			Bitrate:                  st.Bitrate,
+			FrameRate:                st.FrameRate,
+			KeyFrameInterval:         st.KeyFrameInterval.Seconds(),
+			Width:                    st.Width,
+			Height:                   st.Height,
+			TimestampDiscontinuities: st.TimestampDiscontinuities,
+			RTPPacketsReceived:       st.RTPPacketsReceived,
+			RTPPacketsLost:           st.RTPPacketsLost,
+			Jitter:                   st.Jitter.Seconds(),
+			LastUnit: func() *time.Time {
+				if st.LastUnit.IsZero() {
+					return nil
+				}
+				v := st.LastUnit
+				return &v
+			}(),
+		}
+	}
+
+	return ret
+}
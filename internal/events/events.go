@@ -0,0 +1,172 @@
+// Package events contains the event bus used to notify state changes to API clients.
+package events
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/bluenviron/mediamtx/internal/defs"
+)
+
+const (
+	subscriberQueueSize = 256
+)
+
+// Type is the type of an event.
+type Type string
+
+// event types.
+const (
+	TypePathReady                 Type = "pathReady"
+	TypePathNotReady              Type = "pathNotReady"
+	TypePublisherAdded            Type = "publisherAdded"
+	TypePublisherRemoved          Type = "publisherRemoved"
+	TypeReaderAdded               Type = "readerAdded"
+	TypeReaderRemoved             Type = "readerRemoved"
+	TypeAuthFailed                Type = "authFailed"
+	TypeRecordingSegmentCreated   Type = "recordingSegmentCreated"
+	TypeRecordingSegmentCompleted Type = "recordingSegmentCompleted"
+	TypeConfigReloaded            Type = "configReloaded"
+)
+
+var types = map[Type]struct{}{
+	TypePathReady:                 {},
+	TypePathNotReady:              {},
+	TypePublisherAdded:            {},
+	TypePublisherRemoved:          {},
+	TypeReaderAdded:               {},
+	TypeReaderRemoved:             {},
+	TypeAuthFailed:                {},
+	TypeRecordingSegmentCreated:   {},
+	TypeRecordingSegmentCompleted: {},
+	TypeConfigReloaded:            {},
+}
+
+// IsValidType checks whether an event type exists.
+func IsValidType(t Type) bool {
+	_, ok := types[t]
+	return ok
+}
+
+// Event is an event.
+type Event struct {
+	Type        Type                        `json:"type"`
+	Time        time.Time                   `json:"time"`
+	Path        string                      `json:"path,omitempty"`
+	Source      *defs.APIPathSourceOrReader `json:"source,omitempty"`
+	Reader      *defs.APIPathSourceOrReader `json:"reader,omitempty"`
+	SegmentPath string                      `json:"segmentPath,omitempty"`
+	User        string                      `json:"user,omitempty"`
+	IP          net.IP                      `json:"ip,omitempty"`
+	Action      string                      `json:"action,omitempty"`
+	Error       string                      `json:"error,omitempty"`
+}
+
+// Filter allows to receive a subset of events.
+// Empty fields match everything.
+type Filter struct {
+	Paths []string
+	Types []Type
+}
+
+func (f Filter) matches(e *Event) bool {
+	if len(f.Types) != 0 {
+		found := false
+		for _, t := range f.Types {
+			if t == e.Type {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(f.Paths) != 0 {
+		found := false
+		for _, p := range f.Paths {
+			if p == e.Path {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Subscriber is a subscriber of the event bus.
+type Subscriber struct {
+	filter Filter
+	ch     chan *Event
+}
+
+// Events returns a channel that receives events.
+func (s *Subscriber) Events() <-chan *Event {
+	return s.ch
+}
+
+// Bus is an event bus.
+// Events are delivered to subscribers without blocking the publisher;
+// subscribers that are too slow lose events.
+type Bus struct {
+	mutex       sync.RWMutex
+	subscribers map[*Subscriber]struct{}
+}
+
+// Publish sends an event to all subscribers.
+// It can be called on a nil Bus.
+func (b *Bus) Publish(e *Event) {
+	if b == nil {
+		return
+	}
+
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	for s := range b.subscribers {
+		if !s.filter.matches(e) {
+			continue
+		}
+
+		select {
+		case s.ch <- e:
+		default:
+		}
+	}
+}
+
+// Subscribe adds a subscriber.
+func (b *Bus) Subscribe(filter Filter) *Subscriber {
+	s := &Subscriber{
+		filter: filter,
+		ch:     make(chan *Event, subscriberQueueSize),
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.subscribers == nil {
+		b.subscribers = make(map[*Subscriber]struct{})
+	}
+	b.subscribers[s] = struct{}{}
+
+	return s
+}
+
+// Unsubscribe removes a subscriber.
+func (b *Bus) Unsubscribe(s *Subscriber) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	delete(b.subscribers, s)
+}
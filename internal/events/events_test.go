@@ -0,0 +1,52 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBusFilter(t *testing.T) {
+	b := &Bus{}
+
+	all := b.Subscribe(Filter{})
+	defer b.Unsubscribe(all)
+
+	filtered := b.Subscribe(Filter{
+		Paths: []string{"mypath"},
+		Types: []Type{TypePathReady},
+	})
+	defer b.Unsubscribe(filtered)
+
+	b.Publish(&Event{Type: TypePathReady, Path: "otherpath"})
+	b.Publish(&Event{Type: TypePathNotReady, Path: "mypath"})
+	b.Publish(&Event{Type: TypePathReady, Path: "mypath"})
+
+	require.Equal(t, 3, len(all.Events()))
+	require.Equal(t, 1, len(filtered.Events()))
+
+	e := <-filtered.Events()
+	require.Equal(t, TypePathReady, e.Type)
+	require.Equal(t, "mypath", e.Path)
+	require.False(t, e.Time.IsZero())
+}
+
+func TestBusSlowSubscriber(t *testing.T) {
+	b := &Bus{}
+
+	s := b.Subscribe(Filter{})
+
+	for i := 0; i < subscriberQueueSize+10; i++ {
+		b.Publish(&Event{Type: TypeConfigReloaded})
+	}
+
+	require.Equal(t, subscriberQueueSize, len(s.Events()))
+
+	b.Unsubscribe(s)
+	b.Publish(&Event{Type: TypeConfigReloaded})
+	require.Equal(t, subscriberQueueSize, len(s.Events()))
+
+	// publishing on a nil bus is allowed
+	var nb *Bus
+	nb.Publish(&Event{Type: TypeConfigReloaded})
+}
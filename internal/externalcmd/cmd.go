@@ -1,4 +1,4 @@
-// Package externalcmd allows to launch external commands.
+// Package externalcmd allows to launch external commands and to deliver webhooks.
 package externalcmd
 
 import (
@@ -8,9 +8,7 @@ import (
 	"time"
 )
 
-const (
-	restartPause = 5 * time.Second
-)
+var restartPause = 5 * time.Second
 
 var errTerminated = errors.New("terminated")
 
@@ -62,6 +60,22 @@ func NewCmd(
 		terminate: make(chan struct{}),
 	}
 
+	// commands that are URLs are delivered as webhooks.
+	if isWebhook(cmdstr) {
+		if !restart {
+			pool.enqueueWebhook(cmdstr, env, func(err error) {
+				if err != nil {
+					onExit(err)
+				}
+			})
+			return e
+		}
+
+		pool.wg.Add(1)
+		go e.runWebhook()
+		return e
+	}
+
 	pool.wg.Add(1)
 
 	go e.run()
@@ -108,3 +122,33 @@ func (e *Cmd) run() {
 		}
 	}
 }
+
+// runWebhook delivers the webhook again after restartPause, until the command is closed,
+// in the same way processes are restarted.
+func (e *Cmd) runWebhook() {
+	defer e.pool.wg.Done()
+
+	for {
+		done := make(chan error, 1)
+
+		e.pool.enqueueWebhook(e.cmdstr, e.env, func(err error) {
+			done <- err
+		})
+
+		select {
+		case err := <-done:
+			if err != nil {
+				e.onExit(err)
+			}
+
+		case <-e.terminate:
+			return
+		}
+
+		select {
+		case <-time.After(restartPause):
+		case <-e.terminate:
+			return
+		}
+	}
+}
@@ -1,20 +1,47 @@
 package externalcmd
 
 import (
+	"context"
 	"sync"
+	"time"
 )
 
 // Pool is a pool of external commands.
 type Pool struct {
 	wg sync.WaitGroup
+
+	webhookMinRetryPause time.Duration
+
+	webhookMutex     sync.Mutex
+	webhookConf      WebhookConf
+	webhookCtx       context.Context
+	webhookCtxCancel func()
+	webhookWorkers   map[string]*webhookWorker
+	webhookQueued    int
+	webhookWg        sync.WaitGroup
 }
 
 // NewPool allocates a Pool.
 func NewPool() *Pool {
-	return &Pool{}
+	p := &Pool{
+		webhookConf: WebhookConf{
+			Timeout: defaultWebhookTimeout,
+		},
+		webhookMinRetryPause: webhookMinRetryPause,
+		webhookWorkers:       make(map[string]*webhookWorker),
+	}
+
+	p.webhookCtx, p.webhookCtxCancel = context.WithCancel(context.Background())
+
+	return p
 }
 
 // Close waits for all external commands to exit.
+// Pending webhooks are discarded and webhooks being delivered are aborted.
 func (p *Pool) Close() {
 	p.wg.Wait()
+
+	p.webhookCtxCancel()
+	p.discardWebhooks()
+	p.webhookWg.Wait()
 }
@@ -0,0 +1,205 @@
+package externalcmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	webhookQueueSize      = 1024
+	webhookMinRetryPause  = 2 * time.Second
+	webhookMaxRetryPause  = 1 * time.Minute
+	defaultWebhookTimeout = 10 * time.Second
+)
+
+// WebhookConf contains the parameters used to deliver webhooks.
+type WebhookConf struct {
+	// maximum duration of a request.
+	Timeout time.Duration
+
+	// number of additional attempts when a request fails.
+	Retries int
+
+	// when not empty, requests are signed with HMAC-SHA256
+	// and the signature is put in the X-MTX-Signature header.
+	Secret string
+}
+
+type webhook struct {
+	url    string
+	body   []byte
+	onExit OnExitFunc
+}
+
+func isWebhook(cmdstr string) bool {
+	return strings.HasPrefix(cmdstr, "http://") || strings.HasPrefix(cmdstr, "https://")
+}
+
+// SetWebhookConf sets the parameters used to deliver webhooks.
+func (p *Pool) SetWebhookConf(conf WebhookConf) {
+	if conf.Timeout == 0 {
+		conf.Timeout = defaultWebhookTimeout
+	}
+
+	p.webhookMutex.Lock()
+	defer p.webhookMutex.Unlock()
+	p.webhookConf = conf
+}
+
+// webhookWorker delivers webhooks directed to a single URL, in order.
+// This allows a slow or unreachable receiver to delay only its own webhooks.
+type webhookWorker struct {
+	url   string
+	queue []*webhook
+}
+
+// enqueueWebhook adds a webhook to the delivery queue.
+// It never blocks: when the queue is full, the webhook is discarded.
+// onExit is called with a nil error when the webhook is delivered.
+func (p *Pool) enqueueWebhook(url string, env Environment, onExit OnExitFunc) {
+	if env == nil {
+		env = Environment{}
+	}
+
+	body, _ := json.Marshal(env)
+
+	p.webhookMutex.Lock()
+
+	if p.webhookCtx.Err() != nil {
+		p.webhookMutex.Unlock()
+		onExit(errTerminated)
+		return
+	}
+
+	if p.webhookQueued >= webhookQueueSize {
+		p.webhookMutex.Unlock()
+		onExit(fmt.Errorf("webhook queue is full, discarding webhook to %s", url))
+		return
+	}
+
+	w, ok := p.webhookWorkers[url]
+	if !ok {
+		w = &webhookWorker{url: url}
+		p.webhookWorkers[url] = w
+		p.webhookWg.Add(1)
+		go p.runWebhookWorker(w)
+	}
+
+	w.queue = append(w.queue, &webhook{
+		url:    url,
+		body:   body,
+		onExit: onExit,
+	})
+	p.webhookQueued++
+
+	p.webhookMutex.Unlock()
+}
+
+// runWebhookWorker delivers the webhooks of a worker until its queue is empty.
+func (p *Pool) runWebhookWorker(ww *webhookWorker) {
+	defer p.webhookWg.Done()
+
+	for {
+		p.webhookMutex.Lock()
+
+		if len(ww.queue) == 0 {
+			delete(p.webhookWorkers, ww.url)
+			p.webhookMutex.Unlock()
+			return
+		}
+
+		w := ww.queue[0]
+		ww.queue = ww.queue[1:]
+		p.webhookQueued--
+		conf := p.webhookConf
+
+		p.webhookMutex.Unlock()
+
+		p.deliverWebhook(w, conf)
+	}
+}
+
+// discardWebhooks removes all pending webhooks from the queue.
+func (p *Pool) discardWebhooks() {
+	p.webhookMutex.Lock()
+
+	var discarded []*webhook
+	for _, ww := range p.webhookWorkers {
+		discarded = append(discarded, ww.queue...)
+		ww.queue = nil
+	}
+	p.webhookQueued = 0
+
+	p.webhookMutex.Unlock()
+
+	for _, w := range discarded {
+		w.onExit(fmt.Errorf("webhook to %s discarded: %w", w.url, errTerminated))
+	}
+}
+
+func (p *Pool) deliverWebhook(w *webhook, conf WebhookConf) {
+	retryPause := p.webhookMinRetryPause
+
+	for attempt := 0; ; attempt++ {
+		err := doWebhook(p.webhookCtx, w, conf)
+		if err == nil {
+			w.onExit(nil)
+			return
+		}
+
+		if attempt >= conf.Retries || p.webhookCtx.Err() != nil {
+			w.onExit(fmt.Errorf("webhook delivery failed: %w", err))
+			return
+		}
+
+		select {
+		case <-time.After(retryPause):
+		case <-p.webhookCtx.Done():
+			w.onExit(fmt.Errorf("webhook delivery failed: %w", err))
+			return
+		}
+
+		retryPause *= 2
+		if retryPause > webhookMaxRetryPause {
+			retryPause = webhookMaxRetryPause
+		}
+	}
+}
+
+func doWebhook(parentCtx context.Context, w *webhook, conf WebhookConf) error {
+	ctx, ctxCancel := context.WithTimeout(parentCtx, conf.Timeout)
+	defer ctxCancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(w.body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if conf.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(conf.Secret))
+		mac.Write(w.body)
+		req.Header.Set("X-MTX-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("bad status code: %d", res.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package externalcmd
+
+import (
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookSlowReceiver(t *testing.T) {
+	slowLn, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	unblock := make(chan struct{})
+
+	slowServer := &http.Server{Handler: http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		select {
+		case <-unblock:
+		case <-r.Context().Done():
+		}
+	})}
+	go slowServer.Serve(slowLn)
+	defer slowServer.Close()
+
+	fastLn, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	received := make(chan struct{})
+
+	fastServer := &http.Server{Handler: http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+		close(received)
+	})}
+	go fastServer.Serve(fastLn)
+	defer fastServer.Close()
+
+	p := NewPool()
+	p.SetWebhookConf(WebhookConf{
+		Timeout: 1 * time.Hour,
+	})
+
+	slowDone := make(chan error, 2)
+
+	for i := 0; i < 2; i++ {
+		NewCmd(p, "http://"+slowLn.Addr().String()+"/slow", false, nil, func(err error) {
+			slowDone <- err
+		})
+	}
+
+	NewCmd(p, "http://"+fastLn.Addr().String()+"/fast", false, nil, nil)
+
+	// the fast receiver is not delayed by the slow one.
+	select {
+	case <-received:
+	case <-time.After(2 * time.Second):
+		t.Errorf("webhook not received")
+	}
+
+	// pending and in-flight webhooks are aborted.
+	closed := make(chan struct{})
+	go func() {
+		p.Close()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+	case <-time.After(2 * time.Second):
+		t.Errorf("pool not closed")
+	}
+
+	require.Error(t, <-slowDone)
+	require.Error(t, <-slowDone)
+	close(unblock)
+}
+
+func TestWebhookRestart(t *testing.T) {
+	defer func(v time.Duration) { restartPause = v }(restartPause)
+	restartPause = 10 * time.Millisecond
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	received := make(chan struct{}, 10)
+
+	server := &http.Server{Handler: http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+		select {
+		case received <- struct{}{}:
+		default:
+		}
+	})}
+	go server.Serve(ln)
+	defer server.Close()
+
+	p := NewPool()
+	defer p.Close()
+
+	cmd := NewCmd(p, "http://"+ln.Addr().String()+"/hook", true, nil, nil)
+
+	// the webhook is delivered again until the command is closed.
+	for i := 0; i < 3; i++ {
+		select {
+		case <-received:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("webhook not delivered")
+		}
+	}
+
+	cmd.Close()
+}
@@ -0,0 +1,69 @@
+package logger
+
+import (
+	"encoding/hex"
+	"net"
+
+	"github.com/google/uuid"
+)
+
+// Field is a structured field of a log entry.
+type Field struct {
+	Key   string
+	Value string
+}
+
+// Attr is a structured attribute of a log entry.
+// It is passed as argument of Log() and is consumed by a "%v" verb.
+// In text format, it is rendered as a prefix; in JSON format, its fields are
+// added to the entry and it is removed from the message.
+type Attr struct {
+	Text   string
+	Fields []Field
+}
+
+// String implements fmt.Stringer.
+func (a Attr) String() string {
+	return a.Text
+}
+
+// Component returns an attribute that describes a component.
+func Component(name string) Attr {
+	return Attr{
+		Text:   "[" + name + "] ",
+		Fields: []Field{{Key: "component", Value: name}},
+	}
+}
+
+// Path returns an attribute that describes a path.
+func Path(name string) Attr {
+	return Attr{
+		Text:   "[path " + name + "] ",
+		Fields: []Field{{Key: "path", Value: name}},
+	}
+}
+
+// Conn returns an attribute that describes a connection.
+func Conn(id uuid.UUID, remoteAddr net.Addr) Attr {
+	addr := remoteAddr.String()
+	return Attr{
+		Text: "[conn " + addr + "] ",
+		Fields: []Field{
+			{Key: "connID", Value: id.String()},
+			{Key: "remoteAddr", Value: addr},
+		},
+	}
+}
+
+// Session returns an attribute that describes a session.
+// remoteAddr is optional.
+func Session(id uuid.UUID, remoteAddr string) Attr {
+	a := Attr{
+		Text:   "[session " + hex.EncodeToString(id[:4]) + "] ",
+		Fields: []Field{{Key: "sessionID", Value: id.String()}},
+	}
+	if remoteAddr != "" {
+		a.Fields = append(a.Fields, Field{Key: "remoteAddr", Value: remoteAddr})
+	}
+	return a
+}
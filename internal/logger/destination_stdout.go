@@ -9,22 +9,22 @@ import (
 )
 
 type destinationStdout struct {
-	useColor bool
+	logFormat Format
+	useColor  bool
 
 	buf bytes.Buffer
 }
 
-func newDestionationStdout() destination {
+func newDestionationStdout(logFormat Format) destination {
 	return &destinationStdout{
-		useColor: term.IsTerminal(int(os.Stdout.Fd())),
+		logFormat: logFormat,
+		useColor:  logFormat == FormatText && term.IsTerminal(int(os.Stdout.Fd())),
 	}
 }
 
 func (d *destinationStdout) log(t time.Time, level Level, format string, args ...interface{}) {
 	d.buf.Reset()
-	writeTime(&d.buf, t, d.useColor)
-	writeLevel(&d.buf, level, d.useColor)
-	writeContent(&d.buf, format, args)
+	writeEntry(&d.buf, d.logFormat, t, level, d.useColor, format, args)
 	os.Stdout.Write(d.buf.Bytes()) //nolint:errcheck
 }
 
@@ -7,26 +7,26 @@ import (
 )
 
 type destinationSysLog struct {
-	syslog io.WriteCloser
-	buf    bytes.Buffer
+	logFormat Format
+	syslog    io.WriteCloser
+	buf       bytes.Buffer
 }
 
-func newDestinationSyslog() (destination, error) {
+func newDestinationSyslog(logFormat Format) (destination, error) {
 	syslog, err := newSysLog("mediamtx")
 	if err != nil {
 		return nil, err
 	}
 
 	return &destinationSysLog{
-		syslog: syslog,
+		logFormat: logFormat,
+		syslog:    syslog,
 	}, nil
 }
 
 func (d *destinationSysLog) log(t time.Time, level Level, format string, args ...interface{}) {
 	d.buf.Reset()
-	writeTime(&d.buf, t, false)
-	writeLevel(&d.buf, level, false)
-	writeContent(&d.buf, format, args)
+	writeEntry(&d.buf, d.logFormat, t, level, false, format, args)
 	d.syslog.Write(d.buf.Bytes())
 }
 
@@ -0,0 +1,104 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Format is a log format.
+type Format int
+
+// log formats.
+const (
+	// FormatText writes logs as human-readable lines.
+	FormatText Format = iota
+
+	// FormatJSON writes logs as JSON objects, one per line.
+	FormatJSON
+)
+
+func levelString(level Level) string {
+	switch level {
+	case Debug:
+		return "debug"
+
+	case Info:
+		return "info"
+
+	case Warn:
+		return "warn"
+
+	default:
+		return "error"
+	}
+}
+
+func writeJSONString(buf *bytes.Buffer, s string) {
+	byts, _ := json.Marshal(s)
+	buf.Write(byts)
+}
+
+func writeJSON(buf *bytes.Buffer, t time.Time, level Level, format string, args []interface{}) {
+	var fields []Field
+	var args2 []interface{}
+
+	for i, arg := range args {
+		attr, ok := arg.(Attr)
+		if !ok {
+			continue
+		}
+
+		// attributes are removed from the message
+		if args2 == nil {
+			args2 = append([]interface{}(nil), args...)
+		}
+		args2[i] = ""
+
+	outer:
+		for _, f := range attr.Fields {
+			// inner attributes override outer ones
+			for j := range fields {
+				if fields[j].Key == f.Key {
+					fields[j].Value = f.Value
+					continue outer
+				}
+			}
+			fields = append(fields, f)
+		}
+	}
+
+	if args2 == nil {
+		args2 = args
+	}
+
+	buf.WriteString(`{"time":`)
+	writeJSONString(buf, t.Format(time.RFC3339Nano))
+	buf.WriteString(`,"level":`)
+	writeJSONString(buf, levelString(level))
+
+	for _, f := range fields {
+		buf.WriteByte(',')
+		writeJSONString(buf, f.Key)
+		buf.WriteByte(':')
+		writeJSONString(buf, f.Value)
+	}
+
+	buf.WriteString(`,"message":`)
+	writeJSONString(buf, fmt.Sprintf(format, args2...))
+	buf.WriteString("}\n")
+}
+
+func writeEntry(buf *bytes.Buffer, logFormat Format, t time.Time, level Level,
+	useColor bool, format string, args []interface{},
+) {
+	if logFormat == FormatJSON {
+		writeJSON(buf, t, level, format, args)
+		return
+	}
+
+	writeTime(buf, t, useColor)
+	writeLevel(buf, level, useColor)
+	writeContent(buf, format, args)
+}
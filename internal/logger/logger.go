@@ -19,7 +19,7 @@ type Logger struct {
 }
 
 // New allocates a log handler.
-func New(level Level, destinations []Destination, filePath string) (*Logger, error) {
+func New(level Level, logFormat Format, destinations []Destination, filePath string) (*Logger, error) {
 	lh := &Logger{
 		level: level,
 	}
@@ -27,10 +27,10 @@ func New(level Level, destinations []Destination, filePath string) (*Logger, err
 	for _, destType := range destinations {
 		switch destType {
 		case DestinationStdout:
-			lh.destinations = append(lh.destinations, newDestionationStdout())
+			lh.destinations = append(lh.destinations, newDestionationStdout(logFormat))
 
 		case DestinationFile:
-			dest, err := newDestinationFile(filePath)
+			dest, err := newDestinationFile(logFormat, filePath)
 			if err != nil {
 				lh.Close()
 				return nil, err
@@ -38,7 +38,7 @@ func New(level Level, destinations []Destination, filePath string) (*Logger, err
 			lh.destinations = append(lh.destinations, dest)
 
 		case DestinationSyslog:
-			dest, err := newDestinationSyslog()
+			dest, err := newDestinationSyslog(logFormat)
 			if err != nil {
 				lh.Close()
 				return nil, err
@@ -0,0 +1,66 @@
+package logger
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoggerFormats(t *testing.T) {
+	id := uuid.MustParse("5e2b4e4f-7c2a-4b8e-9d5a-2f0c8c1b3d4e")
+	addr := &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 5432}
+
+	for _, ca := range []string{"text", "json"} {
+		t.Run(ca, func(t *testing.T) {
+			dir, err := os.MkdirTemp("", "mediamtx-logger")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			fpath := filepath.Join(dir, "out.log")
+
+			logFormat := FormatText
+			if ca == "json" {
+				logFormat = FormatJSON
+			}
+
+			l, err := New(Info, logFormat, []Destination{DestinationFile}, fpath)
+			require.NoError(t, err)
+
+			l.Log(Info, "%v%v%vtest message %d",
+				Component("RTSP"), Conn(id, addr), Path("mypath"), 15)
+			l.Log(Debug, "hidden")
+			l.Close()
+
+			byts, err := os.ReadFile(fpath)
+			require.NoError(t, err)
+
+			if ca == "text" {
+				require.True(t, strings.HasSuffix(string(byts),
+					" INF [RTSP] [conn 127.0.0.1:5432] [path mypath] test message 15\n"))
+				return
+			}
+
+			var entry map[string]interface{}
+			err = json.Unmarshal(byts, &entry)
+			require.NoError(t, err)
+
+			require.NotEmpty(t, entry["time"])
+			delete(entry, "time")
+
+			require.Equal(t, map[string]interface{}{
+				"level":      "info",
+				"component":  "RTSP",
+				"connID":     "5e2b4e4f-7c2a-4b8e-9d5a-2f0c8c1b3d4e",
+				"remoteAddr": "127.0.0.1:5432",
+				"path":       "mypath",
+				"message":    "test message 15",
+			}, entry)
+		})
+	}
+}
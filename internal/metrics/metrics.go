@@ -17,6 +17,7 @@ import (
 	"github.com/bluenviron/mediamtx/internal/conf"
 	"github.com/bluenviron/mediamtx/internal/logger"
 	"github.com/bluenviron/mediamtx/internal/protocols/httpp"
+	"github.com/bluenviron/mediamtx/internal/record"
 	"github.com/bluenviron/mediamtx/internal/restrictnetwork"
 )
 
@@ -24,12 +25,44 @@ func interfaceIsEmpty(i interface{}) bool {
 	return reflect.ValueOf(i).Kind() != reflect.Ptr || reflect.ValueOf(i).IsNil()
 }
 
-func metric(key string, tags string, value int64) string {
-	return key + tags + " " + strconv.FormatInt(value, 10) + "\n"
+// Label is a label of a metric.
+type Label struct {
+	Key   string
+	Value string
 }
 
-func metricFloat(key string, tags string, value float64) string {
-	return key + tags + " " + strconv.FormatFloat(value, 'f', -1, 64) + "\n"
+// Sample is the value of a metric.
+type Sample struct {
+	Name   string
+	Labels []Label
+	Value  float64
+
+	// Counter is true when the metric is a monotonic counter, false when it is a gauge.
+	Counter bool
+}
+
+func (s Sample) text() string {
+	tags := ""
+	if len(s.Labels) != 0 {
+		tags = "{"
+		for i, l := range s.Labels {
+			if i != 0 {
+				tags += ","
+			}
+			tags += l.Key + "=\"" + l.Value + "\""
+		}
+		tags += "}"
+	}
+
+	return s.Name + tags + " " + strconv.FormatFloat(s.Value, 'f', -1, 64) + "\n"
+}
+
+func gauge(name string, labels []Label, value float64) Sample {
+	return Sample{Name: name, Labels: labels, Value: value}
+}
+
+func counter(name string, labels []Label, value float64) Sample {
+	return Sample{Name: name, Labels: labels, Value: value, Counter: true}
 }
 
 type metricsAuthManager interface {
@@ -40,6 +73,10 @@ type metricsParent interface {
 	logger.Writer
 }
 
+type metricsRecordCleaner interface {
+	Evictions() []record.CleanerEvictions
+}
+
 // Metrics is a metrics provider.
 type Metrics struct {
 	Address     string
@@ -47,20 +84,30 @@ type Metrics struct {
 	AuthManager metricsAuthManager
 	Parent      metricsParent
 
-	httpServer   *httpp.WrappedServer
-	mutex        sync.Mutex
-	pathManager  api.PathManager
-	rtspServer   api.RTSPServer
-	rtspsServer  api.RTSPServer
-	rtmpServer   api.RTMPServer
-	rtmpsServer  api.RTMPServer
-	srtServer    api.SRTServer
-	hlsManager   api.HLSServer
-	webRTCServer api.WebRTCServer
+	httpServer    *httpp.WrappedServer
+	mutex         sync.Mutex
+	pathManager   api.PathManager
+	rtspServer    api.RTSPServer
+	rtspsServer   api.RTSPServer
+	rtmpServer    api.RTMPServer
+	rtmpsServer   api.RTMPServer
+	srtServer     api.SRTServer
+	hlsManager    api.HLSServer
+	webRTCServer  api.WebRTCServer
+	httpFLVServer api.HTTPFLVServer
+	httpTSServer  api.HTTPTSServer
+	dashServer    api.DASHServer
+	recordCleaner metricsRecordCleaner
 }
 
 // Initialize initializes metrics.
 func (m *Metrics) Initialize() error {
+	// metrics can be gathered without the Prometheus listener,
+	// in order to be pushed by the OTLP exporter.
+	if m.Address == "" {
+		return nil
+	}
+
 	router := gin.New()
 	router.SetTrustedProxies(nil) //nolint:errcheck
 
@@ -89,25 +136,37 @@ func (m *Metrics) Initialize() error {
 
 // Close closes Metrics.
 func (m *Metrics) Close() {
+	if m.httpServer == nil {
+		return
+	}
+
 	m.Log(logger.Info, "listener is closing")
 	m.httpServer.Close()
 }
 
 // Log implements logger.Writer.
 func (m *Metrics) Log(level logger.Level, format string, args ...interface{}) {
-	m.Parent.Log(level, "[metrics] "+format, args...)
+	m.Parent.Log(level, "%v"+format, append([]interface{}{logger.Component("metrics")}, args...)...)
 }
 
 func (m *Metrics) mwAuth(ctx *gin.Context) {
 	user, pass, hasCredentials := ctx.Request.BasicAuth()
 
-	err := m.AuthManager.Authenticate(&auth.Request{
-		User:   user,
-		Pass:   pass,
-		Query:  ctx.Request.URL.RawQuery,
-		IP:     net.ParseIP(ctx.ClientIP()),
-		Action: conf.AuthActionMetrics,
-	})
+	req := &auth.Request{
+		User:     user,
+		Pass:     pass,
+		Query:    ctx.Request.URL.RawQuery,
+		IP:       net.ParseIP(ctx.ClientIP()),
+		Action:   conf.AuthActionMetrics,
+		Token:    auth.TokenFromHeader(ctx.Request.Header),
+		Endpoint: ctx.Request.URL.Path,
+	}
+
+	if req.Token != "" {
+		hasCredentials = true
+	}
+
+	err := m.AuthManager.Authenticate(req)
 	if err != nil {
 		if !hasCredentials {
 			ctx.Header("WWW-Authenticate", `Basic realm="mediamtx"`)
@@ -123,8 +182,12 @@ func (m *Metrics) mwAuth(ctx *gin.Context) {
 	}
 }
 
-func (m *Metrics) onMetrics(ctx *gin.Context) {
-	out := ""
+// Gather returns samples of all metrics.
+func (m *Metrics) Gather() []Sample {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var out []Sample
 
 	data, err := m.pathManager.APIPathsList()
 	if err == nil && len(data.Items) != 0 {
@@ -136,26 +199,39 @@ func (m *Metrics) onMetrics(ctx *gin.Context) {
 				state = "notReady"
 			}
 
-			tags := "{name=\"" + i.Name + "\",state=\"" + state + "\"}"
-			out += metric("paths", tags, 1)
-			out += metric("paths_bytes_received", tags, int64(i.BytesReceived))
-			out += metric("paths_bytes_sent", tags, int64(i.BytesSent))
+			tags := []Label{{"name", i.Name}, {"state", state}}
+			out = append(out, gauge("paths", tags, 1))
+			out = append(out, counter("paths_bytes_received", tags, float64(i.BytesReceived)))
+			out = append(out, counter("paths_bytes_sent", tags, float64(i.BytesSent)))
+
+			for j, st := range i.TrackStats {
+				ttags := []Label{{"name", i.Name}, {"track", strconv.FormatInt(int64(j), 10)}, {"codec", st.Codec}}
+				out = append(out, gauge("paths_tracks_bitrate", ttags, st.Bitrate))
+				out = append(out, gauge("paths_tracks_frame_rate", ttags, st.FrameRate))
+				out = append(out, gauge("paths_tracks_keyframe_interval", ttags, st.KeyFrameInterval))
+				out = append(out, gauge("paths_tracks_width", ttags, float64(st.Width)))
+				out = append(out, gauge("paths_tracks_height", ttags, float64(st.Height)))
+				out = append(out, counter("paths_tracks_timestamp_discontinuities", ttags, float64(st.TimestampDiscontinuities)))
+				out = append(out, counter("paths_tracks_rtp_packets_received", ttags, float64(st.RTPPacketsReceived)))
+				out = append(out, counter("paths_tracks_rtp_packets_lost", ttags, float64(st.RTPPacketsLost)))
+				out = append(out, gauge("paths_tracks_jitter", ttags, st.Jitter))
+			}
 		}
 	} else {
-		out += metric("paths", "", 0)
+		out = append(out, gauge("paths", nil, 0))
 	}
 
 	if !interfaceIsEmpty(m.hlsManager) {
 		data, err := m.hlsManager.APIMuxersList()
 		if err == nil && len(data.Items) != 0 {
 			for _, i := range data.Items {
-				tags := "{name=\"" + i.Path + "\"}"
-				out += metric("hls_muxers", tags, 1)
-				out += metric("hls_muxers_bytes_sent", tags, int64(i.BytesSent))
+				tags := []Label{{"name", i.Path}}
+				out = append(out, gauge("hls_muxers", tags, 1))
+				out = append(out, counter("hls_muxers_bytes_sent", tags, float64(i.BytesSent)))
 			}
 		} else {
-			out += metric("hls_muxers", "", 0)
-			out += metric("hls_muxers_bytes_sent", "", 0)
+			out = append(out, gauge("hls_muxers", nil, 0))
+			out = append(out, counter("hls_muxers_bytes_sent", nil, 0))
 		}
 	}
 
@@ -164,15 +240,15 @@ func (m *Metrics) onMetrics(ctx *gin.Context) {
 			data, err := m.rtspServer.APIConnsList()
 			if err == nil && len(data.Items) != 0 {
 				for _, i := range data.Items {
-					tags := "{id=\"" + i.ID.String() + "\"}"
-					out += metric("rtsp_conns", tags, 1)
-					out += metric("rtsp_conns_bytes_received", tags, int64(i.BytesReceived))
-					out += metric("rtsp_conns_bytes_sent", tags, int64(i.BytesSent))
+					tags := []Label{{"id", i.ID.String()}}
+					out = append(out, gauge("rtsp_conns", tags, 1))
+					out = append(out, counter("rtsp_conns_bytes_received", tags, float64(i.BytesReceived)))
+					out = append(out, counter("rtsp_conns_bytes_sent", tags, float64(i.BytesSent)))
 				}
 			} else {
-				out += metric("rtsp_conns", "", 0)
-				out += metric("rtsp_conns_bytes_received", "", 0)
-				out += metric("rtsp_conns_bytes_sent", "", 0)
+				out = append(out, gauge("rtsp_conns", nil, 0))
+				out = append(out, counter("rtsp_conns_bytes_received", nil, 0))
+				out = append(out, counter("rtsp_conns_bytes_sent", nil, 0))
 			}
 		}()
 
@@ -180,15 +256,15 @@ func (m *Metrics) onMetrics(ctx *gin.Context) {
 			data, err := m.rtspServer.APISessionsList()
 			if err == nil && len(data.Items) != 0 {
 				for _, i := range data.Items {
-					tags := "{id=\"" + i.ID.String() + "\",state=\"" + string(i.State) + "\"}"
-					out += metric("rtsp_sessions", tags, 1)
-					out += metric("rtsp_sessions_bytes_received", tags, int64(i.BytesReceived))
-					out += metric("rtsp_sessions_bytes_sent", tags, int64(i.BytesSent))
+					tags := []Label{{"id", i.ID.String()}, {"state", string(i.State)}}
+					out = append(out, gauge("rtsp_sessions", tags, 1))
+					out = append(out, counter("rtsp_sessions_bytes_received", tags, float64(i.BytesReceived)))
+					out = append(out, counter("rtsp_sessions_bytes_sent", tags, float64(i.BytesSent)))
 				}
 			} else {
-				out += metric("rtsp_sessions", "", 0)
-				out += metric("rtsp_sessions_bytes_received", "", 0)
-				out += metric("rtsp_sessions_bytes_sent", "", 0)
+				out = append(out, gauge("rtsp_sessions", nil, 0))
+				out = append(out, counter("rtsp_sessions_bytes_received", nil, 0))
+				out = append(out, counter("rtsp_sessions_bytes_sent", nil, 0))
 			}
 		}()
 	}
@@ -198,15 +274,15 @@ func (m *Metrics) onMetrics(ctx *gin.Context) {
 			data, err := m.rtspsServer.APIConnsList()
 			if err == nil && len(data.Items) != 0 {
 				for _, i := range data.Items {
-					tags := "{id=\"" + i.ID.String() + "\"}"
-					out += metric("rtsps_conns", tags, 1)
-					out += metric("rtsps_conns_bytes_received", tags, int64(i.BytesReceived))
-					out += metric("rtsps_conns_bytes_sent", tags, int64(i.BytesSent))
+					tags := []Label{{"id", i.ID.String()}}
+					out = append(out, gauge("rtsps_conns", tags, 1))
+					out = append(out, counter("rtsps_conns_bytes_received", tags, float64(i.BytesReceived)))
+					out = append(out, counter("rtsps_conns_bytes_sent", tags, float64(i.BytesSent)))
 				}
 			} else {
-				out += metric("rtsps_conns", "", 0)
-				out += metric("rtsps_conns_bytes_received", "", 0)
-				out += metric("rtsps_conns_bytes_sent", "", 0)
+				out = append(out, gauge("rtsps_conns", nil, 0))
+				out = append(out, counter("rtsps_conns_bytes_received", nil, 0))
+				out = append(out, counter("rtsps_conns_bytes_sent", nil, 0))
 			}
 		}()
 
@@ -214,15 +290,15 @@ func (m *Metrics) onMetrics(ctx *gin.Context) {
 			data, err := m.rtspsServer.APISessionsList()
 			if err == nil && len(data.Items) != 0 {
 				for _, i := range data.Items {
-					tags := "{id=\"" + i.ID.String() + "\",state=\"" + string(i.State) + "\"}"
-					out += metric("rtsps_sessions", tags, 1)
-					out += metric("rtsps_sessions_bytes_received", tags, int64(i.BytesReceived))
-					out += metric("rtsps_sessions_bytes_sent", tags, int64(i.BytesSent))
+					tags := []Label{{"id", i.ID.String()}, {"state", string(i.State)}}
+					out = append(out, gauge("rtsps_sessions", tags, 1))
+					out = append(out, counter("rtsps_sessions_bytes_received", tags, float64(i.BytesReceived)))
+					out = append(out, counter("rtsps_sessions_bytes_sent", tags, float64(i.BytesSent)))
 				}
 			} else {
-				out += metric("rtsps_sessions", "", 0)
-				out += metric("rtsps_sessions_bytes_received", "", 0)
-				out += metric("rtsps_sessions_bytes_sent", "", 0)
+				out = append(out, gauge("rtsps_sessions", nil, 0))
+				out = append(out, counter("rtsps_sessions_bytes_received", nil, 0))
+				out = append(out, counter("rtsps_sessions_bytes_sent", nil, 0))
 			}
 		}()
 	}
@@ -231,15 +307,15 @@ func (m *Metrics) onMetrics(ctx *gin.Context) {
 		data, err := m.rtmpServer.APIConnsList()
 		if err == nil && len(data.Items) != 0 {
 			for _, i := range data.Items {
-				tags := "{id=\"" + i.ID.String() + "\",state=\"" + string(i.State) + "\"}"
-				out += metric("rtmp_conns", tags, 1)
-				out += metric("rtmp_conns_bytes_received", tags, int64(i.BytesReceived))
-				out += metric("rtmp_conns_bytes_sent", tags, int64(i.BytesSent))
+				tags := []Label{{"id", i.ID.String()}, {"state", string(i.State)}}
+				out = append(out, gauge("rtmp_conns", tags, 1))
+				out = append(out, counter("rtmp_conns_bytes_received", tags, float64(i.BytesReceived)))
+				out = append(out, counter("rtmp_conns_bytes_sent", tags, float64(i.BytesSent)))
 			}
 		} else {
-			out += metric("rtmp_conns", "", 0)
-			out += metric("rtmp_conns_bytes_received", "", 0)
-			out += metric("rtmp_conns_bytes_sent", "", 0)
+			out = append(out, gauge("rtmp_conns", nil, 0))
+			out = append(out, counter("rtmp_conns_bytes_received", nil, 0))
+			out = append(out, counter("rtmp_conns_bytes_sent", nil, 0))
 		}
 	}
 
@@ -247,15 +323,15 @@ func (m *Metrics) onMetrics(ctx *gin.Context) {
 		data, err := m.rtmpsServer.APIConnsList()
 		if err == nil && len(data.Items) != 0 {
 			for _, i := range data.Items {
-				tags := "{id=\"" + i.ID.String() + "\",state=\"" + string(i.State) + "\"}"
-				out += metric("rtmps_conns", tags, 1)
-				out += metric("rtmps_conns_bytes_received", tags, int64(i.BytesReceived))
-				out += metric("rtmps_conns_bytes_sent", tags, int64(i.BytesSent))
+				tags := []Label{{"id", i.ID.String()}, {"state", string(i.State)}}
+				out = append(out, gauge("rtmps_conns", tags, 1))
+				out = append(out, counter("rtmps_conns_bytes_received", tags, float64(i.BytesReceived)))
+				out = append(out, counter("rtmps_conns_bytes_sent", tags, float64(i.BytesSent)))
 			}
 		} else {
-			out += metric("rtmps_conns", "", 0)
-			out += metric("rtmps_conns_bytes_received", "", 0)
-			out += metric("rtmps_conns_bytes_sent", "", 0)
+			out = append(out, gauge("rtmps_conns", nil, 0))
+			out = append(out, counter("rtmps_conns_bytes_received", nil, 0))
+			out = append(out, counter("rtmps_conns_bytes_sent", nil, 0))
 		}
 	}
 
@@ -263,64 +339,65 @@ func (m *Metrics) onMetrics(ctx *gin.Context) {
 		data, err := m.srtServer.APIConnsList()
 		if err == nil && len(data.Items) != 0 {
 			for _, i := range data.Items {
-				tags := "{id=\"" + i.ID.String() + "\",state=\"" + string(i.State) + "\"}"
-				out += metric("srt_conns", tags, 1)
-				out += metric("srt_conns_packets_sent", tags, int64(i.PacketsSent))
-				out += metric("srt_conns_packets_received", tags, int64(i.PacketsReceived))
-				out += metric("srt_conns_packets_sent_unique", tags, int64(i.PacketsSentUnique))
-				out += metric("srt_conns_packets_received_unique", tags, int64(i.PacketsReceivedUnique))
-				out += metric("srt_conns_packets_send_loss", tags, int64(i.PacketsSendLoss))
-				out += metric("srt_conns_packets_received_loss", tags, int64(i.PacketsReceivedLoss))
-				out += metric("srt_conns_packets_retrans", tags, int64(i.PacketsRetrans))
-				out += metric("srt_conns_packets_received_retrans", tags, int64(i.PacketsReceivedRetrans))
-				out += metric("srt_conns_packets_sent_ack", tags, int64(i.PacketsSentACK))
-				out += metric("srt_conns_packets_received_ack", tags, int64(i.PacketsReceivedACK))
-				out += metric("srt_conns_packets_sent_nak", tags, int64(i.PacketsSentNAK))
-				out += metric("srt_conns_packets_received_nak", tags, int64(i.PacketsReceivedNAK))
-				out += metric("srt_conns_packets_sent_km", tags, int64(i.PacketsSentKM))
-				out += metric("srt_conns_packets_received_km", tags, int64(i.PacketsReceivedKM))
-				out += metric("srt_conns_us_snd_duration", tags, int64(i.UsSndDuration))
-				out += metric("srt_conns_packets_send_drop", tags, int64(i.PacketsSendDrop))
-				out += metric("srt_conns_packets_received_drop", tags, int64(i.PacketsReceivedDrop))
-				out += metric("srt_conns_packets_received_undecrypt", tags, int64(i.PacketsReceivedUndecrypt))
-				out += metric("srt_conns_bytes_sent", tags, int64(i.BytesSent))
-				out += metric("srt_conns_bytes_received", tags, int64(i.BytesReceived))
-				out += metric("srt_conns_bytes_sent_unique", tags, int64(i.BytesSentUnique))
-				out += metric("srt_conns_bytes_received_unique", tags, int64(i.BytesReceivedUnique))
-				out += metric("srt_conns_bytes_received_loss", tags, int64(i.BytesReceivedLoss))
-				out += metric("srt_conns_bytes_retrans", tags, int64(i.BytesRetrans))
-				out += metric("srt_conns_bytes_received_retrans", tags, int64(i.BytesReceivedRetrans))
-				out += metric("srt_conns_bytes_send_drop", tags, int64(i.BytesSendDrop))
-				out += metric("srt_conns_bytes_received_drop", tags, int64(i.BytesReceivedDrop))
-				out += metric("srt_conns_bytes_received_undecrypt", tags, int64(i.BytesReceivedUndecrypt))
-				out += metricFloat("srt_conns_us_packets_send_period", tags, i.UsPacketsSendPeriod)
-				out += metric("srt_conns_packets_flow_window", tags, int64(i.PacketsFlowWindow))
-				out += metric("srt_conns_packets_flight_size", tags, int64(i.PacketsFlightSize))
-				out += metricFloat("srt_conns_ms_rtt", tags, i.MsRTT)
-				out += metricFloat("srt_conns_mbps_send_rate", tags, i.MbpsSendRate)
-				out += metricFloat("srt_conns_mbps_receive_rate", tags, i.MbpsReceiveRate)
-				out += metricFloat("srt_conns_mbps_link_capacity", tags, i.MbpsLinkCapacity)
-				out += metric("srt_conns_bytes_avail_send_buf", tags, int64(i.BytesAvailSendBuf))
-				out += metric("srt_conns_bytes_avail_receive_buf", tags, int64(i.BytesAvailReceiveBuf))
-				out += metricFloat("srt_conns_mbps_max_bw", tags, i.MbpsMaxBW)
-				out += metric("srt_conns_bytes_mss", tags, int64(i.ByteMSS))
-				out += metric("srt_conns_packets_send_buf", tags, int64(i.PacketsSendBuf))
-				out += metric("srt_conns_bytes_send_buf", tags, int64(i.BytesSendBuf))
-				out += metric("srt_conns_ms_send_buf", tags, int64(i.MsSendBuf))
-				out += metric("srt_conns_ms_send_tsb_pd_delay", tags, int64(i.MsSendTsbPdDelay))
-				out += metric("srt_conns_packets_receive_buf", tags, int64(i.PacketsReceiveBuf))
-				out += metric("srt_conns_bytes_receive_buf", tags, int64(i.BytesReceiveBuf))
-				out += metric("srt_conns_ms_receive_buf", tags, int64(i.MsReceiveBuf))
-				out += metric("srt_conns_ms_receive_tsb_pd_delay", tags, int64(i.MsReceiveTsbPdDelay))
-				out += metric("srt_conns_packets_reorder_tolerance", tags, int64(i.PacketsReorderTolerance))
-				out += metric("srt_conns_packets_received_avg_belated_time", tags, int64(i.PacketsReceivedAvgBelatedTime))
-				out += metricFloat("srt_conns_packets_send_loss_rate", tags, i.PacketsSendLossRate)
-				out += metricFloat("srt_conns_packets_received_loss_rate", tags, i.PacketsReceivedLossRate)
+				tags := []Label{{"id", i.ID.String()}, {"state", string(i.State)}}
+				out = append(out, gauge("srt_conns", tags, 1))
+				out = append(out, counter("srt_conns_packets_sent", tags, float64(i.PacketsSent)))
+				out = append(out, counter("srt_conns_packets_received", tags, float64(i.PacketsReceived)))
+				out = append(out, counter("srt_conns_packets_sent_unique", tags, float64(i.PacketsSentUnique)))
+				out = append(out, counter("srt_conns_packets_received_unique", tags, float64(i.PacketsReceivedUnique)))
+				out = append(out, counter("srt_conns_packets_send_loss", tags, float64(i.PacketsSendLoss)))
+				out = append(out, counter("srt_conns_packets_received_loss", tags, float64(i.PacketsReceivedLoss)))
+				out = append(out, counter("srt_conns_packets_retrans", tags, float64(i.PacketsRetrans)))
+				out = append(out, counter("srt_conns_packets_received_retrans", tags, float64(i.PacketsReceivedRetrans)))
+				out = append(out, counter("srt_conns_packets_sent_ack", tags, float64(i.PacketsSentACK)))
+				out = append(out, counter("srt_conns_packets_received_ack", tags, float64(i.PacketsReceivedACK)))
+				out = append(out, counter("srt_conns_packets_sent_nak", tags, float64(i.PacketsSentNAK)))
+				out = append(out, counter("srt_conns_packets_received_nak", tags, float64(i.PacketsReceivedNAK)))
+				out = append(out, counter("srt_conns_packets_sent_km", tags, float64(i.PacketsSentKM)))
+				out = append(out, counter("srt_conns_packets_received_km", tags, float64(i.PacketsReceivedKM)))
+				out = append(out, counter("srt_conns_us_snd_duration", tags, float64(i.UsSndDuration)))
+				out = append(out, counter("srt_conns_packets_send_drop", tags, float64(i.PacketsSendDrop)))
+				out = append(out, counter("srt_conns_packets_received_drop", tags, float64(i.PacketsReceivedDrop)))
+				out = append(out, counter("srt_conns_packets_received_undecrypt", tags, float64(i.PacketsReceivedUndecrypt)))
+				out = append(out, counter("srt_conns_bytes_sent", tags, float64(i.BytesSent)))
+				out = append(out, counter("srt_conns_bytes_received", tags, float64(i.BytesReceived)))
+				out = append(out, counter("srt_conns_bytes_sent_unique", tags, float64(i.BytesSentUnique)))
+				out = append(out, counter("srt_conns_bytes_received_unique", tags, float64(i.BytesReceivedUnique)))
+				out = append(out, counter("srt_conns_bytes_received_loss", tags, float64(i.BytesReceivedLoss)))
+				out = append(out, counter("srt_conns_bytes_retrans", tags, float64(i.BytesRetrans)))
+				out = append(out, counter("srt_conns_bytes_received_retrans", tags, float64(i.BytesReceivedRetrans)))
+				out = append(out, counter("srt_conns_bytes_send_drop", tags, float64(i.BytesSendDrop)))
+				out = append(out, counter("srt_conns_bytes_received_drop", tags, float64(i.BytesReceivedDrop)))
+				out = append(out, counter("srt_conns_bytes_received_undecrypt", tags, float64(i.BytesReceivedUndecrypt)))
+				out = append(out, gauge("srt_conns_us_packets_send_period", tags, i.UsPacketsSendPeriod))
+				out = append(out, gauge("srt_conns_packets_flow_window", tags, float64(i.PacketsFlowWindow)))
+				out = append(out, gauge("srt_conns_packets_flight_size", tags, float64(i.PacketsFlightSize)))
+				out = append(out, gauge("srt_conns_ms_rtt", tags, i.MsRTT))
+				out = append(out, gauge("srt_conns_mbps_send_rate", tags, i.MbpsSendRate))
+				out = append(out, gauge("srt_conns_mbps_receive_rate", tags, i.MbpsReceiveRate))
+				out = append(out, gauge("srt_conns_mbps_link_capacity", tags, i.MbpsLinkCapacity))
+				out = append(out, gauge("srt_conns_bytes_avail_send_buf", tags, float64(i.BytesAvailSendBuf)))
+				out = append(out, gauge("srt_conns_bytes_avail_receive_buf", tags, float64(i.BytesAvailReceiveBuf)))
+				out = append(out, gauge("srt_conns_mbps_max_bw", tags, i.MbpsMaxBW))
+				out = append(out, gauge("srt_conns_bytes_mss", tags, float64(i.ByteMSS)))
+				out = append(out, gauge("srt_conns_packets_send_buf", tags, float64(i.PacketsSendBuf)))
+				out = append(out, gauge("srt_conns_bytes_send_buf", tags, float64(i.BytesSendBuf)))
+				out = append(out, gauge("srt_conns_ms_send_buf", tags, float64(i.MsSendBuf)))
+				out = append(out, gauge("srt_conns_ms_send_tsb_pd_delay", tags, float64(i.MsSendTsbPdDelay)))
+				out = append(out, gauge("srt_conns_packets_receive_buf", tags, float64(i.PacketsReceiveBuf)))
+				out = append(out, gauge("srt_conns_bytes_receive_buf", tags, float64(i.BytesReceiveBuf)))
+				out = append(out, gauge("srt_conns_ms_receive_buf", tags, float64(i.MsReceiveBuf)))
+				out = append(out, gauge("srt_conns_ms_receive_tsb_pd_delay", tags, float64(i.MsReceiveTsbPdDelay)))
+				out = append(out, gauge("srt_conns_packets_reorder_tolerance", tags, float64(i.PacketsReorderTolerance)))
+				out = append(out, gauge("srt_conns_packets_received_avg_belated_time", tags,
+					float64(i.PacketsReceivedAvgBelatedTime)))
+				out = append(out, gauge("srt_conns_packets_send_loss_rate", tags, i.PacketsSendLossRate))
+				out = append(out, gauge("srt_conns_packets_received_loss_rate", tags, i.PacketsReceivedLossRate))
 			}
 		} else {
-			out += metric("srt_conns", "", 0)
-			out += metric("srt_conns_bytes_received", "", 0)
-			out += metric("srt_conns_bytes_sent", "", 0)
+			out = append(out, gauge("srt_conns", nil, 0))
+			out = append(out, counter("srt_conns_bytes_received", nil, 0))
+			out = append(out, counter("srt_conns_bytes_sent", nil, 0))
 		}
 	}
 
@@ -328,18 +405,77 @@ func (m *Metrics) onMetrics(ctx *gin.Context) {
 		data, err := m.webRTCServer.APISessionsList()
 		if err == nil && len(data.Items) != 0 {
 			for _, i := range data.Items {
-				tags := "{id=\"" + i.ID.String() + "\",state=\"" + string(i.State) + "\"}"
-				out += metric("webrtc_sessions", tags, 1)
-				out += metric("webrtc_sessions_bytes_received", tags, int64(i.BytesReceived))
-				out += metric("webrtc_sessions_bytes_sent", tags, int64(i.BytesSent))
+				tags := []Label{{"id", i.ID.String()}, {"state", string(i.State)}}
+				out = append(out, gauge("webrtc_sessions", tags, 1))
+				out = append(out, counter("webrtc_sessions_bytes_received", tags, float64(i.BytesReceived)))
+				out = append(out, counter("webrtc_sessions_bytes_sent", tags, float64(i.BytesSent)))
+			}
+		} else {
+			out = append(out, gauge("webrtc_sessions", nil, 0))
+			out = append(out, counter("webrtc_sessions_bytes_received", nil, 0))
+			out = append(out, counter("webrtc_sessions_bytes_sent", nil, 0))
+		}
+	}
+
+	if !interfaceIsEmpty(m.httpFLVServer) {
+		data, err := m.httpFLVServer.APIConnsList()
+		if err == nil && len(data.Items) != 0 {
+			for _, i := range data.Items {
+				tags := []Label{{"id", i.ID.String()}, {"transport", string(i.Transport)}}
+				out = append(out, gauge("httpflv_conns", tags, 1))
+				out = append(out, counter("httpflv_conns_bytes_sent", tags, float64(i.BytesSent)))
+			}
+		} else {
+			out = append(out, gauge("httpflv_conns", nil, 0))
+			out = append(out, counter("httpflv_conns_bytes_sent", nil, 0))
+		}
+	}
+
+	if !interfaceIsEmpty(m.httpTSServer) {
+		data, err := m.httpTSServer.APIConnsList()
+		if err == nil && len(data.Items) != 0 {
+			for _, i := range data.Items {
+				tags := []Label{{"id", i.ID.String()}}
+				out = append(out, gauge("httpts_conns", tags, 1))
+				out = append(out, counter("httpts_conns_bytes_sent", tags, float64(i.BytesSent)))
 			}
 		} else {
-			out += metric("webrtc_sessions", "", 0)
-			out += metric("webrtc_sessions_bytes_received", "", 0)
-			out += metric("webrtc_sessions_bytes_sent", "", 0)
+			out = append(out, gauge("httpts_conns", nil, 0))
+			out = append(out, counter("httpts_conns_bytes_sent", nil, 0))
 		}
 	}
 
+	if !interfaceIsEmpty(m.dashServer) {
+		data, err := m.dashServer.APIMuxersList()
+		if err == nil && len(data.Items) != 0 {
+			for _, i := range data.Items {
+				tags := []Label{{"name", i.Path}}
+				out = append(out, gauge("dash_muxers", tags, 1))
+				out = append(out, counter("dash_muxers_bytes_sent", tags, float64(i.BytesSent)))
+			}
+		} else {
+			out = append(out, gauge("dash_muxers", nil, 0))
+			out = append(out, counter("dash_muxers_bytes_sent", nil, 0))
+		}
+	}
+
+	if !interfaceIsEmpty(m.recordCleaner) {
+		for _, i := range m.recordCleaner.Evictions() {
+			tags := []Label{{"reason", string(i.Reason)}}
+			out = append(out, counter("recordings_evicted_segments", tags, float64(i.Segments)))
+			out = append(out, counter("recordings_evicted_bytes", tags, float64(i.Bytes)))
+		}
+	}
+
+	return out
+}
+
+func (m *Metrics) onMetrics(ctx *gin.Context) {
+	out := ""
+	for _, s := range m.Gather() {
+		out += s.text()
+	}
+
 	ctx.Writer.WriteHeader(http.StatusOK)
 	io.WriteString(ctx.Writer, out) //nolint:errcheck
 }
@@ -399,3 +535,31 @@ func (m *Metrics) SetWebRTCServer(s api.WebRTCServer) {
 	defer m.mutex.Unlock()
 	m.webRTCServer = s
 }
+
+// SetHTTPFLVServer is called by core.
+func (m *Metrics) SetHTTPFLVServer(s api.HTTPFLVServer) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.httpFLVServer = s
+}
+
+// SetHTTPTSServer is called by core.
+func (m *Metrics) SetHTTPTSServer(s api.HTTPTSServer) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.httpTSServer = s
+}
+
+// SetDASHServer is called by core.
+func (m *Metrics) SetDASHServer(s api.DASHServer) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.dashServer = s
+}
+
+// SetRecordCleaner is called by core.
+func (m *Metrics) SetRecordCleaner(c *record.Cleaner) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.recordCleaner = c
+}
@@ -0,0 +1,209 @@
+package otlp
+
+import (
+	"encoding/hex"
+	"strconv"
+	"time"
+
+	"github.com/bluenviron/mediamtx/internal/metrics"
+)
+
+// OTLP/JSON messages.
+// https://github.com/open-telemetry/opentelemetry-proto/tree/main/opentelemetry/proto
+
+const (
+	aggregationTemporalityCumulative = 2
+
+	spanKindInternal = 1
+	spanKindServer   = 2
+
+	statusCodeOK    = 1
+	statusCodeError = 2
+)
+
+type jsonAnyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type jsonKeyValue struct {
+	Key   string       `json:"key"`
+	Value jsonAnyValue `json:"value"`
+}
+
+type jsonResource struct {
+	Attributes []jsonKeyValue `json:"attributes"`
+}
+
+type jsonScope struct {
+	Name string `json:"name"`
+}
+
+type jsonNumberDataPoint struct {
+	Attributes        []jsonKeyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano,omitempty"`
+	TimeUnixNano      string         `json:"timeUnixNano"`
+	AsDouble          float64        `json:"asDouble"`
+}
+
+type jsonGauge struct {
+	DataPoints []jsonNumberDataPoint `json:"dataPoints"`
+}
+
+type jsonSum struct {
+	DataPoints             []jsonNumberDataPoint `json:"dataPoints"`
+	AggregationTemporality int                   `json:"aggregationTemporality"`
+	IsMonotonic            bool                  `json:"isMonotonic"`
+}
+
+type jsonMetric struct {
+	Name  string     `json:"name"`
+	Gauge *jsonGauge `json:"gauge,omitempty"`
+	Sum   *jsonSum   `json:"sum,omitempty"`
+}
+
+type jsonScopeMetrics struct {
+	Scope   jsonScope    `json:"scope"`
+	Metrics []jsonMetric `json:"metrics"`
+}
+
+type jsonResourceMetrics struct {
+	Resource     jsonResource       `json:"resource"`
+	ScopeMetrics []jsonScopeMetrics `json:"scopeMetrics"`
+}
+
+type jsonMetricsRequest struct {
+	ResourceMetrics []jsonResourceMetrics `json:"resourceMetrics"`
+}
+
+type jsonStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type jsonSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []jsonKeyValue `json:"attributes,omitempty"`
+	Status            jsonStatus     `json:"status"`
+}
+
+type jsonScopeSpans struct {
+	Scope jsonScope  `json:"scope"`
+	Spans []jsonSpan `json:"spans"`
+}
+
+type jsonResourceSpans struct {
+	Resource   jsonResource     `json:"resource"`
+	ScopeSpans []jsonScopeSpans `json:"scopeSpans"`
+}
+
+type jsonTracesRequest struct {
+	ResourceSpans []jsonResourceSpans `json:"resourceSpans"`
+}
+
+var resource = jsonResource{
+	Attributes: []jsonKeyValue{{Key: "service.name", Value: jsonAnyValue{StringValue: "mediamtx"}}},
+}
+
+var scope = jsonScope{Name: "mediamtx"}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+func encodeMetrics(samples []metrics.Sample, startTime time.Time, now time.Time) *jsonMetricsRequest {
+	var out []jsonMetric
+	byName := make(map[string]int)
+
+	for _, s := range samples {
+		dp := jsonNumberDataPoint{
+			TimeUnixNano: unixNano(now),
+			AsDouble:     s.Value,
+		}
+
+		for _, l := range s.Labels {
+			dp.Attributes = append(dp.Attributes, jsonKeyValue{Key: l.Key, Value: jsonAnyValue{StringValue: l.Value}})
+		}
+
+		// samples with the same name are data points of the same metric
+		i, ok := byName[s.Name]
+		if !ok {
+			m := jsonMetric{Name: s.Name}
+			if s.Counter {
+				m.Sum = &jsonSum{
+					AggregationTemporality: aggregationTemporalityCumulative,
+					IsMonotonic:            true,
+				}
+			} else {
+				m.Gauge = &jsonGauge{}
+			}
+
+			i = len(out)
+			byName[s.Name] = i
+			out = append(out, m)
+		}
+
+		if out[i].Sum != nil {
+			dp.StartTimeUnixNano = unixNano(startTime)
+			out[i].Sum.DataPoints = append(out[i].Sum.DataPoints, dp)
+		} else {
+			out[i].Gauge.DataPoints = append(out[i].Gauge.DataPoints, dp)
+		}
+	}
+
+	return &jsonMetricsRequest{
+		ResourceMetrics: []jsonResourceMetrics{{
+			Resource: resource,
+			ScopeMetrics: []jsonScopeMetrics{{
+				Scope:   scope,
+				Metrics: out,
+			}},
+		}},
+	}
+}
+
+func encodeSpans(spans []*Span) *jsonTracesRequest {
+	out := make([]jsonSpan, len(spans))
+
+	for i, s := range spans {
+		js := jsonSpan{
+			TraceID:           hex.EncodeToString(s.traceID[:]),
+			SpanID:            hex.EncodeToString(s.spanID[:]),
+			Name:              s.name,
+			Kind:              spanKindServer,
+			StartTimeUnixNano: unixNano(s.start),
+			EndTimeUnixNano:   unixNano(s.end),
+			Status:            jsonStatus{Code: statusCodeOK},
+		}
+
+		if s.parent != nil {
+			js.ParentSpanID = hex.EncodeToString(s.parent.spanID[:])
+			js.Kind = spanKindInternal
+		}
+
+		for _, a := range s.attributes {
+			js.Attributes = append(js.Attributes, jsonKeyValue{Key: a.Key, Value: jsonAnyValue{StringValue: a.Value}})
+		}
+
+		if s.err != nil {
+			js.Status = jsonStatus{Code: statusCodeError, Message: s.err.Error()}
+		}
+
+		out[i] = js
+	}
+
+	return &jsonTracesRequest{
+		ResourceSpans: []jsonResourceSpans{{
+			Resource: resource,
+			ScopeSpans: []jsonScopeSpans{{
+				Scope: scope,
+				Spans: out,
+			}},
+		}},
+	}
+}
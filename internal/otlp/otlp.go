@@ -0,0 +1,150 @@
+// Package otlp contains an OpenTelemetry exporter.
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/bluenviron/mediamtx/internal/metrics"
+)
+
+type exporterMetrics interface {
+	Gather() []metrics.Sample
+}
+
+type exporterParent interface {
+	logger.Writer
+}
+
+// Exporter is an OpenTelemetry exporter.
+// It periodically pushes metrics and session traces to a collector
+// with the OTLP/HTTP protocol and JSON encoding.
+type Exporter struct {
+	Address     string
+	Interval    conf.StringDuration
+	ReadTimeout conf.StringDuration
+	Metrics     exporterMetrics
+	Parent      exporterParent
+
+	startTime    time.Time
+	httpClient   *http.Client
+	ctx          context.Context
+	ctxCancel    func()
+	mutex        sync.Mutex
+	sessions     map[uuid.UUID]*Span
+	pendingSpans []*Span
+
+	done chan struct{}
+}
+
+// Initialize initializes Exporter.
+func (e *Exporter) Initialize() error {
+	if !strings.HasPrefix(e.Address, "http://") && !strings.HasPrefix(e.Address, "https://") {
+		return fmt.Errorf("invalid address: %s", e.Address)
+	}
+
+	e.startTime = time.Now()
+	e.httpClient = &http.Client{
+		Timeout: time.Duration(e.ReadTimeout),
+	}
+	e.ctx, e.ctxCancel = context.WithCancel(context.Background())
+	e.sessions = make(map[uuid.UUID]*Span)
+	e.done = make(chan struct{})
+
+	e.Log(logger.Info, "exporting to %s", e.Address)
+
+	go e.run()
+
+	return nil
+}
+
+// Close closes Exporter.
+func (e *Exporter) Close() {
+	e.Log(logger.Info, "exporter is closing")
+	e.ctxCancel()
+	<-e.done
+}
+
+// Log implements logger.Writer.
+func (e *Exporter) Log(level logger.Level, format string, args ...interface{}) {
+	e.Parent.Log(level, "%v"+format, append([]interface{}{logger.Component("OTLP")}, args...)...)
+}
+
+func (e *Exporter) run() {
+	defer close(e.done)
+
+	t := time.NewTicker(time.Duration(e.Interval))
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			e.exportMetrics()
+			e.exportSpans()
+
+		case <-e.ctx.Done():
+			// spans of the last interval would be lost otherwise
+			e.exportSpans()
+			return
+		}
+	}
+}
+
+func (e *Exporter) exportMetrics() {
+	if e.Metrics == nil {
+		return
+	}
+
+	err := e.post("/v1/metrics", encodeMetrics(e.Metrics.Gather(), e.startTime, time.Now()))
+	if err != nil {
+		e.Log(logger.Warn, "unable to export metrics: %v", err)
+	}
+}
+
+func (e *Exporter) exportSpans() {
+	spans := e.takeSpans()
+	if len(spans) == 0 {
+		return
+	}
+
+	err := e.post("/v1/traces", encodeSpans(spans))
+	if err != nil {
+		e.Log(logger.Warn, "unable to export traces: %v", err)
+	}
+}
+
+func (e *Exporter) post(path string, msg interface{}) error {
+	byts, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(e.Address, "/")+path, bytes.NewReader(byts))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := e.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("bad status code: %d", res.StatusCode)
+	}
+
+	return nil
+}
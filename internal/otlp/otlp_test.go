@@ -0,0 +1,167 @@
+package otlp
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/bluenviron/mediamtx/internal/metrics"
+)
+
+type nilLogger struct{}
+
+func (nilLogger) Log(logger.Level, string, ...interface{}) {
+}
+
+type dummyMetrics struct{}
+
+func (dummyMetrics) Gather() []metrics.Sample {
+	return []metrics.Sample{
+		{Name: "paths", Labels: []metrics.Label{{Key: "name", Value: "mypath"}}, Value: 1},
+		{Name: "paths_bytes_received", Labels: []metrics.Label{{Key: "name", Value: "mypath"}}, Value: 123, Counter: true},
+		{Name: "paths_bytes_received", Labels: []metrics.Label{{Key: "name", Value: "otherpath"}}, Value: 456, Counter: true},
+	}
+}
+
+// collector is a stand-in for an OTLP collector.
+type collector struct {
+	metrics chan *jsonMetricsRequest
+	traces  chan *jsonTracesRequest
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	byts, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/v1/metrics":
+		var req jsonMetricsRequest
+		err = json.Unmarshal(byts, &req)
+		if err == nil {
+			c.metrics <- &req
+		}
+
+	case "/v1/traces":
+		var req jsonTracesRequest
+		err = json.Unmarshal(byts, &req)
+		if err == nil {
+			c.traces <- &req
+		}
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	w.Write([]byte("{}")) //nolint:errcheck
+}
+
+func TestExporter(t *testing.T) {
+	c := &collector{
+		metrics: make(chan *jsonMetricsRequest, 10),
+		traces:  make(chan *jsonTracesRequest, 10),
+	}
+
+	ts := httptest.NewServer(c)
+	defer ts.Close()
+
+	e := &Exporter{
+		Address:     ts.URL,
+		Interval:    conf.StringDuration(100 * time.Millisecond),
+		ReadTimeout: conf.StringDuration(10 * time.Second),
+		Metrics:     dummyMetrics{},
+		Parent:      nilLogger{},
+	}
+	err := e.Initialize()
+	require.NoError(t, err)
+	defer e.Close()
+
+	sessionID := uuid.New()
+
+	e.StartSpan(sessionID, "path lookup", Attribute{Key: "path", Value: "mypath"}).End(nil)
+	e.StartSpan(sessionID, "auth", Attribute{Key: "path", Value: "mypath"}).End(fmt.Errorf("authentication failed"))
+	e.StartTeardown(sessionID).End(nil)
+
+	// the trace of the session is closed
+	require.Nil(t, e.StartTeardown(sessionID))
+
+	var mreq *jsonMetricsRequest
+	select {
+	case mreq = <-c.metrics:
+	case <-time.After(5 * time.Second):
+		t.Fatal("metrics not received")
+	}
+
+	ms := mreq.ResourceMetrics[0].ScopeMetrics[0].Metrics
+	require.Equal(t, 2, len(ms))
+
+	require.Equal(t, "paths", ms[0].Name)
+	require.NotNil(t, ms[0].Gauge)
+	require.Equal(t, []jsonKeyValue{{Key: "name", Value: jsonAnyValue{StringValue: "mypath"}}},
+		ms[0].Gauge.DataPoints[0].Attributes)
+	require.Equal(t, float64(1), ms[0].Gauge.DataPoints[0].AsDouble)
+
+	require.Equal(t, "paths_bytes_received", ms[1].Name)
+	require.NotNil(t, ms[1].Sum)
+	require.True(t, ms[1].Sum.IsMonotonic)
+	require.Equal(t, aggregationTemporalityCumulative, ms[1].Sum.AggregationTemporality)
+	require.Equal(t, 2, len(ms[1].Sum.DataPoints))
+	require.Equal(t, float64(456), ms[1].Sum.DataPoints[1].AsDouble)
+	require.NotEmpty(t, ms[1].Sum.DataPoints[1].StartTimeUnixNano)
+
+	var treq *jsonTracesRequest
+	select {
+	case treq = <-c.traces:
+	case <-time.After(5 * time.Second):
+		t.Fatal("traces not received")
+	}
+
+	spans := treq.ResourceSpans[0].ScopeSpans[0].Spans
+	require.Equal(t, 4, len(spans))
+
+	require.Equal(t, "path lookup", spans[0].Name)
+	require.Equal(t, "auth", spans[1].Name)
+	require.Equal(t, "teardown", spans[2].Name)
+	require.Equal(t, "session", spans[3].Name)
+
+	for _, s := range spans {
+		require.Equal(t, hex.EncodeToString(sessionID[:]), s.TraceID)
+		require.Contains(t, s.Attributes, jsonKeyValue{Key: "session.id", Value: jsonAnyValue{StringValue: sessionID.String()}})
+	}
+
+	require.Equal(t, "", spans[3].ParentSpanID)
+	require.Equal(t, spans[3].SpanID, spans[0].ParentSpanID)
+	require.Equal(t, spans[3].SpanID, spans[1].ParentSpanID)
+	require.Equal(t, spans[3].SpanID, spans[2].ParentSpanID)
+	require.Equal(t, jsonStatus{Code: statusCodeOK}, spans[0].Status)
+	require.Equal(t, jsonStatus{Code: statusCodeError, Message: "authentication failed"}, spans[1].Status)
+}
+
+func TestExporterNil(t *testing.T) {
+	var e *Exporter
+	e.StartSpan(uuid.New(), "auth").End(nil)
+	e.EndSession(uuid.New(), nil)
+}
@@ -0,0 +1,151 @@
+package otlp
+
+import (
+	"crypto/rand"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// maximum number of sessions whose root span is open.
+	maxOpenSessions = 4096
+
+	// maximum number of ended spans waiting to be exported.
+	maxPendingSpans = 4096
+)
+
+// Attribute is an attribute of a span.
+type Attribute struct {
+	Key   string
+	Value string
+}
+
+// Span is a span of a session trace.
+type Span struct {
+	exporter   *Exporter
+	traceID    uuid.UUID
+	spanID     [8]byte
+	parent     *Span
+	name       string
+	attributes []Attribute
+	start      time.Time
+	end        time.Time
+	err        error
+
+	endsSession bool
+}
+
+// End ends the span.
+// err is the outcome of the operation described by the span.
+// It can be called on a nil span.
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+
+	s.end = time.Now()
+	s.err = err
+	s.exporter.enqueueSpan(s)
+
+	if s.endsSession {
+		s.exporter.EndSession(s.traceID, err)
+	}
+}
+
+func (e *Exporter) newSpan(sessionID uuid.UUID, name string, parent *Span, attributes []Attribute) *Span {
+	s := &Span{
+		exporter:   e,
+		traceID:    sessionID,
+		parent:     parent,
+		name:       name,
+		attributes: append([]Attribute{{Key: "session.id", Value: sessionID.String()}}, attributes...),
+		start:      time.Now(),
+	}
+	rand.Read(s.spanID[:]) //nolint:errcheck
+	return s
+}
+
+// StartSpan starts a span of the trace of a session.
+// The trace ID is the session ID, and all spans are children of a root span
+// that is opened by the first call to StartSpan and closed by EndSession.
+// It can be called on a nil exporter, in which case it returns a nil span.
+func (e *Exporter) StartSpan(sessionID uuid.UUID, name string, attributes ...Attribute) *Span {
+	if e == nil {
+		return nil
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	root, ok := e.sessions[sessionID]
+	if !ok {
+		if len(e.sessions) >= maxOpenSessions {
+			return nil
+		}
+
+		root = e.newSpan(sessionID, "session", nil, attributes)
+		e.sessions[sessionID] = root
+	}
+
+	return e.newSpan(sessionID, name, root, attributes)
+}
+
+// StartTeardown starts the span that describes the teardown of a session.
+// When the span ends, the root span of the session ends too.
+// It returns a nil span if the session has no open trace.
+// It can be called on a nil exporter.
+func (e *Exporter) StartTeardown(sessionID uuid.UUID, attributes ...Attribute) *Span {
+	if e == nil {
+		return nil
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	root, ok := e.sessions[sessionID]
+	if !ok {
+		return nil
+	}
+
+	s := e.newSpan(sessionID, "teardown", root, attributes)
+	s.endsSession = true
+	return s
+}
+
+// EndSession ends the root span of the trace of a session.
+// It can be called on a nil exporter.
+func (e *Exporter) EndSession(sessionID uuid.UUID, err error) {
+	if e == nil {
+		return
+	}
+
+	e.mutex.Lock()
+	root, ok := e.sessions[sessionID]
+	delete(e.sessions, sessionID)
+	e.mutex.Unlock()
+
+	if ok {
+		root.End(err)
+	}
+}
+
+func (e *Exporter) enqueueSpan(s *Span) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if len(e.pendingSpans) >= maxPendingSpans {
+		return
+	}
+
+	e.pendingSpans = append(e.pendingSpans, s)
+}
+
+func (e *Exporter) takeSpans() []*Span {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	spans := e.pendingSpans
+	e.pendingSpans = nil
+	return spans
+}
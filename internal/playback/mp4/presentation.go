@@ -7,19 +7,14 @@ import (
 
 	"github.com/abema/go-mp4"
 	"github.com/bluenviron/mediacommon/pkg/formats/fmp4/seekablebuffer"
+
+	"github.com/bluenviron/mediamtx/internal/protocols/mp4reader"
 )
 
 const (
 	globalTimescale = 1000
 )
 
-func durationMp4ToGo(v int64, timeScale uint32) time.Duration {
-	timeScale64 := int64(timeScale)
-	secs := v / timeScale64
-	dec := v % timeScale64
-	return time.Duration(secs)*time.Second + time.Duration(dec)*time.Second/time.Duration(timeScale64)
-}
-
 // Presentation is timed sequence of video/audio samples.
 type Presentation struct {
 	Tracks []*Track
@@ -69,7 +64,7 @@ func (p *Presentation) sortSamples() (uint32, []*Sample) {
 
 		for i, track := range p.Tracks {
 			if processedSamples[i] < len(track.Samples) {
-				elapsedGo := durationMp4ToGo(elapsed[i], track.TimeScale)
+				elapsedGo := mp4reader.DurationMp4ToGo(elapsed[i], track.TimeScale)
 
 				if bestTrack == -1 || elapsedGo < bestElapsed {
 					bestTrack = i
@@ -6,6 +6,8 @@ import (
 
 	"github.com/bluenviron/mediacommon/pkg/formats/fmp4"
 	"github.com/bluenviron/mediacommon/pkg/formats/fmp4/seekablebuffer"
+
+	"github.com/bluenviron/mediamtx/internal/protocols/mp4reader"
 )
 
 const (
@@ -32,6 +34,11 @@ func findTrack(tracks []*muxerFMP4Track, id int) *muxerFMP4Track {
 type muxerFMP4 struct {
 	w io.Writer
 
+	// do not write the initialization segment, that is served separately.
+	skipInit bool
+	// offset added to timestamps.
+	baseTime time.Duration
+
 	init               *fmp4.Init
 	nextSequenceNumber uint32
 	tracks             []*muxerFMP4Track
@@ -40,7 +47,9 @@ type muxerFMP4 struct {
 }
 
 func (w *muxerFMP4) writeInit(init *fmp4.Init) {
-	w.init = init
+	if !w.skipInit {
+		w.init = init
+	}
 
 	w.tracks = make([]*muxerFMP4Track, len(init.Tracks))
 
@@ -92,7 +101,7 @@ func (w *muxerFMP4) writeSample(
 		})
 		w.curTrack.lastDTS = dts
 
-		partDurationMP4 := durationGoToMp4(partDuration, w.curTrack.timeScale)
+		partDurationMP4 := mp4reader.DurationGoToMp4(partDuration, w.curTrack.timeScale)
 
 		if (w.curTrack.lastDTS - w.curTrack.firstDTS) >= partDurationMP4 {
 			err := w.innerFlush(false)
@@ -145,7 +154,7 @@ func (w *muxerFMP4) innerFlush(final bool) error {
 
 			part.Tracks = append(part.Tracks, &fmp4.PartTrack{
 				ID:       track.id,
-				BaseTime: uint64(track.firstDTS),
+				BaseTime: uint64(track.firstDTS + mp4reader.DurationGoToMp4(w.baseTime, track.timeScale)),
 				Samples:  samples,
 			})
 
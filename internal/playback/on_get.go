@@ -3,6 +3,7 @@ package playback
 import (
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"os"
@@ -39,6 +40,24 @@ func parseDuration(raw string) (time.Duration, error) {
 	return time.ParseDuration(raw)
 }
 
+func segmentReadInit(recordFormat conf.RecordFormat, r io.ReadSeeker) (*fmp4.Init, error) {
+	if recordFormat == conf.RecordFormatFMP4 {
+		return segmentFMP4ReadInit(r)
+	}
+
+	init, err := segmentMPEGTSReadInit(r)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = r.Seek(0, io.SeekStart)
+	if err != nil {
+		return nil, err
+	}
+
+	return init, nil
+}
+
 func seekAndMux(
 	recordFormat conf.RecordFormat,
 	segments []*Segment,
@@ -46,67 +65,73 @@ func seekAndMux(
 	duration time.Duration,
 	m muxer,
 ) error {
+	var firstInit *fmp4.Init
+	var segmentEnd time.Time
+
+	f, err := os.Open(segments[0].Fpath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	firstInit, err = segmentReadInit(recordFormat, f)
+	if err != nil {
+		return err
+	}
+
+	m.writeInit(firstInit)
+
+	segmentStartOffset := start.Sub(segments[0].Start)
+
+	var segmentMaxElapsed time.Duration
+
 	if recordFormat == conf.RecordFormatFMP4 {
-		var firstInit *fmp4.Init
-		var segmentEnd time.Time
+		segmentMaxElapsed, err = segmentFMP4SeekAndMuxParts(f, segmentStartOffset, duration, firstInit, m)
+	} else {
+		segmentMaxElapsed, err = segmentMPEGTSSeekAndMux(f, segmentStartOffset, duration, firstInit, m)
+	}
+	if err != nil {
+		return err
+	}
+
+	segmentEnd = start.Add(segmentMaxElapsed)
 
-		f, err := os.Open(segments[0].Fpath)
+	for _, seg := range segments[1:] {
+		f, err := os.Open(seg.Fpath)
 		if err != nil {
 			return err
 		}
 		defer f.Close()
 
-		firstInit, err = segmentFMP4ReadInit(f)
+		init, err := segmentReadInit(recordFormat, f)
 		if err != nil {
 			return err
 		}
 
-		m.writeInit(firstInit)
-
-		segmentStartOffset := start.Sub(segments[0].Start)
-
-		segmentMaxElapsed, err := segmentFMP4SeekAndMuxParts(f, segmentStartOffset, duration, firstInit, m)
-		if err != nil {
-			return err
+		if !segmentFMP4CanBeConcatenated(firstInit, segmentEnd, init, seg.Start) {
+			break
 		}
 
-		segmentEnd = start.Add(segmentMaxElapsed)
-
-		for _, seg := range segments[1:] {
-			f, err := os.Open(seg.Fpath)
-			if err != nil {
-				return err
-			}
-			defer f.Close()
-
-			init, err := segmentFMP4ReadInit(f)
-			if err != nil {
-				return err
-			}
-
-			if !segmentFMP4CanBeConcatenated(firstInit, segmentEnd, init, seg.Start) {
-				break
-			}
-
-			segmentStartOffset := seg.Start.Sub(start)
+		segmentStartOffset := seg.Start.Sub(start)
 
-			segmentMaxElapsed, err := segmentFMP4MuxParts(f, segmentStartOffset, duration, firstInit, m)
-			if err != nil {
-				return err
-			}
-
-			segmentEnd = start.Add(segmentMaxElapsed)
+		if recordFormat == conf.RecordFormatFMP4 {
+			segmentMaxElapsed, err = segmentFMP4MuxParts(f, segmentStartOffset, duration, firstInit, m)
+		} else {
+			segmentMaxElapsed, err = segmentMPEGTSMux(f, segmentStartOffset, duration, firstInit, m)
 		}
-
-		err = m.flush()
 		if err != nil {
 			return err
 		}
 
-		return nil
+		segmentEnd = start.Add(segmentMaxElapsed)
+	}
+
+	err = m.flush()
+	if err != nil {
+		return err
 	}
 
-	return fmt.Errorf("MPEG-TS format is not supported yet")
+	return nil
 }
 
 func (p *Server) onGet(ctx *gin.Context) {
@@ -1,6 +1,7 @@
 package playback
 
 import (
+	"bufio"
 	"io"
 	"net/http"
 	"net/url"
@@ -12,6 +13,7 @@ import (
 	"github.com/bluenviron/mediacommon/pkg/codecs/mpeg4audio"
 	"github.com/bluenviron/mediacommon/pkg/formats/fmp4"
 	"github.com/bluenviron/mediacommon/pkg/formats/fmp4/seekablebuffer"
+	"github.com/bluenviron/mediacommon/pkg/formats/mpegts"
 	"github.com/bluenviron/mediamtx/internal/auth"
 	"github.com/bluenviron/mediamtx/internal/conf"
 	"github.com/bluenviron/mediamtx/internal/test"
@@ -216,6 +218,54 @@ func writeSegment3(t *testing.T, fpath string) {
 	require.NoError(t, err)
 }
 
+// writeSegmentMPEGTS writes a MPEG-TS segment with one video frame and one audio frame per second.
+// Video frames are IDRs when the second is even.
+// Timestamps start right before the 33-bit wraparound.
+func writeSegmentMPEGTS(t *testing.T, fpath string) {
+	videoTrack := &mpegts.Track{
+		Codec: &mpegts.CodecH264{},
+	}
+
+	audioTrack := &mpegts.Track{
+		Codec: &mpegts.CodecMPEG4Audio{
+			Config: mpeg4audio.Config{
+				Type:         2,
+				SampleRate:   44100,
+				ChannelCount: 2,
+			},
+		},
+	}
+
+	f, err := os.Create(fpath)
+	require.NoError(t, err)
+	defer f.Close()
+
+	bw := bufio.NewWriter(f)
+	w := mpegts.NewWriter(bw, []*mpegts.Track{videoTrack, audioTrack})
+
+	for i := int64(0); i < 4; i++ {
+		ts := (0x1FFFFFFFF - 90000 + i*90000) & 0x1FFFFFFFF
+
+		au := [][]byte{{1, byte(i)}} // non-IDR
+		if (i % 2) == 0 {
+			au = [][]byte{
+				test.FormatH264.SPS,
+				test.FormatH264.PPS,
+				{5, byte(i)}, // IDR
+			}
+		}
+
+		err = w.WriteH26x(videoTrack, ts, ts, (i%2) == 0, au)
+		require.NoError(t, err)
+
+		err = w.WriteMPEG4Audio(audioTrack, ts, [][]byte{{1, 2, 3, 4}})
+		require.NoError(t, err)
+	}
+
+	err = bw.Flush()
+	require.NoError(t, err)
+}
+
 func TestOnGet(t *testing.T) {
 	for _, format := range []string{"fmp4", "mp4"} {
 		t.Run(format, func(t *testing.T) {
@@ -241,12 +291,13 @@ func TestOnGet(t *testing.T) {
 				AuthManager: &test.AuthManager{
 					Func: func(req *auth.Request) error {
 						require.Equal(t, &auth.Request{
-							User:   "myuser",
-							Pass:   "mypass",
-							IP:     req.IP,
-							Action: "playback",
-							Path:   "mypath",
-							Query:  req.Query,
+							User:     "myuser",
+							Pass:     "mypass",
+							IP:       req.IP,
+							Action:   "playback",
+							Path:     "mypath",
+							Query:    req.Query,
+							Endpoint: "/get",
 						}, req)
 						return nil
 					},
@@ -687,3 +738,104 @@ func TestOnGetNTPCompensation(t *testing.T) {
 		},
 	}, parts)
 }
+
+func TestOnGetMPEGTS(t *testing.T) {
+	for _, format := range []string{"fmp4", "mp4"} {
+		t.Run(format, func(t *testing.T) {
+			dir, err := os.MkdirTemp("", "mediamtx-playback")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			err = os.Mkdir(filepath.Join(dir, "mypath"), 0o755)
+			require.NoError(t, err)
+
+			writeSegmentMPEGTS(t, filepath.Join(dir, "mypath", "2008-11-07_11-22-00-500000.ts"))
+			writeSegmentMPEGTS(t, filepath.Join(dir, "mypath", "2008-11-07_11-22-04-500000.ts"))
+
+			s := &Server{
+				Address:     "127.0.0.1:9996",
+				ReadTimeout: conf.StringDuration(10 * time.Second),
+				PathConfs: map[string]*conf.Path{
+					"mypath": {
+						RecordPath:   filepath.Join(dir, "%path/%Y-%m-%d_%H-%M-%S-%f"),
+						RecordFormat: conf.RecordFormatMPEGTS,
+					},
+				},
+				AuthManager: test.NilAuthManager,
+				Parent:      test.NilLogger,
+			}
+			err = s.Initialize()
+			require.NoError(t, err)
+			defer s.Close()
+
+			v := url.Values{}
+			v.Set("path", "mypath")
+			v.Set("start", time.Date(2008, 11, 0o7, 11, 22, 2, 0, time.Local).Format(time.RFC3339Nano))
+			v.Set("duration", "4")
+			v.Set("format", format)
+
+			res, err := http.Get("http://localhost:9996/get?" + v.Encode())
+			require.NoError(t, err)
+			defer res.Body.Close()
+
+			require.Equal(t, http.StatusOK, res.StatusCode)
+
+			buf, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+
+			if format == "fmp4" {
+				var parts fmp4.Parts
+				err = parts.Unmarshal(buf)
+				require.NoError(t, err)
+
+				var videoBaseTime uint64
+				var videoSamples []*fmp4.PartSample
+
+				for _, part := range parts {
+					for _, track := range part.Tracks {
+						if track.ID == 1 {
+							if videoSamples == nil {
+								videoBaseTime = track.BaseTime
+							}
+							videoSamples = append(videoSamples, track.Samples...)
+						}
+					}
+				}
+
+				// the output starts with the first IDR after the requested start,
+				// and segments are concatenated.
+				require.Equal(t, uint64(45000), videoBaseTime)
+				require.Equal(t, []*fmp4.PartSample{
+					{
+						Duration: 90000,
+						Payload:  []byte{0, 0, 0, 2, 5, 2},
+					},
+					{
+						Duration:        90000,
+						IsNonSyncSample: true,
+						Payload:         []byte{0, 0, 0, 2, 1, 3},
+					},
+					{
+						Duration: 90000,
+						Payload:  []byte{0, 0, 0, 2, 5, 0},
+					},
+					{
+						Duration:        90000,
+						IsNonSyncSample: true,
+						Payload:         []byte{0, 0, 0, 2, 1, 1},
+					},
+				}, func() []*fmp4.PartSample {
+					// remove parameters from IDRs
+					for _, sample := range videoSamples {
+						if !sample.IsNonSyncSample {
+							sample.Payload = sample.Payload[len(sample.Payload)-6:]
+						}
+					}
+					return videoSamples
+				}())
+			} else {
+				require.NotEmpty(t, buf)
+			}
+		})
+	}
+}
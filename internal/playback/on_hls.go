@@ -0,0 +1,397 @@
+package playback
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bluenviron/gohlslib/pkg/playlist"
+	"github.com/bluenviron/mediacommon/pkg/formats/fmp4"
+	"github.com/bluenviron/mediacommon/pkg/formats/fmp4/seekablebuffer"
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// segments of HLS playlists are cut at the first key frame after this duration.
+const hlsSegmentMinDuration = 6 * time.Second
+
+type hlsSegment struct {
+	start    time.Time
+	duration time.Duration
+}
+
+func hlsReadRecordingSegment(seg *Segment) (*fmp4.Init, time.Duration, []time.Duration, error) {
+	f, err := os.Open(seg.Fpath)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	defer f.Close()
+
+	init, err := segmentFMP4ReadInit(f)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	_, err = f.Seek(0, io.SeekStart)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	maxDuration, err := segmentFMP4ReadMaxDuration(f, init)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	_, err = f.Seek(0, io.SeekStart)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	syncSamples, err := segmentFMP4ReadSyncSamples(f, init)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	return init, maxDuration, syncSamples, nil
+}
+
+// hlsComputeSegments splits recordings into HLS segments.
+// Segments start with a key frame, in order to be decodable independently,
+// except for the first one, that starts at the requested time.
+func hlsComputeSegments(
+	segments []*Segment,
+	start time.Time,
+	duration time.Duration,
+) ([]hlsSegment, error) {
+	var firstInit *fmp4.Init
+	var recordingEnd time.Time
+	var syncSamples []time.Time
+
+	for _, seg := range segments {
+		init, maxDuration, segSyncSamples, err := hlsReadRecordingSegment(seg)
+		if err != nil {
+			return nil, err
+		}
+
+		if firstInit == nil {
+			firstInit = init
+		} else if !segmentFMP4CanBeConcatenated(firstInit, recordingEnd, init, seg.Start) {
+			break
+		}
+
+		for _, dts := range segSyncSamples {
+			syncSamples = append(syncSamples, seg.Start.Add(dts))
+		}
+
+		recordingEnd = seg.Start.Add(maxDuration)
+	}
+
+	end := start.Add(duration)
+	if recordingEnd.Before(end) {
+		end = recordingEnd
+	}
+
+	if !end.After(start) {
+		return nil, errNoSegmentsFound
+	}
+
+	var out []hlsSegment
+	cur := start
+
+	for _, t := range syncSamples {
+		if !t.Before(end) {
+			break
+		}
+
+		if t.Sub(cur) >= hlsSegmentMinDuration {
+			out = append(out, hlsSegment{
+				start:    cur,
+				duration: t.Sub(cur),
+			})
+			cur = t
+		}
+	}
+
+	out = append(out, hlsSegment{
+		start:    cur,
+		duration: end.Sub(cur),
+	})
+
+	return out, nil
+}
+
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
+
+func (p *Server) onHLS(ctx *gin.Context) {
+	pathName, file := path.Split(strings.TrimPrefix(ctx.Param("name"), "/"))
+	pathName = strings.TrimSuffix(pathName, "/")
+
+	if pathName == "" {
+		p.writeError(ctx, http.StatusBadRequest, fmt.Errorf("invalid path"))
+		return
+	}
+
+	switch file {
+	case "index.m3u8":
+		p.onHLSPlaylist(ctx, pathName)
+
+	case "init.mp4":
+		p.onHLSInit(ctx, pathName)
+
+	case "segment.mp4":
+		p.onHLSSegment(ctx, pathName)
+
+	default:
+		p.writeError(ctx, http.StatusNotFound, fmt.Errorf("file not found: %s", file))
+	}
+}
+
+func (p *Server) hlsFindPathConf(ctx *gin.Context, pathName string) (*conf.Path, bool) {
+	pathConf, err := p.safeFindPathConf(pathName)
+	if err != nil {
+		p.writeError(ctx, http.StatusBadRequest, err)
+		return nil, false
+	}
+
+	if pathConf.RecordFormat != conf.RecordFormatFMP4 {
+		p.writeError(ctx, http.StatusBadRequest, fmt.Errorf("HLS is available for the fMP4 format only"))
+		return nil, false
+	}
+
+	return pathConf, true
+}
+
+func (p *Server) onHLSPlaylist(ctx *gin.Context, pathName string) {
+	if !p.doAuth(ctx, pathName) {
+		return
+	}
+
+	start, err := time.Parse(time.RFC3339, ctx.Query("start"))
+	if err != nil {
+		p.writeError(ctx, http.StatusBadRequest, fmt.Errorf("invalid start: %w", err))
+		return
+	}
+
+	duration, err := parseDuration(ctx.Query("duration"))
+	if err != nil {
+		p.writeError(ctx, http.StatusBadRequest, fmt.Errorf("invalid duration: %w", err))
+		return
+	}
+
+	pathConf, ok := p.hlsFindPathConf(ctx, pathName)
+	if !ok {
+		return
+	}
+
+	segments, err := findSegmentsInTimespan(pathConf, pathName, start, duration)
+	if err != nil {
+		if errors.Is(err, errNoSegmentsFound) {
+			p.writeError(ctx, http.StatusNotFound, err)
+		} else {
+			p.writeError(ctx, http.StatusBadRequest, err)
+		}
+		return
+	}
+
+	hlsSegments, err := hlsComputeSegments(segments, start, duration)
+	if err != nil {
+		if errors.Is(err, errNoSegmentsFound) {
+			p.writeError(ctx, http.StatusNotFound, err)
+		} else {
+			p.writeError(ctx, http.StatusBadRequest, err)
+		}
+		return
+	}
+
+	// keep additional query parameters (i.e. credentials) in URLs of segments
+	baseQuery := ctx.Request.URL.Query()
+	baseQuery.Del("start")
+	baseQuery.Del("duration")
+
+	uri := func(file string, v url.Values) string {
+		for key, vals := range baseQuery {
+			v[key] = vals
+		}
+		return file + "?" + v.Encode()
+	}
+
+	pl := &playlist.Media{
+		Version:             7,
+		IndependentSegments: true,
+		PlaylistType: func() *playlist.MediaPlaylistType {
+			v := playlist.MediaPlaylistType(playlist.MediaPlaylistTypeVOD)
+			return &v
+		}(),
+		Map: &playlist.MediaMap{
+			URI: uri("init.mp4", url.Values{
+				"start": []string{start.Format(time.RFC3339Nano)},
+			}),
+		},
+		Endlist: true,
+	}
+
+	for i, seg := range hlsSegments {
+		plSeg := &playlist.MediaSegment{
+			Duration: seg.duration,
+			URI: uri("segment.mp4", url.Values{
+				"start":    []string{seg.start.Format(time.RFC3339Nano)},
+				"duration": []string{formatSeconds(seg.duration)},
+				"offset":   []string{formatSeconds(seg.start.Sub(start))},
+			}),
+		}
+
+		if i == 0 {
+			v := seg.start
+			plSeg.DateTime = &v
+		}
+
+		pl.Segments = append(pl.Segments, plSeg)
+
+		if targetDuration := int(math.Ceil(seg.duration.Seconds())); targetDuration > pl.TargetDuration {
+			pl.TargetDuration = targetDuration
+		}
+	}
+
+	byts, err := pl.Marshal()
+	if err != nil {
+		p.writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.Header("Content-Type", "application/vnd.apple.mpegurl")
+	ctx.Writer.Write(byts) //nolint:errcheck
+}
+
+func (p *Server) onHLSInit(ctx *gin.Context, pathName string) {
+	if !p.doAuth(ctx, pathName) {
+		return
+	}
+
+	start, err := time.Parse(time.RFC3339, ctx.Query("start"))
+	if err != nil {
+		p.writeError(ctx, http.StatusBadRequest, fmt.Errorf("invalid start: %w", err))
+		return
+	}
+
+	pathConf, ok := p.hlsFindPathConf(ctx, pathName)
+	if !ok {
+		return
+	}
+
+	segments, err := findSegmentsInTimespan(pathConf, pathName, start, 0)
+	if err != nil {
+		if errors.Is(err, errNoSegmentsFound) {
+			p.writeError(ctx, http.StatusNotFound, err)
+		} else {
+			p.writeError(ctx, http.StatusBadRequest, err)
+		}
+		return
+	}
+
+	init, err := func() (*fmp4.Init, error) {
+		f, err := os.Open(segments[0].Fpath)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		return segmentFMP4ReadInit(f)
+	}()
+	if err != nil {
+		p.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	var buf seekablebuffer.Buffer
+	err = init.Marshal(&buf)
+	if err != nil {
+		p.writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.Header("Content-Type", "video/mp4")
+	ctx.Writer.Write(buf.Bytes()) //nolint:errcheck
+}
+
+func (p *Server) onHLSSegment(ctx *gin.Context, pathName string) {
+	if !p.doAuth(ctx, pathName) {
+		return
+	}
+
+	start, err := time.Parse(time.RFC3339, ctx.Query("start"))
+	if err != nil {
+		p.writeError(ctx, http.StatusBadRequest, fmt.Errorf("invalid start: %w", err))
+		return
+	}
+
+	duration, err := parseDuration(ctx.Query("duration"))
+	if err != nil {
+		p.writeError(ctx, http.StatusBadRequest, fmt.Errorf("invalid duration: %w", err))
+		return
+	}
+
+	offset, err := parseDuration(ctx.Query("offset"))
+	if err != nil {
+		p.writeError(ctx, http.StatusBadRequest, fmt.Errorf("invalid offset: %w", err))
+		return
+	}
+
+	pathConf, ok := p.hlsFindPathConf(ctx, pathName)
+	if !ok {
+		return
+	}
+
+	segments, err := findSegmentsInTimespan(pathConf, pathName, start, duration)
+	if err != nil {
+		if errors.Is(err, errNoSegmentsFound) {
+			p.writeError(ctx, http.StatusNotFound, err)
+		} else {
+			p.writeError(ctx, http.StatusBadRequest, err)
+		}
+		return
+	}
+
+	ww := &writerWrapper{ctx: ctx}
+
+	// timestamps are relative to the start of the playlist
+	m := &muxerFMP4{
+		w:        ww,
+		skipInit: true,
+		baseTime: offset,
+	}
+
+	err = seekAndMux(pathConf.RecordFormat, segments, start, duration, m)
+	if err != nil {
+		// user aborted the download
+		var neterr *net.OpError
+		if errors.As(err, &neterr) {
+			return
+		}
+
+		// nothing has been written yet; send back JSON
+		if !ww.written {
+			if errors.Is(err, errNoSegmentsFound) {
+				p.writeError(ctx, http.StatusNotFound, err)
+			} else {
+				p.writeError(ctx, http.StatusBadRequest, err)
+			}
+			return
+		}
+
+		// something has already been written: abort and write logs only
+		p.Log(logger.Error, err.Error())
+		return
+	}
+}
@@ -0,0 +1,127 @@
+package playback
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bluenviron/gohlslib/pkg/playlist"
+	"github.com/bluenviron/mediacommon/pkg/formats/fmp4"
+	"github.com/bluenviron/mediamtx/internal/auth"
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOnHLS(t *testing.T) {
+	dir, err := os.MkdirTemp("", "mediamtx-playback")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	err = os.Mkdir(filepath.Join(dir, "mypath"), 0o755)
+	require.NoError(t, err)
+
+	writeSegment1(t, filepath.Join(dir, "mypath", "2008-11-07_11-22-00-500000.mp4"))
+	writeSegment2(t, filepath.Join(dir, "mypath", "2008-11-07_11-23-02-500000.mp4"))
+	writeSegment2(t, filepath.Join(dir, "mypath", "2008-11-07_11-23-04-500000.mp4"))
+
+	s := &Server{
+		Address:     "127.0.0.1:9996",
+		ReadTimeout: conf.StringDuration(10 * time.Second),
+		PathConfs: map[string]*conf.Path{
+			"mypath": {
+				RecordPath: filepath.Join(dir, "%path/%Y-%m-%d_%H-%M-%S-%f"),
+			},
+		},
+		AuthManager: &test.AuthManager{
+			Func: func(req *auth.Request) error {
+				require.Equal(t, "mypath", req.Path)
+				require.Equal(t, conf.AuthActionPlayback, req.Action)
+
+				// credentials are passed to segments through the query
+				v, err := url.ParseQuery(req.Query)
+				require.NoError(t, err)
+				require.Equal(t, "mytoken", v.Get("token"))
+				return nil
+			},
+		},
+		Parent: test.NilLogger,
+	}
+	err = s.Initialize()
+	require.NoError(t, err)
+	defer s.Close()
+
+	get := func(u string) []byte {
+		res, err := http.Get(u)
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		buf, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return buf
+	}
+
+	start := time.Date(2008, 11, 0o7, 11, 22, 0, 500000000, time.Local)
+
+	v := url.Values{}
+	v.Set("start", start.Format(time.RFC3339Nano))
+	v.Set("duration", "70")
+	v.Set("token", "mytoken")
+
+	baseURL := "http://localhost:9996/hls/mypath/"
+
+	var pl playlist.Media
+	err = pl.Unmarshal(get(baseURL + "index.m3u8?" + v.Encode()))
+	require.NoError(t, err)
+
+	require.Equal(t, true, pl.Endlist)
+	require.Equal(t, 30, pl.TargetDuration)
+
+	// segments start at key frames and are not concatenated with the last recording,
+	// that is not contiguous.
+	var durations []time.Duration
+	for _, seg := range pl.Segments {
+		durations = append(durations, seg.Duration)
+	}
+	require.Equal(t, []time.Duration{30 * time.Second, 30 * time.Second, 5 * time.Second}, durations)
+
+	var init fmp4.Init
+	err = init.Unmarshal(bytes.NewReader(get(baseURL + pl.Map.URI)))
+	require.NoError(t, err)
+	require.Equal(t, 2, len(init.Tracks))
+
+	var parts fmp4.Parts
+	err = parts.Unmarshal(get(baseURL + pl.Segments[1].URI))
+	require.NoError(t, err)
+
+	// timestamps are relative to the start of the playlist
+	require.Equal(t, fmp4.Parts{
+		{
+			SequenceNumber: 0,
+			Tracks: []*fmp4.PartTrack{
+				{
+					ID:       1,
+					BaseTime: 30 * 90000,
+					Samples: []*fmp4.PartSample{
+						{
+							Duration: 30 * 90000,
+							Payload:  []byte{1, 2},
+						},
+					},
+				},
+			},
+		},
+	}, parts)
+
+	var parts2 fmp4.Parts
+	err = parts2.Unmarshal(get(baseURL + pl.Segments[2].URI))
+	require.NoError(t, err)
+	require.Equal(t, uint64(60*90000), parts2[0].Tracks[0].BaseTime)
+}
@@ -3,7 +3,6 @@ package playback
 import (
 	"encoding/json"
 	"errors"
-	"fmt"
 	"io"
 	"net/http"
 	"os"
@@ -25,62 +24,66 @@ type listEntry struct {
 	Duration listEntryDuration `json:"duration"`
 }
 
-func computeDurationAndConcatenate(recordFormat conf.RecordFormat, segments []*Segment) ([]listEntry, error) {
+func readSegmentInitAndMaxDuration(recordFormat conf.RecordFormat, seg *Segment) (*fmp4.Init, time.Duration, error) {
+	f, err := os.Open(seg.Fpath)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	init, err := segmentReadInit(recordFormat, f)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	_, err = f.Seek(0, io.SeekStart)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var maxDuration time.Duration
+
 	if recordFormat == conf.RecordFormatFMP4 {
-		out := []listEntry{}
-		var prevInit *fmp4.Init
-
-		for _, seg := range segments {
-			err := func() error {
-				f, err := os.Open(seg.Fpath)
-				if err != nil {
-					return err
-				}
-				defer f.Close()
-
-				init, err := segmentFMP4ReadInit(f)
-				if err != nil {
-					return err
-				}
-
-				_, err = f.Seek(0, io.SeekStart)
-				if err != nil {
-					return err
-				}
-
-				maxDuration, err := segmentFMP4ReadMaxDuration(f, init)
-				if err != nil {
-					return err
-				}
-
-				if len(out) != 0 && segmentFMP4CanBeConcatenated(
-					prevInit,
-					out[len(out)-1].Start.Add(time.Duration(out[len(out)-1].Duration)),
-					init,
-					seg.Start) {
-					prevStart := out[len(out)-1].Start
-					curEnd := seg.Start.Add(maxDuration)
-					out[len(out)-1].Duration = listEntryDuration(curEnd.Sub(prevStart))
-				} else {
-					out = append(out, listEntry{
-						Start:    seg.Start,
-						Duration: listEntryDuration(maxDuration),
-					})
-				}
-
-				prevInit = init
-
-				return nil
-			}()
-			if err != nil {
-				return nil, err
-			}
+		maxDuration, err = segmentFMP4ReadMaxDuration(f, init)
+	} else {
+		maxDuration, err = segmentMPEGTSReadMaxDuration(f)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return init, maxDuration, nil
+}
+
+func computeDurationAndConcatenate(recordFormat conf.RecordFormat, segments []*Segment) ([]listEntry, error) {
+	out := []listEntry{}
+	var prevInit *fmp4.Init
+
+	for _, seg := range segments {
+		init, maxDuration, err := readSegmentInitAndMaxDuration(recordFormat, seg)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(out) != 0 && segmentFMP4CanBeConcatenated(
+			prevInit,
+			out[len(out)-1].Start.Add(time.Duration(out[len(out)-1].Duration)),
+			init,
+			seg.Start) {
+			prevStart := out[len(out)-1].Start
+			curEnd := seg.Start.Add(maxDuration)
+			out[len(out)-1].Duration = listEntryDuration(curEnd.Sub(prevStart))
+		} else {
+			out = append(out, listEntry{
+				Start:    seg.Start,
+				Duration: listEntryDuration(maxDuration),
+			})
 		}
 
-		return out, nil
+		prevInit = init
 	}
 
-	return nil, fmt.Errorf("MPEG-TS format is not supported yet")
+	return out, nil
 }
 
 func (p *Server) onList(ctx *gin.Context) {
@@ -38,12 +38,13 @@ func TestOnList(t *testing.T) {
 		AuthManager: &test.AuthManager{
 			Func: func(req *auth.Request) error {
 				require.Equal(t, &auth.Request{
-					User:   "myuser",
-					Pass:   "mypass",
-					IP:     req.IP,
-					Action: "playback",
-					Query:  "path=mypath",
-					Path:   "mypath",
+					User:     "myuser",
+					Pass:     "mypass",
+					IP:       req.IP,
+					Action:   "playback",
+					Query:    "path=mypath",
+					Path:     "mypath",
+					Endpoint: "/list",
 				}, req)
 				return nil
 			},
@@ -143,3 +144,53 @@ func TestOnListDifferentInit(t *testing.T) {
 		},
 	}, out)
 }
+
+func TestOnListMPEGTS(t *testing.T) {
+	dir, err := os.MkdirTemp("", "mediamtx-playback")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	err = os.Mkdir(filepath.Join(dir, "mypath"), 0o755)
+	require.NoError(t, err)
+
+	writeSegmentMPEGTS(t, filepath.Join(dir, "mypath", "2008-11-07_11-22-00-500000.ts"))
+	writeSegmentMPEGTS(t, filepath.Join(dir, "mypath", "2008-11-07_11-22-04-500000.ts"))
+	writeSegmentMPEGTS(t, filepath.Join(dir, "mypath", "2009-11-07_11-23-02-500000.ts"))
+
+	s := &Server{
+		Address:     "127.0.0.1:9996",
+		ReadTimeout: conf.StringDuration(10 * time.Second),
+		PathConfs: map[string]*conf.Path{
+			"mypath": {
+				RecordPath:   filepath.Join(dir, "%path/%Y-%m-%d_%H-%M-%S-%f"),
+				RecordFormat: conf.RecordFormatMPEGTS,
+			},
+		},
+		AuthManager: test.NilAuthManager,
+		Parent:      test.NilLogger,
+	}
+	err = s.Initialize()
+	require.NoError(t, err)
+	defer s.Close()
+
+	res, err := http.Get("http://localhost:9996/list?path=mypath")
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var out interface{}
+	err = json.NewDecoder(res.Body).Decode(&out)
+	require.NoError(t, err)
+
+	require.Equal(t, []interface{}{
+		map[string]interface{}{
+			"duration": float64(8),
+			"start":    time.Date(2008, 11, 0o7, 11, 22, 0, 500000000, time.Local).Format(time.RFC3339Nano),
+		},
+		map[string]interface{}{
+			"duration": float64(4),
+			"start":    time.Date(2009, 11, 0o7, 11, 23, 2, 500000000, time.Local).Format(time.RFC3339Nano),
+		},
+	}, out)
+}
@@ -10,11 +10,12 @@ import (
 
 	"github.com/abema/go-mp4"
 	"github.com/bluenviron/mediacommon/pkg/formats/fmp4"
+
+	"github.com/bluenviron/mediamtx/internal/protocols/mp4reader"
 )
 
 const (
-	sampleFlagIsNonSyncSample = 1 << 16
-	concatenationTolerance    = 500 * time.Millisecond
+	concatenationTolerance = 500 * time.Millisecond
 )
 
 var errTerminated = errors.New("terminated")
@@ -25,20 +26,6 @@ type readSeekerAt interface {
 	io.ReaderAt
 }
 
-func durationGoToMp4(v time.Duration, timeScale uint32) int64 {
-	timeScale64 := int64(timeScale)
-	secs := v / time.Second
-	dec := v % time.Second
-	return int64(secs)*timeScale64 + int64(dec)*timeScale64/int64(time.Second)
-}
-
-func durationMp4ToGo(v int64, timeScale uint32) time.Duration {
-	timeScale64 := int64(timeScale)
-	secs := v / timeScale64
-	dec := v % timeScale64
-	return time.Duration(secs)*time.Second + time.Duration(dec)*time.Second/time.Duration(timeScale64)
-}
-
 func findInitTrack(tracks []*fmp4.InitTrack, id int) *fmp4.InitTrack {
 	for _, track := range tracks {
 		if track.ID == id {
@@ -332,7 +319,7 @@ func segmentFMP4ReadMaxDuration(
 			elapsed += int64(entry.SampleDuration)
 		}
 
-		elapsedGo := durationMp4ToGo(elapsed, track.TimeScale)
+		elapsedGo := mp4reader.DurationMp4ToGo(elapsed, track.TimeScale)
 
 		if elapsedGo > maxElapsed {
 			maxElapsed = elapsedGo
@@ -349,118 +336,78 @@ func segmentFMP4SeekAndMuxParts(
 	init *fmp4.Init,
 	m muxer,
 ) (time.Duration, error) {
-	var segmentStartOffsetMP4 int64
-	var durationMP4 int64
-	moofOffset := uint64(0)
-	var tfhd *mp4.Tfhd
-	var tfdt *mp4.Tfdt
 	atLeastOnePartWritten := false
-	var timeScale uint32
 	var maxMuxerDTS time.Duration
-	breakAtNextMdat := false
 
-	_, err := mp4.ReadBoxStructure(r, func(h *mp4.ReadHandle) (interface{}, error) {
-		switch h.BoxInfo.Type.String() {
-		case "moof":
-			moofOffset = h.BoxInfo.Offset
-			return h.Expand()
+	err := mp4reader.ReadTrackRuns(r, func(run *mp4reader.TrackRun) error {
+		track := findInitTrack(init.Tracks, run.TrackID)
+		if track == nil {
+			return fmt.Errorf("invalid track ID: %v", run.TrackID)
+		}
 
-		case "traf":
-			return h.Expand()
+		m.setTrack(run.TrackID)
+		segmentStartOffsetMP4 := mp4reader.DurationGoToMp4(segmentStartOffset, track.TimeScale)
+		durationMP4 := mp4reader.DurationGoToMp4(duration, track.TimeScale)
 
-		case "tfhd":
-			box, _, err := h.ReadPayload()
-			if err != nil {
-				return nil, err
-			}
-			tfhd = box.(*mp4.Tfhd)
+		muxerDTS := int64(run.BaseTime) - segmentStartOffsetMP4
+		atLeastOneSampleWritten := false
+		stop := false
 
-		case "tfdt":
-			box, _, err := h.ReadPayload()
-			if err != nil {
-				return nil, err
+		for _, sa := range run.Samples {
+			if muxerDTS >= durationMP4 {
+				stop = true
+				break
 			}
-			tfdt = box.(*mp4.Tfdt)
 
-			track := findInitTrack(init.Tracks, int(tfhd.TrackID))
-			if track == nil {
-				return nil, fmt.Errorf("invalid track ID: %v", tfhd.TrackID)
+			if muxerDTS >= 0 {
+				atLeastOnePartWritten = true
 			}
 
-			m.setTrack(int(tfhd.TrackID))
-			timeScale = track.TimeScale
-			segmentStartOffsetMP4 = durationGoToMp4(segmentStartOffset, track.TimeScale)
-			durationMP4 = durationGoToMp4(duration, track.TimeScale)
-
-		case "trun":
-			box, _, err := h.ReadPayload()
+			sampleOffset := sa.Offset
+			sampleSize := sa.Size
+
+			err := m.writeSample(
+				muxerDTS,
+				sa.PTSOffset,
+				sa.IsNonSyncSample,
+				sa.Size,
+				func() ([]byte, error) {
+					payload := make([]byte, sampleSize)
+					n, err := r.ReadAt(payload, int64(sampleOffset))
+					if err != nil {
+						return nil, err
+					}
+					if n != int(sampleSize) {
+						return nil, fmt.Errorf("partial read")
+					}
+
+					return payload, nil
+				},
+			)
 			if err != nil {
-				return nil, err
-			}
-			trun := box.(*mp4.Trun)
-
-			dataOffset := moofOffset + uint64(trun.DataOffset)
-			muxerDTS := int64(tfdt.BaseMediaDecodeTimeV1) - segmentStartOffsetMP4
-			atLeastOneSampleWritten := false
-
-			for _, e := range trun.Entries {
-				if muxerDTS >= durationMP4 {
-					breakAtNextMdat = true
-					break
-				}
-
-				if muxerDTS >= 0 {
-					atLeastOnePartWritten = true
-				}
-
-				sampleOffset := dataOffset
-				sampleSize := e.SampleSize
-
-				err = m.writeSample(
-					muxerDTS,
-					e.SampleCompositionTimeOffsetV1,
-					(e.SampleFlags&sampleFlagIsNonSyncSample) != 0,
-					e.SampleSize,
-					func() ([]byte, error) {
-						payload := make([]byte, sampleSize)
-						n, err := r.ReadAt(payload, int64(sampleOffset))
-						if err != nil {
-							return nil, err
-						}
-						if n != int(sampleSize) {
-							return nil, fmt.Errorf("partial read")
-						}
-
-						return payload, nil
-					},
-				)
-				if err != nil {
-					return nil, err
-				}
-
-				atLeastOneSampleWritten = true
-				dataOffset += uint64(e.SampleSize)
-				muxerDTS += int64(e.SampleDuration)
+				return err
 			}
 
-			if atLeastOneSampleWritten {
-				m.writeFinalDTS(muxerDTS)
-			}
+			atLeastOneSampleWritten = true
+			muxerDTS += int64(sa.Duration)
+		}
 
-			muxerDTSGo := durationMp4ToGo(muxerDTS, timeScale)
+		if atLeastOneSampleWritten {
+			m.writeFinalDTS(muxerDTS)
+		}
 
-			if muxerDTSGo > maxMuxerDTS {
-				maxMuxerDTS = muxerDTSGo
-			}
+		muxerDTSGo := mp4reader.DurationMp4ToGo(muxerDTS, track.TimeScale)
 
-		case "mdat":
-			if breakAtNextMdat {
-				return nil, errTerminated
-			}
+		if muxerDTSGo > maxMuxerDTS {
+			maxMuxerDTS = muxerDTSGo
 		}
-		return nil, nil
+
+		if stop {
+			return mp4reader.ErrStop
+		}
+		return nil
 	})
-	if err != nil && !errors.Is(err, errTerminated) {
+	if err != nil {
 		return 0, err
 	}
 
@@ -478,115 +425,110 @@ func segmentFMP4MuxParts(
 	init *fmp4.Init,
 	m muxer,
 ) (time.Duration, error) {
-	var segmentStartOffsetMP4 int64
-	var durationMP4 int64
-	moofOffset := uint64(0)
-	var tfhd *mp4.Tfhd
-	var tfdt *mp4.Tfdt
-	var timeScale uint32
 	var maxMuxerDTS time.Duration
-	breakAtNextMdat := false
 
-	_, err := mp4.ReadBoxStructure(r, func(h *mp4.ReadHandle) (interface{}, error) {
-		switch h.BoxInfo.Type.String() {
-		case "moof":
-			moofOffset = h.BoxInfo.Offset
-			return h.Expand()
+	err := mp4reader.ReadTrackRuns(r, func(run *mp4reader.TrackRun) error {
+		track := findInitTrack(init.Tracks, run.TrackID)
+		if track == nil {
+			return fmt.Errorf("invalid track ID: %v", run.TrackID)
+		}
 
-		case "traf":
-			return h.Expand()
+		m.setTrack(run.TrackID)
+		segmentStartOffsetMP4 := mp4reader.DurationGoToMp4(segmentStartOffset, track.TimeScale)
+		durationMP4 := mp4reader.DurationGoToMp4(duration, track.TimeScale)
 
-		case "tfhd":
-			box, _, err := h.ReadPayload()
-			if err != nil {
-				return nil, err
+		muxerDTS := int64(run.BaseTime) + segmentStartOffsetMP4
+		atLeastOneSampleWritten := false
+		stop := false
+
+		for _, sa := range run.Samples {
+			if muxerDTS >= durationMP4 {
+				stop = true
+				break
 			}
-			tfhd = box.(*mp4.Tfhd)
 
-		case "tfdt":
-			box, _, err := h.ReadPayload()
+			sampleOffset := sa.Offset
+			sampleSize := sa.Size
+
+			err := m.writeSample(
+				muxerDTS,
+				sa.PTSOffset,
+				sa.IsNonSyncSample,
+				sa.Size,
+				func() ([]byte, error) {
+					payload := make([]byte, sampleSize)
+					n, err := r.ReadAt(payload, int64(sampleOffset))
+					if err != nil {
+						return nil, err
+					}
+					if n != int(sampleSize) {
+						return nil, fmt.Errorf("partial read")
+					}
+
+					return payload, nil
+				},
+			)
 			if err != nil {
-				return nil, err
+				return err
 			}
-			tfdt = box.(*mp4.Tfdt)
 
-			track := findInitTrack(init.Tracks, int(tfhd.TrackID))
-			if track == nil {
-				return nil, fmt.Errorf("invalid track ID: %v", tfhd.TrackID)
-			}
+			atLeastOneSampleWritten = true
+			muxerDTS += int64(sa.Duration)
+		}
 
-			m.setTrack(int(tfhd.TrackID))
-			timeScale = track.TimeScale
-			segmentStartOffsetMP4 = durationGoToMp4(segmentStartOffset, track.TimeScale)
-			durationMP4 = durationGoToMp4(duration, track.TimeScale)
+		if atLeastOneSampleWritten {
+			m.writeFinalDTS(muxerDTS)
+		}
 
-		case "trun":
-			box, _, err := h.ReadPayload()
-			if err != nil {
-				return nil, err
-			}
-			trun := box.(*mp4.Trun)
-
-			dataOffset := moofOffset + uint64(trun.DataOffset)
-			muxerDTS := int64(tfdt.BaseMediaDecodeTimeV1) + segmentStartOffsetMP4
-			atLeastOneSampleWritten := false
-
-			for _, e := range trun.Entries {
-				if muxerDTS >= durationMP4 {
-					breakAtNextMdat = true
-					break
-				}
-
-				sampleOffset := dataOffset
-				sampleSize := e.SampleSize
-
-				err = m.writeSample(
-					muxerDTS,
-					e.SampleCompositionTimeOffsetV1,
-					(e.SampleFlags&sampleFlagIsNonSyncSample) != 0,
-					e.SampleSize,
-					func() ([]byte, error) {
-						payload := make([]byte, sampleSize)
-						n, err := r.ReadAt(payload, int64(sampleOffset))
-						if err != nil {
-							return nil, err
-						}
-						if n != int(sampleSize) {
-							return nil, fmt.Errorf("partial read")
-						}
-
-						return payload, nil
-					},
-				)
-				if err != nil {
-					return nil, err
-				}
-
-				atLeastOneSampleWritten = true
-				dataOffset += uint64(e.SampleSize)
-				muxerDTS += int64(e.SampleDuration)
-			}
+		muxerDTSGo := mp4reader.DurationMp4ToGo(muxerDTS, track.TimeScale)
 
-			if atLeastOneSampleWritten {
-				m.writeFinalDTS(muxerDTS)
-			}
+		if muxerDTSGo > maxMuxerDTS {
+			maxMuxerDTS = muxerDTSGo
+		}
 
-			muxerDTSGo := durationMp4ToGo(muxerDTS, timeScale)
+		if stop {
+			return mp4reader.ErrStop
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
 
-			if muxerDTSGo > maxMuxerDTS {
-				maxMuxerDTS = muxerDTSGo
-			}
+	return maxMuxerDTS, nil
+}
 
-		case "mdat":
-			if breakAtNextMdat {
-				return nil, errTerminated
+// segmentFMP4ReadSyncSamples returns the DTS of sync samples of the main track,
+// that is the first video track or the first track if there's no video.
+func segmentFMP4ReadSyncSamples(
+	r io.ReadSeeker,
+	init *fmp4.Init,
+) ([]time.Duration, error) {
+	mainTrack := init.Tracks[0]
+	for _, track := range init.Tracks {
+		if track.Codec.IsVideo() {
+			mainTrack = track
+			break
+		}
+	}
+
+	var out []time.Duration
+
+	err := mp4reader.ReadTrackRuns(r, func(run *mp4reader.TrackRun) error {
+		if run.TrackID != mainTrack.ID {
+			return nil
+		}
+
+		for _, sa := range run.Samples {
+			if !sa.IsNonSyncSample {
+				out = append(out, mp4reader.DurationMp4ToGo(sa.DTS, mainTrack.TimeScale))
 			}
 		}
-		return nil, nil
+		return nil
 	})
-	if err != nil && !errors.Is(err, errTerminated) {
-		return 0, err
+	if err != nil {
+		return nil, err
 	}
 
-	return maxMuxerDTS, nil
+	return out, nil
 }
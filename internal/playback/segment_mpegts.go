@@ -0,0 +1,562 @@
+package playback
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/bluenviron/mediacommon/pkg/codecs/ac3"
+	"github.com/bluenviron/mediacommon/pkg/codecs/h264"
+	"github.com/bluenviron/mediacommon/pkg/codecs/h265"
+	"github.com/bluenviron/mediacommon/pkg/codecs/mpeg1audio"
+	"github.com/bluenviron/mediacommon/pkg/codecs/mpeg4audio"
+	"github.com/bluenviron/mediacommon/pkg/codecs/opus"
+	"github.com/bluenviron/mediacommon/pkg/formats/fmp4"
+	"github.com/bluenviron/mediacommon/pkg/formats/mpegts"
+
+	"github.com/bluenviron/mediamtx/internal/protocols/mp4reader"
+)
+
+// since tracks are interleaved, samples of a track can be written after samples of other tracks
+// that have a greater DTS. Reading is stopped when DTS exceeds the requested duration by this amount.
+const mpegtsMaxInterleave = 1 * time.Second
+
+// eofReader keeps track of whether the end of the file has been reached.
+type eofReader struct {
+	r   io.Reader
+	eof bool
+}
+
+func (r *eofReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if errors.Is(err, io.EOF) {
+		r.eof = true
+	}
+	return n, err
+}
+
+type segmentMPEGTSSample struct {
+	dts             int64
+	ptsOffset       int32
+	duration        int64 // zero when unknown
+	isNonSyncSample bool
+	payload         []byte
+}
+
+type segmentMPEGTSTrack struct {
+	id        int
+	timeScale uint32
+
+	// filled when parameters of the track are found.
+	codec fmp4.Codec
+}
+
+// segmentMPEGTSReader reads samples of a MPEG-TS segment
+// and converts them into the format used by fMP4.
+// Timestamps are relative to the first PES packet of the segment.
+type segmentMPEGTSReader struct {
+	r        io.Reader
+	onSample func(track *segmentMPEGTSTrack, sample *segmentMPEGTSSample) error
+
+	tracks  []*segmentMPEGTSTrack
+	timeDec *mpegts.TimeDecoder
+}
+
+func mpeg1audioChannelCount(cm mpeg1audio.ChannelMode) int {
+	switch cm {
+	case mpeg1audio.ChannelModeStereo,
+		mpeg1audio.ChannelModeJointStereo,
+		mpeg1audio.ChannelModeDualChannel:
+		return 2
+
+	default:
+		return 1
+	}
+}
+
+// removeEmptyNALUs removes empty NALUs, that can be found in malformed or truncated recordings.
+func removeEmptyNALUs(au [][]byte) [][]byte {
+	n := 0
+	for _, nalu := range au {
+		if len(nalu) != 0 {
+			n++
+		}
+	}
+
+	if n == len(au) {
+		return au
+	}
+
+	out := make([][]byte, 0, n)
+	for _, nalu := range au {
+		if len(nalu) != 0 {
+			out = append(out, nalu)
+		}
+	}
+	return out
+}
+
+func (sr *segmentMPEGTSReader) decodeTime(ts int64) time.Duration {
+	if sr.timeDec == nil {
+		sr.timeDec = mpegts.NewTimeDecoder(ts)
+	}
+	return sr.timeDec.Decode(ts)
+}
+
+func (sr *segmentMPEGTSReader) addTrack(timeScale uint32) *segmentMPEGTSTrack {
+	track := &segmentMPEGTSTrack{
+		id:        len(sr.tracks) + 1,
+		timeScale: timeScale,
+	}
+	sr.tracks = append(sr.tracks, track)
+	return track
+}
+
+func (sr *segmentMPEGTSReader) read() error {
+	er := &eofReader{r: sr.r}
+
+	r, err := mpegts.NewReader(bufio.NewReader(er))
+	if err != nil {
+		return err
+	}
+
+	r.OnDecodeError(func(_ error) {
+	})
+
+	// tracks that cannot be converted into fMP4 without additional parameters
+	// (MPEG-1 and MPEG-4 Video) are skipped.
+	for _, mtrack := range r.Tracks() {
+		switch codec := mtrack.Codec.(type) {
+		case *mpegts.CodecH264:
+			track := sr.addTrack(90000)
+			var sps []byte
+			var pps []byte
+
+			r.OnDataH26x(mtrack, func(pts int64, dts int64, au [][]byte) error {
+				au = removeEmptyNALUs(au)
+				if len(au) == 0 {
+					return nil
+				}
+
+				for _, nalu := range au {
+					switch h264.NALUType(nalu[0] & 0x1F) {
+					case h264.NALUTypeSPS:
+						sps = nalu
+
+					case h264.NALUTypePPS:
+						pps = nalu
+					}
+				}
+
+				if track.codec == nil && sps != nil && pps != nil {
+					track.codec = &fmp4.CodecH264{
+						SPS: sps,
+						PPS: pps,
+					}
+				}
+
+				payload, err := h264.AVCCMarshal(au)
+				if err != nil {
+					return err
+				}
+
+				dtsGo := sr.decodeTime(dts)
+				ptsGo := sr.decodeTime(pts)
+
+				return sr.onSample(track, &segmentMPEGTSSample{
+					dts:             mp4reader.DurationGoToMp4(dtsGo, track.timeScale),
+					ptsOffset:       int32(mp4reader.DurationGoToMp4(ptsGo-dtsGo, track.timeScale)),
+					isNonSyncSample: !h264.IDRPresent(au),
+					payload:         payload,
+				})
+			})
+
+		case *mpegts.CodecH265:
+			track := sr.addTrack(90000)
+			var vps []byte
+			var sps []byte
+			var pps []byte
+
+			r.OnDataH26x(mtrack, func(pts int64, dts int64, au [][]byte) error {
+				au = removeEmptyNALUs(au)
+				if len(au) == 0 {
+					return nil
+				}
+
+				for _, nalu := range au {
+					switch h265.NALUType((nalu[0] >> 1) & 0b111111) {
+					case h265.NALUType_VPS_NUT:
+						vps = nalu
+
+					case h265.NALUType_SPS_NUT:
+						sps = nalu
+
+					case h265.NALUType_PPS_NUT:
+						pps = nalu
+					}
+				}
+
+				if track.codec == nil && vps != nil && sps != nil && pps != nil {
+					track.codec = &fmp4.CodecH265{
+						VPS: vps,
+						SPS: sps,
+						PPS: pps,
+					}
+				}
+
+				payload, err := h264.AVCCMarshal(au)
+				if err != nil {
+					return err
+				}
+
+				dtsGo := sr.decodeTime(dts)
+				ptsGo := sr.decodeTime(pts)
+
+				return sr.onSample(track, &segmentMPEGTSSample{
+					dts:             mp4reader.DurationGoToMp4(dtsGo, track.timeScale),
+					ptsOffset:       int32(mp4reader.DurationGoToMp4(ptsGo-dtsGo, track.timeScale)),
+					isNonSyncSample: !h265.IsRandomAccess(au),
+					payload:         payload,
+				})
+			})
+
+		case *mpegts.CodecOpus:
+			track := sr.addTrack(48000)
+			track.codec = &fmp4.CodecOpus{
+				ChannelCount: codec.ChannelCount,
+			}
+
+			r.OnDataOpus(mtrack, func(pts int64, packets [][]byte) error {
+				dts := mp4reader.DurationGoToMp4(sr.decodeTime(pts), track.timeScale)
+
+				for _, packet := range packets {
+					duration := mp4reader.DurationGoToMp4(opus.PacketDuration(packet), track.timeScale)
+
+					err := sr.onSample(track, &segmentMPEGTSSample{
+						dts:      dts,
+						duration: duration,
+						payload:  packet,
+					})
+					if err != nil {
+						return err
+					}
+
+					dts += duration
+				}
+
+				return nil
+			})
+
+		case *mpegts.CodecMPEG4Audio:
+			track := sr.addTrack(uint32(codec.Config.SampleRate))
+			track.codec = &fmp4.CodecMPEG4Audio{
+				Config: codec.Config,
+			}
+
+			r.OnDataMPEG4Audio(mtrack, func(pts int64, aus [][]byte) error {
+				dts := mp4reader.DurationGoToMp4(sr.decodeTime(pts), track.timeScale)
+
+				for _, au := range aus {
+					err := sr.onSample(track, &segmentMPEGTSSample{
+						dts:      dts,
+						duration: mpeg4audio.SamplesPerAccessUnit,
+						payload:  au,
+					})
+					if err != nil {
+						return err
+					}
+
+					dts += mpeg4audio.SamplesPerAccessUnit
+				}
+
+				return nil
+			})
+
+		case *mpegts.CodecMPEG1Audio:
+			track := sr.addTrack(90000)
+
+			r.OnDataMPEG1Audio(mtrack, func(pts int64, frames [][]byte) error {
+				dtsGo := sr.decodeTime(pts)
+
+				for _, frame := range frames {
+					var h mpeg1audio.FrameHeader
+					err := h.Unmarshal(frame)
+					if err != nil {
+						return err
+					}
+
+					if track.codec == nil {
+						track.codec = &fmp4.CodecMPEG1Audio{
+							SampleRate:   h.SampleRate,
+							ChannelCount: mpeg1audioChannelCount(h.ChannelMode),
+						}
+					}
+
+					duration := time.Duration(h.SampleCount()) * time.Second / time.Duration(h.SampleRate)
+
+					err = sr.onSample(track, &segmentMPEGTSSample{
+						dts:      mp4reader.DurationGoToMp4(dtsGo, track.timeScale),
+						duration: mp4reader.DurationGoToMp4(duration, track.timeScale),
+						payload:  frame,
+					})
+					if err != nil {
+						return err
+					}
+
+					dtsGo += duration
+				}
+
+				return nil
+			})
+
+		case *mpegts.CodecAC3:
+			track := sr.addTrack(uint32(codec.SampleRate))
+
+			r.OnDataAC3(mtrack, func(pts int64, frame []byte) error {
+				if track.codec == nil {
+					var syncInfo ac3.SyncInfo
+					err := syncInfo.Unmarshal(frame)
+					if err != nil {
+						return fmt.Errorf("invalid AC-3 frame: %w", err)
+					}
+
+					var bsi ac3.BSI
+					err = bsi.Unmarshal(frame[5:])
+					if err != nil {
+						return fmt.Errorf("invalid AC-3 frame: %w", err)
+					}
+
+					track.codec = &fmp4.CodecAC3{
+						SampleRate:   syncInfo.SampleRate(),
+						ChannelCount: bsi.ChannelCount(),
+						Fscod:        syncInfo.Fscod,
+						Bsid:         bsi.Bsid,
+						Bsmod:        bsi.Bsmod,
+						Acmod:        bsi.Acmod,
+						LfeOn:        bsi.LfeOn,
+						BitRateCode:  syncInfo.Frmsizecod >> 1,
+					}
+				}
+
+				return sr.onSample(track, &segmentMPEGTSSample{
+					dts:      mp4reader.DurationGoToMp4(sr.decodeTime(pts), track.timeScale),
+					duration: ac3.SamplesPerFrame,
+					payload:  frame,
+				})
+			})
+		}
+	}
+
+	if len(sr.tracks) == 0 {
+		return fmt.Errorf("no supported tracks found")
+	}
+
+	for {
+		err := r.Read()
+		if err != nil {
+			if er.eof {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+func segmentMPEGTSReadInit(r io.Reader) (*fmp4.Init, error) {
+	sr := &segmentMPEGTSReader{
+		r: r,
+	}
+
+	// stop as soon as parameters of all tracks are found.
+	sr.onSample = func(_ *segmentMPEGTSTrack, _ *segmentMPEGTSSample) error {
+		for _, track := range sr.tracks {
+			if track.codec == nil {
+				return nil
+			}
+		}
+		return errTerminated
+	}
+
+	err := sr.read()
+	if err != nil && !errors.Is(err, errTerminated) {
+		return nil, err
+	}
+
+	init := &fmp4.Init{}
+
+	// tracks whose parameters are not found are skipped.
+	for _, track := range sr.tracks {
+		if track.codec != nil {
+			init.Tracks = append(init.Tracks, &fmp4.InitTrack{
+				ID:        track.id,
+				TimeScale: track.timeScale,
+				Codec:     track.codec,
+			})
+		}
+	}
+
+	if len(init.Tracks) == 0 {
+		return nil, fmt.Errorf("no supported tracks found")
+	}
+
+	return init, nil
+}
+
+func segmentMPEGTSReadMaxDuration(r io.Reader) (time.Duration, error) {
+	var maxElapsed time.Duration
+	lastDTS := make(map[int]int64)
+
+	sr := &segmentMPEGTSReader{
+		r: r,
+		onSample: func(track *segmentMPEGTSTrack, sample *segmentMPEGTSSample) error {
+			// when the duration is unknown (video), use the one of the previous sample.
+			duration := sample.duration
+			if prev, ok := lastDTS[track.id]; ok && duration == 0 {
+				duration = sample.dts - prev
+			}
+			lastDTS[track.id] = sample.dts
+
+			elapsed := mp4reader.DurationMp4ToGo(sample.dts+duration, track.timeScale)
+			if elapsed > maxElapsed {
+				maxElapsed = elapsed
+			}
+			return nil
+		},
+	}
+
+	err := sr.read()
+	if err != nil {
+		return 0, err
+	}
+
+	return maxElapsed, nil
+}
+
+type segmentMPEGTSMuxerTrack struct {
+	written  bool
+	lastDTS  int64
+	duration int64
+}
+
+// segmentMPEGTSMuxSamples writes samples of a segment to the muxer.
+// segmentStartOffset is added to sample timestamps.
+func segmentMPEGTSMuxSamples(
+	r io.Reader,
+	segmentStartOffset time.Duration,
+	duration time.Duration,
+	init *fmp4.Init,
+	m muxer,
+) (time.Duration, bool, error) {
+	tracks := make(map[int]*segmentMPEGTSMuxerTrack)
+	atLeastOneSampleWritten := false
+	var maxMuxerDTS time.Duration
+
+	sr := &segmentMPEGTSReader{
+		r: r,
+		onSample: func(track *segmentMPEGTSTrack, sample *segmentMPEGTSSample) error {
+			if findInitTrack(init.Tracks, track.id) == nil {
+				return nil
+			}
+
+			muxerDTS := sample.dts + mp4reader.DurationGoToMp4(segmentStartOffset, track.timeScale)
+			muxerDTSGo := mp4reader.DurationMp4ToGo(muxerDTS, track.timeScale)
+
+			if muxerDTSGo >= duration {
+				if muxerDTSGo >= (duration + mpegtsMaxInterleave) {
+					return errTerminated
+				}
+				return nil
+			}
+
+			if muxerDTS >= 0 {
+				atLeastOneSampleWritten = true
+			}
+
+			mt, ok := tracks[track.id]
+			if !ok {
+				mt = &segmentMPEGTSMuxerTrack{}
+				tracks[track.id] = mt
+			}
+
+			m.setTrack(track.id)
+
+			err := m.writeSample(
+				muxerDTS,
+				sample.ptsOffset,
+				sample.isNonSyncSample,
+				uint32(len(sample.payload)),
+				func() ([]byte, error) {
+					return sample.payload, nil
+				},
+			)
+			if err != nil {
+				return err
+			}
+
+			// when the duration is unknown (video), use the one of the previous sample.
+			if sample.duration != 0 {
+				mt.duration = sample.duration
+			} else if mt.written {
+				mt.duration = muxerDTS - mt.lastDTS
+			}
+
+			mt.written = true
+			mt.lastDTS = muxerDTS
+			return nil
+		},
+	}
+
+	err := sr.read()
+	if err != nil && !errors.Is(err, errTerminated) {
+		return 0, false, err
+	}
+
+	for _, track := range init.Tracks {
+		mt, ok := tracks[track.ID]
+		if !ok {
+			continue
+		}
+
+		m.setTrack(track.ID)
+		m.writeFinalDTS(mt.lastDTS + mt.duration)
+
+		muxerDTSGo := mp4reader.DurationMp4ToGo(mt.lastDTS+mt.duration, track.TimeScale)
+		if muxerDTSGo > maxMuxerDTS {
+			maxMuxerDTS = muxerDTSGo
+		}
+	}
+
+	return maxMuxerDTS, atLeastOneSampleWritten, nil
+}
+
+func segmentMPEGTSSeekAndMux(
+	r io.Reader,
+	segmentStartOffset time.Duration,
+	duration time.Duration,
+	init *fmp4.Init,
+	m muxer,
+) (time.Duration, error) {
+	maxMuxerDTS, atLeastOneSampleWritten, err := segmentMPEGTSMuxSamples(r, -segmentStartOffset, duration, init, m)
+	if err != nil {
+		return 0, err
+	}
+
+	if !atLeastOneSampleWritten {
+		return 0, errNoSegmentsFound
+	}
+
+	return maxMuxerDTS, nil
+}
+
+func segmentMPEGTSMux(
+	r io.Reader,
+	segmentStartOffset time.Duration,
+	duration time.Duration,
+	init *fmp4.Init,
+	m muxer,
+) (time.Duration, error) {
+	maxMuxerDTS, _, err := segmentMPEGTSMuxSamples(r, segmentStartOffset, duration, init, m)
+	return maxMuxerDTS, err
+}
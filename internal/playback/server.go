@@ -43,6 +43,7 @@ func (p *Server) Initialize() error {
 
 	group.GET("/list", p.onList)
 	group.GET("/get", p.onGet)
+	group.GET("/hls/*name", p.onHLS)
 
 	network, address := restrictnetwork.Restrict("tcp", p.Address)
 
@@ -73,7 +74,7 @@ func (p *Server) Close() {
 
 // Log implements logger.Writer.
 func (p *Server) Log(level logger.Level, format string, args ...interface{}) {
-	p.Parent.Log(level, "[playback] "+format, args...)
+	p.Parent.Log(level, "%v"+format, append([]interface{}{logger.Component("playback")}, args...)...)
 }
 
 // ReloadPathConfs is called by core.Core.
@@ -102,14 +103,22 @@ func (p *Server) safeFindPathConf(name string) (*conf.Path, error) {
 func (p *Server) doAuth(ctx *gin.Context, pathName string) bool {
 	user, pass, hasCredentials := ctx.Request.BasicAuth()
 
-	err := p.AuthManager.Authenticate(&auth.Request{
-		User:   user,
-		Pass:   pass,
-		Query:  ctx.Request.URL.RawQuery,
-		IP:     net.ParseIP(ctx.ClientIP()),
-		Action: conf.AuthActionPlayback,
-		Path:   pathName,
-	})
+	req := &auth.Request{
+		User:     user,
+		Pass:     pass,
+		Query:    ctx.Request.URL.RawQuery,
+		IP:       net.ParseIP(ctx.ClientIP()),
+		Action:   conf.AuthActionPlayback,
+		Path:     pathName,
+		Token:    auth.TokenFromHeader(ctx.Request.Header),
+		Endpoint: ctx.Request.URL.Path,
+	}
+
+	if req.Token != "" {
+		hasCredentials = true
+	}
+
+	err := p.AuthManager.Authenticate(req)
 	if err != nil {
 		if !hasCredentials {
 			ctx.Header("WWW-Authenticate", `Basic realm="mediamtx"`)
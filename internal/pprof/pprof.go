@@ -66,7 +66,7 @@ func (pp *PPROF) Close() {
 
 // Log implements logger.Writer.
 func (pp *PPROF) Log(level logger.Level, format string, args ...interface{}) {
-	pp.Parent.Log(level, "[pprof] "+format, args...)
+	pp.Parent.Log(level, "%v"+format, append([]interface{}{logger.Component("pprof")}, args...)...)
 }
 
 func (pp *PPROF) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -74,13 +74,21 @@ func (pp *PPROF) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	ip, _, _ := net.SplitHostPort(strings.TrimSpace(r.RemoteAddr))
 
-	err := pp.AuthManager.Authenticate(&auth.Request{
-		User:   user,
-		Pass:   pass,
-		Query:  r.URL.RawQuery,
-		IP:     net.ParseIP(ip),
-		Action: conf.AuthActionMetrics,
-	})
+	req := &auth.Request{
+		User:     user,
+		Pass:     pass,
+		Query:    r.URL.RawQuery,
+		IP:       net.ParseIP(ip),
+		Action:   conf.AuthActionMetrics,
+		Token:    auth.TokenFromHeader(r.Header),
+		Endpoint: r.URL.Path,
+	}
+
+	if req.Token != "" {
+		hasCredentials = true
+	}
+
+	err := pp.AuthManager.Authenticate(req)
 	if err != nil {
 		if !hasCredentials {
 			w.Header().Set("WWW-Authenticate", `Basic realm="mediamtx"`)
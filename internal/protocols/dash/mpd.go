@@ -0,0 +1,178 @@
+package dash
+
+import (
+	"encoding/xml"
+	"strconv"
+	"time"
+
+	"github.com/bluenviron/gohlslib/pkg/codecparams"
+)
+
+func marshalDuration(d time.Duration) string {
+	return "PT" + strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "S"
+}
+
+func marshalTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000Z")
+}
+
+type mpdS struct {
+	T uint64 `xml:"t,attr"`
+	D uint64 `xml:"d,attr"`
+}
+
+type mpdSegmentTemplate struct {
+	Timescale                uint32  `xml:"timescale,attr"`
+	Initialization           string  `xml:"initialization,attr"`
+	Media                    string  `xml:"media,attr"`
+	StartNumber              uint64  `xml:"startNumber,attr"`
+	AvailabilityTimeOffset   float64 `xml:"availabilityTimeOffset,attr,omitempty"`
+	AvailabilityTimeComplete *bool   `xml:"availabilityTimeComplete,attr"`
+	Timeline                 []mpdS  `xml:"SegmentTimeline>S"`
+}
+
+type mpdRepresentation struct {
+	ID                string             `xml:"id,attr"`
+	Codecs            string             `xml:"codecs,attr"`
+	Bandwidth         uint64             `xml:"bandwidth,attr"`
+	AudioSamplingRate uint32             `xml:"audioSamplingRate,attr,omitempty"`
+	SegmentTemplate   mpdSegmentTemplate `xml:"SegmentTemplate"`
+}
+
+type mpdAdaptationSet struct {
+	ID               int               `xml:"id,attr"`
+	ContentType      string            `xml:"contentType,attr"`
+	MimeType         string            `xml:"mimeType,attr"`
+	SegmentAlignment bool              `xml:"segmentAlignment,attr"`
+	StartWithSAP     int               `xml:"startWithSAP,attr"`
+	Representation   mpdRepresentation `xml:"Representation"`
+}
+
+type mpdPeriod struct {
+	ID             string             `xml:"id,attr"`
+	Start          string             `xml:"start,attr"`
+	AdaptationSets []mpdAdaptationSet `xml:"AdaptationSet"`
+}
+
+type mpdLatency struct {
+	Target int64 `xml:"target,attr"`
+}
+
+type mpdServiceDescription struct {
+	ID      int        `xml:"id,attr"`
+	Latency mpdLatency `xml:"Latency"`
+}
+
+type mpdUTCTiming struct {
+	SchemeIDURI string `xml:"schemeIdUri,attr"`
+	Value       string `xml:"value,attr"`
+}
+
+type mpd struct {
+	XMLName               xml.Name               `xml:"MPD"`
+	XMLNS                 string                 `xml:"xmlns,attr"`
+	Profiles              string                 `xml:"profiles,attr"`
+	Type                  string                 `xml:"type,attr"`
+	AvailabilityStartTime string                 `xml:"availabilityStartTime,attr"`
+	PublishTime           string                 `xml:"publishTime,attr"`
+	MinimumUpdatePeriod   string                 `xml:"minimumUpdatePeriod,attr"`
+	MinBufferTime         string                 `xml:"minBufferTime,attr"`
+	TimeShiftBufferDepth  string                 `xml:"timeShiftBufferDepth,attr"`
+	MaxSegmentDuration    string                 `xml:"maxSegmentDuration,attr"`
+	ServiceDescription    *mpdServiceDescription `xml:"ServiceDescription"`
+	Period                mpdPeriod              `xml:"Period"`
+	UTCTiming             mpdUTCTiming           `xml:"UTCTiming"`
+}
+
+// generateMPD generates the MPD.
+// It must be called with the mutex locked.
+func (m *Muxer) generateMPD() ([]byte, error) {
+	now := time.Now()
+
+	doc := &mpd{
+		XMLNS:                 "urn:mpeg:dash:schema:mpd:2011",
+		Profiles:              "urn:mpeg:dash:profile:isoff-live:2011",
+		Type:                  "dynamic",
+		AvailabilityStartTime: marshalTime(m.availabilityStartTime),
+		PublishTime:           marshalTime(now),
+		MinimumUpdatePeriod:   marshalDuration(m.SegmentDuration),
+		MinBufferTime:         marshalDuration(m.SegmentDuration),
+		TimeShiftBufferDepth:  marshalDuration(time.Duration(m.SegmentCount) * m.SegmentDuration),
+		MaxSegmentDuration:    marshalDuration(2 * m.SegmentDuration),
+		Period: mpdPeriod{
+			ID:    "0",
+			Start: marshalDuration(0),
+		},
+		UTCTiming: mpdUTCTiming{
+			SchemeIDURI: "urn:mpeg:dash:utc:direct:2014",
+			Value:       marshalTime(now),
+		},
+	}
+
+	if m.LowLatency {
+		doc.MinimumUpdatePeriod = marshalDuration(m.PartDuration)
+		doc.MinBufferTime = marshalDuration(m.PartDuration)
+		doc.ServiceDescription = &mpdServiceDescription{
+			Latency: mpdLatency{
+				Target: (m.SegmentDuration + m.PartDuration).Milliseconds(),
+			},
+		}
+	}
+
+	for i, t := range m.tracks {
+		// tracks are advertised only after they produced a fragment.
+		if len(t.fragments) == 0 {
+			continue
+		}
+
+		st := mpdSegmentTemplate{
+			Timescale:      t.timeScale,
+			Initialization: "init_" + strconv.FormatInt(int64(t.id), 10) + ".mp4",
+			Media:          "seg_" + strconv.FormatInt(int64(t.id), 10) + "_$Number$.mp4",
+			StartNumber:    t.fragments[0].number,
+		}
+
+		for _, f := range t.fragments {
+			st.Timeline = append(st.Timeline, mpdS{
+				T: f.startTime,
+				D: f.duration,
+			})
+		}
+
+		if m.LowLatency {
+			st.AvailabilityTimeOffset = (m.SegmentDuration - m.PartDuration).Seconds()
+			v := false
+			st.AvailabilityTimeComplete = &v
+		}
+
+		as := mpdAdaptationSet{
+			ID:               i,
+			SegmentAlignment: true,
+			StartWithSAP:     1,
+			Representation: mpdRepresentation{
+				ID:              strconv.FormatInt(int64(t.id), 10),
+				Codecs:          codecparams.Marshal(t.track.Codec),
+				Bandwidth:       t.bandwidth(),
+				SegmentTemplate: st,
+			},
+		}
+
+		if t.isVideo {
+			as.ContentType = "video"
+			as.MimeType = "video/mp4"
+		} else {
+			as.ContentType = "audio"
+			as.MimeType = "audio/mp4"
+			as.Representation.AudioSamplingRate = t.timeScale
+		}
+
+		doc.Period.AdaptationSets = append(doc.Period.AdaptationSets, as)
+	}
+
+	byts, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), byts...), nil
+}
@@ -0,0 +1,439 @@
+// Package dash contains a MPEG-DASH muxer.
+package dash
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/bluenviron/gohlslib/pkg/codecs"
+	"github.com/bluenviron/mediacommon/pkg/codecs/av1"
+	"github.com/bluenviron/mediacommon/pkg/codecs/h264"
+	"github.com/bluenviron/mediacommon/pkg/codecs/h265"
+	"github.com/bluenviron/mediacommon/pkg/codecs/mpeg4audio"
+	"github.com/bluenviron/mediacommon/pkg/codecs/opus"
+	"github.com/bluenviron/mediacommon/pkg/codecs/vp9"
+	"github.com/bluenviron/mediacommon/pkg/formats/fmp4"
+)
+
+type dtsExtractor interface {
+	Extract([][]byte, time.Duration) (time.Duration, error)
+}
+
+func allocateDTSExtractor(codec codecs.Codec) dtsExtractor {
+	if _, ok := codec.(*codecs.H265); ok {
+		return h265.NewDTSExtractor()
+	}
+	return h264.NewDTSExtractor()
+}
+
+func durationGoToMp4(v time.Duration, timeScale uint32) uint64 {
+	timeScale64 := uint64(timeScale)
+	secs := v / time.Second
+	dec := v % time.Second
+	return uint64(secs)*timeScale64 + uint64(dec)*timeScale64/uint64(time.Second)
+}
+
+func timeScale(codec codecs.Codec) uint32 {
+	switch codec := codec.(type) {
+	case *codecs.MPEG4Audio:
+		return uint32(codec.SampleRate)
+
+	case *codecs.Opus:
+		return 48000
+	}
+
+	return 90000
+}
+
+// Track is a track of the muxer.
+type Track struct {
+	Codec codecs.Codec
+}
+
+// Muxer is a MPEG-DASH muxer.
+// It produces a dynamic MPD and CMAF fMP4 segments, one representation for each track.
+type Muxer struct {
+	// number of completed segments kept in the MPD.
+	SegmentCount int
+
+	// minimum duration of each segment.
+	SegmentDuration time.Duration
+
+	// duration of each chunk, used in low-latency mode.
+	PartDuration time.Duration
+
+	// enables low-latency mode, in which segments are delivered
+	// with chunked transfer encoding while they are being generated.
+	LowLatency bool
+
+	// video track.
+	VideoTrack *Track
+
+	// audio track.
+	AudioTrack *Track
+
+	mutex                          sync.Mutex
+	closed                         bool
+	changed                        chan struct{}
+	tracks                         []*muxerTrack
+	videoTrack                     *muxerTrack
+	audioTrack                     *muxerTrack
+	leadingTrack                   *muxerTrack
+	videoFirstRandomAccessReceived bool
+	videoDTSExtractor              dtsExtractor
+	started                        bool
+	startDTS                       time.Duration
+	availabilityStartTime          time.Time
+	segmentStartDTS                time.Duration
+	partStartDTS                   time.Duration
+	nextSequenceNumber             uint32
+}
+
+// Start initializes the muxer.
+func (m *Muxer) Start() error {
+	if m.VideoTrack == nil && m.AudioTrack == nil {
+		return fmt.Errorf("one between VideoTrack and AudioTrack is required")
+	}
+
+	if m.SegmentCount < 2 {
+		return fmt.Errorf("SegmentCount must be greater than 1")
+	}
+
+	if m.LowLatency && m.PartDuration >= m.SegmentDuration {
+		return fmt.Errorf("PartDuration must be less than SegmentDuration")
+	}
+
+	m.changed = make(chan struct{})
+
+	if m.VideoTrack != nil {
+		m.videoTrack = &muxerTrack{
+			id:        len(m.tracks) + 1,
+			track:     m.VideoTrack,
+			isVideo:   true,
+			timeScale: timeScale(m.VideoTrack.Codec),
+		}
+		m.tracks = append(m.tracks, m.videoTrack)
+	}
+
+	if m.AudioTrack != nil {
+		m.audioTrack = &muxerTrack{
+			id:        len(m.tracks) + 1,
+			track:     m.AudioTrack,
+			timeScale: timeScale(m.AudioTrack.Codec),
+		}
+		m.tracks = append(m.tracks, m.audioTrack)
+	}
+
+	m.leadingTrack = m.tracks[0]
+	m.nextSequenceNumber = 1
+
+	return nil
+}
+
+// Close closes a Muxer.
+func (m *Muxer) Close() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.closed = true
+	m.notifyChange()
+}
+
+// notifyChange wakes up all requests that are waiting for new content.
+func (m *Muxer) notifyChange() {
+	close(m.changed)
+	m.changed = make(chan struct{})
+}
+
+// WriteAV1 writes an AV1 temporal unit.
+func (m *Muxer) WriteAV1(ntp time.Time, pts time.Duration, tu [][]byte) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	codec := m.VideoTrack.Codec.(*codecs.AV1)
+	randomAccess := false
+
+	for _, obu := range tu {
+		var h av1.OBUHeader
+		err := h.Unmarshal(obu)
+		if err != nil {
+			return err
+		}
+
+		if h.Type == av1.OBUTypeSequenceHeader {
+			randomAccess = true
+
+			codec.SequenceHeader = obu
+		}
+	}
+
+	if !m.videoFirstRandomAccessReceived {
+		if !randomAccess {
+			return nil
+		}
+		m.videoFirstRandomAccessReceived = true
+	}
+
+	ps, err := fmp4.NewPartSampleAV1(randomAccess, tu)
+	if err != nil {
+		return err
+	}
+
+	return m.writeSample(m.videoTrack, &muxerSample{
+		PartSample: ps,
+		dts:        pts,
+		ntp:        ntp,
+	})
+}
+
+// WriteVP9 writes a VP9 frame.
+func (m *Muxer) WriteVP9(ntp time.Time, pts time.Duration, frame []byte) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var h vp9.Header
+	err := h.Unmarshal(frame)
+	if err != nil {
+		return err
+	}
+
+	codec := m.VideoTrack.Codec.(*codecs.VP9)
+	randomAccess := false
+
+	if h.FrameType == vp9.FrameTypeKeyFrame {
+		randomAccess = true
+
+		codec.Width = h.Width()
+		codec.Height = h.Height()
+		codec.Profile = h.Profile
+		codec.BitDepth = h.ColorConfig.BitDepth
+		codec.ChromaSubsampling = h.ChromaSubsampling()
+		codec.ColorRange = h.ColorConfig.ColorRange
+	}
+
+	if !m.videoFirstRandomAccessReceived {
+		if !randomAccess {
+			return nil
+		}
+		m.videoFirstRandomAccessReceived = true
+	}
+
+	return m.writeSample(m.videoTrack, &muxerSample{
+		PartSample: &fmp4.PartSample{
+			IsNonSyncSample: !randomAccess,
+			Payload:         frame,
+		},
+		dts: pts,
+		ntp: ntp,
+	})
+}
+
+// WriteH26x writes an H264 or an H265 access unit.
+func (m *Muxer) WriteH26x(ntp time.Time, pts time.Duration, au [][]byte) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	randomAccess := false
+
+	switch codec := m.VideoTrack.Codec.(type) {
+	case *codecs.H265:
+		for _, nalu := range au {
+			typ := h265.NALUType((nalu[0] >> 1) & 0b111111)
+
+			switch typ {
+			case h265.NALUType_IDR_W_RADL, h265.NALUType_IDR_N_LP, h265.NALUType_CRA_NUT:
+				randomAccess = true
+
+			case h265.NALUType_VPS_NUT:
+				codec.VPS = nalu
+
+			case h265.NALUType_SPS_NUT:
+				codec.SPS = nalu
+
+			case h265.NALUType_PPS_NUT:
+				codec.PPS = nalu
+			}
+		}
+
+	case *codecs.H264:
+		nonIDRPresent := false
+
+		for _, nalu := range au {
+			typ := h264.NALUType(nalu[0] & 0x1F)
+
+			switch typ {
+			case h264.NALUTypeIDR:
+				randomAccess = true
+
+			case h264.NALUTypeNonIDR:
+				nonIDRPresent = true
+
+			case h264.NALUTypeSPS:
+				codec.SPS = nalu
+
+			case h264.NALUTypePPS:
+				codec.PPS = nalu
+			}
+		}
+
+		if !randomAccess && !nonIDRPresent {
+			return nil
+		}
+	}
+
+	if !m.videoFirstRandomAccessReceived {
+		// skip sample silently until we find one with an IDR
+		if !randomAccess {
+			return nil
+		}
+
+		m.videoFirstRandomAccessReceived = true
+		m.videoDTSExtractor = allocateDTSExtractor(m.VideoTrack.Codec)
+	}
+
+	dts, err := m.videoDTSExtractor.Extract(au, pts)
+	if err != nil {
+		return fmt.Errorf("unable to extract DTS: %w", err)
+	}
+
+	ps, err := fmp4.NewPartSampleH26x(
+		int32(durationGoToMp4(pts-dts, 90000)),
+		randomAccess,
+		au)
+	if err != nil {
+		return err
+	}
+
+	return m.writeSample(m.videoTrack, &muxerSample{
+		PartSample: ps,
+		dts:        dts,
+		ntp:        ntp,
+	})
+}
+
+// WriteOpus writes Opus packets.
+func (m *Muxer) WriteOpus(ntp time.Time, pts time.Duration, packets [][]byte) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, packet := range packets {
+		err := m.writeSample(m.audioTrack, &muxerSample{
+			PartSample: &fmp4.PartSample{
+				Payload: packet,
+			},
+			dts: pts,
+			ntp: ntp,
+		})
+		if err != nil {
+			return err
+		}
+
+		duration := opus.PacketDuration(packet)
+		ntp = ntp.Add(duration)
+		pts += duration
+	}
+
+	return nil
+}
+
+// WriteMPEG4Audio writes MPEG-4 Audio access units.
+func (m *Muxer) WriteMPEG4Audio(ntp time.Time, pts time.Duration, aus [][]byte) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	sampleRate := time.Duration(m.AudioTrack.Codec.(*codecs.MPEG4Audio).Config.SampleRate)
+
+	for i, au := range aus {
+		auNTP := ntp.Add(time.Duration(i) * mpeg4audio.SamplesPerAccessUnit *
+			time.Second / sampleRate)
+		auPTS := pts + time.Duration(i)*mpeg4audio.SamplesPerAccessUnit*
+			time.Second/sampleRate
+
+		err := m.writeSample(m.audioTrack, &muxerSample{
+			PartSample: &fmp4.PartSample{
+				Payload: au,
+			},
+			dts: auPTS,
+			ntp: auNTP,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *Muxer) writeSample(track *muxerTrack, sample *muxerSample) error {
+	if m.closed {
+		return nil
+	}
+
+	if !m.started {
+		// the leading track decides where the presentation starts.
+		if track != m.leadingTrack {
+			return nil
+		}
+
+		m.started = true
+		m.startDTS = sample.dts
+		m.availabilityStartTime = time.Now()
+	}
+
+	// discard samples that precede the beginning of the presentation.
+	if sample.dts < m.startDTS {
+		return nil
+	}
+
+	sample.dts -= m.startDTS
+
+	if !track.pushSample(sample) || track != m.leadingTrack {
+		return nil
+	}
+
+	// switch segment when the queued sample is a random access point.
+	if !sample.IsNonSyncSample && (sample.dts-m.segmentStartDTS) >= m.SegmentDuration {
+		m.segmentStartDTS = sample.dts
+		m.partStartDTS = sample.dts
+
+		for _, t := range m.tracks {
+			err := t.finalizeFragment(m)
+			if err != nil {
+				return err
+			}
+
+			t.trimFragments(m.SegmentCount)
+		}
+
+		m.notifyChange()
+		return nil
+	}
+
+	if m.LowLatency && (sample.dts-m.partStartDTS) >= m.PartDuration {
+		m.partStartDTS = sample.dts
+
+		for _, t := range m.tracks {
+			err := t.flushChunk(m)
+			if err != nil {
+				return err
+			}
+		}
+
+		m.notifyChange()
+	}
+
+	return nil
+}
+
+func (m *Muxer) newSequenceNumber() uint32 {
+	v := m.nextSequenceNumber
+	m.nextSequenceNumber++
+	return v
+}
+
+// Handle handles a HTTP request.
+func (m *Muxer) Handle(w http.ResponseWriter, r *http.Request) {
+	m.handle(w, r)
+}
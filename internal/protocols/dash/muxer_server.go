@@ -0,0 +1,234 @@
+package dash
+
+import (
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var (
+	reInit    = regexp.MustCompile(`^init_([0-9]+)\.mp4$`)
+	reSegment = regexp.MustCompile(`^seg_([0-9]+)_([0-9]+)\.mp4$`)
+)
+
+// maximum time spent by a request waiting for content that is not available yet.
+const waitTimeout = 30 * time.Second
+
+func (m *Muxer) findTrack(id string) *muxerTrack {
+	for _, t := range m.tracks {
+		if strconv.FormatInt(int64(t.id), 10) == id {
+			return t
+		}
+	}
+	return nil
+}
+
+func trackMimeType(t *muxerTrack) string {
+	if t.isVideo {
+		return "video/mp4"
+	}
+	return "audio/mp4"
+}
+
+// waitChange waits until the muxer content changes.
+func waitChange(r *http.Request, changed chan struct{}, timeout *time.Timer) bool {
+	select {
+	case <-changed:
+		return true
+
+	case <-timeout.C:
+		return false
+
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+func (m *Muxer) handle(w http.ResponseWriter, r *http.Request) {
+	name := path.Base(r.URL.Path)
+
+	switch {
+	case name == "index.mpd":
+		m.handleMPD(w, r)
+
+	case reInit.MatchString(name):
+		m.handleInit(w, reInit.FindStringSubmatch(name)[1])
+
+	case reSegment.MatchString(name):
+		match := reSegment.FindStringSubmatch(name)
+		number, err := strconv.ParseUint(match[2], 10, 64)
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		m.handleSegment(w, r, match[1], number)
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (m *Muxer) handleMPD(w http.ResponseWriter, r *http.Request) {
+	timeout := time.NewTimer(waitTimeout)
+	defer timeout.Stop()
+
+	m.mutex.Lock()
+
+	// wait until the leading track produces its first fragment.
+	for !m.closed && len(m.leadingTrack.fragments) == 0 {
+		changed := m.changed
+		m.mutex.Unlock()
+
+		if !waitChange(r, changed, timeout) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		m.mutex.Lock()
+	}
+
+	if m.closed {
+		m.mutex.Unlock()
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	byts, err := m.generateMPD()
+	m.mutex.Unlock()
+
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/dash+xml")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	w.Write(byts)
+}
+
+func (m *Muxer) handleInit(w http.ResponseWriter, id string) {
+	m.mutex.Lock()
+
+	t := m.findTrack(id)
+	if t == nil || len(t.fragments) == 0 {
+		m.mutex.Unlock()
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	byts, err := t.generateInit()
+	m.mutex.Unlock()
+
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", trackMimeType(t))
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	w.Write(byts)
+}
+
+func (m *Muxer) handleSegment(w http.ResponseWriter, r *http.Request, id string, number uint64) {
+	timeout := time.NewTimer(waitTimeout)
+	defer timeout.Stop()
+
+	m.mutex.Lock()
+
+	t := m.findTrack(id)
+	if t == nil {
+		m.mutex.Unlock()
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	var f *muxerFragment
+
+	for {
+		if m.closed {
+			m.mutex.Unlock()
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		f = t.findFragment(number)
+		if f != nil {
+			break
+		}
+
+		// in low-latency mode, the next segment can be requested before it is generated.
+		if !m.LowLatency || number != t.nextNumber+1 {
+			m.mutex.Unlock()
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		changed := m.changed
+		m.mutex.Unlock()
+
+		if !waitChange(r, changed, timeout) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		m.mutex.Lock()
+	}
+
+	// chunks are never modified once created, therefore they can be written without the mutex.
+	chunks := f.chunks
+	complete := f.complete
+	changed := m.changed
+	m.mutex.Unlock()
+
+	w.Header().Set("Content-Type", trackMimeType(t))
+
+	if complete {
+		w.Header().Set("Cache-Control", "max-age=3600")
+		w.WriteHeader(http.StatusOK)
+
+		for _, chunk := range chunks {
+			w.Write(chunk)
+		}
+		return
+	}
+
+	// the segment is being generated: send chunks as soon as they are available.
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	rc := http.NewResponseController(w)
+	sent := 0
+
+	for {
+		for _, chunk := range chunks[sent:] {
+			_, err := w.Write(chunk)
+			if err != nil {
+				return
+			}
+		}
+		sent = len(chunks)
+
+		err := rc.Flush()
+		if err != nil {
+			return
+		}
+
+		if complete {
+			return
+		}
+
+		if !waitChange(r, changed, timeout) {
+			return
+		}
+
+		m.mutex.Lock()
+		chunks = f.chunks
+		complete = f.complete || m.closed
+		changed = m.changed
+		m.mutex.Unlock()
+	}
+}
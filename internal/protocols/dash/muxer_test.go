@@ -0,0 +1,204 @@
+package dash
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bluenviron/gohlslib/pkg/codecs"
+	"github.com/bluenviron/mediacommon/pkg/codecs/mpeg4audio"
+	"github.com/bluenviron/mediacommon/pkg/formats/fmp4"
+	"github.com/stretchr/testify/require"
+)
+
+var testSPS = []byte{
+	0x67, 0x42, 0xc0, 0x28, 0xd9, 0x00, 0x78, 0x02,
+	0x27, 0xe5, 0x84, 0x00, 0x00, 0x03, 0x00, 0x04,
+	0x00, 0x00, 0x03, 0x00, 0xf0, 0x3c, 0x60, 0xc9,
+	0x20,
+}
+
+func newTestMuxer(t *testing.T, lowLatency bool) *Muxer {
+	m := &Muxer{
+		SegmentCount:    3,
+		SegmentDuration: 1 * time.Second,
+		PartDuration:    200 * time.Millisecond,
+		LowLatency:      lowLatency,
+		VideoTrack: &Track{
+			Codec: &codecs.H264{
+				SPS: testSPS,
+				PPS: []byte{0x08},
+			},
+		},
+		AudioTrack: &Track{
+			Codec: &codecs.MPEG4Audio{
+				Config: mpeg4audio.Config{
+					Type:         2,
+					SampleRate:   44100,
+					ChannelCount: 2,
+				},
+			},
+		},
+	}
+	err := m.Start()
+	require.NoError(t, err)
+	return m
+}
+
+func writeTestSamples(t *testing.T, m *Muxer, from int, to int) {
+	for i := from; i < to; i++ {
+		pts := time.Duration(i) * 100 * time.Millisecond
+
+		au := [][]byte{{1}} // non-IDR
+		if i%10 == 0 {
+			au = [][]byte{testSPS, {0x08}, {5}} // IDR
+		}
+
+		err := m.WriteH26x(time.Time{}, pts, au)
+		require.NoError(t, err)
+
+		err = m.WriteMPEG4Audio(time.Time{}, pts, [][]byte{{1, 2, 3, 4}})
+		require.NoError(t, err)
+	}
+}
+
+func doRequest(m *Muxer, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/"+path, nil)
+	m.Handle(w, r)
+	return w
+}
+
+func TestMuxer(t *testing.T) {
+	m := newTestMuxer(t, false)
+	defer m.Close()
+
+	// samples before the first IDR are discarded
+	err := m.WriteH26x(time.Time{}, 0, [][]byte{{1}})
+	require.NoError(t, err)
+
+	writeTestSamples(t, m, 0, 31)
+
+	res := doRequest(m, "index.mpd")
+	require.Equal(t, http.StatusOK, res.Code)
+	require.Equal(t, "application/dash+xml", res.Header().Get("Content-Type"))
+
+	var doc mpd
+	err = xml.Unmarshal(res.Body.Bytes(), &doc)
+	require.NoError(t, err)
+
+	require.Equal(t, "dynamic", doc.Type)
+	require.Len(t, doc.Period.AdaptationSets, 2)
+
+	video := doc.Period.AdaptationSets[0]
+	require.Equal(t, "video", video.ContentType)
+	require.Equal(t, "avc1.42c028", video.Representation.Codecs)
+	require.Equal(t, uint32(90000), video.Representation.SegmentTemplate.Timescale)
+	require.Equal(t, "init_1.mp4", video.Representation.SegmentTemplate.Initialization)
+	require.Equal(t, "seg_1_$Number$.mp4", video.Representation.SegmentTemplate.Media)
+	require.Equal(t, uint64(1), video.Representation.SegmentTemplate.StartNumber)
+	require.Equal(t, []mpdS{
+		{T: 0, D: 90000},
+		{T: 90000, D: 90000},
+		{T: 180000, D: 90000},
+	}, video.Representation.SegmentTemplate.Timeline)
+
+	audio := doc.Period.AdaptationSets[1]
+	require.Equal(t, "audio", audio.ContentType)
+	require.Equal(t, "mp4a.40.2", audio.Representation.Codecs)
+	require.Equal(t, uint32(44100), audio.Representation.AudioSamplingRate)
+
+	res = doRequest(m, "init_1.mp4")
+	require.Equal(t, http.StatusOK, res.Code)
+
+	var init fmp4.Init
+	err = init.Unmarshal(bytes.NewReader(res.Body.Bytes()))
+	require.NoError(t, err)
+	require.Len(t, init.Tracks, 1)
+	require.Equal(t, 1, init.Tracks[0].ID)
+	require.Equal(t, uint32(90000), init.Tracks[0].TimeScale)
+
+	res = doRequest(m, "seg_1_2.mp4")
+	require.Equal(t, http.StatusOK, res.Code)
+
+	var parts fmp4.Parts
+	err = parts.Unmarshal(res.Body.Bytes())
+	require.NoError(t, err)
+	require.Len(t, parts, 1)
+	require.Equal(t, 1, parts[0].Tracks[0].ID)
+	require.Equal(t, uint64(90000), parts[0].Tracks[0].BaseTime)
+	require.Len(t, parts[0].Tracks[0].Samples, 10)
+	require.False(t, parts[0].Tracks[0].Samples[0].IsNonSyncSample)
+	require.True(t, parts[0].Tracks[0].Samples[1].IsNonSyncSample)
+
+	res = doRequest(m, "seg_1_4.mp4")
+	require.Equal(t, http.StatusNotFound, res.Code)
+
+	// old segments are removed
+	writeTestSamples(t, m, 31, 41)
+
+	res = doRequest(m, "seg_1_1.mp4")
+	require.Equal(t, http.StatusNotFound, res.Code)
+
+	res = doRequest(m, "seg_1_4.mp4")
+	require.Equal(t, http.StatusOK, res.Code)
+}
+
+func TestMuxerLowLatency(t *testing.T) {
+	m := newTestMuxer(t, true)
+	defer m.Close()
+
+	writeTestSamples(t, m, 0, 11)
+
+	res := doRequest(m, "index.mpd")
+	require.Equal(t, http.StatusOK, res.Code)
+
+	var doc mpd
+	err := xml.Unmarshal(res.Body.Bytes(), &doc)
+	require.NoError(t, err)
+
+	require.NotNil(t, doc.ServiceDescription)
+	require.Equal(t, 0.8, doc.Period.AdaptationSets[0].Representation.SegmentTemplate.AvailabilityTimeOffset)
+
+	s := httptest.NewServer(http.HandlerFunc(m.Handle))
+	defer s.Close()
+
+	done := make(chan *http.Response)
+
+	// the next segment is requested before it is generated
+	go func() {
+		hres, err2 := http.Get(s.URL + "/seg_1_2.mp4")
+		if err2 != nil {
+			close(done)
+			return
+		}
+		done <- hres
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+
+	writeTestSamples(t, m, 11, 21)
+
+	hres := <-done
+	require.NotNil(t, hres)
+	defer hres.Body.Close()
+	require.Equal(t, http.StatusOK, hres.StatusCode)
+
+	byts, err := io.ReadAll(hres.Body)
+	require.NoError(t, err)
+
+	var parts fmp4.Parts
+	err = parts.Unmarshal(byts)
+	require.NoError(t, err)
+	require.Greater(t, len(parts), 1)
+
+	samples := 0
+	for _, part := range parts {
+		samples += len(part.Tracks[0].Samples)
+	}
+	require.Equal(t, 10, samples)
+}
@@ -0,0 +1,177 @@
+package dash
+
+import (
+	"time"
+
+	"github.com/bluenviron/gohlslib/pkg/codecs"
+	"github.com/bluenviron/mediacommon/pkg/formats/fmp4"
+	"github.com/bluenviron/mediacommon/pkg/formats/fmp4/seekablebuffer"
+)
+
+type muxerSample struct {
+	*fmp4.PartSample
+	dts time.Duration
+	ntp time.Time
+}
+
+// muxerFragment is a segment of a track.
+// In low-latency mode, it is made of multiple chunks, each one with its own moof and mdat.
+type muxerFragment struct {
+	number    uint64
+	startTime uint64
+	duration  uint64
+	chunks    [][]byte
+	size      uint64
+	complete  bool
+}
+
+type muxerTrack struct {
+	id        int
+	track     *Track
+	isVideo   bool
+	timeScale uint32
+
+	nextSample       *muxerSample
+	pendingSamples   []*fmp4.PartSample
+	pendingStartTime uint64
+	current          *muxerFragment
+	fragments        []*muxerFragment
+	nextNumber       uint64
+}
+
+// pushSample queues a sample and moves the previous one into the current fragment,
+// since its duration can now be computed. It returns true if a sample has been moved.
+func (t *muxerTrack) pushSample(sample *muxerSample) bool {
+	prev := t.nextSample
+	t.nextSample = sample
+
+	if prev == nil {
+		return false
+	}
+
+	prevTime := durationGoToMp4(prev.dts, t.timeScale)
+	curTime := durationGoToMp4(sample.dts, t.timeScale)
+
+	if curTime > prevTime {
+		prev.Duration = uint32(curTime - prevTime)
+	} else {
+		prev.Duration = 0
+	}
+
+	if t.current == nil {
+		t.nextNumber++
+		t.current = &muxerFragment{
+			number:    t.nextNumber,
+			startTime: prevTime,
+		}
+	}
+
+	if len(t.pendingSamples) == 0 {
+		t.pendingStartTime = prevTime
+	}
+
+	t.pendingSamples = append(t.pendingSamples, prev.PartSample)
+	t.current.duration += uint64(prev.Duration)
+
+	return true
+}
+
+// flushChunk encodes pending samples into a chunk of the current fragment.
+func (t *muxerTrack) flushChunk(m *Muxer) error {
+	if len(t.pendingSamples) == 0 {
+		return nil
+	}
+
+	part := &fmp4.Part{
+		SequenceNumber: m.newSequenceNumber(),
+		Tracks: []*fmp4.PartTrack{{
+			ID:       t.id,
+			BaseTime: t.pendingStartTime,
+			Samples:  t.pendingSamples,
+		}},
+	}
+
+	var buf seekablebuffer.Buffer
+	err := part.Marshal(&buf)
+	if err != nil {
+		return err
+	}
+
+	t.current.chunks = append(t.current.chunks, buf.Bytes())
+	t.current.size += uint64(buf.Len())
+	t.pendingSamples = nil
+
+	return nil
+}
+
+func (t *muxerTrack) finalizeFragment(m *Muxer) error {
+	if t.current == nil {
+		return nil
+	}
+
+	err := t.flushChunk(m)
+	if err != nil {
+		return err
+	}
+
+	t.current.complete = true
+	t.fragments = append(t.fragments, t.current)
+	t.current = nil
+
+	return nil
+}
+
+func (t *muxerTrack) trimFragments(count int) {
+	if len(t.fragments) > count {
+		t.fragments = t.fragments[len(t.fragments)-count:]
+	}
+}
+
+func (t *muxerTrack) findFragment(number uint64) *muxerFragment {
+	for _, f := range t.fragments {
+		if f.number == number {
+			return f
+		}
+	}
+
+	if t.current != nil && t.current.number == number {
+		return t.current
+	}
+
+	return nil
+}
+
+// bandwidth returns the average bitrate of completed fragments.
+func (t *muxerTrack) bandwidth() uint64 {
+	var size uint64
+	var duration uint64
+
+	for _, f := range t.fragments {
+		size += f.size
+		duration += f.duration
+	}
+
+	if duration == 0 {
+		return 0
+	}
+
+	return size * 8 * uint64(t.timeScale) / duration
+}
+
+func (t *muxerTrack) generateInit() ([]byte, error) {
+	init := &fmp4.Init{
+		Tracks: []*fmp4.InitTrack{{
+			ID:        t.id,
+			TimeScale: t.timeScale,
+			Codec:     codecs.ToFMP4(t.track.Codec),
+		}},
+	}
+
+	var buf seekablebuffer.Buffer
+	err := init.Marshal(&buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
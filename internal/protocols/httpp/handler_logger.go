@@ -1,8 +1,10 @@
 package httpp
 
 import (
+	"bufio"
 	"bytes"
 	"fmt"
+	"net"
 	"net/http"
 	"net/http/httputil"
 
@@ -12,7 +14,7 @@ import (
 type loggerWriter struct {
 	w      http.ResponseWriter
 	status int
-	buf    bytes.Buffer
+	size   int
 }
 
 func (w *loggerWriter) Header() http.Header {
@@ -23,8 +25,9 @@ func (w *loggerWriter) Write(b []byte) (int, error) {
 	if w.status == 0 {
 		w.status = http.StatusOK
 	}
-	w.buf.Write(b)
-	return w.w.Write(b)
+	n, err := w.w.Write(b)
+	w.size += n
+	return n, err
 }
 
 func (w *loggerWriter) WriteHeader(statusCode int) {
@@ -32,13 +35,39 @@ func (w *loggerWriter) WriteHeader(statusCode int) {
 	w.w.WriteHeader(statusCode)
 }
 
+// Flush implements http.Flusher, needed by streaming responses.
+func (w *loggerWriter) Flush() {
+	if f, ok := w.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying http.ResponseWriter, needed by http.ResponseController.
+func (w *loggerWriter) Unwrap() http.ResponseWriter {
+	return w.w
+}
+
+// Hijack implements http.Hijacker, needed by WebSocket connections.
+func (w *loggerWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.w.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("hijacking is not supported")
+	}
+
+	if w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+
+	return h.Hijack()
+}
+
 func (w *loggerWriter) dump() string {
 	var buf bytes.Buffer
 	fmt.Fprintf(&buf, "%s %d %s\n", "HTTP/1.1", w.status, http.StatusText(w.status))
 	w.w.Header().Write(&buf) //nolint:errcheck
 	buf.Write([]byte("\n"))
-	if w.buf.Len() > 0 {
-		fmt.Fprintf(&buf, "(body of %d bytes)", w.buf.Len())
+	if w.size > 0 {
+		fmt.Fprintf(&buf, "(body of %d bytes)", w.size)
 	}
 	return buf.String()
 }
@@ -50,12 +79,17 @@ type handlerLogger struct {
 }
 
 func (h *handlerLogger) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	connAttr := logger.Attr{
+		Text:   "[conn " + r.RemoteAddr + "] ",
+		Fields: []logger.Field{{Key: "remoteAddr", Value: r.RemoteAddr}},
+	}
+
 	byts, _ := httputil.DumpRequest(r, true)
-	h.log.Log(logger.Debug, "[conn %v] [c->s] %s", r.RemoteAddr, string(byts))
+	h.log.Log(logger.Debug, "%v[c->s] %s", connAttr, string(byts))
 
 	logw := &loggerWriter{w: w}
 
 	h.Handler.ServeHTTP(logw, r)
 
-	h.log.Log(logger.Debug, "[conn %v] [s->c] %s", r.RemoteAddr, logw.dump())
+	h.log.Log(logger.Debug, "%v[s->c] %s", connAttr, logw.dump())
 }
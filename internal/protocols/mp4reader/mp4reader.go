@@ -0,0 +1,200 @@
+// Package mp4reader contains utilities to read MP4 files.
+package mp4reader
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/abema/go-mp4"
+)
+
+const (
+	sampleFlagIsNonSyncSample = 1 << 16
+)
+
+// ErrStop can be returned by the callback of ReadTrackRuns
+// in order to stop reading at the end of the current fragment.
+var ErrStop = errors.New("stop")
+
+// DurationMp4ToGo converts a MP4 timestamp into a time.Duration.
+func DurationMp4ToGo(v int64, timeScale uint32) time.Duration {
+	timeScale64 := int64(timeScale)
+	secs := v / timeScale64
+	dec := v % timeScale64
+	return time.Duration(secs)*time.Second + time.Duration(dec)*time.Second/time.Duration(timeScale64)
+}
+
+// DurationGoToMp4 converts a time.Duration into a MP4 timestamp.
+func DurationGoToMp4(v time.Duration, timeScale uint32) int64 {
+	timeScale64 := int64(timeScale)
+	secs := v / time.Second
+	dec := v % time.Second
+	return int64(secs)*timeScale64 + int64(dec)*timeScale64/int64(time.Second)
+}
+
+// Sample is a sample of a MP4 file.
+type Sample struct {
+	DTS             int64
+	PTSOffset       int32
+	Duration        uint32
+	IsNonSyncSample bool
+	Offset          uint64
+	Size            uint32
+}
+
+// TrackRun is a sequence of contiguous samples of a track,
+// contained into a fragment of a fragmented MP4 file.
+type TrackRun struct {
+	TrackID  int
+	BaseTime uint64
+	Samples  []*Sample
+}
+
+func newTrackRun(tfhd *mp4.Tfhd, tfdt *mp4.Tfdt, trun *mp4.Trun, dataOffset uint64) *TrackRun {
+	run := &TrackRun{
+		TrackID:  int(tfhd.TrackID),
+		BaseTime: tfdt.GetBaseMediaDecodeTime(),
+		Samples:  make([]*Sample, len(trun.Entries)),
+	}
+
+	dts := int64(run.BaseTime)
+
+	for i, e := range trun.Entries {
+		sa := &Sample{
+			DTS:    dts,
+			Offset: dataOffset,
+		}
+
+		// fields that are not present in the trun box
+		// are filled with the defaults of the tfhd box.
+
+		switch {
+		case trun.CheckFlag(0x100):
+			sa.Duration = e.SampleDuration
+		case tfhd.CheckFlag(mp4.TfhdDefaultSampleDurationPresent):
+			sa.Duration = tfhd.DefaultSampleDuration
+		}
+
+		switch {
+		case trun.CheckFlag(0x200):
+			sa.Size = e.SampleSize
+		case tfhd.CheckFlag(mp4.TfhdDefaultSampleSizePresent):
+			sa.Size = tfhd.DefaultSampleSize
+		}
+
+		var flags uint32
+
+		switch {
+		case trun.CheckFlag(0x400):
+			flags = e.SampleFlags
+		case i == 0 && trun.CheckFlag(0x004):
+			flags = trun.FirstSampleFlags
+		case tfhd.CheckFlag(mp4.TfhdDefaultSampleFlagsPresent):
+			flags = tfhd.DefaultSampleFlags
+		}
+
+		sa.IsNonSyncSample = (flags & sampleFlagIsNonSyncSample) != 0
+
+		if trun.CheckFlag(0x800) {
+			sa.PTSOffset = int32(trun.GetSampleCompositionTimeOffset(i))
+		}
+
+		run.Samples[i] = sa
+		dts += int64(sa.Duration)
+		dataOffset += uint64(sa.Size)
+	}
+
+	return run
+}
+
+// ReadTrackRuns reads the track runs of a fragmented MP4 file, in the order they appear.
+func ReadTrackRuns(r io.ReadSeeker, onTrackRun func(*TrackRun) error) error {
+	var moofOffset uint64
+	var tfhd *mp4.Tfhd
+	var tfdt *mp4.Tfdt
+	var dataOffset uint64
+	stopAtNextMdat := false
+
+	_, err := mp4.ReadBoxStructure(r, func(h *mp4.ReadHandle) (interface{}, error) {
+		switch h.BoxInfo.Type.String() {
+		case "moof":
+			moofOffset = h.BoxInfo.Offset
+			return h.Expand()
+
+		case "traf":
+			tfhd = nil
+			tfdt = nil
+			return h.Expand()
+
+		case "tfhd":
+			box, _, err := h.ReadPayload()
+			if err != nil {
+				return nil, err
+			}
+			tfhd = box.(*mp4.Tfhd)
+
+			if tfhd.CheckFlag(mp4.TfhdBaseDataOffsetPresent) {
+				dataOffset = tfhd.BaseDataOffset
+			} else {
+				dataOffset = moofOffset
+			}
+
+		case "tfdt":
+			box, _, err := h.ReadPayload()
+			if err != nil {
+				return nil, err
+			}
+			tfdt = box.(*mp4.Tfdt)
+
+		case "trun":
+			if tfhd == nil || tfdt == nil {
+				return nil, fmt.Errorf("tfhd or tfdt box not found")
+			}
+
+			box, _, err := h.ReadPayload()
+			if err != nil {
+				return nil, err
+			}
+			trun := box.(*mp4.Trun)
+
+			// when the data offset is not present, data of the run
+			// starts after the data of the previous run.
+			if trun.CheckFlag(0x001) {
+				if tfhd.CheckFlag(mp4.TfhdBaseDataOffsetPresent) {
+					dataOffset = tfhd.BaseDataOffset
+				} else {
+					dataOffset = moofOffset
+				}
+				dataOffset += uint64(trun.DataOffset)
+			}
+
+			run := newTrackRun(tfhd, tfdt, trun, dataOffset)
+
+			if len(run.Samples) != 0 {
+				last := run.Samples[len(run.Samples)-1]
+				dataOffset = last.Offset + uint64(last.Size)
+			}
+
+			err = onTrackRun(run)
+			if err != nil {
+				if !errors.Is(err, ErrStop) {
+					return nil, err
+				}
+				stopAtNextMdat = true
+			}
+
+		case "mdat":
+			if stopAtNextMdat {
+				return nil, ErrStop
+			}
+		}
+		return nil, nil
+	})
+	if err != nil && !errors.Is(err, ErrStop) {
+		return err
+	}
+
+	return nil
+}
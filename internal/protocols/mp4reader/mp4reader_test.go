@@ -0,0 +1,96 @@
+package mp4reader
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/bluenviron/mediacommon/pkg/formats/fmp4"
+	"github.com/bluenviron/mediacommon/pkg/formats/fmp4/seekablebuffer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDuration(t *testing.T) {
+	require.Equal(t, 1500*time.Millisecond, DurationMp4ToGo(135000, 90000))
+	require.Equal(t, int64(135000), DurationGoToMp4(1500*time.Millisecond, 90000))
+}
+
+func TestReadTrackRuns(t *testing.T) {
+	var buf seekablebuffer.Buffer
+
+	for i := 0; i < 2; i++ {
+		part := fmp4.Part{
+			SequenceNumber: uint32(i),
+			Tracks: []*fmp4.PartTrack{
+				{
+					ID:       1,
+					BaseTime: uint64(i) * 180000,
+					Samples: []*fmp4.PartSample{
+						{
+							Duration:  90000,
+							PTSOffset: 90000,
+							Payload:   []byte{1, 2, 3},
+						},
+						{
+							Duration:        90000,
+							IsNonSyncSample: true,
+							Payload:         []byte{4, 5},
+						},
+					},
+				},
+				{
+					ID:       2,
+					BaseTime: uint64(i) * 96000,
+					Samples: []*fmp4.PartSample{{
+						Duration: 96000,
+						Payload:  []byte{6, 7, 8, 9},
+					}},
+				},
+			},
+		}
+		err := part.Marshal(&buf)
+		require.NoError(t, err)
+	}
+
+	byts := buf.Bytes()
+
+	var runs []*TrackRun
+
+	err := ReadTrackRuns(bytes.NewReader(byts), func(run *TrackRun) error {
+		runs = append(runs, run)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, runs, 4)
+
+	require.Equal(t, 1, runs[2].TrackID)
+	require.Equal(t, uint64(180000), runs[2].BaseTime)
+	require.Len(t, runs[2].Samples, 2)
+
+	sa := runs[2].Samples[0]
+	require.Equal(t, int64(180000), sa.DTS)
+	require.Equal(t, int32(90000), sa.PTSOffset)
+	require.Equal(t, uint32(90000), sa.Duration)
+	require.Equal(t, false, sa.IsNonSyncSample)
+	require.Equal(t, []byte{1, 2, 3}, byts[sa.Offset:sa.Offset+uint64(sa.Size)])
+
+	sa = runs[2].Samples[1]
+	require.Equal(t, int64(270000), sa.DTS)
+	require.Equal(t, true, sa.IsNonSyncSample)
+	require.Equal(t, []byte{4, 5}, byts[sa.Offset:sa.Offset+uint64(sa.Size)])
+
+	sa = runs[3].Samples[0]
+	require.Equal(t, 2, runs[3].TrackID)
+	require.Equal(t, int64(96000), sa.DTS)
+	require.Equal(t, []byte{6, 7, 8, 9}, byts[sa.Offset:sa.Offset+uint64(sa.Size)])
+
+	// reading is stopped at the end of the current fragment.
+	runs = nil
+
+	err = ReadTrackRuns(bytes.NewReader(byts), func(run *TrackRun) error {
+		runs = append(runs, run)
+		return ErrStop
+	})
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+}
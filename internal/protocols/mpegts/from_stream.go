@@ -10,7 +10,6 @@ import (
 	"github.com/bluenviron/mediacommon/pkg/codecs/h264"
 	"github.com/bluenviron/mediacommon/pkg/codecs/h265"
 	mcmpegts "github.com/bluenviron/mediacommon/pkg/formats/mpegts"
-	srt "github.com/datarhei/gosrt"
 
 	"github.com/bluenviron/mediamtx/internal/asyncwriter"
 	"github.com/bluenviron/mediamtx/internal/stream"
@@ -21,12 +20,17 @@ func durationGoToMPEGTS(v time.Duration) int64 {
 	return int64(v.Seconds() * 90000)
 }
 
+// writeDeadlineSetter is implemented by srt.Conn, net.Conn and http.ResponseController.
+type writeDeadlineSetter interface {
+	SetWriteDeadline(t time.Time) error
+}
+
 // FromStream links a server stream to a MPEG-TS writer.
 func FromStream(
 	stream *stream.Stream,
 	writer *asyncwriter.Writer,
 	bw *bufio.Writer,
-	sconn srt.Conn,
+	conn writeDeadlineSetter,
 	writeTimeout time.Duration,
 ) error {
 	var w *mcmpegts.Writer
@@ -68,7 +72,7 @@ func FromStream(
 						return err
 					}
 
-					sconn.SetWriteDeadline(time.Now().Add(writeTimeout))
+					conn.SetWriteDeadline(time.Now().Add(writeTimeout))
 					err = (*w).WriteH26x(track, durationGoToMPEGTS(tunit.PTS), durationGoToMPEGTS(dts), randomAccess, tunit.AU)
 					if err != nil {
 						return err
@@ -101,7 +105,7 @@ func FromStream(
 						return err
 					}
 
-					sconn.SetWriteDeadline(time.Now().Add(writeTimeout))
+					conn.SetWriteDeadline(time.Now().Add(writeTimeout))
 					err = (*w).WriteH26x(track, durationGoToMPEGTS(tunit.PTS), durationGoToMPEGTS(dts), idrPresent, tunit.AU)
 					if err != nil {
 						return err
@@ -128,7 +132,7 @@ func FromStream(
 					}
 					lastPTS = tunit.PTS
 
-					sconn.SetWriteDeadline(time.Now().Add(writeTimeout))
+					conn.SetWriteDeadline(time.Now().Add(writeTimeout))
 					err := (*w).WriteMPEG4Video(track, durationGoToMPEGTS(tunit.PTS), tunit.Frame)
 					if err != nil {
 						return err
@@ -155,7 +159,7 @@ func FromStream(
 					}
 					lastPTS = tunit.PTS
 
-					sconn.SetWriteDeadline(time.Now().Add(writeTimeout))
+					conn.SetWriteDeadline(time.Now().Add(writeTimeout))
 					err := (*w).WriteMPEG1Video(track, durationGoToMPEGTS(tunit.PTS), tunit.Frame)
 					if err != nil {
 						return err
@@ -179,7 +183,7 @@ func FromStream(
 						return nil
 					}
 
-					sconn.SetWriteDeadline(time.Now().Add(writeTimeout))
+					conn.SetWriteDeadline(time.Now().Add(writeTimeout))
 					err := (*w).WriteOpus(track, durationGoToMPEGTS(tunit.PTS), tunit.Packets)
 					if err != nil {
 						return err
@@ -198,7 +202,7 @@ func FromStream(
 						return nil
 					}
 
-					sconn.SetWriteDeadline(time.Now().Add(writeTimeout))
+					conn.SetWriteDeadline(time.Now().Add(writeTimeout))
 					err := (*w).WriteMPEG4Audio(track, durationGoToMPEGTS(tunit.PTS), tunit.AUs)
 					if err != nil {
 						return err
@@ -215,7 +219,7 @@ func FromStream(
 						return nil
 					}
 
-					sconn.SetWriteDeadline(time.Now().Add(writeTimeout))
+					conn.SetWriteDeadline(time.Now().Add(writeTimeout))
 					err := (*w).WriteMPEG1Audio(track, durationGoToMPEGTS(tunit.PTS), tunit.Frames)
 					if err != nil {
 						return err
@@ -238,7 +242,7 @@ func FromStream(
 						framePTS := tunit.PTS + time.Duration(i)*ac3.SamplesPerFrame*
 							time.Second/sampleRate
 
-						sconn.SetWriteDeadline(time.Now().Add(writeTimeout))
+						conn.SetWriteDeadline(time.Now().Add(writeTimeout))
 						err := (*w).WriteAC3(track, durationGoToMPEGTS(framePTS), frame)
 						if err != nil {
 							return err
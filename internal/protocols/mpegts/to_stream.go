@@ -19,6 +19,19 @@ var ErrNoTracks = errors.New("no supported tracks found (supported are H265, H26
 
 // ToStream converts a MPEG-TS stream to a server stream.
 func ToStream(r *mpegts.Reader, stream **stream.Stream) ([]*description.Media, error) {
+	return ToUnits(r, func(medi *description.Media, _ time.Duration, u unit.Unit) error {
+		(*stream).WriteUnit(medi, medi.Formats[0], u)
+		return nil
+	})
+}
+
+// ToUnits converts a MPEG-TS stream to units, that are passed to onUnit
+// together with the media they belong to and their DTS.
+// Errors returned by onUnit are returned by r.Read().
+func ToUnits(
+	r *mpegts.Reader,
+	onUnit func(*description.Media, time.Duration, unit.Unit) error,
+) ([]*description.Media, error) {
 	var medias []*description.Media //nolint:prealloc
 
 	var td *mpegts.TimeDecoder
@@ -41,15 +54,15 @@ func ToStream(r *mpegts.Reader, stream **stream.Stream) ([]*description.Media, e
 				}},
 			}
 
-			r.OnDataH26x(track, func(pts int64, _ int64, au [][]byte) error {
-				(*stream).WriteUnit(medi, medi.Formats[0], &unit.H265{
+			r.OnDataH26x(track, func(pts int64, dts int64, au [][]byte) error {
+				u := &unit.H265{
 					Base: unit.Base{
 						NTP: time.Now(),
 						PTS: decodeTime(pts),
 					},
 					AU: au,
-				})
-				return nil
+				}
+				return onUnit(medi, decodeTime(dts), u)
 			})
 
 		case *mpegts.CodecH264:
@@ -61,15 +74,15 @@ func ToStream(r *mpegts.Reader, stream **stream.Stream) ([]*description.Media, e
 				}},
 			}
 
-			r.OnDataH26x(track, func(pts int64, _ int64, au [][]byte) error {
-				(*stream).WriteUnit(medi, medi.Formats[0], &unit.H264{
+			r.OnDataH26x(track, func(pts int64, dts int64, au [][]byte) error {
+				u := &unit.H264{
 					Base: unit.Base{
 						NTP: time.Now(),
 						PTS: decodeTime(pts),
 					},
 					AU: au,
-				})
-				return nil
+				}
+				return onUnit(medi, decodeTime(dts), u)
 			})
 
 		case *mpegts.CodecMPEG4Video:
@@ -81,14 +94,13 @@ func ToStream(r *mpegts.Reader, stream **stream.Stream) ([]*description.Media, e
 			}
 
 			r.OnDataMPEGxVideo(track, func(pts int64, frame []byte) error {
-				(*stream).WriteUnit(medi, medi.Formats[0], &unit.MPEG4Video{
+				return onUnit(medi, decodeTime(pts), &unit.MPEG4Video{
 					Base: unit.Base{
 						NTP: time.Now(),
 						PTS: decodeTime(pts),
 					},
 					Frame: frame,
 				})
-				return nil
 			})
 
 		case *mpegts.CodecMPEG1Video:
@@ -98,14 +110,13 @@ func ToStream(r *mpegts.Reader, stream **stream.Stream) ([]*description.Media, e
 			}
 
 			r.OnDataMPEGxVideo(track, func(pts int64, frame []byte) error {
-				(*stream).WriteUnit(medi, medi.Formats[0], &unit.MPEG1Video{
+				return onUnit(medi, decodeTime(pts), &unit.MPEG1Video{
 					Base: unit.Base{
 						NTP: time.Now(),
 						PTS: decodeTime(pts),
 					},
 					Frame: frame,
 				})
-				return nil
 			})
 
 		case *mpegts.CodecOpus:
@@ -118,14 +129,13 @@ func ToStream(r *mpegts.Reader, stream **stream.Stream) ([]*description.Media, e
 			}
 
 			r.OnDataOpus(track, func(pts int64, packets [][]byte) error {
-				(*stream).WriteUnit(medi, medi.Formats[0], &unit.Opus{
+				return onUnit(medi, decodeTime(pts), &unit.Opus{
 					Base: unit.Base{
 						NTP: time.Now(),
 						PTS: decodeTime(pts),
 					},
 					Packets: packets,
 				})
-				return nil
 			})
 
 		case *mpegts.CodecMPEG4Audio:
@@ -141,14 +151,13 @@ func ToStream(r *mpegts.Reader, stream **stream.Stream) ([]*description.Media, e
 			}
 
 			r.OnDataMPEG4Audio(track, func(pts int64, aus [][]byte) error {
-				(*stream).WriteUnit(medi, medi.Formats[0], &unit.MPEG4Audio{
+				return onUnit(medi, decodeTime(pts), &unit.MPEG4Audio{
 					Base: unit.Base{
 						NTP: time.Now(),
 						PTS: decodeTime(pts),
 					},
 					AUs: aus,
 				})
-				return nil
 			})
 
 		case *mpegts.CodecMPEG1Audio:
@@ -158,14 +167,13 @@ func ToStream(r *mpegts.Reader, stream **stream.Stream) ([]*description.Media, e
 			}
 
 			r.OnDataMPEG1Audio(track, func(pts int64, frames [][]byte) error {
-				(*stream).WriteUnit(medi, medi.Formats[0], &unit.MPEG1Audio{
+				return onUnit(medi, decodeTime(pts), &unit.MPEG1Audio{
 					Base: unit.Base{
 						NTP: time.Now(),
 						PTS: decodeTime(pts),
 					},
 					Frames: frames,
 				})
-				return nil
 			})
 
 		case *mpegts.CodecAC3:
@@ -179,14 +187,13 @@ func ToStream(r *mpegts.Reader, stream **stream.Stream) ([]*description.Media, e
 			}
 
 			r.OnDataAC3(track, func(pts int64, frame []byte) error {
-				(*stream).WriteUnit(medi, medi.Formats[0], &unit.AC3{
+				return onUnit(medi, decodeTime(pts), &unit.AC3{
 					Base: unit.Base{
 						NTP: time.Now(),
 						PTS: decodeTime(pts),
 					},
 					Frames: [][]byte{frame},
 				})
-				return nil
 			})
 
 		default:
@@ -0,0 +1,111 @@
+package rtmp
+
+import (
+	"encoding/binary"
+	"io"
+	"time"
+
+	"github.com/bluenviron/mediamtx/internal/protocols/rtmp/amf0"
+	"github.com/bluenviron/mediamtx/internal/protocols/rtmp/bytecounter"
+	"github.com/bluenviron/mediamtx/internal/protocols/rtmp/message"
+)
+
+const (
+	flvHeaderSize    = 9
+	flvTagHeaderSize = 11
+)
+
+func flvHeaderFlags(msg message.Message) byte {
+	if tmsg, ok := msg.(*message.DataAMF0); ok {
+		for _, v := range tmsg.Payload {
+			if obj, ok := v.(amf0.Object); ok {
+				var flags byte
+
+				if v, ok := obj.Get("audiocodecid"); ok && v != float64(0) {
+					flags |= 0x04
+				}
+
+				if v, ok := obj.Get("videocodecid"); ok && v != float64(0) {
+					flags |= 0x01
+				}
+
+				return flags
+			}
+		}
+	}
+
+	return 0x05
+}
+
+// FLVConn writes messages as FLV tags into a io.Writer.
+// It can be used in place of Conn to mux streams into FLV.
+type FLVConn struct {
+	bc            *bytecounter.Writer
+	headerWritten bool
+}
+
+// NewFLVConn allocates a FLVConn.
+func NewFLVConn(w io.Writer) *FLVConn {
+	return &FLVConn{
+		bc: bytecounter.NewWriter(w),
+	}
+}
+
+// BytesSent returns the number of bytes sent.
+func (c *FLVConn) BytesSent() uint64 {
+	return c.bc.Count()
+}
+
+// Write writes a message.
+func (c *FLVConn) Write(msg message.Message) error {
+	if !c.headerWritten {
+		// audio and video flags are filled with the content of the first message,
+		// that contains stream metadata.
+		buf := make([]byte, flvHeaderSize+4)
+		copy(buf, "FLV")
+		buf[3] = 1 // version
+		buf[4] = flvHeaderFlags(msg)
+		binary.BigEndian.PutUint32(buf[5:], flvHeaderSize)
+
+		_, err := c.bc.Write(buf)
+		if err != nil {
+			return err
+		}
+
+		c.headerWritten = true
+	}
+
+	// in FLV, metadata is stored without the @setDataFrame prefix
+	if tmsg, ok := msg.(*message.DataAMF0); ok &&
+		len(tmsg.Payload) >= 1 && tmsg.Payload[0] == "@setDataFrame" {
+		msg = &message.DataAMF0{
+			ChunkStreamID:   tmsg.ChunkStreamID,
+			MessageStreamID: tmsg.MessageStreamID,
+			Payload:         tmsg.Payload[1:],
+		}
+	}
+
+	raw, err := message.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	ts := uint32(raw.Timestamp / time.Millisecond)
+	bodyLen := len(raw.Body)
+
+	buf := make([]byte, flvTagHeaderSize+bodyLen+4)
+	buf[0] = raw.Type
+	buf[1] = byte(bodyLen >> 16)
+	buf[2] = byte(bodyLen >> 8)
+	buf[3] = byte(bodyLen)
+	buf[4] = byte(ts >> 16)
+	buf[5] = byte(ts >> 8)
+	buf[6] = byte(ts)
+	buf[7] = byte(ts >> 24)
+	// stream ID is always zero
+	copy(buf[flvTagHeaderSize:], raw.Body)
+	binary.BigEndian.PutUint32(buf[flvTagHeaderSize+bodyLen:], uint32(flvTagHeaderSize+bodyLen))
+
+	_, err = c.bc.Write(buf)
+	return err
+}
@@ -0,0 +1,337 @@
+package rtmp
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/format"
+	"github.com/bluenviron/mediacommon/pkg/codecs/ac3"
+	"github.com/bluenviron/mediacommon/pkg/codecs/h264"
+	"github.com/bluenviron/mediacommon/pkg/codecs/h265"
+	"github.com/bluenviron/mediacommon/pkg/codecs/mpeg1audio"
+	"github.com/bluenviron/mediacommon/pkg/codecs/mpeg4audio"
+	"github.com/bluenviron/mediacommon/pkg/codecs/opus"
+
+	"github.com/bluenviron/mediamtx/internal/asyncwriter"
+	"github.com/bluenviron/mediamtx/internal/stream"
+	"github.com/bluenviron/mediamtx/internal/unit"
+)
+
+// ErrNoSupportedCodecs is returned by FromStream when the stream doesn't contain any supported codec.
+var ErrNoSupportedCodecs = errors.New(
+	"the stream doesn't contain any supported codec, which are currently " +
+		"H264, H265, AV1, VP9, MPEG-4 Audio, MPEG-1/2 Audio, Opus, AC-3")
+
+// writeDeadlineSetter is implemented by net.Conn and http.ResponseController.
+type writeDeadlineSetter interface {
+	SetWriteDeadline(t time.Time) error
+}
+
+func setupVideo(
+	strm *stream.Stream,
+	writer *asyncwriter.Writer,
+	w **Writer,
+	nconn writeDeadlineSetter,
+	writeTimeout time.Duration,
+) format.Format {
+	var videoFormatH264 *format.H264
+	videoMedia := strm.Desc().FindFormat(&videoFormatH264)
+
+	if videoFormatH264 != nil {
+		var videoDTSExtractor *h264.DTSExtractor
+
+		strm.AddReader(writer, videoMedia, videoFormatH264, func(u unit.Unit) error {
+			tunit := u.(*unit.H264)
+
+			if tunit.AU == nil {
+				return nil
+			}
+
+			idrPresent := false
+			nonIDRPresent := false
+
+			for _, nalu := range tunit.AU {
+				typ := h264.NALUType(nalu[0] & 0x1F)
+				switch typ {
+				case h264.NALUTypeIDR:
+					idrPresent = true
+
+				case h264.NALUTypeNonIDR:
+					nonIDRPresent = true
+				}
+			}
+
+			var dts time.Duration
+
+			// wait until we receive an IDR
+			if videoDTSExtractor == nil {
+				if !idrPresent {
+					return nil
+				}
+
+				videoDTSExtractor = h264.NewDTSExtractor()
+
+				var err error
+				dts, err = videoDTSExtractor.Extract(tunit.AU, tunit.PTS)
+				if err != nil {
+					return err
+				}
+			} else {
+				if !idrPresent && !nonIDRPresent {
+					return nil
+				}
+
+				var err error
+				dts, err = videoDTSExtractor.Extract(tunit.AU, tunit.PTS)
+				if err != nil {
+					return err
+				}
+			}
+
+			nconn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			return (*w).WriteH264(tunit.PTS, dts, idrPresent, tunit.AU)
+		})
+
+		return videoFormatH264
+	}
+
+	var videoFormatH265 *format.H265
+	videoMedia = strm.Desc().FindFormat(&videoFormatH265)
+
+	if videoFormatH265 != nil {
+		var videoDTSExtractor *h265.DTSExtractor
+
+		strm.AddReader(writer, videoMedia, videoFormatH265, func(u unit.Unit) error {
+			tunit := u.(*unit.H265)
+
+			if tunit.AU == nil {
+				return nil
+			}
+
+			randomAccess := h265.IsRandomAccess(tunit.AU)
+
+			// wait until we receive a random access unit
+			if videoDTSExtractor == nil {
+				if !randomAccess {
+					return nil
+				}
+				videoDTSExtractor = h265.NewDTSExtractor()
+			}
+
+			dts, err := videoDTSExtractor.Extract(tunit.AU, tunit.PTS)
+			if err != nil {
+				return err
+			}
+
+			nconn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			return (*w).WriteH265(tunit.PTS, dts, randomAccess, tunit.AU)
+		})
+
+		return videoFormatH265
+	}
+
+	var videoFormatAV1 *format.AV1
+	videoMedia = strm.Desc().FindFormat(&videoFormatAV1)
+
+	if videoFormatAV1 != nil {
+		strm.AddReader(writer, videoMedia, videoFormatAV1, func(u unit.Unit) error {
+			tunit := u.(*unit.AV1)
+
+			if tunit.TU == nil {
+				return nil
+			}
+
+			nconn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			return (*w).WriteAV1(tunit.PTS, tunit.TU)
+		})
+
+		return videoFormatAV1
+	}
+
+	var videoFormatVP9 *format.VP9
+	videoMedia = strm.Desc().FindFormat(&videoFormatVP9)
+
+	if videoFormatVP9 != nil {
+		strm.AddReader(writer, videoMedia, videoFormatVP9, func(u unit.Unit) error {
+			tunit := u.(*unit.VP9)
+
+			if tunit.Frame == nil {
+				return nil
+			}
+
+			nconn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			return (*w).WriteVP9(tunit.PTS, tunit.Frame)
+		})
+
+		return videoFormatVP9
+	}
+
+	return nil
+}
+
+func setupAudio(
+	strm *stream.Stream,
+	writer *asyncwriter.Writer,
+	w **Writer,
+	nconn writeDeadlineSetter,
+	writeTimeout time.Duration,
+) format.Format {
+	var audioFormatMPEG4Audio *format.MPEG4Audio
+	audioMedia := strm.Desc().FindFormat(&audioFormatMPEG4Audio)
+
+	if audioMedia != nil {
+		strm.AddReader(writer, audioMedia, audioFormatMPEG4Audio, func(u unit.Unit) error {
+			tunit := u.(*unit.MPEG4Audio)
+
+			if tunit.AUs == nil {
+				return nil
+			}
+
+			for i, au := range tunit.AUs {
+				nconn.SetWriteDeadline(time.Now().Add(writeTimeout))
+				err := (*w).WriteMPEG4Audio(
+					tunit.PTS+time.Duration(i)*mpeg4audio.SamplesPerAccessUnit*
+						time.Second/time.Duration(audioFormatMPEG4Audio.ClockRate()),
+					au,
+				)
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+
+		return audioFormatMPEG4Audio
+	}
+
+	var audioFormatMPEG1 *format.MPEG1Audio
+	audioMedia = strm.Desc().FindFormat(&audioFormatMPEG1)
+
+	if audioMedia != nil {
+		strm.AddReader(writer, audioMedia, audioFormatMPEG1, func(u unit.Unit) error {
+			tunit := u.(*unit.MPEG1Audio)
+
+			pts := tunit.PTS
+
+			for _, frame := range tunit.Frames {
+				var h mpeg1audio.FrameHeader
+				err := h.Unmarshal(frame)
+				if err != nil {
+					return err
+				}
+
+				if !(!h.MPEG2 && h.Layer == 3) {
+					return fmt.Errorf("RTMP only supports MPEG-1 layer 3 audio")
+				}
+
+				nconn.SetWriteDeadline(time.Now().Add(writeTimeout))
+				err = (*w).WriteMPEG1Audio(pts, &h, frame)
+				if err != nil {
+					return err
+				}
+
+				pts += time.Duration(h.SampleCount()) *
+					time.Second / time.Duration(h.SampleRate)
+			}
+
+			return nil
+		})
+
+		return audioFormatMPEG1
+	}
+
+	var audioFormatOpus *format.Opus
+	audioMedia = strm.Desc().FindFormat(&audioFormatOpus)
+
+	if audioMedia != nil {
+		strm.AddReader(writer, audioMedia, audioFormatOpus, func(u unit.Unit) error {
+			tunit := u.(*unit.Opus)
+
+			if tunit.Packets == nil {
+				return nil
+			}
+
+			pts := tunit.PTS
+
+			for _, packet := range tunit.Packets {
+				nconn.SetWriteDeadline(time.Now().Add(writeTimeout))
+				err := (*w).WriteOpus(pts, packet)
+				if err != nil {
+					return err
+				}
+
+				pts += opus.PacketDuration(packet)
+			}
+
+			return nil
+		})
+
+		return audioFormatOpus
+	}
+
+	var audioFormatAC3 *format.AC3
+	audioMedia = strm.Desc().FindFormat(&audioFormatAC3)
+
+	if audioMedia != nil {
+		strm.AddReader(writer, audioMedia, audioFormatAC3, func(u unit.Unit) error {
+			tunit := u.(*unit.AC3)
+
+			if tunit.Frames == nil {
+				return nil
+			}
+
+			for i, frame := range tunit.Frames {
+				nconn.SetWriteDeadline(time.Now().Add(writeTimeout))
+				err := (*w).WriteAC3(
+					tunit.PTS+time.Duration(i)*ac3.SamplesPerFrame*
+						time.Second/time.Duration(audioFormatAC3.ClockRate()),
+					frame,
+				)
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+
+		return audioFormatAC3
+	}
+
+	return nil
+}
+
+// FromStream links a server stream to a RTMP or FLV connection.
+func FromStream(
+	strm *stream.Stream,
+	writer *asyncwriter.Writer,
+	conn MessageWriter,
+	nconn writeDeadlineSetter,
+	writeTimeout time.Duration,
+) error {
+	var w *Writer
+
+	videoFormat := setupVideo(
+		strm,
+		writer,
+		&w,
+		nconn,
+		writeTimeout)
+
+	audioFormat := setupAudio(
+		strm,
+		writer,
+		&w,
+		nconn,
+		writeTimeout)
+
+	if videoFormat == nil && audioFormat == nil {
+		return ErrNoSupportedCodecs
+	}
+
+	var err error
+	w, err = NewWriter(conn, videoFormat, audioFormat)
+	return err
+}
@@ -19,6 +19,7 @@ const (
 	CodecPCMA       = 7
 	CodecPCMU       = 8
 	CodecMPEG4Audio = 10
+	CodecExHeader   = 9
 )
 
 // audio rates
@@ -0,0 +1,26 @@
+package message
+
+import (
+	"fmt"
+
+	"github.com/bluenviron/mediamtx/internal/protocols/rtmp/rawmessage"
+)
+
+// ExtendedAudioSequenceEnd is a sequence end extended audio message.
+type ExtendedAudioSequenceEnd struct {
+	FourCC FourCC
+}
+
+func (m *ExtendedAudioSequenceEnd) unmarshal(raw *rawmessage.Message) error {
+	if len(raw.Body) != 5 {
+		return fmt.Errorf("invalid body size")
+	}
+
+	m.FourCC = FourCC(raw.Body[1])<<24 | FourCC(raw.Body[2])<<16 | FourCC(raw.Body[3])<<8 | FourCC(raw.Body[4])
+
+	return nil
+}
+
+func (m ExtendedAudioSequenceEnd) marshal() (*rawmessage.Message, error) {
+	return nil, fmt.Errorf("TODO")
+}
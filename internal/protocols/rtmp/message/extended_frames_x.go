@@ -13,6 +13,7 @@ type ExtendedFramesX struct {
 	DTS             time.Duration
 	MessageStreamID uint32
 	FourCC          FourCC
+	IsKeyFrame      bool
 	Payload         []byte
 }
 
@@ -24,6 +25,7 @@ func (m *ExtendedFramesX) unmarshal(raw *rawmessage.Message) error {
 	m.ChunkStreamID = raw.ChunkStreamID
 	m.DTS = raw.Timestamp
 	m.MessageStreamID = raw.MessageStreamID
+	m.IsKeyFrame = ((raw.Body[0] >> 4) & 0b111) == 1
 	m.FourCC = FourCC(raw.Body[1])<<24 | FourCC(raw.Body[2])<<16 | FourCC(raw.Body[3])<<8 | FourCC(raw.Body[4])
 	m.Payload = raw.Body[5:]
 
@@ -37,7 +39,11 @@ func (m ExtendedFramesX) marshalBodySize() int {
 func (m ExtendedFramesX) marshal() (*rawmessage.Message, error) {
 	body := make([]byte, m.marshalBodySize())
 
-	body[0] = 0b10000000 | byte(ExtendedTypeFramesX)
+	if m.IsKeyFrame {
+		body[0] = 0b10000000 | 1<<4 | byte(ExtendedTypeFramesX)
+	} else {
+		body[0] = 0b10000000 | 2<<4 | byte(ExtendedTypeFramesX)
+	}
 	body[1] = uint8(m.FourCC >> 24)
 	body[2] = uint8(m.FourCC >> 16)
 	body[3] = uint8(m.FourCC >> 8)
@@ -34,7 +34,7 @@ func (m ExtendedSequenceStart) marshalBodySize() int {
 func (m ExtendedSequenceStart) marshal() (*rawmessage.Message, error) {
 	body := make([]byte, m.marshalBodySize())
 
-	body[0] = 0b10000000 | byte(ExtendedTypeSequenceStart)
+	body[0] = 0b10000000 | 1<<4 | byte(ExtendedTypeSequenceStart)
 	body[1] = uint8(m.FourCC >> 24)
 	body[2] = uint8(m.FourCC >> 16)
 	body[3] = uint8(m.FourCC >> 8)
@@ -60,7 +60,17 @@ const (
 	ExtendedTypeMPEG2TSSequenceStart ExtendedType = 5
 )
 
-// FourCC is an identifier of a video codec.
+// ExtendedAudioType is a audio message extended type.
+type ExtendedAudioType uint8
+
+// audio message extended types.
+const (
+	ExtendedAudioTypeSequenceStart ExtendedAudioType = 0
+	ExtendedAudioTypeCodedFrames   ExtendedAudioType = 1
+	ExtendedAudioTypeSequenceEnd   ExtendedAudioType = 2
+)
+
+// FourCC is an identifier of a video or audio codec.
 type FourCC uint32
 
 // video codec identifiers.
@@ -70,8 +80,19 @@ var (
 	FourCCHEVC FourCC = 'h'<<24 | 'v'<<16 | 'c'<<8 | '1'
 )
 
+// audio codec identifiers.
+var (
+	FourCCOpus FourCC = 'O'<<24 | 'p'<<16 | 'u'<<8 | 's'
+	FourCCAC3  FourCC = 'a'<<24 | 'c'<<16 | '-'<<8 | '3'
+)
+
 // Message is a message.
 type Message interface {
 	unmarshal(*rawmessage.Message) error
 	marshal() (*rawmessage.Message, error)
 }
+
+// Marshal encodes a message into a raw message.
+func Marshal(msg Message) (*rawmessage.Message, error) {
+	return msg.marshal()
+}
@@ -62,6 +62,39 @@ func allocateMessage(raw *rawmessage.Message) (Message, error) {
 		return &DataAMF0{}, nil
 
 	case TypeAudio:
+		if len(raw.Body) < 1 {
+			return nil, fmt.Errorf("not enough bytes")
+		}
+
+		if (raw.Body[0] >> 4) == CodecExHeader {
+			if len(raw.Body) < 5 {
+				return nil, fmt.Errorf("not enough bytes")
+			}
+
+			fourCC := FourCC(raw.Body[1])<<24 | FourCC(raw.Body[2])<<16 | FourCC(raw.Body[3])<<8 | FourCC(raw.Body[4])
+
+			switch fourCC {
+			case FourCCOpus, FourCCAC3:
+			default:
+				return nil, fmt.Errorf("invalid fourCC: %v", fourCC)
+			}
+
+			extendedType := ExtendedAudioType(raw.Body[0] & 0x0F)
+
+			switch extendedType {
+			case ExtendedAudioTypeSequenceStart:
+				return &ExtendedAudioSequenceStart{}, nil
+
+			case ExtendedAudioTypeCodedFrames:
+				return &ExtendedAudioCodedFrames{}, nil
+
+			case ExtendedAudioTypeSequenceEnd:
+				return &ExtendedAudioSequenceEnd{}, nil
+
+			default:
+				return nil, fmt.Errorf("invalid extended audio type: %v", extendedType)
+			}
+		}
 		return &Audio{}, nil
 
 	case TypeVideo:
@@ -242,7 +242,7 @@ var readWriterCases = []struct {
 		},
 		[]byte{
 			0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0x08, 0x09,
-			0x01, 0x00, 0x00, 0x00, 0x80, 0x68, 0x76, 0x63,
+			0x01, 0x00, 0x00, 0x00, 0x90, 0x68, 0x76, 0x63,
 			0x31, 0x01, 0x02, 0x03,
 		},
 	},
@@ -253,12 +253,13 @@ var readWriterCases = []struct {
 			DTS:             15100 * time.Millisecond,
 			MessageStreamID: 0x1000000,
 			FourCC:          FourCCHEVC,
+			IsKeyFrame:      true,
 			PTSDelta:        30 * time.Millisecond,
 			Payload:         []byte{0x01, 0x02, 0x03},
 		},
 		[]byte{
 			0x04, 0x00, 0x3a, 0xfc, 0x00, 0x00, 0x0b, 0x09,
-			0x01, 0x00, 0x00, 0x00, 0x81, 0x68, 0x76, 0x63,
+			0x01, 0x00, 0x00, 0x00, 0x91, 0x68, 0x76, 0x63,
 			0x31, 0x00, 0x00, 0x1e, 0x01, 0x02, 0x03,
 		},
 	},
@@ -273,10 +274,39 @@ var readWriterCases = []struct {
 		},
 		[]byte{
 			0x04, 0x00, 0x3a, 0xfc, 0x00, 0x00, 0x08, 0x09,
-			0x01, 0x00, 0x00, 0x00, 0x83, 0x68, 0x76, 0x63,
+			0x01, 0x00, 0x00, 0x00, 0xa3, 0x68, 0x76, 0x63,
 			0x31, 0x01, 0x02, 0x03,
 		},
 	},
+	{
+		"extended audio sequence start",
+		&ExtendedAudioSequenceStart{
+			ChunkStreamID:   4,
+			MessageStreamID: 0x1000000,
+			FourCC:          FourCCOpus,
+			Config:          []byte{0x01, 0x02, 0x03},
+		},
+		[]byte{
+			0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0x08, 0x08,
+			0x01, 0x00, 0x00, 0x00, 0x90, 0x4f, 0x70, 0x75,
+			0x73, 0x01, 0x02, 0x03,
+		},
+	},
+	{
+		"extended audio coded frames",
+		&ExtendedAudioCodedFrames{
+			ChunkStreamID:   4,
+			DTS:             15100 * time.Millisecond,
+			MessageStreamID: 0x1000000,
+			FourCC:          FourCCAC3,
+			Payload:         []byte{0x01, 0x02, 0x03},
+		},
+		[]byte{
+			0x04, 0x00, 0x3a, 0xfc, 0x00, 0x00, 0x08, 0x08,
+			0x01, 0x00, 0x00, 0x00, 0x91, 0x61, 0x63, 0x2d,
+			0x33, 0x01, 0x02, 0x03,
+		},
+	},
 }
 
 func TestReader(t *testing.T) {
@@ -8,6 +8,7 @@ import (
 
 	"github.com/abema/go-mp4"
 	"github.com/bluenviron/gortsplib/v4/pkg/format"
+	"github.com/bluenviron/mediacommon/pkg/codecs/ac3"
 	"github.com/bluenviron/mediacommon/pkg/codecs/av1"
 	"github.com/bluenviron/mediacommon/pkg/codecs/h264"
 	"github.com/bluenviron/mediacommon/pkg/codecs/h265"
@@ -31,12 +32,18 @@ type OnDataVP9Func func(pts time.Duration, frame []byte)
 // OnDataH26xFunc is the prototype of the callback passed to OnDataH26x().
 type OnDataH26xFunc func(pts time.Duration, au [][]byte)
 
+// OnDataOpusFunc is the prototype of the callback passed to OnDataOpus().
+type OnDataOpusFunc func(pts time.Duration, packet []byte)
+
 // OnDataMPEG4AudioFunc is the prototype of the callback passed to OnDataMPEG4Audio().
 type OnDataMPEG4AudioFunc func(pts time.Duration, au []byte)
 
 // OnDataMPEG1AudioFunc is the prototype of the callback passed to OnDataMPEG1Audio().
 type OnDataMPEG1AudioFunc func(pts time.Duration, frame []byte)
 
+// OnDataAC3Func is the prototype of the callback passed to OnDataAC3().
+type OnDataAC3Func func(pts time.Duration, frame []byte)
+
 // OnDataG711Func is the prototype of the callback passed to OnDataG711().
 type OnDataG711Func func(pts time.Duration, samples []byte)
 
@@ -99,10 +106,13 @@ func hasAudio(md amf0.Object, audioTrack *format.Format) (bool, error) {
 
 		case message.CodecPCMU:
 			return true, nil
+
+		case float64(message.FourCCOpus), float64(message.FourCCAC3):
+			return true, nil
 		}
 
 	case string:
-		if vt == "mp4a" {
+		if vt == "mp4a" || vt == "Opus" || vt == "ac-3" {
 			return true, nil
 		}
 	}
@@ -151,6 +161,37 @@ func trackFromAACDecoderConfig(data []byte) (format.Format, error) {
 	}, nil
 }
 
+func trackFromOpusIDHeader(data []byte) (format.Format, error) {
+	if len(data) < 19 || string(data[:8]) != "OpusHead" {
+		return nil, fmt.Errorf("invalid Opus ID header")
+	}
+
+	return &format.Opus{
+		PayloadTyp: 96,
+		IsStereo:   data[9] >= 2,
+	}, nil
+}
+
+func trackFromAC3Frame(frame []byte) (format.Format, error) {
+	var syncInfo ac3.SyncInfo
+	err := syncInfo.Unmarshal(frame)
+	if err != nil {
+		return nil, fmt.Errorf("invalid AC-3 frame: %w", err)
+	}
+
+	var bsi ac3.BSI
+	err = bsi.Unmarshal(frame[5:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid AC-3 frame: %w", err)
+	}
+
+	return &format.AC3{
+		PayloadTyp:   96,
+		SampleRate:   syncInfo.SampleRate(),
+		ChannelCount: bsi.ChannelCount(),
+	}, nil
+}
+
 func tracksFromMetadata(conn *Conn, payload []interface{}) (format.Format, format.Format, error) {
 	if len(payload) != 1 {
 		return nil, nil, fmt.Errorf("invalid metadata")
@@ -320,6 +361,30 @@ func tracksFromMetadata(conn *Conn, payload []interface{}) (format.Format, forma
 				}
 			}
 
+		case *message.ExtendedAudioSequenceStart:
+			if !hasAudio {
+				return nil, nil, fmt.Errorf("unexpected audio packet")
+			}
+
+			if audioTrack == nil && msg.FourCC == message.FourCCOpus {
+				audioTrack, err = trackFromOpusIDHeader(msg.Config)
+				if err != nil {
+					return nil, nil, err
+				}
+			}
+
+		case *message.ExtendedAudioCodedFrames:
+			if !hasAudio {
+				return nil, nil, fmt.Errorf("unexpected audio packet")
+			}
+
+			if audioTrack == nil && msg.FourCC == message.FourCCAC3 {
+				audioTrack, err = trackFromAC3Frame(msg.Payload)
+				if err != nil {
+					return nil, nil, err
+				}
+			}
+
 		case *message.Audio:
 			if !hasAudio {
 				return nil, nil, fmt.Errorf("unexpected audio packet")
@@ -463,7 +528,7 @@ type Reader struct {
 	videoTrack  format.Format
 	audioTrack  format.Format
 	onDataVideo func(message.Message) error
-	onDataAudio func(*message.Audio) error
+	onDataAudio func(message.Message) error
 }
 
 // NewReader allocates a Reader.
@@ -631,10 +696,20 @@ func (r *Reader) OnDataH264(cb OnDataH26xFunc) {
 	}
 }
 
+// OnDataOpus sets a callback that is called when Opus data is received.
+func (r *Reader) OnDataOpus(cb OnDataOpusFunc) {
+	r.onDataAudio = func(msg message.Message) error {
+		if msg, ok := msg.(*message.ExtendedAudioCodedFrames); ok {
+			cb(msg.DTS, msg.Payload)
+		}
+		return nil
+	}
+}
+
 // OnDataMPEG4Audio sets a callback that is called when MPEG-4 Audio data is received.
 func (r *Reader) OnDataMPEG4Audio(cb OnDataMPEG4AudioFunc) {
-	r.onDataAudio = func(msg *message.Audio) error {
-		if msg.AACType == message.AudioAACTypeAU {
+	r.onDataAudio = func(msg message.Message) error {
+		if msg, ok := msg.(*message.Audio); ok && msg.AACType == message.AudioAACTypeAU {
 			cb(msg.DTS, msg.Payload)
 		}
 		return nil
@@ -643,16 +718,30 @@ func (r *Reader) OnDataMPEG4Audio(cb OnDataMPEG4AudioFunc) {
 
 // OnDataMPEG1Audio sets a callback that is called when MPEG-1 Audio data is received.
 func (r *Reader) OnDataMPEG1Audio(cb OnDataMPEG1AudioFunc) {
-	r.onDataAudio = func(msg *message.Audio) error {
-		cb(msg.DTS, msg.Payload)
+	r.onDataAudio = func(msg message.Message) error {
+		if msg, ok := msg.(*message.Audio); ok {
+			cb(msg.DTS, msg.Payload)
+		}
+		return nil
+	}
+}
+
+// OnDataAC3 sets a callback that is called when AC-3 data is received.
+func (r *Reader) OnDataAC3(cb OnDataAC3Func) {
+	r.onDataAudio = func(msg message.Message) error {
+		if msg, ok := msg.(*message.ExtendedAudioCodedFrames); ok {
+			cb(msg.DTS, msg.Payload)
+		}
 		return nil
 	}
 }
 
 // OnDataG711 sets a callback that is called when G711 data is received.
 func (r *Reader) OnDataG711(cb OnDataG711Func) {
-	r.onDataAudio = func(msg *message.Audio) error {
-		cb(msg.DTS, msg.Payload)
+	r.onDataAudio = func(msg message.Message) error {
+		if msg, ok := msg.(*message.Audio); ok {
+			cb(msg.DTS, msg.Payload)
+		}
 		return nil
 	}
 }
@@ -662,23 +751,30 @@ func (r *Reader) OnDataLPCM(cb OnDataLPCMFunc) {
 	bitDepth := r.audioTrack.(*format.LPCM).BitDepth
 
 	if bitDepth == 16 {
-		r.onDataAudio = func(msg *message.Audio) error {
-			le := len(msg.Payload)
+		r.onDataAudio = func(msg message.Message) error {
+			amsg, ok := msg.(*message.Audio)
+			if !ok {
+				return nil
+			}
+
+			le := len(amsg.Payload)
 			if le%2 != 0 {
 				return fmt.Errorf("invalid payload length: %d", le)
 			}
 
 			// convert from little endian to big endian
 			for i := 0; i < le; i += 2 {
-				msg.Payload[i], msg.Payload[i+1] = msg.Payload[i+1], msg.Payload[i]
+				amsg.Payload[i], amsg.Payload[i+1] = amsg.Payload[i+1], amsg.Payload[i]
 			}
 
-			cb(msg.DTS, msg.Payload)
+			cb(amsg.DTS, amsg.Payload)
 			return nil
 		}
 	} else {
-		r.onDataAudio = func(msg *message.Audio) error {
-			cb(msg.DTS, msg.Payload)
+		r.onDataAudio = func(msg message.Message) error {
+			if msg, ok := msg.(*message.Audio); ok {
+				cb(msg.DTS, msg.Payload)
+			}
 			return nil
 		}
 	}
@@ -699,7 +795,7 @@ func (r *Reader) Read() error {
 
 		return r.onDataVideo(msg)
 
-	case *message.Audio:
+	case *message.Audio, *message.ExtendedAudioCodedFrames:
 		if r.onDataAudio == nil {
 			return fmt.Errorf("received an audio packet, but track is not set up")
 		}
@@ -1,12 +1,18 @@
 package rtmp
 
 import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
 	"time"
 
+	"github.com/abema/go-mp4"
 	"github.com/bluenviron/gortsplib/v4/pkg/format"
+	"github.com/bluenviron/mediacommon/pkg/codecs/av1"
 	"github.com/bluenviron/mediacommon/pkg/codecs/h264"
+	"github.com/bluenviron/mediacommon/pkg/codecs/h265"
 	"github.com/bluenviron/mediacommon/pkg/codecs/mpeg1audio"
-	"github.com/bluenviron/mediacommon/pkg/codecs/mpeg4audio"
+	"github.com/bluenviron/mediacommon/pkg/codecs/vp9"
 
 	"github.com/bluenviron/mediamtx/internal/protocols/rtmp/amf0"
 	"github.com/bluenviron/mediamtx/internal/protocols/rtmp/h264conf"
@@ -43,13 +49,154 @@ func mpeg1AudioChannels(m mpeg1audio.ChannelMode) bool {
 	return m != mpeg1audio.ChannelModeMono
 }
 
-// Writer is a wrapper around Conn that provides utilities to mux outgoing data.
+func boolToUint8(v bool) uint8 {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+func marshalBox(box mp4.IImmutableBox) ([]byte, error) {
+	var buf bytes.Buffer
+	_, err := mp4.Marshal(&buf, box, mp4.Context{})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func h265DecoderConfig(vps []byte, sps []byte, pps []byte) ([]byte, error) {
+	var spsp h265.SPS
+	err := spsp.Unmarshal(sps)
+	if err != nil {
+		return nil, err
+	}
+
+	return marshalBox(&mp4.HvcC{
+		ConfigurationVersion:        1,
+		GeneralProfileIdc:           spsp.ProfileTierLevel.GeneralProfileIdc,
+		GeneralProfileCompatibility: spsp.ProfileTierLevel.GeneralProfileCompatibilityFlag,
+		GeneralConstraintIndicator: [6]uint8{
+			sps[7], sps[8], sps[9],
+			sps[10], sps[11], sps[12],
+		},
+		GeneralLevelIdc: spsp.ProfileTierLevel.GeneralLevelIdc,
+		// MinSpatialSegmentationIdc
+		// ParallelismType
+		ChromaFormatIdc:      uint8(spsp.ChromaFormatIdc),
+		BitDepthLumaMinus8:   uint8(spsp.BitDepthLumaMinus8),
+		BitDepthChromaMinus8: uint8(spsp.BitDepthChromaMinus8),
+		// AvgFrameRate
+		// ConstantFrameRate
+		NumTemporalLayers: 1,
+		// TemporalIdNested
+		LengthSizeMinusOne: 3,
+		NumOfNaluArrays:    3,
+		NaluArrays: []mp4.HEVCNaluArray{
+			{
+				NaluType: byte(h265.NALUType_VPS_NUT),
+				NumNalus: 1,
+				Nalus: []mp4.HEVCNalu{{
+					Length:  uint16(len(vps)),
+					NALUnit: vps,
+				}},
+			},
+			{
+				NaluType: byte(h265.NALUType_SPS_NUT),
+				NumNalus: 1,
+				Nalus: []mp4.HEVCNalu{{
+					Length:  uint16(len(sps)),
+					NALUnit: sps,
+				}},
+			},
+			{
+				NaluType: byte(h265.NALUType_PPS_NUT),
+				NumNalus: 1,
+				Nalus: []mp4.HEVCNalu{{
+					Length:  uint16(len(pps)),
+					NALUnit: pps,
+				}},
+			},
+		},
+	})
+}
+
+func av1DecoderConfig(sequenceHeader []byte) ([]byte, error) {
+	var sh av1.SequenceHeader
+	err := sh.Unmarshal(sequenceHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	bs, err := av1.BitstreamMarshal([][]byte{sequenceHeader})
+	if err != nil {
+		return nil, err
+	}
+
+	return marshalBox(&mp4.Av1C{
+		Marker:               1,
+		Version:              1,
+		SeqProfile:           sh.SeqProfile,
+		SeqLevelIdx0:         sh.SeqLevelIdx[0],
+		SeqTier0:             boolToUint8(sh.SeqTier[0]),
+		HighBitdepth:         boolToUint8(sh.ColorConfig.HighBitDepth),
+		TwelveBit:            boolToUint8(sh.ColorConfig.TwelveBit),
+		Monochrome:           boolToUint8(sh.ColorConfig.MonoChrome),
+		ChromaSubsamplingX:   boolToUint8(sh.ColorConfig.SubsamplingX),
+		ChromaSubsamplingY:   boolToUint8(sh.ColorConfig.SubsamplingY),
+		ChromaSamplePosition: uint8(sh.ColorConfig.ChromaSamplePosition),
+		ConfigOBUs:           bs,
+	})
+}
+
+func vp9DecoderConfig(h *vp9.Header) ([]byte, error) {
+	return marshalBox(&mp4.VpcC{
+		FullBox: mp4.FullBox{
+			Version: 1,
+		},
+		Profile:            h.Profile,
+		Level:              10, // level 1
+		BitDepth:           h.ColorConfig.BitDepth,
+		ChromaSubsampling:  h.ChromaSubsampling(),
+		VideoFullRangeFlag: boolToUint8(h.ColorConfig.ColorRange),
+	})
+}
+
+func opusChannelCount(forma *format.Opus) int {
+	if forma.IsStereo {
+		return 2
+	}
+	return 1
+}
+
+// opusIDHeader returns an Opus ID header, as defined in RFC7845.
+// pre-skip, output gain and channel mapping family are left to zero.
+func opusIDHeader(channelCount int) []byte {
+	buf := make([]byte, 19)
+	copy(buf, "OpusHead")
+	buf[8] = 1 // version
+	buf[9] = uint8(channelCount)
+	binary.LittleEndian.PutUint32(buf[12:], 48000) // input sample rate
+	return buf
+}
+
+// MessageWriter writes messages. It is implemented by Conn and FLVConn.
+type MessageWriter interface {
+	Write(msg message.Message) error
+}
+
+// Writer is a wrapper around a MessageWriter that provides utilities to mux outgoing data.
 type Writer struct {
-	conn *Conn
+	conn MessageWriter
+
+	videoConfigWritten bool
+	h265VPS            []byte
+	h265SPS            []byte
+	h265PPS            []byte
 }
 
 // NewWriter allocates a Writer.
-func NewWriter(conn *Conn, videoTrack format.Format, audioTrack format.Format) (*Writer, error) {
+func NewWriter(conn MessageWriter, videoTrack format.Format, audioTrack format.Format) (*Writer, error) {
 	w := &Writer{
 		conn: conn,
 	}
@@ -81,6 +228,15 @@ func (w *Writer) writeTracks(videoTrack format.Format, audioTrack format.Format)
 						case *format.H264:
 							return message.CodecH264
 
+						case *format.H265:
+							return float64(message.FourCCHEVC)
+
+						case *format.AV1:
+							return float64(message.FourCCAV1)
+
+						case *format.VP9:
+							return float64(message.FourCCVP9)
+
 						default:
 							return 0
 						}
@@ -100,6 +256,12 @@ func (w *Writer) writeTracks(videoTrack format.Format, audioTrack format.Format)
 						case *format.MPEG4Audio:
 							return message.CodecMPEG4Audio
 
+						case *format.Opus:
+							return float64(message.FourCCOpus)
+
+						case *format.AC3:
+							return float64(message.FourCCAC3)
+
 						default:
 							return 0
 						}
@@ -112,7 +274,8 @@ func (w *Writer) writeTracks(videoTrack format.Format, audioTrack format.Format)
 		return err
 	}
 
-	if videoTrack, ok := videoTrack.(*format.H264); ok {
+	switch videoTrack := videoTrack.(type) {
+	case *format.H264:
 		// write decoder config only if SPS and PPS are available.
 		// if they're not available yet, they're sent later.
 		if sps, pps := videoTrack.SafeParams(); sps != nil && pps != nil {
@@ -133,36 +296,234 @@ func (w *Writer) writeTracks(videoTrack format.Format, audioTrack format.Format)
 				return err
 			}
 		}
+
+	case *format.H265:
+		// write decoder config only if VPS, SPS and PPS are available.
+		// if they're not available yet, they're sent later.
+		if vps, sps, pps := videoTrack.SafeParams(); vps != nil && sps != nil && pps != nil {
+			err = w.writeH265Config(vps, sps, pps)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	switch audioTrack := audioTrack.(type) {
+	case *format.MPEG4Audio:
+		audioConfig := audioTrack.GetConfig()
+
+		if audioConfig != nil {
+			enc, err := audioConfig.Marshal()
+			if err != nil {
+				return err
+			}
+
+			err = w.conn.Write(&message.Audio{
+				ChunkStreamID:   message.AudioChunkStreamID,
+				MessageStreamID: 0x1000000,
+				Codec:           message.CodecMPEG4Audio,
+				Rate:            message.Rate44100,
+				Depth:           message.Depth16,
+				IsStereo:        true,
+				AACType:         message.AudioAACTypeConfig,
+				Payload:         enc,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+	case *format.Opus:
+		err = w.conn.Write(&message.ExtendedAudioSequenceStart{
+			ChunkStreamID:   message.AudioChunkStreamID,
+			MessageStreamID: 0x1000000,
+			FourCC:          message.FourCCOpus,
+			Config:          opusIDHeader(opusChannelCount(audioTrack)),
+		})
+		if err != nil {
+			return err
+		}
 	}
 
-	var audioConfig *mpeg4audio.AudioSpecificConfig
+	return nil
+}
+
+func (w *Writer) writeH265Config(vps []byte, sps []byte, pps []byte) error {
+	conf, err := h265DecoderConfig(vps, sps, pps)
+	if err != nil {
+		return fmt.Errorf("unable to generate H265 config: %w", err)
+	}
 
-	if track, ok := audioTrack.(*format.MPEG4Audio); ok {
-		audioConfig = track.GetConfig()
+	err = w.conn.Write(&message.ExtendedSequenceStart{
+		ChunkStreamID:   message.VideoChunkStreamID,
+		MessageStreamID: 0x1000000,
+		FourCC:          message.FourCCHEVC,
+		Config:          conf,
+	})
+	if err != nil {
+		return err
 	}
 
-	if audioConfig != nil {
-		enc, err := audioConfig.Marshal()
+	w.videoConfigWritten = true
+	w.h265VPS = vps
+	w.h265SPS = sps
+	w.h265PPS = pps
+	return nil
+}
+
+// WriteAV1 writes AV1 data.
+// Temporal units are discarded until a sequence header is received.
+func (w *Writer) WriteAV1(pts time.Duration, tu [][]byte) error {
+	randomAccess := false
+
+	for _, obu := range tu {
+		var h av1.OBUHeader
+		err := h.Unmarshal(obu)
 		if err != nil {
 			return err
 		}
 
-		err = w.conn.Write(&message.Audio{
-			ChunkStreamID:   message.AudioChunkStreamID,
+		if h.Type == av1.OBUTypeSequenceHeader {
+			if !w.videoConfigWritten {
+				conf, err := av1DecoderConfig(obu)
+				if err != nil {
+					return fmt.Errorf("unable to generate AV1 config: %w", err)
+				}
+
+				err = w.conn.Write(&message.ExtendedSequenceStart{
+					ChunkStreamID:   message.VideoChunkStreamID,
+					MessageStreamID: 0x1000000,
+					FourCC:          message.FourCCAV1,
+					Config:          conf,
+				})
+				if err != nil {
+					return err
+				}
+
+				w.videoConfigWritten = true
+			}
+
+			randomAccess = true
+		}
+	}
+
+	if !w.videoConfigWritten {
+		return nil
+	}
+
+	bs, err := av1.BitstreamMarshal(tu)
+	if err != nil {
+		return err
+	}
+
+	return w.conn.Write(&message.ExtendedCodedFrames{
+		ChunkStreamID:   message.VideoChunkStreamID,
+		MessageStreamID: 0x1000000,
+		FourCC:          message.FourCCAV1,
+		IsKeyFrame:      randomAccess,
+		Payload:         bs,
+		DTS:             pts,
+	})
+}
+
+// WriteVP9 writes VP9 data.
+// Frames are discarded until a key frame is received.
+func (w *Writer) WriteVP9(pts time.Duration, frame []byte) error {
+	var h vp9.Header
+	err := h.Unmarshal(frame)
+	if err != nil {
+		return err
+	}
+
+	randomAccess := (h.FrameType == vp9.FrameTypeKeyFrame)
+
+	if !w.videoConfigWritten {
+		if !randomAccess {
+			return nil
+		}
+
+		conf, err := vp9DecoderConfig(&h)
+		if err != nil {
+			return fmt.Errorf("unable to generate VP9 config: %w", err)
+		}
+
+		err = w.conn.Write(&message.ExtendedSequenceStart{
+			ChunkStreamID:   message.VideoChunkStreamID,
 			MessageStreamID: 0x1000000,
-			Codec:           message.CodecMPEG4Audio,
-			Rate:            message.Rate44100,
-			Depth:           message.Depth16,
-			IsStereo:        true,
-			AACType:         message.AudioAACTypeConfig,
-			Payload:         enc,
+			FourCC:          message.FourCCVP9,
+			Config:          conf,
 		})
 		if err != nil {
 			return err
 		}
+
+		w.videoConfigWritten = true
 	}
 
-	return nil
+	return w.conn.Write(&message.ExtendedCodedFrames{
+		ChunkStreamID:   message.VideoChunkStreamID,
+		MessageStreamID: 0x1000000,
+		FourCC:          message.FourCCVP9,
+		IsKeyFrame:      randomAccess,
+		Payload:         frame,
+		DTS:             pts,
+	})
+}
+
+// WriteH265 writes H265 data.
+// Access units are discarded until the decoder config is written.
+func (w *Writer) WriteH265(pts time.Duration, dts time.Duration, randomAccess bool, au [][]byte) error {
+	// write decoder config if it was not available when tracks were written,
+	// or write it again if parameters have changed.
+	if randomAccess {
+		var vps, sps, pps []byte
+
+		for _, nalu := range au {
+			if len(nalu) == 0 {
+				continue
+			}
+
+			typ := h265.NALUType((nalu[0] >> 1) & 0b111111)
+
+			switch typ {
+			case h265.NALUType_VPS_NUT:
+				vps = nalu
+
+			case h265.NALUType_SPS_NUT:
+				sps = nalu
+
+			case h265.NALUType_PPS_NUT:
+				pps = nalu
+			}
+		}
+
+		if vps != nil && sps != nil && pps != nil &&
+			(!bytes.Equal(vps, w.h265VPS) || !bytes.Equal(sps, w.h265SPS) || !bytes.Equal(pps, w.h265PPS)) {
+			err := w.writeH265Config(vps, sps, pps)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if !w.videoConfigWritten {
+		return nil
+	}
+
+	avcc, err := h264.AVCCMarshal(au)
+	if err != nil {
+		return err
+	}
+
+	return w.conn.Write(&message.ExtendedCodedFrames{
+		ChunkStreamID:   message.VideoChunkStreamID,
+		MessageStreamID: 0x1000000,
+		FourCC:          message.FourCCHEVC,
+		IsKeyFrame:      randomAccess,
+		Payload:         avcc,
+		DTS:             dts,
+		PTSDelta:        pts - dts,
+	})
 }
 
 // WriteH264 writes H264 data.
@@ -184,6 +545,17 @@ func (w *Writer) WriteH264(pts time.Duration, dts time.Duration, idrPresent bool
 	})
 }
 
+// WriteOpus writes a Opus packet.
+func (w *Writer) WriteOpus(pts time.Duration, packet []byte) error {
+	return w.conn.Write(&message.ExtendedAudioCodedFrames{
+		ChunkStreamID:   message.AudioChunkStreamID,
+		MessageStreamID: 0x1000000,
+		FourCC:          message.FourCCOpus,
+		Payload:         packet,
+		DTS:             pts,
+	})
+}
+
 // WriteMPEG4Audio writes MPEG-4 Audio data.
 func (w *Writer) WriteMPEG4Audio(pts time.Duration, au []byte) error {
 	return w.conn.Write(&message.Audio{
@@ -212,3 +584,14 @@ func (w *Writer) WriteMPEG1Audio(pts time.Duration, h *mpeg1audio.FrameHeader, f
 		DTS:             pts,
 	})
 }
+
+// WriteAC3 writes a AC-3 frame.
+func (w *Writer) WriteAC3(pts time.Duration, frame []byte) error {
+	return w.conn.Write(&message.ExtendedAudioCodedFrames{
+		ChunkStreamID:   message.AudioChunkStreamID,
+		MessageStreamID: 0x1000000,
+		FourCC:          message.FourCCAC3,
+		Payload:         frame,
+		DTS:             pts,
+	})
+}
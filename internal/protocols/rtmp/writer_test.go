@@ -3,14 +3,18 @@ package rtmp
 import (
 	"bytes"
 	"testing"
+	"time"
 
+	"github.com/abema/go-mp4"
 	"github.com/bluenviron/gortsplib/v4/pkg/format"
+	"github.com/bluenviron/mediacommon/pkg/codecs/h265"
 	"github.com/bluenviron/mediacommon/pkg/codecs/mpeg4audio"
 	"github.com/stretchr/testify/require"
 
 	"github.com/bluenviron/mediamtx/internal/protocols/rtmp/amf0"
 	"github.com/bluenviron/mediamtx/internal/protocols/rtmp/bytecounter"
 	"github.com/bluenviron/mediamtx/internal/protocols/rtmp/message"
+	"github.com/bluenviron/mediamtx/internal/test"
 )
 
 func TestWriteTracks(t *testing.T) {
@@ -96,3 +100,213 @@ func TestWriteTracks(t *testing.T) {
 		Payload:         []byte{0x12, 0x10},
 	}, msg)
 }
+
+func TestWriteTracksEnhanced(t *testing.T) {
+	var buf bytes.Buffer
+	c := newNoHandshakeConn(&buf)
+
+	_, err := NewWriter(c, test.FormatH265, &format.Opus{
+		PayloadTyp: 96,
+		IsStereo:   true,
+	})
+	require.NoError(t, err)
+
+	bc := bytecounter.NewReadWriter(&buf)
+	mrw := message.NewReadWriter(bc, bc, true)
+
+	msg, err := mrw.Read()
+	require.NoError(t, err)
+	require.Equal(t, &message.DataAMF0{
+		ChunkStreamID:   4,
+		MessageStreamID: 0x1000000,
+		Payload: []interface{}{
+			"@setDataFrame",
+			"onMetaData",
+			amf0.Object{
+				{Key: "videodatarate", Value: float64(0)},
+				{Key: "videocodecid", Value: float64(message.FourCCHEVC)},
+				{Key: "audiodatarate", Value: float64(0)},
+				{Key: "audiocodecid", Value: float64(message.FourCCOpus)},
+			},
+		},
+	}, msg)
+
+	msg, err = mrw.Read()
+	require.NoError(t, err)
+	require.IsType(t, &message.ExtendedSequenceStart{}, msg)
+	require.Equal(t, message.FourCCHEVC, msg.(*message.ExtendedSequenceStart).FourCC)
+
+	var hvcc mp4.HvcC
+	conf := msg.(*message.ExtendedSequenceStart).Config
+	_, err = mp4.Unmarshal(bytes.NewReader(conf), uint64(len(conf)), &hvcc, mp4.Context{})
+	require.NoError(t, err)
+	require.Equal(t, test.FormatH265.VPS, h265FindNALU(hvcc.NaluArrays, h265.NALUType_VPS_NUT))
+	require.Equal(t, test.FormatH265.SPS, h265FindNALU(hvcc.NaluArrays, h265.NALUType_SPS_NUT))
+	require.Equal(t, test.FormatH265.PPS, h265FindNALU(hvcc.NaluArrays, h265.NALUType_PPS_NUT))
+
+	msg, err = mrw.Read()
+	require.NoError(t, err)
+	require.Equal(t, &message.ExtendedAudioSequenceStart{
+		ChunkStreamID:   message.AudioChunkStreamID,
+		MessageStreamID: 0x1000000,
+		FourCC:          message.FourCCOpus,
+		Config: []byte{
+			'O', 'p', 'u', 's', 'H', 'e', 'a', 'd',
+			0x01, 0x02, 0x00, 0x00, 0x80, 0xbb, 0x00, 0x00,
+			0x00, 0x00, 0x00,
+		},
+	}, msg)
+}
+
+func TestWriteAV1(t *testing.T) {
+	var buf bytes.Buffer
+	c := newNoHandshakeConn(&buf)
+
+	w, err := NewWriter(c, &format.AV1{PayloadTyp: 96}, nil)
+	require.NoError(t, err)
+
+	// non-random access units are discarded until a sequence header is received
+	err = w.WriteAV1(1*time.Second, [][]byte{{0x30, 0x01, 0x02}})
+	require.NoError(t, err)
+
+	sequenceHeader := []byte{
+		8, 0, 0, 0, 66, 167, 191, 228, 96, 13, 0, 64,
+	}
+
+	err = w.WriteAV1(2*time.Second, [][]byte{sequenceHeader, {0x30, 0x01, 0x02}})
+	require.NoError(t, err)
+
+	bc := bytecounter.NewReadWriter(&buf)
+	mrw := message.NewReadWriter(bc, bc, true)
+
+	msg, err := mrw.Read()
+	require.NoError(t, err)
+	require.IsType(t, &message.DataAMF0{}, msg)
+
+	msg, err = mrw.Read()
+	require.NoError(t, err)
+	require.IsType(t, &message.ExtendedSequenceStart{}, msg)
+	require.Equal(t, message.FourCCAV1, msg.(*message.ExtendedSequenceStart).FourCC)
+
+	msg, err = mrw.Read()
+	require.NoError(t, err)
+	require.Equal(t, &message.ExtendedCodedFrames{
+		ChunkStreamID:   message.VideoChunkStreamID,
+		DTS:             2 * time.Second,
+		MessageStreamID: 0x1000000,
+		FourCC:          message.FourCCAV1,
+		IsKeyFrame:      true,
+		Payload: []byte{
+			0x0a, 0x0b, 0x00, 0x00, 0x00, 0x42, 0xa7, 0xbf,
+			0xe4, 0x60, 0x0d, 0x00, 0x40, 0x32, 0x02, 0x01,
+			0x02,
+		},
+	}, msg)
+}
+
+func TestWriteH265(t *testing.T) {
+	var buf bytes.Buffer
+	c := newNoHandshakeConn(&buf)
+
+	w, err := NewWriter(c, &format.H265{PayloadTyp: 96}, nil)
+	require.NoError(t, err)
+
+	// access units are discarded until parameters are received
+	err = w.WriteH265(1*time.Second, 1*time.Second, false, [][]byte{{0x02, 0x01, 0xaf}})
+	require.NoError(t, err)
+
+	params := [][]byte{test.FormatH265.VPS, test.FormatH265.SPS, test.FormatH265.PPS}
+
+	err = w.WriteH265(2*time.Second, 2*time.Second, true, append(params, []byte{0x26, 0x01, 0xaf}))
+	require.NoError(t, err)
+
+	// unchanged parameters are not written again
+	err = w.WriteH265(3*time.Second, 3*time.Second, true, append(params, []byte{0x26, 0x01, 0xaf}))
+	require.NoError(t, err)
+
+	// changed parameters are written again
+	pps := append(append([]byte(nil), test.FormatH265.PPS...), 0x01)
+	err = w.WriteH265(4*time.Second, 4*time.Second, true,
+		[][]byte{test.FormatH265.VPS, test.FormatH265.SPS, pps, {0x26, 0x01, 0xaf}})
+	require.NoError(t, err)
+
+	bc := bytecounter.NewReadWriter(&buf)
+	mrw := message.NewReadWriter(bc, bc, true)
+
+	msg, err := mrw.Read()
+	require.NoError(t, err)
+	require.IsType(t, &message.DataAMF0{}, msg)
+
+	readPPS := func() []byte {
+		msg, err := mrw.Read()
+		require.NoError(t, err)
+		require.IsType(t, &message.ExtendedSequenceStart{}, msg)
+
+		var hvcc mp4.HvcC
+		conf := msg.(*message.ExtendedSequenceStart).Config
+		_, err = mp4.Unmarshal(bytes.NewReader(conf), uint64(len(conf)), &hvcc, mp4.Context{})
+		require.NoError(t, err)
+		return h265FindNALU(hvcc.NaluArrays, h265.NALUType_PPS_NUT)
+	}
+
+	readDTS := func() time.Duration {
+		msg, err := mrw.Read()
+		require.NoError(t, err)
+		require.IsType(t, &message.ExtendedCodedFrames{}, msg)
+		return msg.(*message.ExtendedCodedFrames).DTS
+	}
+
+	require.Equal(t, test.FormatH265.PPS, readPPS())
+	require.Equal(t, 2*time.Second, readDTS())
+	require.Equal(t, 3*time.Second, readDTS())
+	require.Equal(t, pps, readPPS())
+	require.Equal(t, 4*time.Second, readDTS())
+}
+
+func TestWriterReaderEnhanced(t *testing.T) {
+	var buf bytes.Buffer
+	c := newNoHandshakeConn(&buf)
+
+	w, err := NewWriter(c, test.FormatH265, &format.Opus{
+		PayloadTyp: 96,
+		IsStereo:   true,
+	})
+	require.NoError(t, err)
+
+	err = w.WriteH265(2*time.Second, 1*time.Second, true, [][]byte{{0x26, 0x01, 0xaf}})
+	require.NoError(t, err)
+
+	err = w.WriteOpus(3*time.Second, []byte{0xf8, 0xff, 0xfe})
+	require.NoError(t, err)
+
+	r, err := NewReader(c)
+	require.NoError(t, err)
+
+	videoTrack, audioTrack := r.Tracks()
+	require.Equal(t, &format.H265{
+		PayloadTyp: 96,
+		VPS:        test.FormatH265.VPS,
+		SPS:        test.FormatH265.SPS,
+		PPS:        test.FormatH265.PPS,
+	}, videoTrack)
+	require.Equal(t, &format.Opus{
+		PayloadTyp: 96,
+		IsStereo:   true,
+	}, audioTrack)
+
+	r.OnDataH265(func(pts time.Duration, au [][]byte) {
+		require.Equal(t, 2*time.Second, pts)
+		require.Equal(t, [][]byte{{0x26, 0x01, 0xaf}}, au)
+	})
+
+	r.OnDataOpus(func(pts time.Duration, packet []byte) {
+		require.Equal(t, 3*time.Second, pts)
+		require.Equal(t, []byte{0xf8, 0xff, 0xfe}, packet)
+	})
+
+	err = r.Read()
+	require.NoError(t, err)
+
+	err = r.Read()
+	require.NoError(t, err)
+}
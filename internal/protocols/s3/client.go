@@ -0,0 +1,212 @@
+// Package s3 contains a client for S3-compatible object storages.
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type errorResponse struct {
+	XMLName xml.Name `xml:"Error"`
+	Code    string   `xml:"Code"`
+	Message string   `xml:"Message"`
+}
+
+type initiateMultipartUploadResult struct {
+	XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+	UploadID string   `xml:"UploadId"`
+}
+
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type completeMultipartUpload struct {
+	XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+	Parts   []completedPart `xml:"Part"`
+}
+
+func decodeError(statusCode int, body []byte) error {
+	var er errorResponse
+	err := xml.Unmarshal(body, &er)
+	if err == nil && er.Code != "" {
+		return fmt.Errorf("bad status code: %v (%s: %s)", statusCode, er.Code, er.Message)
+	}
+	return fmt.Errorf("bad status code: %v", statusCode)
+}
+
+// Client is a client for S3-compatible object storages.
+// Objects are addressed with path-style URLs, that are supported by most implementations.
+type Client struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	HTTPClient      *http.Client
+}
+
+func (c *Client) objectURL(key string, query url.Values) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSuffix(c.Endpoint, "/") + "/" +
+		uriEncode(c.Bucket, true) + "/" + uriEncode(key, false))
+	if err != nil {
+		return nil, err
+	}
+
+	if query != nil {
+		u.RawQuery = canonicalQuery(query)
+	}
+
+	return u, nil
+}
+
+func (c *Client) do(
+	ctx context.Context,
+	method string,
+	key string,
+	query url.Values,
+	body []byte,
+) (*http.Response, []byte, error) {
+	u, err := c.objectURL(key, query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req.ContentLength = int64(len(body))
+
+	if c.AccessKeyID != "" {
+		s := &signer{
+			region:          c.Region,
+			accessKeyID:     c.AccessKeyID,
+			secretAccessKey: c.SecretAccessKey,
+		}
+		s.sign(req, hashSHA256(body), time.Now())
+	}
+
+	res, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, nil, decodeError(res.StatusCode, resBody)
+	}
+
+	return res, resBody, nil
+}
+
+// PutObject uploads an object with a single request.
+func (c *Client) PutObject(ctx context.Context, key string, body []byte) error {
+	_, _, err := c.do(ctx, http.MethodPut, key, nil, body)
+	return err
+}
+
+// CreateMultipartUpload starts a multipart upload and returns its ID.
+func (c *Client) CreateMultipartUpload(ctx context.Context, key string) (string, error) {
+	_, body, err := c.do(ctx, http.MethodPost, key, url.Values{"uploads": []string{""}}, nil)
+	if err != nil {
+		return "", err
+	}
+
+	var res initiateMultipartUploadResult
+	err = xml.Unmarshal(body, &res)
+	if err != nil {
+		return "", err
+	}
+
+	if res.UploadID == "" {
+		return "", fmt.Errorf("upload ID not provided")
+	}
+
+	return res.UploadID, nil
+}
+
+// UploadPart uploads a part of a multipart upload and returns its ETag.
+// Part numbers start from 1.
+func (c *Client) UploadPart(
+	ctx context.Context,
+	key string,
+	uploadID string,
+	partNumber int,
+	body []byte,
+) (string, error) {
+	res, _, err := c.do(ctx, http.MethodPut, key, url.Values{
+		"partNumber": []string{strconv.FormatInt(int64(partNumber), 10)},
+		"uploadId":   []string{uploadID},
+	}, body)
+	if err != nil {
+		return "", err
+	}
+
+	etag := res.Header.Get("ETag")
+	if etag == "" {
+		return "", fmt.Errorf("ETag not provided")
+	}
+
+	return etag, nil
+}
+
+// CompleteMultipartUpload completes a multipart upload.
+// ETags must be sorted by part number.
+func (c *Client) CompleteMultipartUpload(
+	ctx context.Context,
+	key string,
+	uploadID string,
+	etags []string,
+) error {
+	req := completeMultipartUpload{
+		Parts: make([]completedPart, len(etags)),
+	}
+
+	for i, etag := range etags {
+		req.Parts[i] = completedPart{
+			PartNumber: i + 1,
+			ETag:       etag,
+		}
+	}
+
+	byts, err := xml.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	res, body, err := c.do(ctx, http.MethodPost, key, url.Values{"uploadId": []string{uploadID}}, byts)
+	if err != nil {
+		return err
+	}
+
+	// the request may fail after the response status has been sent.
+	var er errorResponse
+	err = xml.Unmarshal(body, &er)
+	if err == nil && er.Code != "" {
+		return decodeError(res.StatusCode, body)
+	}
+
+	return nil
+}
+
+// AbortMultipartUpload aborts a multipart upload.
+func (c *Client) AbortMultipartUpload(ctx context.Context, key string, uploadID string) error {
+	_, _, err := c.do(ctx, http.MethodDelete, key, url.Values{"uploadId": []string{uploadID}}, nil)
+	return err
+}
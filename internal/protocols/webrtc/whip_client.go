@@ -227,6 +227,11 @@ func (c *WHIPClient) Close() error {
 	return err
 }
 
+// BytesSent returns sent bytes.
+func (c *WHIPClient) BytesSent() uint64 {
+	return c.pc.BytesSent()
+}
+
 // Wait waits for client errors.
 func (c *WHIPClient) Wait(ctx context.Context) error {
 	select {
@@ -0,0 +1,66 @@
+// Package push contains the push agent.
+package push
+
+import (
+	"time"
+
+	"github.com/bluenviron/mediamtx/internal/defs"
+	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/bluenviron/mediamtx/internal/stream"
+)
+
+// Agent publishes a stream to remote servers.
+type Agent struct {
+	WriteQueueSize    int
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	UDPMaxPayloadSize int
+	Targets           []string
+	Stream            *stream.Stream
+	Parent            logger.Writer
+
+	minRetryPause time.Duration
+	maxRetryPause time.Duration
+
+	targets []*target
+}
+
+// Initialize initializes Agent.
+func (a *Agent) Initialize() {
+	if a.minRetryPause == 0 {
+		a.minRetryPause = 2 * time.Second
+	}
+	if a.maxRetryPause == 0 {
+		a.maxRetryPause = 30 * time.Second
+	}
+
+	for _, u := range a.Targets {
+		t := &target{
+			agent: a,
+			url:   u,
+		}
+		t.initialize()
+		a.targets = append(a.targets, t)
+	}
+}
+
+// Log implements logger.Writer.
+func (a *Agent) Log(level logger.Level, format string, args ...interface{}) {
+	a.Parent.Log(level, "%v"+format, append([]interface{}{logger.Component("push")}, args...)...)
+}
+
+// Close closes the agent.
+func (a *Agent) Close() {
+	for _, t := range a.targets {
+		t.close()
+	}
+}
+
+// APITargets returns the state of targets.
+func (a *Agent) APITargets() []defs.APIPathPushTarget {
+	ret := make([]defs.APIPathPushTarget, len(a.targets))
+	for i, t := range a.targets {
+		ret[i] = t.apiItem()
+	}
+	return ret
+}
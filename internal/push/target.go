@@ -0,0 +1,210 @@
+package push
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bluenviron/mediamtx/internal/defs"
+	"github.com/bluenviron/mediamtx/internal/logger"
+)
+
+var errTerminated = fmt.Errorf("terminated")
+
+// query parameters that contain credentials.
+var sensitiveQueryParams = []string{
+	"passphrase",
+	"streamid",
+	"key",
+	"token",
+	"jwt",
+	"pass",
+	"password",
+	"secret",
+	"signature",
+}
+
+func isSensitiveQueryParam(key string) bool {
+	key = strings.ToLower(key)
+	for _, k := range sensitiveQueryParams {
+		if key == k {
+			return true
+		}
+	}
+	return false
+}
+
+// redactURL removes credentials from a URL, both from the user info
+// and from query parameters, preserving the order of parameters.
+func redactURL(u string) string {
+	pu, err := url.Parse(u)
+	if err != nil {
+		return u
+	}
+
+	if pu.RawQuery != "" {
+		params := strings.Split(pu.RawQuery, "&")
+		for i, param := range params {
+			key, _, _ := strings.Cut(param, "=")
+			if k, err := url.QueryUnescape(key); err == nil && isSensitiveQueryParam(k) {
+				params[i] = key + "=xxxxx"
+			}
+		}
+		pu.RawQuery = strings.Join(params, "&")
+	}
+
+	return pu.Redacted()
+}
+
+type target struct {
+	agent *Agent
+	url   string
+
+	ctx           context.Context
+	ctxCancel     func()
+	logAttr       logger.Attr
+	mutex         sync.RWMutex
+	state         defs.APIPathPushTargetState
+	lastError     string
+	bytesSent     func() uint64
+	prevBytesSent uint64
+
+	done chan struct{}
+}
+
+func (t *target) initialize() {
+	t.ctx, t.ctxCancel = context.WithCancel(context.Background())
+	redacted := redactURL(t.url)
+	t.logAttr = logger.Attr{
+		Text:   "[" + redacted + "] ",
+		Fields: []logger.Field{{Key: "target", Value: redacted}},
+	}
+	t.state = defs.APIPathPushTargetStateConnecting
+	t.done = make(chan struct{})
+
+	go t.run()
+}
+
+func (t *target) close() {
+	t.ctxCancel()
+	<-t.done
+}
+
+// Log implements logger.Writer.
+func (t *target) Log(level logger.Level, format string, args ...interface{}) {
+	t.agent.Log(level, "%v"+format, append([]interface{}{t.logAttr}, args...)...)
+}
+
+func (t *target) run() {
+	defer close(t.done)
+
+	retryPause := t.agent.minRetryPause
+
+	for {
+		t.setState(defs.APIPathPushTargetStateConnecting, "")
+
+		err := t.runInner()
+
+		t.mutex.RLock()
+		published := (t.state == defs.APIPathPushTargetStatePublishing)
+		t.mutex.RUnlock()
+
+		if t.ctx.Err() != nil {
+			return
+		}
+
+		// reset the pause after a successful attempt
+		if published {
+			retryPause = t.agent.minRetryPause
+		}
+
+		t.Log(logger.Error, "%v, retrying in %v", err, retryPause)
+		t.setState(defs.APIPathPushTargetStateWaiting, err.Error())
+
+		select {
+		case <-time.After(retryPause):
+		case <-t.ctx.Done():
+			return
+		}
+
+		retryPause *= 2
+		if retryPause > t.agent.maxRetryPause {
+			retryPause = t.agent.maxRetryPause
+		}
+	}
+}
+
+func (t *target) runInner() error {
+	switch {
+	case strings.HasPrefix(t.url, "rtmp://") ||
+		strings.HasPrefix(t.url, "rtmps://"):
+		return t.runRTMP()
+
+	case strings.HasPrefix(t.url, "rtsp://") ||
+		strings.HasPrefix(t.url, "rtsps://"):
+		return t.runRTSP()
+
+	case strings.HasPrefix(t.url, "srt://"):
+		return t.runSRT()
+
+	case strings.HasPrefix(t.url, "udp://"):
+		return t.runUDP()
+
+	case strings.HasPrefix(t.url, "whip://") ||
+		strings.HasPrefix(t.url, "whips://"):
+		return t.runWebRTC()
+
+	default:
+		return fmt.Errorf("unsupported target: %s", t.url)
+	}
+}
+
+func (t *target) setState(state defs.APIPathPushTargetState, lastError string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.state = state
+	if lastError != "" {
+		t.lastError = lastError
+	}
+}
+
+// setPublishing is called by protocol handlers once the connection is established.
+func (t *target) setPublishing(bytesSent func() uint64) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.state = defs.APIPathPushTargetStatePublishing
+	t.bytesSent = bytesSent
+
+	t.Log(logger.Info, "publishing")
+}
+
+// setNotPublishing is called by protocol handlers before the connection is closed.
+func (t *target) setNotPublishing() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.prevBytesSent += t.bytesSent()
+	t.bytesSent = nil
+}
+
+func (t *target) apiItem() defs.APIPathPushTarget {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	bytesSent := t.prevBytesSent
+	if t.bytesSent != nil {
+		bytesSent += t.bytesSent()
+	}
+
+	return defs.APIPathPushTarget{
+		URL:       redactURL(t.url),
+		State:     t.state,
+		LastError: t.lastError,
+		BytesSent: bytesSent,
+	}
+}
@@ -0,0 +1,94 @@
+package push
+
+import (
+	"context"
+	ctls "crypto/tls"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/bluenviron/mediamtx/internal/asyncwriter"
+	"github.com/bluenviron/mediamtx/internal/protocols/rtmp"
+)
+
+func (t *target) runRTMP() error {
+	u, err := url.Parse(t.url)
+	if err != nil {
+		return err
+	}
+
+	// add default port
+	_, _, err = net.SplitHostPort(u.Host)
+	if err != nil {
+		if u.Scheme == "rtmp" {
+			u.Host = net.JoinHostPort(u.Host, "1935")
+		} else {
+			u.Host = net.JoinHostPort(u.Host, "1936")
+		}
+	}
+
+	nconn, err := func() (net.Conn, error) {
+		ctx2, cancel2 := context.WithTimeout(t.ctx, t.agent.ReadTimeout)
+		defer cancel2()
+
+		if u.Scheme == "rtmp" {
+			return (&net.Dialer{}).DialContext(ctx2, "tcp", u.Host)
+		}
+
+		return (&ctls.Dialer{}).DialContext(ctx2, "tcp", u.Host)
+	}()
+	if err != nil {
+		return err
+	}
+
+	writeDone := make(chan error)
+	go func() {
+		writeDone <- t.runRTMPWriter(u, nconn)
+	}()
+
+	select {
+	case err := <-writeDone:
+		nconn.Close()
+		return err
+
+	case <-t.ctx.Done():
+		nconn.Close()
+		<-writeDone
+		return errTerminated
+	}
+}
+
+func (t *target) runRTMPWriter(u *url.URL, nconn net.Conn) error {
+	nconn.SetReadDeadline(time.Now().Add(t.agent.ReadTimeout))
+	nconn.SetWriteDeadline(time.Now().Add(t.agent.WriteTimeout))
+	conn, err := rtmp.NewClientConn(nconn, u, true)
+	if err != nil {
+		return err
+	}
+
+	writer := asyncwriter.New(t.agent.WriteQueueSize, t)
+
+	defer t.agent.Stream.RemoveReader(writer)
+
+	err = rtmp.FromStream(t.agent.Stream, writer, conn, nconn, t.agent.WriteTimeout)
+	if err != nil {
+		return err
+	}
+
+	t.setPublishing(conn.BytesSent)
+	defer t.setNotPublishing()
+
+	// disable read deadline
+	nconn.SetReadDeadline(time.Time{})
+
+	writer.Start()
+	defer writer.Stop()
+
+	select {
+	case <-t.ctx.Done():
+		return errTerminated
+
+	case err := <-writer.Error():
+		return err
+	}
+}
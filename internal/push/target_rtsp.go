@@ -0,0 +1,99 @@
+package push
+
+import (
+	"sync/atomic"
+
+	"github.com/bluenviron/gortsplib/v4"
+	"github.com/bluenviron/gortsplib/v4/pkg/base"
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+
+	"github.com/bluenviron/mediamtx/internal/asyncwriter"
+	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/bluenviron/mediamtx/internal/unit"
+)
+
+func (t *target) runRTSP() error {
+	c := &gortsplib.Client{
+		ReadTimeout:    t.agent.ReadTimeout,
+		WriteTimeout:   t.agent.WriteTimeout,
+		WriteQueueSize: t.agent.WriteQueueSize,
+		BytesSent:      new(uint64),
+		OnRequest: func(req *base.Request) {
+			t.Log(logger.Debug, "[c->s] %v", req)
+		},
+		OnResponse: func(res *base.Response) {
+			t.Log(logger.Debug, "[s->c] %v", res)
+		},
+		OnTransportSwitch: func(err error) {
+			t.Log(logger.Warn, err.Error())
+		},
+	}
+
+	// the client edits the description, therefore it can't be shared with the stream.
+	desc := &description.Session{}
+	medias := make(map[*description.Media]*description.Media)
+
+	for _, medi := range t.agent.Stream.Desc().Medias {
+		cmedi := &description.Media{
+			Type:    medi.Type,
+			ID:      medi.ID,
+			Formats: medi.Formats,
+		}
+		desc.Medias = append(desc.Medias, cmedi)
+		medias[medi] = cmedi
+	}
+
+	err := c.StartRecording(t.url, desc)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	writer := asyncwriter.New(t.agent.WriteQueueSize, t)
+
+	defer t.agent.Stream.RemoveReader(writer)
+
+	for _, medi := range t.agent.Stream.Desc().Medias {
+		cmedi := medias[medi]
+
+		for _, forma := range medi.Formats {
+			t.agent.Stream.AddReader(writer, medi, forma, func(u unit.Unit) error {
+				for _, pkt := range u.GetRTPPackets() {
+					err := c.WritePacketRTPWithNTP(cmedi, pkt, u.GetNTP())
+					if err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		}
+	}
+
+	t.setPublishing(func() uint64 {
+		return atomic.LoadUint64(c.BytesSent)
+	})
+	defer t.setNotPublishing()
+
+	writer.Start()
+	defer writer.Stop()
+
+	clientErr := make(chan error)
+	go func() {
+		clientErr <- c.Wait()
+	}()
+
+	select {
+	case <-t.ctx.Done():
+		c.Close()
+		<-clientErr
+		return errTerminated
+
+	case err := <-writer.Error():
+		c.Close()
+		<-clientErr
+		return err
+
+	case err := <-clientErr:
+		return err
+	}
+}
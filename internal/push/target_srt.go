@@ -0,0 +1,69 @@
+package push
+
+import (
+	"bufio"
+	"time"
+
+	srt "github.com/datarhei/gosrt"
+
+	"github.com/bluenviron/mediamtx/internal/asyncwriter"
+	"github.com/bluenviron/mediamtx/internal/protocols/mpegts"
+)
+
+func srtMaxPayloadSize(u int) int {
+	return ((u - 16) / 188) * 188 // 16 = SRT header, 188 = MPEG-TS packet
+}
+
+func (t *target) runSRT() error {
+	conf := srt.DefaultConfig()
+	address, err := conf.UnmarshalURL(t.url)
+	if err != nil {
+		return err
+	}
+
+	conf.ConnectionTimeout = t.agent.ReadTimeout
+	conf.PayloadSize = uint32(srtMaxPayloadSize(t.agent.UDPMaxPayloadSize))
+
+	err = conf.Validate()
+	if err != nil {
+		return err
+	}
+
+	sconn, err := srt.Dial("srt", address, conf)
+	if err != nil {
+		return err
+	}
+	defer sconn.Close()
+
+	writer := asyncwriter.New(t.agent.WriteQueueSize, t)
+
+	defer t.agent.Stream.RemoveReader(writer)
+
+	bw := bufio.NewWriterSize(sconn, srtMaxPayloadSize(t.agent.UDPMaxPayloadSize))
+
+	err = mpegts.FromStream(t.agent.Stream, writer, bw, sconn, t.agent.WriteTimeout)
+	if err != nil {
+		return err
+	}
+
+	t.setPublishing(func() uint64 {
+		var s srt.Statistics
+		sconn.Stats(&s)
+		return s.Accumulated.ByteSent
+	})
+	defer t.setNotPublishing()
+
+	// disable read deadline
+	sconn.SetReadDeadline(time.Time{})
+
+	writer.Start()
+	defer writer.Stop()
+
+	select {
+	case <-t.ctx.Done():
+		return errTerminated
+
+	case err := <-writer.Error():
+		return err
+	}
+}
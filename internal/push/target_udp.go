@@ -0,0 +1,142 @@
+package push
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+
+	"github.com/bluenviron/mediamtx/internal/asyncwriter"
+	"github.com/bluenviron/mediamtx/internal/protocols/mpegts"
+)
+
+const (
+	udpDefaultPacketSize = 1316 // 7 MPEG-TS packets
+	udpDefaultTTL        = 1
+)
+
+type udpOptions struct {
+	address    string
+	ttl        int
+	packetSize int
+}
+
+// parseUDPURL parses the URL of a UDP target, in the format
+// udp://host:port?ttl=4&pkt_size=1316
+func parseUDPURL(u string) (*udpOptions, error) {
+	pu, err := url.Parse(u)
+	if err != nil {
+		return nil, err
+	}
+
+	if pu.Scheme != "udp" {
+		return nil, fmt.Errorf("unsupported scheme: %s", pu.Scheme)
+	}
+
+	if pu.Port() == "" {
+		return nil, fmt.Errorf("port is missing")
+	}
+
+	opts := &udpOptions{
+		address:    pu.Host,
+		ttl:        udpDefaultTTL,
+		packetSize: udpDefaultPacketSize,
+	}
+
+	q := pu.Query()
+
+	if v := q.Get("ttl"); v != "" {
+		opts.ttl, err = strconv.Atoi(v)
+		if err != nil || opts.ttl < 0 || opts.ttl > 255 {
+			return nil, fmt.Errorf("invalid TTL: %s", v)
+		}
+	}
+
+	if v := q.Get("pkt_size"); v != "" {
+		opts.packetSize, err = strconv.Atoi(v)
+		if err != nil || opts.packetSize <= 0 || (opts.packetSize%188) != 0 {
+			return nil, fmt.Errorf("invalid packet size: %s (must be a multiple of 188)", v)
+		}
+	}
+
+	return opts, nil
+}
+
+// udpWriter writes into a UDP socket and counts sent bytes.
+type udpWriter struct {
+	conn      net.Conn
+	bytesSent atomic.Uint64
+}
+
+func (w *udpWriter) Write(p []byte) (int, error) {
+	n, err := w.conn.Write(p)
+	w.bytesSent.Add(uint64(n))
+	return n, err
+}
+
+func setUDPTTL(conn *net.UDPConn, ttl int) error {
+	ip := conn.RemoteAddr().(*net.UDPAddr).IP
+
+	if ip.To4() != nil {
+		if ip.IsMulticast() {
+			return ipv4.NewPacketConn(conn).SetMulticastTTL(ttl)
+		}
+		return ipv4.NewConn(conn).SetTTL(ttl)
+	}
+
+	if ip.IsMulticast() {
+		return ipv6.NewPacketConn(conn).SetMulticastHopLimit(ttl)
+	}
+	return ipv6.NewConn(conn).SetHopLimit(ttl)
+}
+
+func (t *target) runUDP() error {
+	opts, err := parseUDPURL(t.url)
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.Dial("udp", opts.address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	err = setUDPTTL(conn.(*net.UDPConn), opts.ttl)
+	if err != nil {
+		return err
+	}
+
+	writer := asyncwriter.New(t.agent.WriteQueueSize, t)
+
+	defer t.agent.Stream.RemoveReader(writer)
+
+	uw := &udpWriter{conn: conn}
+
+	// every flush of the buffer produces a single datagram.
+	bw := bufio.NewWriterSize(uw, opts.packetSize)
+
+	err = mpegts.FromStream(t.agent.Stream, writer, bw, conn, t.agent.WriteTimeout)
+	if err != nil {
+		return err
+	}
+
+	t.setPublishing(uw.bytesSent.Load)
+	defer t.setNotPublishing()
+
+	writer.Start()
+	defer writer.Stop()
+
+	select {
+	case <-t.ctx.Done():
+		return errTerminated
+
+	case err := <-writer.Error():
+		return err
+	}
+}
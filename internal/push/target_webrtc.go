@@ -0,0 +1,91 @@
+package push
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/bluenviron/mediamtx/internal/asyncwriter"
+	"github.com/bluenviron/mediamtx/internal/protocols/webrtc"
+)
+
+func (t *target) runWebRTC() error {
+	u, err := url.Parse(t.url)
+	if err != nil {
+		return err
+	}
+
+	u.Scheme = strings.ReplaceAll(u.Scheme, "whip", "http")
+
+	tr := &http.Transport{}
+	defer tr.CloseIdleConnections()
+
+	client := webrtc.WHIPClient{
+		HTTPClient: &http.Client{
+			Timeout:   t.agent.ReadTimeout,
+			Transport: tr,
+		},
+		URL: u,
+		Log: t,
+	}
+
+	writer := asyncwriter.New(t.agent.WriteQueueSize, t)
+
+	videoTrack, videoSetup := webrtc.FindVideoTrack(t.agent.Stream, writer)
+	audioTrack, audioSetup := webrtc.FindAudioTrack(t.agent.Stream, writer)
+
+	if videoTrack == nil && audioTrack == nil {
+		return webrtc.ErrNoSupportedCodecs
+	}
+
+	tracks, err := client.Publish(t.ctx, videoTrack, audioTrack)
+	if err != nil {
+		return err
+	}
+	defer client.Close() //nolint:errcheck
+
+	defer t.agent.Stream.RemoveReader(writer)
+
+	n := 0
+
+	if videoTrack != nil {
+		err := videoSetup(tracks[n])
+		if err != nil {
+			return err
+		}
+		n++
+	}
+
+	if audioTrack != nil {
+		err := audioSetup(tracks[n])
+		if err != nil {
+			return err
+		}
+	}
+
+	t.setPublishing(client.BytesSent)
+	defer t.setNotPublishing()
+
+	writer.Start()
+	defer writer.Stop()
+
+	ctx, ctxCancel := context.WithCancel(t.ctx)
+	defer ctxCancel()
+
+	clientErr := make(chan error, 1)
+	go func() {
+		clientErr <- client.Wait(ctx)
+	}()
+
+	select {
+	case <-t.ctx.Done():
+		return errTerminated
+
+	case err := <-writer.Error():
+		return err
+
+	case err := <-clientErr:
+		return err
+	}
+}
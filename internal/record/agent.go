@@ -1,6 +1,8 @@
 package record
 
 import (
+	"io"
+	"sync/atomic"
 	"time"
 
 	"github.com/bluenviron/mediamtx/internal/conf"
@@ -17,6 +19,7 @@ type Agent struct {
 	SegmentDuration   time.Duration
 	PathName          string
 	Stream            *stream.Stream
+	PreRoll           *PreRollBuffer
 	OnSegmentCreate   OnSegmentFunc
 	OnSegmentComplete OnSegmentFunc
 	Parent            logger.Writer
@@ -24,6 +27,7 @@ type Agent struct {
 	restartPause time.Duration
 
 	currentInstance *agentInstance
+	bytesWritten    uint64
 
 	terminate chan struct{}
 	done      chan struct{}
@@ -46,8 +50,11 @@ func (w *Agent) Initialize() {
 	w.terminate = make(chan struct{})
 	w.done = make(chan struct{})
 
+	// the pre-roll buffer is written by the first instance only,
+	// otherwise it would be written again after an error.
 	w.currentInstance = &agentInstance{
-		agent: w,
+		agent:   w,
+		preRoll: w.PreRoll,
 	}
 	w.currentInstance.initialize()
 
@@ -56,7 +63,7 @@ func (w *Agent) Initialize() {
 
 // Log implements logger.Writer.
 func (w *Agent) Log(level logger.Level, format string, args ...interface{}) {
-	w.Parent.Log(level, "[record] "+format, args...)
+	w.Parent.Log(level, "%v"+format, append([]interface{}{logger.Component("record")}, args...)...)
 }
 
 // Close closes the agent.
@@ -66,6 +73,11 @@ func (w *Agent) Close() {
 	<-w.done
 }
 
+// BytesWritten returns the number of bytes written to disk.
+func (w *Agent) BytesWritten() uint64 {
+	return atomic.LoadUint64(&w.bytesWritten)
+}
+
 func (w *Agent) run() {
 	defer close(w.done)
 
@@ -90,3 +102,15 @@ func (w *Agent) run() {
 		w.currentInstance.initialize()
 	}
 }
+
+// countingWriter counts bytes written to segments.
+type countingWriter struct {
+	w     io.Writer
+	agent *Agent
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	atomic.AddUint64(&w.agent.bytesWritten, uint64(n))
+	return n, err
+}
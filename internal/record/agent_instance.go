@@ -4,11 +4,15 @@ import (
 	"strings"
 	"time"
 
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	rtspformat "github.com/bluenviron/gortsplib/v4/pkg/format"
 	"github.com/bluenviron/mediacommon/pkg/formats/fmp4"
 
 	"github.com/bluenviron/mediamtx/internal/asyncwriter"
 	"github.com/bluenviron/mediamtx/internal/conf"
 	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/bluenviron/mediamtx/internal/stream"
+	"github.com/bluenviron/mediamtx/internal/unit"
 )
 
 // OnSegmentFunc is the prototype of the function passed as runOnSegmentStart / runOnSegmentComplete
@@ -21,11 +25,16 @@ type sample struct {
 }
 
 type agentInstance struct {
-	agent *Agent
+	agent   *Agent
+	preRoll *PreRollBuffer
 
-	pathFormat string
-	writer     *asyncwriter.Writer
-	format     format
+	pathFormat       string
+	writer           *asyncwriter.Writer
+	format           format
+	preRollReaders   map[rtspformat.Format]stream.ReadFunc
+	preRollEntries   []*preRollEntry
+	preRollWritten   bool
+	preRollDuplicate map[unit.Unit]struct{}
 
 	terminate chan struct{}
 	done      chan struct{}
@@ -43,6 +52,7 @@ func (a *agentInstance) initialize() {
 	a.done = make(chan struct{})
 
 	a.writer = asyncwriter.New(a.agent.WriteQueueSize, a.agent)
+	a.preRollReaders = make(map[rtspformat.Format]stream.ReadFunc)
 
 	switch a.agent.Format {
 	case conf.RecordFormatMPEGTS:
@@ -58,9 +68,79 @@ func (a *agentInstance) initialize() {
 		a.format.initialize()
 	}
 
+	// the buffer is read after readers have been added,
+	// in order to avoid gaps between the buffer and live units.
+	if a.preRoll != nil {
+		a.preRollEntries = a.preRoll.snapshot()
+	}
+
 	go a.run()
 }
 
+// addReader adds a reader of the stream.
+// When a pre-roll buffer is available, its content is passed to readers before live units.
+func (a *agentInstance) addReader(medi *description.Media, forma rtspformat.Format, cb stream.ReadFunc) {
+	if a.preRoll == nil {
+		a.agent.Stream.AddReader(a.writer, medi, forma, cb)
+		return
+	}
+
+	a.preRollReaders[forma] = cb
+
+	a.agent.Stream.AddReader(a.writer, medi, forma, func(u unit.Unit) error {
+		if !a.preRollWritten {
+			a.preRollWritten = true
+
+			err := a.writePreRoll()
+			if err != nil {
+				return err
+			}
+		}
+
+		// units that are both in the pre-roll buffer and in the queue must be written once.
+		// they can only be at the beginning of the queue.
+		if a.preRollDuplicate != nil {
+			if _, ok := a.preRollDuplicate[u]; ok {
+				delete(a.preRollDuplicate, u)
+				return nil
+			}
+			a.preRollDuplicate = nil
+		}
+
+		return cb(u)
+	})
+}
+
+func (a *agentInstance) writePreRoll() error {
+	entries := a.preRollEntries
+	a.preRollEntries = nil
+
+	if len(entries) == 0 {
+		return nil
+	}
+
+	a.agent.Log(logger.Debug, "writing %v of pre-roll",
+		entries[len(entries)-1].unit.GetPTS()-entries[0].unit.GetPTS())
+
+	a.preRollDuplicate = make(map[unit.Unit]struct{}, len(entries))
+
+	for _, e := range entries {
+		a.preRollDuplicate[e.unit] = struct{}{}
+
+		cb, ok := a.preRollReaders[e.format]
+		if !ok {
+			continue
+		}
+
+		err := cb(e.unit)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (a *agentInstance) close() {
 	close(a.terminate)
 	<-a.done
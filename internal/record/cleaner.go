@@ -5,37 +5,85 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/bluenviron/mediamtx/internal/conf"
 	"github.com/bluenviron/mediamtx/internal/logger"
 )
 
-var timeNow = time.Now
+var (
+	timeNow   = time.Now
+	diskUsage = defaultDiskUsage
+)
+
+// when size or free space limits are set, they are checked with this period.
+const cleanerSizeCheckInterval = 10 * time.Second
+
+// CleanerEvictionReason is the reason why a segment has been removed.
+type CleanerEvictionReason string
+
+// eviction reasons.
+const (
+	CleanerEvictionReasonAge       CleanerEvictionReason = "age"
+	CleanerEvictionReasonMaxSize   CleanerEvictionReason = "maxSize"
+	CleanerEvictionReasonFreeSpace CleanerEvictionReason = "freeSpace"
+)
+
+// CleanerEvictions are statistics about removed segments.
+type CleanerEvictions struct {
+	Reason   CleanerEvictionReason
+	Segments uint64
+	Bytes    uint64
+}
 
 // CleanerEntry is a cleaner entry.
 type CleanerEntry struct {
 	Path        string
 	Format      conf.RecordFormat
 	DeleteAfter time.Duration
+	MaxSize     uint64
+}
+
+type cleanerSegment struct {
+	fpath  string
+	stream string
+	start  time.Time
+	size   uint64
 }
 
-// Cleaner removes expired recording segments from disk.
+// Cleaner removes recording segments from disk when they are expired,
+// when they exceed the maximum size of their path,
+// or when free space of the volume that contains them is below a threshold.
 type Cleaner struct {
-	Entries []CleanerEntry
-	Parent  logger.Writer
+	Entries      []CleanerEntry
+	MinFreeSpace uint64
+	Parent       logger.Writer
 
 	ctx       context.Context
 	ctxCancel func()
 
+	mutex     sync.Mutex
+	evictions map[CleanerEvictionReason]*CleanerEvictions
+
 	done chan struct{}
 }
 
 // Initialize initializes a Cleaner.
 func (c *Cleaner) Initialize() {
 	c.ctx, c.ctxCancel = context.WithCancel(context.Background())
+	c.evictions = make(map[CleanerEvictionReason]*CleanerEvictions)
 	c.done = make(chan struct{})
 
+	for _, reason := range []CleanerEvictionReason{
+		CleanerEvictionReasonAge,
+		CleanerEvictionReasonMaxSize,
+		CleanerEvictionReasonFreeSpace,
+	} {
+		c.evictions[reason] = &CleanerEvictions{Reason: reason}
+	}
+
 	go c.run()
 }
 
@@ -47,7 +95,24 @@ func (c *Cleaner) Close() {
 
 // Log implements logger.Writer.
 func (c *Cleaner) Log(level logger.Level, format string, args ...interface{}) {
-	c.Parent.Log(level, "[record cleaner]"+format, args...)
+	c.Parent.Log(level, "%v"+format, append([]interface{}{logger.Component("record cleaner")}, args...)...)
+}
+
+// Evictions returns statistics about removed segments, grouped by reason.
+func (c *Cleaner) Evictions() []CleanerEvictions {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	out := make([]CleanerEvictions, 0, len(c.evictions))
+	for _, e := range c.evictions {
+		out = append(out, *e)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Reason < out[j].Reason
+	})
+
+	return out
 }
 
 func (c *Cleaner) run() {
@@ -55,12 +120,18 @@ func (c *Cleaner) run() {
 
 	interval := 30 * 60 * time.Second
 	for _, e := range c.Entries {
-		if interval > (e.DeleteAfter / 2) {
+		if e.DeleteAfter != 0 && interval > (e.DeleteAfter/2) {
 			interval = e.DeleteAfter / 2
 		}
+		if e.MaxSize != 0 && interval > cleanerSizeCheckInterval {
+			interval = cleanerSizeCheckInterval
+		}
+	}
+	if c.MinFreeSpace != 0 && interval > cleanerSizeCheckInterval {
+		interval = cleanerSizeCheckInterval
 	}
 
-	c.doRun() //nolint:errcheck
+	c.doRun()
 
 	for {
 		select {
@@ -74,20 +145,60 @@ func (c *Cleaner) run() {
 }
 
 func (c *Cleaner) doRun() {
+	now := timeNow()
+
+	// segments of all entries, grouped by volume
+	volumes := make(map[string]map[string]*cleanerSegment)
+	var commonPaths []string
+
 	for _, e := range c.Entries {
-		c.doRunEntry(&e) //nolint:errcheck
+		entryPath := PathAddExtension(e.Path, e.Format)
+
+		// we have to convert to absolute paths
+		// otherwise, entryPath and fpath inside Walk() won't have common elements
+		entryPath, _ = filepath.Abs(entryPath)
+
+		commonPath := CommonPath(entryPath)
+
+		segments := c.listSegments(entryPath, commonPath)
+
+		if e.DeleteAfter != 0 {
+			segments = c.removeExpired(segments, now, e.DeleteAfter)
+		}
+
+		if e.MaxSize != 0 {
+			segments = c.removeExceedingSize(segments, e.MaxSize)
+		}
+
+		if c.MinFreeSpace != 0 && len(segments) != 0 {
+			volume, _, err := diskUsage(commonPath)
+			if err != nil {
+				c.Log(logger.Warn, "unable to get free space of %s: %v", commonPath, err)
+			} else {
+				if _, ok := volumes[volume]; !ok {
+					volumes[volume] = make(map[string]*cleanerSegment)
+				}
+
+				for _, seg := range segments {
+					volumes[volume][seg.fpath] = seg
+				}
+			}
+		}
+
+		commonPaths = append(commonPaths, commonPath)
 	}
-}
 
-func (c *Cleaner) doRunEntry(e *CleanerEntry) error {
-	entryPath := PathAddExtension(e.Path, e.Format)
+	for _, segments := range volumes {
+		c.freeSpace(segments)
+	}
 
-	// we have to convert to absolute paths
-	// otherwise, entryPath and fpath inside Walk() won't have common elements
-	entryPath, _ = filepath.Abs(entryPath)
+	for _, commonPath := range commonPaths {
+		removeEmptyDirs(commonPath)
+	}
+}
 
-	commonPath := CommonPath(entryPath)
-	now := timeNow()
+func (c *Cleaner) listSegments(entryPath string, commonPath string) []*cleanerSegment {
+	var segments []*cleanerSegment
 
 	filepath.Walk(commonPath, func(fpath string, info fs.FileInfo, err error) error { //nolint:errcheck
 		if err != nil {
@@ -98,16 +209,139 @@ func (c *Cleaner) doRunEntry(e *CleanerEntry) error {
 			var pa Path
 			ok := pa.Decode(entryPath, fpath)
 			if ok {
-				if now.Sub(pa.Start) > e.DeleteAfter {
-					c.Log(logger.Debug, "removing %s", fpath)
-					os.Remove(fpath)
-				}
+				segments = append(segments, &cleanerSegment{
+					fpath:  fpath,
+					stream: entryPath + "|" + pa.Path,
+					start:  pa.Start,
+					size:   uint64(info.Size()),
+				})
 			}
 		}
 
 		return nil
 	})
 
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].start.Before(segments[j].start)
+	})
+
+	return segments
+}
+
+func (c *Cleaner) remove(seg *cleanerSegment, reason CleanerEvictionReason) bool {
+	err := os.Remove(seg.fpath)
+	if err != nil {
+		c.Log(logger.Warn, "unable to remove %s: %v", seg.fpath, err)
+		return false
+	}
+
+	if reason == CleanerEvictionReasonAge {
+		c.Log(logger.Debug, "removed %s (reason: %s)", seg.fpath, reason)
+	} else {
+		c.Log(logger.Info, "removed %s (reason: %s)", seg.fpath, reason)
+	}
+
+	c.mutex.Lock()
+	c.evictions[reason].Segments++
+	c.evictions[reason].Bytes += seg.size
+	c.mutex.Unlock()
+
+	return true
+}
+
+func (c *Cleaner) removeExpired(segments []*cleanerSegment, now time.Time, deleteAfter time.Duration) []*cleanerSegment {
+	var out []*cleanerSegment
+
+	for _, seg := range segments {
+		if now.Sub(seg.start) > deleteAfter && c.remove(seg, CleanerEvictionReasonAge) {
+			continue
+		}
+		out = append(out, seg)
+	}
+
+	return out
+}
+
+// newestSegments returns the last segment of each stream, that may still be in use.
+func newestSegments(segments []*cleanerSegment) map[*cleanerSegment]struct{} {
+	newest := make(map[string]*cleanerSegment)
+
+	for _, seg := range segments {
+		if cur, ok := newest[seg.stream]; !ok || seg.start.After(cur.start) {
+			newest[seg.stream] = seg
+		}
+	}
+
+	out := make(map[*cleanerSegment]struct{}, len(newest))
+	for _, seg := range newest {
+		out[seg] = struct{}{}
+	}
+
+	return out
+}
+
+func (c *Cleaner) removeExceedingSize(segments []*cleanerSegment, maxSize uint64) []*cleanerSegment {
+	// the limit is applied to each stream separately
+	sizes := make(map[string]uint64)
+	for _, seg := range segments {
+		sizes[seg.stream] += seg.size
+	}
+
+	newest := newestSegments(segments)
+	var out []*cleanerSegment
+
+	// segments are sorted by age, therefore the oldest ones are removed first
+	for _, seg := range segments {
+		if _, ok := newest[seg]; !ok && sizes[seg.stream] > maxSize &&
+			c.remove(seg, CleanerEvictionReasonMaxSize) {
+			sizes[seg.stream] -= seg.size
+			continue
+		}
+		out = append(out, seg)
+	}
+
+	return out
+}
+
+func (c *Cleaner) freeSpace(segmentsMap map[string]*cleanerSegment) {
+	segments := make([]*cleanerSegment, 0, len(segmentsMap))
+	for _, seg := range segmentsMap {
+		segments = append(segments, seg)
+	}
+
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].start.Before(segments[j].start)
+	})
+
+	_, free, err := diskUsage(filepath.Dir(segments[0].fpath))
+	if err != nil {
+		c.Log(logger.Warn, "unable to get free space: %v", err)
+		return
+	}
+
+	newest := newestSegments(segments)
+
+	// segments of all paths that share the volume are removed, starting from the oldest ones
+	for _, seg := range segments {
+		if free >= c.MinFreeSpace {
+			return
+		}
+
+		if _, ok := newest[seg]; ok {
+			continue
+		}
+
+		if c.remove(seg, CleanerEvictionReasonFreeSpace) {
+			free += seg.size
+		}
+	}
+
+	if free < c.MinFreeSpace {
+		c.Log(logger.Warn, "free space is below 'recordMinFreeSpace' and there are no more segments that can be removed")
+	}
+}
+
+func removeEmptyDirs(commonPath string) {
 	filepath.Walk(commonPath, func(fpath string, info fs.FileInfo, err error) error { //nolint:errcheck
 		if err != nil {
 			return err
@@ -119,6 +353,4 @@ func (c *Cleaner) doRunEntry(e *CleanerEntry) error {
 
 		return nil
 	})
-
-	return nil
 }
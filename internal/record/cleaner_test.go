@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/logger"
 	"github.com/bluenviron/mediamtx/internal/test"
 	"github.com/stretchr/testify/require"
 )
@@ -50,3 +51,141 @@ func TestCleaner(t *testing.T) {
 	_, err = os.Stat(filepath.Join(dir, specialChars+"_mypath", "2009-05-20_22-15-25-000427.mp4"))
 	require.NoError(t, err)
 }
+
+func TestCleanerMaxSize(t *testing.T) {
+	dir, err := os.MkdirTemp("", "mediamtx-cleaner")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, pathName := range []string{"path1", "path2"} {
+		err = os.Mkdir(filepath.Join(dir, pathName), 0o755)
+		require.NoError(t, err)
+
+		for _, name := range []string{
+			"2009-05-20_22-15-25-000001.mp4",
+			"2009-05-20_22-15-26-000001.mp4",
+			"2009-05-20_22-15-27-000001.mp4",
+		} {
+			err = os.WriteFile(filepath.Join(dir, pathName, name), make([]byte, 100), 0o644)
+			require.NoError(t, err)
+		}
+	}
+
+	c := &Cleaner{
+		Entries: []CleanerEntry{{
+			Path:    filepath.Join(dir, "%path/%Y-%m-%d_%H-%M-%S-%f"),
+			Format:  conf.RecordFormatFMP4,
+			MaxSize: 250,
+		}},
+		Parent: test.NilLogger,
+	}
+	c.Initialize()
+	defer c.Close()
+
+	time.Sleep(500 * time.Millisecond)
+
+	for _, pathName := range []string{"path1", "path2"} {
+		_, err = os.Stat(filepath.Join(dir, pathName, "2009-05-20_22-15-25-000001.mp4"))
+		require.Error(t, err)
+
+		_, err = os.Stat(filepath.Join(dir, pathName, "2009-05-20_22-15-26-000001.mp4"))
+		require.NoError(t, err)
+
+		_, err = os.Stat(filepath.Join(dir, pathName, "2009-05-20_22-15-27-000001.mp4"))
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, []CleanerEvictions{
+		{Reason: CleanerEvictionReasonAge},
+		{Reason: CleanerEvictionReasonFreeSpace},
+		{Reason: CleanerEvictionReasonMaxSize, Segments: 2, Bytes: 200},
+	}, c.Evictions())
+}
+
+func TestCleanerMinFreeSpace(t *testing.T) {
+	dir, err := os.MkdirTemp("", "mediamtx-cleaner")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	free := uint64(0)
+
+	diskUsage = func(string) (string, uint64, error) {
+		return "myvolume", free, nil
+	}
+	defer func() {
+		diskUsage = defaultDiskUsage
+	}()
+
+	for _, fpath := range []string{
+		"path1/2009-05-20_22-15-25-000001.mp4",
+		"path1/2009-05-20_22-15-28-000001.mp4",
+		"path2/2009-05-20_22-15-26-000001.ts",
+		"path2/2009-05-20_22-15-27-000001.ts",
+	} {
+		err = os.MkdirAll(filepath.Join(dir, filepath.Dir(fpath)), 0o755)
+		require.NoError(t, err)
+
+		err = os.WriteFile(filepath.Join(dir, fpath), make([]byte, 100), 0o644)
+		require.NoError(t, err)
+	}
+
+	c := &Cleaner{
+		Entries: []CleanerEntry{
+			{
+				Path:   filepath.Join(dir, "path1/%Y-%m-%d_%H-%M-%S-%f"),
+				Format: conf.RecordFormatFMP4,
+			},
+			{
+				Path:   filepath.Join(dir, "path2/%Y-%m-%d_%H-%M-%S-%f"),
+				Format: conf.RecordFormatMPEGTS,
+			},
+		},
+		MinFreeSpace: 150,
+		Parent:       test.NilLogger,
+	}
+	c.Initialize()
+	defer c.Close()
+
+	time.Sleep(500 * time.Millisecond)
+
+	// the oldest segments of both paths are removed until free space is enough.
+	_, err = os.Stat(filepath.Join(dir, "path1/2009-05-20_22-15-25-000001.mp4"))
+	require.Error(t, err)
+
+	_, err = os.Stat(filepath.Join(dir, "path2/2009-05-20_22-15-26-000001.ts"))
+	require.Error(t, err)
+
+	// the last segment of each path is never removed.
+	_, err = os.Stat(filepath.Join(dir, "path1/2009-05-20_22-15-28-000001.mp4"))
+	require.NoError(t, err)
+
+	_, err = os.Stat(filepath.Join(dir, "path2/2009-05-20_22-15-27-000001.ts"))
+	require.NoError(t, err)
+
+	require.Equal(t, []CleanerEvictions{
+		{Reason: CleanerEvictionReasonAge},
+		{Reason: CleanerEvictionReasonFreeSpace, Segments: 2, Bytes: 200},
+		{Reason: CleanerEvictionReasonMaxSize},
+	}, c.Evictions())
+}
+
+type captureLogger struct {
+	format string
+	args   []interface{}
+}
+
+func (l *captureLogger) Log(_ logger.Level, format string, args ...interface{}) {
+	l.format = format
+	l.args = args
+}
+
+func TestCleanerLog(t *testing.T) {
+	l := &captureLogger{}
+
+	c := &Cleaner{Parent: l}
+	c.Log(logger.Info, "removed %s", "seg.mp4")
+
+	// the component is passed as an argument, in order to be a field of structured logs.
+	require.Equal(t, "%vremoved %s", l.format)
+	require.Equal(t, []interface{}{logger.Component("record cleaner"), "seg.mp4"}, l.args)
+}
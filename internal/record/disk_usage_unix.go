@@ -0,0 +1,20 @@
+//go:build !windows
+// +build !windows
+
+package record
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// defaultDiskUsage returns the ID of the volume that contains the given path, and its free space.
+func defaultDiskUsage(path string) (string, uint64, error) {
+	var st syscall.Statfs_t
+	err := syscall.Statfs(path, &st)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return fmt.Sprintf("%v", st.Fsid), uint64(st.Bavail) * uint64(st.Bsize), nil //nolint:unconvert
+}
@@ -0,0 +1,31 @@
+//go:build windows
+// +build windows
+
+package record
+
+import (
+	"path/filepath"
+
+	"golang.org/x/sys/windows"
+)
+
+// defaultDiskUsage returns the ID of the volume that contains the given path, and its free space.
+func defaultDiskUsage(path string) (string, uint64, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", 0, err
+	}
+
+	ptr, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return "", 0, err
+	}
+
+	var free uint64
+	err = windows.GetDiskFreeSpaceEx(ptr, &free, nil, nil)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return filepath.VolumeName(path), free, nil
+}
@@ -153,7 +153,7 @@ func (f *formatFMP4) initialize() {
 
 				firstReceived := false
 
-				f.a.agent.Stream.AddReader(f.a.writer, media, forma, func(u unit.Unit) error {
+				f.a.addReader(media, forma, func(u unit.Unit) error {
 					tunit := u.(*unit.AV1)
 					if tunit.TU == nil {
 						return nil
@@ -211,7 +211,7 @@ func (f *formatFMP4) initialize() {
 
 				firstReceived := false
 
-				f.a.agent.Stream.AddReader(f.a.writer, media, forma, func(u unit.Unit) error {
+				f.a.addReader(media, forma, func(u unit.Unit) error {
 					tunit := u.(*unit.VP9)
 					if tunit.Frame == nil {
 						return nil
@@ -309,7 +309,7 @@ func (f *formatFMP4) initialize() {
 
 				var dtsExtractor *h265.DTSExtractor
 
-				f.a.agent.Stream.AddReader(f.a.writer, media, forma, func(u unit.Unit) error {
+				f.a.addReader(media, forma, func(u unit.Unit) error {
 					tunit := u.(*unit.H265)
 					if tunit.AU == nil {
 						return nil
@@ -387,7 +387,7 @@ func (f *formatFMP4) initialize() {
 
 				var dtsExtractor *h264.DTSExtractor
 
-				f.a.agent.Stream.AddReader(f.a.writer, media, forma, func(u unit.Unit) error {
+				f.a.addReader(media, forma, func(u unit.Unit) error {
 					tunit := u.(*unit.H264)
 					if tunit.AU == nil {
 						return nil
@@ -464,7 +464,7 @@ func (f *formatFMP4) initialize() {
 				firstReceived := false
 				var lastPTS time.Duration
 
-				f.a.agent.Stream.AddReader(f.a.writer, media, forma, func(u unit.Unit) error {
+				f.a.addReader(media, forma, func(u unit.Unit) error {
 					tunit := u.(*unit.MPEG4Video)
 					if tunit.Frame == nil {
 						return nil
@@ -517,7 +517,7 @@ func (f *formatFMP4) initialize() {
 				firstReceived := false
 				var lastPTS time.Duration
 
-				f.a.agent.Stream.AddReader(f.a.writer, media, forma, func(u unit.Unit) error {
+				f.a.addReader(media, forma, func(u unit.Unit) error {
 					tunit := u.(*unit.MPEG1Video)
 					if tunit.Frame == nil {
 						return nil
@@ -566,7 +566,7 @@ func (f *formatFMP4) initialize() {
 
 				parsed := false
 
-				f.a.agent.Stream.AddReader(f.a.writer, media, forma, func(u unit.Unit) error {
+				f.a.addReader(media, forma, func(u unit.Unit) error {
 					tunit := u.(*unit.MJPEG)
 					if tunit.Frame == nil {
 						return nil
@@ -603,7 +603,7 @@ func (f *formatFMP4) initialize() {
 				}
 				track := addTrack(forma, codec)
 
-				f.a.agent.Stream.AddReader(f.a.writer, media, forma, func(u unit.Unit) error {
+				f.a.addReader(media, forma, func(u unit.Unit) error {
 					tunit := u.(*unit.Opus)
 					if tunit.Packets == nil {
 						return nil
@@ -637,7 +637,7 @@ func (f *formatFMP4) initialize() {
 
 				sampleRate := time.Duration(forma.ClockRate())
 
-				f.a.agent.Stream.AddReader(f.a.writer, media, forma, func(u unit.Unit) error {
+				f.a.addReader(media, forma, func(u unit.Unit) error {
 					tunit := u.(*unit.MPEG4Audio)
 					if tunit.AUs == nil {
 						return nil
@@ -671,7 +671,7 @@ func (f *formatFMP4) initialize() {
 
 				parsed := false
 
-				f.a.agent.Stream.AddReader(f.a.writer, media, forma, func(u unit.Unit) error {
+				f.a.addReader(media, forma, func(u unit.Unit) error {
 					tunit := u.(*unit.MPEG1Audio)
 					if tunit.Frames == nil {
 						return nil
@@ -726,7 +726,7 @@ func (f *formatFMP4) initialize() {
 
 				parsed := false
 
-				f.a.agent.Stream.AddReader(f.a.writer, media, forma, func(u unit.Unit) error {
+				f.a.addReader(media, forma, func(u unit.Unit) error {
 					tunit := u.(*unit.AC3)
 					if tunit.Frames == nil {
 						return nil
@@ -788,7 +788,7 @@ func (f *formatFMP4) initialize() {
 				}
 				track := addTrack(forma, codec)
 
-				f.a.agent.Stream.AddReader(f.a.writer, media, forma, func(u unit.Unit) error {
+				f.a.addReader(media, forma, func(u unit.Unit) error {
 					tunit := u.(*unit.G711)
 					if tunit.Samples == nil {
 						return nil
@@ -819,7 +819,7 @@ func (f *formatFMP4) initialize() {
 				}
 				track := addTrack(forma, codec)
 
-				f.a.agent.Stream.AddReader(f.a.writer, media, forma, func(u unit.Unit) error {
+				f.a.addReader(media, forma, func(u unit.Unit) error {
 					tunit := u.(*unit.LPCM)
 					if tunit.Samples == nil {
 						return nil
@@ -69,7 +69,7 @@ func (p *formatFMP4Part) close() error {
 
 		p.s.f.a.agent.OnSegmentCreate(p.s.path)
 
-		err = writeInit(fi, p.s.f.tracks)
+		err = writeInit(&countingWriter{w: fi, agent: p.s.f.a.agent}, p.s.f.tracks)
 		if err != nil {
 			fi.Close()
 			return err
@@ -78,7 +78,7 @@ func (p *formatFMP4Part) close() error {
 		p.s.fi = fi
 	}
 
-	return writePart(p.s.fi, p.sequenceNumber, p.partTracks)
+	return writePart(&countingWriter{w: p.s.fi, agent: p.s.f.a.agent}, p.sequenceNumber, p.partTracks)
 }
 
 func (p *formatFMP4Part) record(track *formatFMP4Track, sample *sample) error {
@@ -71,7 +71,7 @@ func (f *formatMPEGTS) initialize() {
 
 				var dtsExtractor *h265.DTSExtractor
 
-				f.a.agent.Stream.AddReader(f.a.writer, media, forma, func(u unit.Unit) error {
+				f.a.addReader(media, forma, func(u unit.Unit) error {
 					tunit := u.(*unit.H265)
 					if tunit.AU == nil {
 						return nil
@@ -99,7 +99,7 @@ func (f *formatMPEGTS) initialize() {
 
 				var dtsExtractor *h264.DTSExtractor
 
-				f.a.agent.Stream.AddReader(f.a.writer, media, forma, func(u unit.Unit) error {
+				f.a.addReader(media, forma, func(u unit.Unit) error {
 					tunit := u.(*unit.H264)
 					if tunit.AU == nil {
 						return nil
@@ -128,7 +128,7 @@ func (f *formatMPEGTS) initialize() {
 				firstReceived := false
 				var lastPTS time.Duration
 
-				f.a.agent.Stream.AddReader(f.a.writer, media, forma, func(u unit.Unit) error {
+				f.a.addReader(media, forma, func(u unit.Unit) error {
 					tunit := u.(*unit.MPEG4Video)
 					if tunit.Frame == nil {
 						return nil
@@ -158,7 +158,7 @@ func (f *formatMPEGTS) initialize() {
 				firstReceived := false
 				var lastPTS time.Duration
 
-				f.a.agent.Stream.AddReader(f.a.writer, media, forma, func(u unit.Unit) error {
+				f.a.addReader(media, forma, func(u unit.Unit) error {
 					tunit := u.(*unit.MPEG1Video)
 					if tunit.Frame == nil {
 						return nil
@@ -192,7 +192,7 @@ func (f *formatMPEGTS) initialize() {
 					}(),
 				})
 
-				f.a.agent.Stream.AddReader(f.a.writer, media, forma, func(u unit.Unit) error {
+				f.a.addReader(media, forma, func(u unit.Unit) error {
 					tunit := u.(*unit.Opus)
 					if tunit.Packets == nil {
 						return nil
@@ -211,7 +211,7 @@ func (f *formatMPEGTS) initialize() {
 					Config: *forma.GetConfig(),
 				})
 
-				f.a.agent.Stream.AddReader(f.a.writer, media, forma, func(u unit.Unit) error {
+				f.a.addReader(media, forma, func(u unit.Unit) error {
 					tunit := u.(*unit.MPEG4Audio)
 					if tunit.AUs == nil {
 						return nil
@@ -228,7 +228,7 @@ func (f *formatMPEGTS) initialize() {
 			case *rtspformat.MPEG1Audio:
 				track := addTrack(forma, &mpegts.CodecMPEG1Audio{})
 
-				f.a.agent.Stream.AddReader(f.a.writer, media, forma, func(u unit.Unit) error {
+				f.a.addReader(media, forma, func(u unit.Unit) error {
 					tunit := u.(*unit.MPEG1Audio)
 					if tunit.Frames == nil {
 						return nil
@@ -247,7 +247,7 @@ func (f *formatMPEGTS) initialize() {
 
 				sampleRate := time.Duration(forma.SampleRate)
 
-				f.a.agent.Stream.AddReader(f.a.writer, media, forma, func(u unit.Unit) error {
+				f.a.addReader(media, forma, func(u unit.Unit) error {
 					tunit := u.(*unit.AC3)
 					if tunit.Frames == nil {
 						return nil
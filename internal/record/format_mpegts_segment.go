@@ -3,6 +3,7 @@ package record
 import (
 	"os"
 	"path/filepath"
+	"sync/atomic"
 	"time"
 
 	"github.com/bluenviron/mediamtx/internal/logger"
@@ -61,5 +62,7 @@ func (s *formatMPEGTSSegment) Write(p []byte) (int, error) {
 		s.fi = fi
 	}
 
-	return s.fi.Write(p)
+	n, err := s.fi.Write(p)
+	atomic.AddUint64(&s.f.a.agent.bytesWritten, uint64(n))
+	return n, err
 }
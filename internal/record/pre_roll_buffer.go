@@ -0,0 +1,187 @@
+package record
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	rtspformat "github.com/bluenviron/gortsplib/v4/pkg/format"
+	"github.com/bluenviron/mediacommon/pkg/codecs/av1"
+	"github.com/bluenviron/mediacommon/pkg/codecs/h264"
+	"github.com/bluenviron/mediacommon/pkg/codecs/h265"
+	"github.com/bluenviron/mediacommon/pkg/codecs/mpeg4video"
+	"github.com/bluenviron/mediacommon/pkg/codecs/vp9"
+
+	"github.com/bluenviron/mediamtx/internal/asyncwriter"
+	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/bluenviron/mediamtx/internal/stream"
+	"github.com/bluenviron/mediamtx/internal/unit"
+)
+
+// unitIsRandomAccess returns whether a unit can be decoded independently.
+// ok is false when a video unit doesn't contain any data.
+func unitIsRandomAccess(u unit.Unit) (randomAccess bool, ok bool) {
+	switch tunit := u.(type) {
+	case *unit.H264:
+		return h264.IDRPresent(tunit.AU), tunit.AU != nil
+
+	case *unit.H265:
+		return h265.IsRandomAccess(tunit.AU), tunit.AU != nil
+
+	case *unit.AV1:
+		if tunit.TU == nil {
+			return false, false
+		}
+		randomAccess, err := av1.ContainsKeyFrame(tunit.TU)
+		return err == nil && randomAccess, true
+
+	case *unit.VP9:
+		if tunit.Frame == nil {
+			return false, false
+		}
+		var h vp9.Header
+		err := h.Unmarshal(tunit.Frame)
+		return err == nil && h.FrameType == vp9.FrameTypeKeyFrame, true
+
+	case *unit.VP8:
+		if len(tunit.Frame) == 0 {
+			return false, false
+		}
+		return (tunit.Frame[0] & 0x01) == 0, true
+
+	case *unit.MPEG4Video:
+		if tunit.Frame == nil {
+			return false, false
+		}
+		return bytes.Contains(tunit.Frame, []byte{0, 0, 1, byte(mpeg4video.GroupOfVOPStartCode)}), true
+
+	case *unit.MPEG1Video:
+		if tunit.Frame == nil {
+			return false, false
+		}
+		return bytes.Contains(tunit.Frame, []byte{0, 0, 1, 0xB8}), true
+	}
+
+	// remaining formats don't have inter-frame dependencies
+	return true, true
+}
+
+type preRollEntry struct {
+	format       rtspformat.Format
+	unit         unit.Unit
+	randomAccess bool
+}
+
+// PreRollBuffer keeps the last units of a stream in memory,
+// in order to write them at the beginning of recordings.
+// The buffer always starts with a video key frame, if the stream contains video.
+type PreRollBuffer struct {
+	Duration       time.Duration
+	WriteQueueSize int
+	Stream         *stream.Stream
+	Parent         logger.Writer
+
+	writer   *asyncwriter.Writer
+	hasVideo bool
+
+	mutex   sync.Mutex
+	entries []*preRollEntry
+}
+
+// Initialize initializes PreRollBuffer.
+func (b *PreRollBuffer) Initialize() {
+	b.writer = asyncwriter.New(b.WriteQueueSize, b)
+
+	for _, media := range b.Stream.Desc().Medias {
+		if media.Type == description.MediaTypeVideo {
+			b.hasVideo = true
+		}
+	}
+
+	for _, media := range b.Stream.Desc().Medias {
+		isVideo := (media.Type == description.MediaTypeVideo)
+
+		for _, forma := range media.Formats {
+			cforma := forma
+
+			b.Stream.AddReader(b.writer, media, forma, func(u unit.Unit) error {
+				randomAccess, ok := unitIsRandomAccess(u)
+				if !ok {
+					return nil
+				}
+
+				// when there's video, the buffer can be cut at video key frames only.
+				if b.hasVideo && !isVideo {
+					randomAccess = false
+				}
+
+				b.add(&preRollEntry{
+					format:       cforma,
+					unit:         u,
+					randomAccess: randomAccess,
+				})
+				return nil
+			})
+		}
+	}
+
+	b.writer.Start()
+
+	b.Log(logger.Debug, "buffering the last %v", b.Duration)
+}
+
+// Close closes PreRollBuffer.
+func (b *PreRollBuffer) Close() {
+	b.Stream.RemoveReader(b.writer)
+	b.writer.Stop()
+}
+
+// Log implements logger.Writer.
+func (b *PreRollBuffer) Log(level logger.Level, format string, args ...interface{}) {
+	b.Parent.Log(level, "%v"+format, append([]interface{}{logger.Component("record pre-roll")}, args...)...)
+}
+
+func (b *PreRollBuffer) add(e *preRollEntry) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if len(b.entries) == 0 && !e.randomAccess {
+		return
+	}
+
+	b.entries = append(b.entries, e)
+
+	pts := e.unit.GetPTS()
+
+	// remove the oldest entries as long as the remaining ones start with
+	// a random access entry and still cover the entire duration.
+	for {
+		next := -1
+		for i := 1; i < len(b.entries); i++ {
+			if b.entries[i].randomAccess {
+				next = i
+				break
+			}
+		}
+
+		if next < 0 || (pts-b.entries[next].unit.GetPTS()) < b.Duration {
+			break
+		}
+
+		for i := 0; i < next; i++ {
+			b.entries[i] = nil
+		}
+		b.entries = b.entries[next:]
+	}
+}
+
+// snapshot returns the entries currently in the buffer.
+func (b *PreRollBuffer) snapshot() []*preRollEntry {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	out := make([]*preRollEntry, len(b.entries))
+	copy(out, b.entries)
+	return out
+}
@@ -0,0 +1,190 @@
+package record
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	rtspformat "github.com/bluenviron/gortsplib/v4/pkg/format"
+	"github.com/bluenviron/mediacommon/pkg/codecs/mpeg4audio"
+	"github.com/bluenviron/mediacommon/pkg/formats/fmp4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/stream"
+	"github.com/bluenviron/mediamtx/internal/test"
+	"github.com/bluenviron/mediamtx/internal/unit"
+)
+
+var preRollTestDesc = &description.Session{Medias: []*description.Media{
+	{
+		Type: description.MediaTypeVideo,
+		Formats: []rtspformat.Format{&rtspformat.H264{
+			PayloadTyp:        96,
+			PacketizationMode: 1,
+		}},
+	},
+	{
+		Type: description.MediaTypeAudio,
+		Formats: []rtspformat.Format{&rtspformat.MPEG4Audio{
+			PayloadTyp: 96,
+			Config: &mpeg4audio.Config{
+				Type:         2,
+				SampleRate:   44100,
+				ChannelCount: 2,
+			},
+			SizeLength:       13,
+			IndexLength:      3,
+			IndexDeltaLength: 3,
+		}},
+	},
+}}
+
+// writePreRollTestUnits writes one video frame and one audio frame per second.
+// Video frames are IDRs when the second is even.
+func writePreRollTestUnits(strm *stream.Stream, start int, end int) {
+	for i := start; i < end; i++ {
+		au := [][]byte{{1}} // non-IDR
+		if (i % 2) == 0 {
+			au = [][]byte{
+				test.FormatH264.SPS,
+				test.FormatH264.PPS,
+				{5}, // IDR
+			}
+		}
+
+		strm.WriteUnit(preRollTestDesc.Medias[0], preRollTestDesc.Medias[0].Formats[0], &unit.H264{
+			Base: unit.Base{
+				PTS: time.Duration(i) * time.Second,
+				NTP: time.Date(2008, 0o5, 20, 22, 15, 25, 0, time.UTC).Add(time.Duration(i) * time.Second),
+			},
+			AU: au,
+		})
+
+		strm.WriteUnit(preRollTestDesc.Medias[1], preRollTestDesc.Medias[1].Formats[0], &unit.MPEG4Audio{
+			Base: unit.Base{
+				PTS: time.Duration(i) * time.Second,
+			},
+			AUs: [][]byte{{1, 2, 3, 4}},
+		})
+	}
+}
+
+func waitPreRollEntries(t *testing.T, b *PreRollBuffer, lastPTS time.Duration) []*preRollEntry {
+	for i := 0; ; i++ {
+		entries := b.snapshot()
+		if len(entries) != 0 && entries[len(entries)-1].unit.GetPTS() == lastPTS {
+			return entries
+		}
+
+		require.Less(t, i, 100)
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestPreRollBuffer(t *testing.T) {
+	strm, err := stream.New(
+		1460,
+		preRollTestDesc,
+		true,
+		test.NilLogger,
+	)
+	require.NoError(t, err)
+	defer strm.Close()
+
+	b := &PreRollBuffer{
+		Duration:       2 * time.Second,
+		WriteQueueSize: 1024,
+		Stream:         strm,
+		Parent:         test.NilLogger,
+	}
+	b.Initialize()
+	defer b.Close()
+
+	// units before the first IDR are discarded
+	writePreRollTestUnits(strm, 1, 8)
+
+	entries := waitPreRollEntries(t, b, 7*time.Second)
+
+	var pts []time.Duration
+	for _, e := range entries {
+		pts = append(pts, e.unit.GetPTS())
+	}
+
+	// the buffer starts with the newest IDR that is at least 2 seconds old
+	require.Equal(t, []time.Duration{
+		4 * time.Second, 4 * time.Second,
+		5 * time.Second, 5 * time.Second,
+		6 * time.Second, 6 * time.Second,
+		7 * time.Second, 7 * time.Second,
+	}, pts)
+	require.Equal(t, true, entries[0].randomAccess)
+}
+
+func TestAgentPreRoll(t *testing.T) {
+	strm, err := stream.New(
+		1460,
+		preRollTestDesc,
+		true,
+		test.NilLogger,
+	)
+	require.NoError(t, err)
+	defer strm.Close()
+
+	dir, err := os.MkdirTemp("", "mediamtx-agent")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	b := &PreRollBuffer{
+		Duration:       2 * time.Second,
+		WriteQueueSize: 1024,
+		Stream:         strm,
+		Parent:         test.NilLogger,
+	}
+	b.Initialize()
+	defer b.Close()
+
+	writePreRollTestUnits(strm, 0, 6)
+	waitPreRollEntries(t, b, 5*time.Second)
+
+	w := &Agent{
+		WriteQueueSize:  1024,
+		PathFormat:      filepath.Join(dir, "%path/%Y-%m-%d_%H-%M-%S-%f"),
+		Format:          conf.RecordFormatFMP4,
+		PartDuration:    100 * time.Millisecond,
+		SegmentDuration: 1 * time.Hour,
+		PathName:        "mypath",
+		Stream:          strm,
+		PreRoll:         b,
+		Parent:          test.NilLogger,
+	}
+	w.Initialize()
+
+	writePreRollTestUnits(strm, 6, 9)
+
+	time.Sleep(50 * time.Millisecond)
+
+	w.Close()
+
+	// the segment starts with the pre-roll
+	byts, err := os.ReadFile(filepath.Join(dir, "mypath", "2008-05-20_22-15-27-000000.mp4"))
+	require.NoError(t, err)
+
+	var parts fmp4.Parts
+	err = parts.Unmarshal(byts)
+	require.NoError(t, err)
+
+	// samples of the pre-roll and of the live stream are written once.
+	// the last video sample is kept in memory, since its duration is unknown.
+	count := 0
+	for _, part := range parts {
+		for _, track := range part.Tracks {
+			if track.ID == 1 {
+				count += len(track.Samples)
+			}
+		}
+	}
+	require.Equal(t, 6, count)
+}
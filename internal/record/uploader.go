@@ -0,0 +1,358 @@
+package record
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/bluenviron/mediamtx/internal/protocols/s3"
+)
+
+const (
+	uploaderPartAttempts = 3
+)
+
+// UploadKey returns the object key of a segment.
+// prefix is a template encoded with Path.Encode, followed by the file name of the segment.
+func UploadKey(
+	prefix string,
+	pathFormat string,
+	format conf.RecordFormat,
+	pathName string,
+	segmentPath string,
+) string {
+	pathFormat = PathAddExtension(strings.ReplaceAll(pathFormat, "%path", pathName), format)
+
+	var pa Path
+	pa.Decode(pathFormat, segmentPath)
+	pa.Path = pathName
+
+	return pa.Encode(prefix) + filepath.Base(segmentPath)
+}
+
+type uploadJob struct {
+	File        string `json:"file"`
+	Key         string `json:"key"`
+	DeleteLocal bool   `json:"deleteLocal"`
+}
+
+// Uploader uploads recording segments to a S3-compatible object storage.
+// Pending uploads are stored in a queue file, in order to be resumed after a restart.
+type Uploader struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	PartSize        uint64
+	QueuePath       string
+	Timeout         time.Duration
+	Parent          logger.Writer
+
+	minRetryPause time.Duration
+	maxRetryPause time.Duration
+
+	client    *s3.Client
+	ctx       context.Context
+	ctxCancel func()
+	mutex     sync.Mutex
+	queue     []uploadJob
+
+	// in
+	chEnqueue chan struct{}
+
+	// out
+	done chan struct{}
+}
+
+// Initialize initializes Uploader.
+func (u *Uploader) Initialize() error {
+	if u.minRetryPause == 0 {
+		u.minRetryPause = 2 * time.Second
+	}
+	if u.maxRetryPause == 0 {
+		u.maxRetryPause = 5 * time.Minute
+	}
+
+	err := u.loadQueue()
+	if err != nil {
+		return err
+	}
+
+	u.client = &s3.Client{
+		Endpoint:        u.Endpoint,
+		Region:          u.Region,
+		Bucket:          u.Bucket,
+		AccessKeyID:     u.AccessKeyID,
+		SecretAccessKey: u.SecretAccessKey,
+		HTTPClient: &http.Client{
+			Timeout: u.Timeout,
+		},
+	}
+
+	u.ctx, u.ctxCancel = context.WithCancel(context.Background())
+	u.chEnqueue = make(chan struct{}, 1)
+	u.done = make(chan struct{})
+
+	if len(u.queue) != 0 {
+		u.Log(logger.Info, "resuming %d pending uploads", len(u.queue))
+	}
+
+	go u.run()
+
+	return nil
+}
+
+// Close closes the Uploader.
+func (u *Uploader) Close() {
+	u.ctxCancel()
+	<-u.done
+}
+
+// Log implements logger.Writer.
+func (u *Uploader) Log(level logger.Level, format string, args ...interface{}) {
+	u.Parent.Log(level, "%v"+format, append([]interface{}{logger.Component("record uploader")}, args...)...)
+}
+
+// Enqueue adds a segment to the upload queue.
+func (u *Uploader) Enqueue(segmentPath string, key string, deleteLocal bool) {
+	u.mutex.Lock()
+	u.queue = append(u.queue, uploadJob{
+		File:        segmentPath,
+		Key:         key,
+		DeleteLocal: deleteLocal,
+	})
+	err := u.saveQueue()
+	u.mutex.Unlock()
+
+	if err != nil {
+		u.Log(logger.Warn, "unable to save queue: %v", err)
+	}
+
+	select {
+	case u.chEnqueue <- struct{}{}:
+	default:
+	}
+}
+
+func (u *Uploader) loadQueue() error {
+	byts, err := os.ReadFile(u.QueuePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	return json.Unmarshal(byts, &u.queue)
+}
+
+func (u *Uploader) saveQueue() error {
+	byts, err := json.Marshal(u.queue)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(u.QueuePath)
+
+	err = os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file and then rename it, in order to never leave a partial queue on disk.
+	tmp, err := os.CreateTemp(dir, filepath.Base(u.QueuePath)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(byts)
+	tmp.Close()
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), u.QueuePath)
+}
+
+func (u *Uploader) firstJob() (uploadJob, bool) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	if len(u.queue) == 0 {
+		return uploadJob{}, false
+	}
+	return u.queue[0], true
+}
+
+func (u *Uploader) removeFirstJob() {
+	u.mutex.Lock()
+	u.queue = u.queue[1:]
+	err := u.saveQueue()
+	u.mutex.Unlock()
+
+	if err != nil {
+		u.Log(logger.Warn, "unable to save queue: %v", err)
+	}
+}
+
+func (u *Uploader) run() {
+	defer close(u.done)
+
+	retryPause := u.minRetryPause
+
+	for {
+		job, ok := u.firstJob()
+		if !ok {
+			select {
+			case <-u.chEnqueue:
+				continue
+			case <-u.ctx.Done():
+				return
+			}
+		}
+
+		err := u.upload(job)
+
+		switch {
+		case err == nil:
+			u.Log(logger.Debug, "uploaded %s to %s", job.File, job.Key)
+
+			if job.DeleteLocal {
+				err := os.Remove(job.File)
+				if err != nil {
+					u.Log(logger.Warn, "unable to delete %s: %v", job.File, err)
+				}
+			}
+
+			u.removeFirstJob()
+			retryPause = u.minRetryPause
+
+		case errors.Is(err, os.ErrNotExist):
+			u.Log(logger.Warn, "segment %s doesn't exist anymore, skipping", job.File)
+			u.removeFirstJob()
+
+		default:
+			if u.ctx.Err() != nil {
+				return
+			}
+
+			u.Log(logger.Error, "unable to upload %s: %v, retrying in %v", job.File, err, retryPause)
+
+			select {
+			case <-time.After(retryPause):
+			case <-u.ctx.Done():
+				return
+			}
+
+			retryPause *= 2
+			if retryPause > u.maxRetryPause {
+				retryPause = u.maxRetryPause
+			}
+		}
+	}
+}
+
+func (u *Uploader) upload(job uploadJob) error {
+	f, err := os.Open(job.File)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	if uint64(fi.Size()) <= u.PartSize {
+		byts, err := io.ReadAll(f)
+		if err != nil {
+			return err
+		}
+
+		return u.client.PutObject(u.ctx, job.Key, byts)
+	}
+
+	uploadID, err := u.client.CreateMultipartUpload(u.ctx, job.Key)
+	if err != nil {
+		return err
+	}
+
+	err = u.uploadParts(f, job.Key, uploadID)
+	if err != nil {
+		// use a separate context, since the main one may be canceled
+		ctx, ctxCancel := context.WithTimeout(context.Background(), u.client.HTTPClient.Timeout)
+		u.client.AbortMultipartUpload(ctx, job.Key, uploadID) //nolint:errcheck
+		ctxCancel()
+		return err
+	}
+
+	return nil
+}
+
+func (u *Uploader) uploadParts(f *os.File, key string, uploadID string) error {
+	buf := make([]byte, u.PartSize)
+	var etags []string
+
+	for {
+		n, err := io.ReadFull(f, buf)
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return err
+		}
+
+		etag, err := u.uploadPart(key, uploadID, len(etags)+1, buf[:n])
+		if err != nil {
+			return err
+		}
+
+		etags = append(etags, etag)
+
+		if n < len(buf) {
+			break
+		}
+	}
+
+	return u.client.CompleteMultipartUpload(u.ctx, key, uploadID, etags)
+}
+
+func (u *Uploader) uploadPart(key string, uploadID string, partNumber int, byts []byte) (string, error) {
+	retryPause := u.minRetryPause
+
+	for i := 1; ; i++ {
+		etag, err := u.client.UploadPart(u.ctx, key, uploadID, partNumber, byts)
+		if err == nil {
+			return etag, nil
+		}
+
+		if i == uploaderPartAttempts || u.ctx.Err() != nil {
+			return "", err
+		}
+
+		u.Log(logger.Debug, "unable to upload part %d of %s: %v, retrying in %v", partNumber, key, err, retryPause)
+
+		select {
+		case <-time.After(retryPause):
+		case <-u.ctx.Done():
+			return "", err
+		}
+
+		retryPause *= 2
+		if retryPause > u.maxRetryPause {
+			retryPause = u.maxRetryPause
+		}
+	}
+}
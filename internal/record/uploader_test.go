@@ -0,0 +1,182 @@
+package record
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/test"
+	"github.com/stretchr/testify/require"
+)
+
+// dummyS3 is a minimal S3-compatible object storage.
+type dummyS3 struct {
+	mutex     sync.Mutex
+	objects   map[string][]byte
+	uploads   map[string]map[int][]byte
+	failFirst int
+	received  chan string
+}
+
+func (s *dummyS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=myuser/") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	if s.failFirst > 0 {
+		s.failFirst--
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("<Error><Code>InternalError</Code><Message>test</Message></Error>")) //nolint:errcheck
+		return
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, "/mybucket/")
+	q := r.URL.Query()
+	body, _ := io.ReadAll(r.Body)
+
+	switch {
+	case r.Method == http.MethodPost && q.Has("uploads"):
+		s.uploads["upload1"] = make(map[int][]byte)
+		w.Write([]byte("<InitiateMultipartUploadResult><UploadId>upload1</UploadId>" + //nolint:errcheck
+			"</InitiateMultipartUploadResult>"))
+
+	case r.Method == http.MethodPut && q.Has("uploadId"):
+		n, _ := strconv.ParseInt(q.Get("partNumber"), 10, 64)
+		s.uploads[q.Get("uploadId")][int(n)] = body
+		w.Header().Set("ETag", fmt.Sprintf(`"etag%d"`, n))
+
+	case r.Method == http.MethodPost && q.Has("uploadId"):
+		var req struct {
+			Parts []struct {
+				PartNumber int
+				ETag       string
+			} `xml:"Part"`
+		}
+		xml.Unmarshal(body, &req) //nolint:errcheck
+
+		var buf bytes.Buffer
+		for _, p := range req.Parts {
+			buf.Write(s.uploads[q.Get("uploadId")][p.PartNumber])
+		}
+
+		s.objects[key] = buf.Bytes()
+		s.received <- key
+
+	case r.Method == http.MethodPut:
+		s.objects[key] = body
+		s.received <- key
+
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func TestUploadKey(t *testing.T) {
+	key := UploadKey(
+		"%path/%Y/%m/%d/",
+		"./recordings/%path/%Y-%m-%d_%H-%M-%S-%f",
+		conf.RecordFormatFMP4,
+		"my/path",
+		"./recordings/my/path/2008-05-20_22-15-25-000125.mp4")
+	require.Equal(t, "my/path/2008/05/20/2008-05-20_22-15-25-000125.mp4", key)
+}
+
+func TestUploader(t *testing.T) {
+	for _, ca := range []string{"single", "multipart"} {
+		t.Run(ca, func(t *testing.T) {
+			dir, err := os.MkdirTemp("", "mediamtx-uploader")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			s3 := &dummyS3{
+				objects:   make(map[string][]byte),
+				uploads:   make(map[string]map[int][]byte),
+				failFirst: 1,
+				received:  make(chan string, 10),
+			}
+
+			ts := httptest.NewServer(s3)
+			defer ts.Close()
+
+			content := bytes.Repeat([]byte{1, 2, 3, 4}, 10)
+
+			seg1 := filepath.Join(dir, "seg1.mp4")
+			err = os.WriteFile(seg1, content, 0o644)
+			require.NoError(t, err)
+
+			seg2 := filepath.Join(dir, "seg2.mp4")
+			err = os.WriteFile(seg2, content, 0o644)
+			require.NoError(t, err)
+
+			queuePath := filepath.Join(dir, "queue.json")
+
+			// the queue of a previous run
+			err = os.WriteFile(queuePath, []byte(`[{"file":"`+seg1+`","key":"mypath/seg1.mp4","deleteLocal":true}]`), 0o644)
+			require.NoError(t, err)
+
+			partSize := uint64(1024)
+			if ca == "multipart" {
+				partSize = 16
+			}
+
+			u := &Uploader{
+				Endpoint:        ts.URL,
+				Region:          "us-east-1",
+				Bucket:          "mybucket",
+				AccessKeyID:     "myuser",
+				SecretAccessKey: "mypass",
+				PartSize:        partSize,
+				QueuePath:       queuePath,
+				Timeout:         10 * time.Second,
+				Parent:          test.NilLogger,
+				minRetryPause:   1 * time.Millisecond,
+			}
+			err = u.Initialize()
+			require.NoError(t, err)
+			defer u.Close()
+
+			u.Enqueue(seg2, "mypath/seg2.mp4", false)
+
+			require.Equal(t, "mypath/seg1.mp4", <-s3.received)
+			require.Equal(t, "mypath/seg2.mp4", <-s3.received)
+
+			s3.mutex.Lock()
+			require.Equal(t, content, s3.objects["mypath/seg1.mp4"])
+			require.Equal(t, content, s3.objects["mypath/seg2.mp4"])
+			s3.mutex.Unlock()
+
+			for i := 0; ; i++ {
+				byts, err := os.ReadFile(queuePath)
+				require.NoError(t, err)
+
+				if string(byts) == "[]" {
+					break
+				}
+
+				require.Less(t, i, 100)
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			_, err = os.Stat(seg1)
+			require.Error(t, err)
+
+			_, err = os.Stat(seg2)
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package dash
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	gopath "path"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/bluenviron/mediamtx/internal/auth"
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/defs"
+	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/bluenviron/mediamtx/internal/protocols/httpp"
+	"github.com/bluenviron/mediamtx/internal/restrictnetwork"
+)
+
+type httpServer struct {
+	address        string
+	encryption     bool
+	serverKey      string
+	serverCert     string
+	allowOrigin    string
+	trustedProxies conf.IPNetworks
+	readTimeout    conf.StringDuration
+	pathManager    serverPathManager
+	parent         *Server
+
+	inner *httpp.WrappedServer
+}
+
+func (s *httpServer) initialize() error {
+	if s.encryption {
+		if s.serverCert == "" {
+			return fmt.Errorf("server cert is missing")
+		}
+	} else {
+		s.serverKey = ""
+		s.serverCert = ""
+	}
+
+	router := gin.New()
+	router.SetTrustedProxies(s.trustedProxies.ToTrustedProxies()) //nolint:errcheck
+
+	router.NoRoute(s.onRequest)
+
+	network, address := restrictnetwork.Restrict("tcp", s.address)
+
+	var err error
+	s.inner, err = httpp.NewWrappedServer(
+		network,
+		address,
+		time.Duration(s.readTimeout),
+		s.serverCert,
+		s.serverKey,
+		router,
+		s,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Log implements logger.Writer.
+func (s *httpServer) Log(level logger.Level, format string, args ...interface{}) {
+	s.parent.Log(level, format, args...)
+}
+
+func (s *httpServer) close() {
+	s.inner.Close()
+}
+
+func (s *httpServer) onRequest(ctx *gin.Context) {
+	ctx.Writer.Header().Set("Access-Control-Allow-Origin", s.allowOrigin)
+	ctx.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
+
+	switch ctx.Request.Method {
+	case http.MethodOptions:
+		ctx.Writer.Header().Set("Access-Control-Allow-Methods", "OPTIONS, GET")
+		ctx.Writer.Header().Set("Access-Control-Allow-Headers", "Authorization, Range")
+		ctx.Writer.WriteHeader(http.StatusNoContent)
+		return
+
+	case http.MethodGet:
+
+	default:
+		return
+	}
+
+	// remove leading prefix
+	pa := ctx.Request.URL.Path[1:]
+
+	if !strings.HasSuffix(pa, ".mpd") && !strings.HasSuffix(pa, ".mp4") {
+		ctx.Writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	dir, fname := gopath.Dir(pa), gopath.Base(pa)
+	if dir == "." {
+		ctx.Writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	user, pass, hasCredentials := ctx.Request.BasicAuth()
+
+	pathConf, err := s.pathManager.FindPathConf(defs.PathFindPathConfReq{
+		AccessRequest: defs.PathAccessRequest{
+			Name:    dir,
+			Query:   ctx.Request.URL.RawQuery,
+			Publish: false,
+			IP:      net.ParseIP(ctx.ClientIP()),
+			User:    user,
+			Pass:    pass,
+			Proto:   auth.ProtocolDASH,
+		},
+	})
+	if err != nil {
+		var terr auth.Error
+		if errors.As(err, &terr) {
+			if !hasCredentials {
+				ctx.Header("WWW-Authenticate", `Basic realm="mediamtx"`)
+				ctx.Writer.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			s.Log(logger.Info, "connection %v failed to authenticate: %v", httpp.RemoteAddr(ctx), terr.Message)
+
+			// wait some seconds to mitigate brute force attacks
+			<-time.After(auth.PauseAfterError)
+
+			ctx.Writer.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		ctx.Writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	mux, err := s.parent.getMuxer(serverGetMuxerReq{
+		path:           dir,
+		remoteAddr:     httpp.RemoteAddr(ctx),
+		query:          ctx.Request.URL.RawQuery,
+		sourceOnDemand: pathConf.SourceOnDemand,
+	})
+	if err != nil {
+		ctx.Writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	mi := mux.getInstance()
+	if mi == nil {
+		ctx.Writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	ctx.Request.URL.Path = fname
+	mi.handleRequest(ctx)
+}
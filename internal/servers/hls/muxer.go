@@ -99,7 +99,10 @@ func (m *muxer) Close() {
 
 // Log implements logger.Writer.
 func (m *muxer) Log(level logger.Level, format string, args ...interface{}) {
-	m.parent.Log(level, "[muxer %s] "+format, append([]interface{}{m.pathName}, args...)...)
+	m.parent.Log(level, "%v"+format, append([]interface{}{logger.Attr{
+		Text:   "[muxer " + m.pathName + "] ",
+		Fields: []logger.Field{{Key: "path", Value: m.pathName}},
+	}}, args...)...)
 }
 
 // PathName returns the path name.
@@ -134,7 +134,7 @@ func (s *Server) Initialize() error {
 
 // Log implements logger.Writer.
 func (s *Server) Log(level logger.Level, format string, args ...interface{}) {
-	s.Parent.Log(level, "[HLS] "+format, args...)
+	s.Parent.Log(level, "%v"+format, append([]interface{}{logger.Component("HLS")}, args...)...)
 }
 
 // Close closes the server.
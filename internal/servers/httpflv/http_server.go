@@ -0,0 +1,109 @@
+package httpflv
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/defs"
+	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/bluenviron/mediamtx/internal/protocols/httpp"
+	"github.com/bluenviron/mediamtx/internal/restrictnetwork"
+)
+
+func writeError(ctx *gin.Context, statusCode int, err error) {
+	ctx.JSON(statusCode, &defs.APIError{
+		Error: err.Error(),
+	})
+}
+
+func isWebSocketUpgrade(req *http.Request) bool {
+	return strings.EqualFold(req.Header.Get("Upgrade"), "websocket")
+}
+
+type httpServer struct {
+	address        string
+	encryption     bool
+	serverKey      string
+	serverCert     string
+	allowOrigin    string
+	trustedProxies conf.IPNetworks
+	readTimeout    conf.StringDuration
+	parent         *Server
+
+	inner *httpp.WrappedServer
+}
+
+func (s *httpServer) initialize() error {
+	if s.encryption {
+		if s.serverCert == "" {
+			return fmt.Errorf("server cert is missing")
+		}
+	} else {
+		s.serverKey = ""
+		s.serverCert = ""
+	}
+
+	router := gin.New()
+	router.SetTrustedProxies(s.trustedProxies.ToTrustedProxies()) //nolint:errcheck
+	router.NoRoute(s.onRequest)
+
+	network, address := restrictnetwork.Restrict("tcp", s.address)
+
+	var err error
+	s.inner, err = httpp.NewWrappedServer(
+		network,
+		address,
+		time.Duration(s.readTimeout),
+		s.serverCert,
+		s.serverKey,
+		router,
+		s,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Log implements logger.Writer.
+func (s *httpServer) Log(level logger.Level, format string, args ...interface{}) {
+	s.parent.Log(level, format, args...)
+}
+
+func (s *httpServer) close() {
+	s.inner.Close()
+}
+
+func (s *httpServer) onRequest(ctx *gin.Context) {
+	ctx.Writer.Header().Set("Access-Control-Allow-Origin", s.allowOrigin)
+	ctx.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
+
+	switch ctx.Request.Method {
+	case http.MethodOptions:
+		ctx.Writer.Header().Set("Access-Control-Allow-Methods", "OPTIONS, GET")
+		ctx.Writer.Header().Set("Access-Control-Allow-Headers", "Authorization")
+		ctx.Writer.WriteHeader(http.StatusNoContent)
+		return
+
+	case http.MethodGet:
+
+	default:
+		return
+	}
+
+	// remove leading prefix
+	pa := ctx.Request.URL.Path[1:]
+
+	if !strings.HasSuffix(pa, ".flv") || len(pa) <= len(".flv") {
+		ctx.Writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	s.parent.handleConn(ctx, strings.TrimSuffix(pa, ".flv"), isWebSocketUpgrade(ctx.Request))
+}
@@ -0,0 +1,288 @@
+// Package httpflv contains a HTTP-FLV server.
+package httpflv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/defs"
+	"github.com/bluenviron/mediamtx/internal/externalcmd"
+	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/bluenviron/mediamtx/internal/stream"
+)
+
+// ErrConnNotFound is returned when a connection is not found.
+var ErrConnNotFound = errors.New("connection not found")
+
+type serverAPIConnsListRes struct {
+	data *defs.APIHTTPFLVConnList
+	err  error
+}
+
+type serverAPIConnsListReq struct {
+	res chan serverAPIConnsListRes
+}
+
+type serverAPIConnsGetRes struct {
+	data *defs.APIHTTPFLVConn
+	err  error
+}
+
+type serverAPIConnsGetReq struct {
+	uuid uuid.UUID
+	res  chan serverAPIConnsGetRes
+}
+
+type serverAPIConnsKickRes struct {
+	err error
+}
+
+type serverAPIConnsKickReq struct {
+	uuid uuid.UUID
+	res  chan serverAPIConnsKickRes
+}
+
+type serverPathManager interface {
+	AddReader(req defs.PathAddReaderReq) (defs.Path, *stream.Stream, error)
+}
+
+type serverParent interface {
+	logger.Writer
+}
+
+// Server is a HTTP-FLV server.
+type Server struct {
+	Address         string
+	Encryption      bool
+	ServerKey       string
+	ServerCert      string
+	AllowOrigin     string
+	TrustedProxies  conf.IPNetworks
+	ReadTimeout     conf.StringDuration
+	WriteTimeout    conf.StringDuration
+	WriteQueueSize  int
+	ExternalCmdPool *externalcmd.Pool
+	PathManager     serverPathManager
+	Parent          serverParent
+
+	ctx        context.Context
+	ctxCancel  func()
+	wg         sync.WaitGroup
+	httpServer *httpServer
+	conns      map[*conn]struct{}
+
+	// in
+	chNewConn      chan *conn
+	chCloseConn    chan *conn
+	chAPIConnsList chan serverAPIConnsListReq
+	chAPIConnsGet  chan serverAPIConnsGetReq
+	chAPIConnsKick chan serverAPIConnsKickReq
+}
+
+// Initialize initializes the server.
+func (s *Server) Initialize() error {
+	s.ctx, s.ctxCancel = context.WithCancel(context.Background())
+
+	s.conns = make(map[*conn]struct{})
+	s.chNewConn = make(chan *conn)
+	s.chCloseConn = make(chan *conn)
+	s.chAPIConnsList = make(chan serverAPIConnsListReq)
+	s.chAPIConnsGet = make(chan serverAPIConnsGetReq)
+	s.chAPIConnsKick = make(chan serverAPIConnsKickReq)
+
+	s.httpServer = &httpServer{
+		address:        s.Address,
+		encryption:     s.Encryption,
+		serverKey:      s.ServerKey,
+		serverCert:     s.ServerCert,
+		allowOrigin:    s.AllowOrigin,
+		trustedProxies: s.TrustedProxies,
+		readTimeout:    s.ReadTimeout,
+		parent:         s,
+	}
+	err := s.httpServer.initialize()
+	if err != nil {
+		s.ctxCancel()
+		return err
+	}
+
+	s.Log(logger.Info, "listener opened on "+s.Address)
+
+	s.wg.Add(1)
+	go s.run()
+
+	return nil
+}
+
+// Log implements logger.Writer.
+func (s *Server) Log(level logger.Level, format string, args ...interface{}) {
+	s.Parent.Log(level, "%v"+format, append([]interface{}{logger.Component("HTTP-FLV")}, args...)...)
+}
+
+// Close closes the server.
+func (s *Server) Close() {
+	s.Log(logger.Info, "listener is closing")
+	s.ctxCancel()
+	s.wg.Wait()
+}
+
+func (s *Server) run() {
+	defer s.wg.Done()
+
+outer:
+	for {
+		select {
+		case c := <-s.chNewConn:
+			// the connection runs inside the goroutine of the HTTP request.
+			// Add it to the wait group in order to wait for it when closing the server.
+			s.wg.Add(1)
+			s.conns[c] = struct{}{}
+
+		case c := <-s.chCloseConn:
+			delete(s.conns, c)
+
+		case req := <-s.chAPIConnsList:
+			data := &defs.APIHTTPFLVConnList{
+				Items: []*defs.APIHTTPFLVConn{},
+			}
+
+			for c := range s.conns {
+				data.Items = append(data.Items, c.apiItem())
+			}
+
+			sort.Slice(data.Items, func(i, j int) bool {
+				return data.Items[i].Created.Before(data.Items[j].Created)
+			})
+
+			req.res <- serverAPIConnsListRes{data: data}
+
+		case req := <-s.chAPIConnsGet:
+			c := s.findConnByUUID(req.uuid)
+			if c == nil {
+				req.res <- serverAPIConnsGetRes{err: ErrConnNotFound}
+				continue
+			}
+
+			req.res <- serverAPIConnsGetRes{data: c.apiItem()}
+
+		case req := <-s.chAPIConnsKick:
+			c := s.findConnByUUID(req.uuid)
+			if c == nil {
+				req.res <- serverAPIConnsKickRes{err: ErrConnNotFound}
+				continue
+			}
+
+			delete(s.conns, c)
+			c.Close()
+			req.res <- serverAPIConnsKickRes{}
+
+		case <-s.ctx.Done():
+			break outer
+		}
+	}
+
+	s.ctxCancel()
+
+	s.httpServer.close()
+}
+
+func (s *Server) findConnByUUID(uuid uuid.UUID) *conn {
+	for c := range s.conns {
+		if c.uuid == uuid {
+			return c
+		}
+	}
+	return nil
+}
+
+// handleConn is called by httpServer.
+func (s *Server) handleConn(ctx *gin.Context, pathName string, webSocket bool) {
+	c := &conn{
+		parentCtx:       s.ctx,
+		writeTimeout:    s.WriteTimeout,
+		writeQueueSize:  s.WriteQueueSize,
+		ginCtx:          ctx,
+		pathName:        pathName,
+		webSocket:       webSocket,
+		wg:              &s.wg,
+		externalCmdPool: s.ExternalCmdPool,
+		pathManager:     s.PathManager,
+		parent:          s,
+	}
+	c.initialize()
+
+	select {
+	case s.chNewConn <- c:
+	case <-s.ctx.Done():
+		ctx.Writer.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	c.run()
+}
+
+// closeConn is called by conn.
+func (s *Server) closeConn(c *conn) {
+	select {
+	case s.chCloseConn <- c:
+	case <-s.ctx.Done():
+	}
+}
+
+// APIConnsList is called by api.
+func (s *Server) APIConnsList() (*defs.APIHTTPFLVConnList, error) {
+	req := serverAPIConnsListReq{
+		res: make(chan serverAPIConnsListRes),
+	}
+
+	select {
+	case s.chAPIConnsList <- req:
+		res := <-req.res
+		return res.data, res.err
+
+	case <-s.ctx.Done():
+		return nil, fmt.Errorf("terminated")
+	}
+}
+
+// APIConnsGet is called by api.
+func (s *Server) APIConnsGet(uuid uuid.UUID) (*defs.APIHTTPFLVConn, error) {
+	req := serverAPIConnsGetReq{
+		uuid: uuid,
+		res:  make(chan serverAPIConnsGetRes),
+	}
+
+	select {
+	case s.chAPIConnsGet <- req:
+		res := <-req.res
+		return res.data, res.err
+
+	case <-s.ctx.Done():
+		return nil, fmt.Errorf("terminated")
+	}
+}
+
+// APIConnsKick is called by api.
+func (s *Server) APIConnsKick(uuid uuid.UUID) error {
+	req := serverAPIConnsKickReq{
+		uuid: uuid,
+		res:  make(chan serverAPIConnsKickRes),
+	}
+
+	select {
+	case s.chAPIConnsKick <- req:
+		res := <-req.res
+		return res.err
+
+	case <-s.ctx.Done():
+		return fmt.Errorf("terminated")
+	}
+}
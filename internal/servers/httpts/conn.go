@@ -0,0 +1,243 @@
+package httpts
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/bluenviron/mediamtx/internal/asyncwriter"
+	"github.com/bluenviron/mediamtx/internal/auth"
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/defs"
+	"github.com/bluenviron/mediamtx/internal/externalcmd"
+	"github.com/bluenviron/mediamtx/internal/hooks"
+	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/bluenviron/mediamtx/internal/protocols/httpp"
+	"github.com/bluenviron/mediamtx/internal/protocols/mpegts"
+)
+
+// httpWriter writes to a HTTP response and flushes after every write,
+// in order to send MPEG-TS packets as soon as they are available.
+type httpWriter struct {
+	w http.ResponseWriter
+}
+
+func (w *httpWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	if err != nil {
+		return n, err
+	}
+
+	w.w.(http.Flusher).Flush()
+	return n, nil
+}
+
+// countingWriter counts the bytes written into another writer.
+type countingWriter struct {
+	w io.Writer
+	n atomic.Uint64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n.Add(uint64(n))
+	return n, err
+}
+
+// maximum amount of MPEG-TS data sent at once (a multiple of the MPEG-TS packet size).
+const tsMaxChunkSize = 188 * 32
+
+type conn struct {
+	parentCtx       context.Context
+	writeTimeout    conf.StringDuration
+	writeQueueSize  int
+	ginCtx          *gin.Context
+	pathName        string
+	wg              *sync.WaitGroup
+	externalCmdPool *externalcmd.Pool
+	pathManager     serverPathManager
+	parent          *Server
+
+	ctx        context.Context
+	ctxCancel  func()
+	uuid       uuid.UUID
+	created    time.Time
+	remoteAddr net.Addr
+	query      string
+	mutex      sync.RWMutex
+	cw         *countingWriter
+}
+
+func (c *conn) initialize() {
+	c.ctx, c.ctxCancel = context.WithCancel(c.parentCtx)
+
+	c.uuid = uuid.New()
+	c.created = time.Now()
+	c.remoteAddr, _ = net.ResolveTCPAddr("tcp", httpp.RemoteAddr(c.ginCtx))
+	c.query = c.ginCtx.Request.URL.RawQuery
+}
+
+// Close closes a conn.
+func (c *conn) Close() {
+	c.ctxCancel()
+}
+
+// Log implements logger.Writer.
+func (c *conn) Log(level logger.Level, format string, args ...interface{}) {
+	c.parent.Log(level, "%v"+format, append([]interface{}{logger.Conn(c.uuid, c.remoteAddr)}, args...)...)
+}
+
+// run is called by httpServer, inside the goroutine of the HTTP request.
+func (c *conn) run() {
+	defer c.wg.Done()
+
+	c.Log(logger.Info, "opened")
+
+	err := c.runInner()
+
+	c.ctxCancel()
+
+	c.parent.closeConn(c)
+
+	c.Log(logger.Info, "closed: %v", err)
+}
+
+func (c *conn) runInner() error {
+	user, pass, hasCredentials := c.ginCtx.Request.BasicAuth()
+	if !hasCredentials {
+		query := c.ginCtx.Request.URL.Query()
+		user = query.Get("user")
+		pass = query.Get("pass")
+		hasCredentials = (user != "" || pass != "")
+	}
+
+	path, stream, err := c.pathManager.AddReader(defs.PathAddReaderReq{
+		Author: c,
+		AccessRequest: defs.PathAccessRequest{
+			Name:  c.pathName,
+			Query: c.query,
+			IP:    net.ParseIP(c.ginCtx.ClientIP()),
+			User:  user,
+			Pass:  pass,
+			Proto: auth.ProtocolHTTPTS,
+			ID:    &c.uuid,
+		},
+	})
+	if err != nil {
+		var terr1 auth.Error
+		if errors.As(err, &terr1) {
+			if !hasCredentials {
+				c.ginCtx.Header("WWW-Authenticate", `Basic realm="mediamtx"`)
+				c.ginCtx.Writer.WriteHeader(http.StatusUnauthorized)
+				return err
+			}
+
+			// wait some seconds to mitigate brute force attacks
+			<-time.After(auth.PauseAfterError)
+
+			writeError(c.ginCtx, http.StatusUnauthorized, terr1)
+			return err
+		}
+
+		var terr2 *defs.PathNoOnePublishingError
+		if errors.As(err, &terr2) {
+			writeError(c.ginCtx, http.StatusNotFound, err)
+			return err
+		}
+
+		writeError(c.ginCtx, http.StatusBadRequest, err)
+		return err
+	}
+
+	defer path.RemoveReader(defs.PathRemoveReaderReq{Author: c})
+
+	c.ginCtx.Writer.Header().Set("Content-Type", "video/mp2t")
+	c.ginCtx.Writer.Header().Set("Cache-Control", "no-cache")
+	c.ginCtx.Writer.WriteHeader(http.StatusOK)
+
+	// send the header immediately, since MPEG-TS data is available only after the first unit.
+	c.ginCtx.Writer.Flush()
+
+	cw := &countingWriter{w: &httpWriter{w: c.ginCtx.Writer}}
+
+	c.mutex.Lock()
+	c.cw = cw
+	c.mutex.Unlock()
+
+	writer := asyncwriter.New(c.writeQueueSize, c)
+
+	defer stream.RemoveReader(writer)
+
+	bw := bufio.NewWriterSize(cw, tsMaxChunkSize)
+
+	err = mpegts.FromStream(stream, writer, bw,
+		http.NewResponseController(c.ginCtx.Writer), time.Duration(c.writeTimeout))
+	if err != nil {
+		return err
+	}
+
+	c.Log(logger.Info, "is reading from path '%s', %s",
+		path.Name(), defs.FormatsInfo(stream.FormatsForReader(writer)))
+
+	onUnreadHook := hooks.OnRead(hooks.OnReadParams{
+		Logger:          c,
+		ExternalCmdPool: c.externalCmdPool,
+		Conf:            path.SafeConf(),
+		ExternalCmdEnv:  path.ExternalCmdEnv(),
+		Reader:          c.APIReaderDescribe(),
+		Query:           c.query,
+	})
+	defer onUnreadHook()
+
+	writer.Start()
+	defer writer.Stop()
+
+	select {
+	case <-c.ctx.Done():
+		return fmt.Errorf("terminated")
+
+	case <-c.ginCtx.Request.Context().Done():
+		return fmt.Errorf("connection closed by client")
+
+	case err := <-writer.Error():
+		return err
+	}
+}
+
+// APIReaderDescribe implements reader.
+func (c *conn) APIReaderDescribe() defs.APIPathSourceOrReader {
+	return defs.APIPathSourceOrReader{
+		Type: "httptsConn",
+		ID:   c.uuid.String(),
+	}
+}
+
+func (c *conn) apiItem() *defs.APIHTTPTSConn {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	bytesSent := uint64(0)
+
+	if c.cw != nil {
+		bytesSent = c.cw.n.Load()
+	}
+
+	return &defs.APIHTTPTSConn{
+		ID:         c.uuid,
+		Created:    c.created,
+		RemoteAddr: c.remoteAddr.String(),
+		Path:       c.pathName,
+		Query:      c.query,
+		BytesSent:  bytesSent,
+	}
+}
@@ -145,7 +145,7 @@ func (s *Server) Log(level logger.Level, format string, args ...interface{}) {
 		}
 		return "RTMP"
 	}()
-	s.Parent.Log(level, "[%s] "+format, append([]interface{}{label}, args...)...)
+	s.Parent.Log(level, "%v"+format, append([]interface{}{logger.Component(label)}, args...)...)
 }
 
 // Close closes the server.
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	"github.com/bluenviron/gortsplib/v4/pkg/format"
 	"github.com/bluenviron/mediamtx/internal/asyncwriter"
 	"github.com/bluenviron/mediamtx/internal/auth"
 	"github.com/bluenviron/mediamtx/internal/conf"
@@ -271,3 +272,89 @@ func TestServerRead(t *testing.T) {
 		})
 	}
 }
+
+func TestServerReadEnhanced(t *testing.T) {
+	desc := &description.Session{Medias: []*description.Media{
+		{
+			Type:    description.MediaTypeVideo,
+			Formats: []format.Format{test.FormatH265},
+		},
+		{
+			Type: description.MediaTypeAudio,
+			Formats: []format.Format{&format.Opus{
+				PayloadTyp: 96,
+				IsStereo:   true,
+			}},
+		},
+	}}
+
+	stream, err := stream.New(
+		1460,
+		desc,
+		true,
+		test.NilLogger,
+	)
+	require.NoError(t, err)
+
+	path := &dummyPath{stream: stream}
+
+	pathManager := &dummyPathManager{path: path}
+
+	s := &Server{
+		Address:             "127.0.0.1:1935",
+		ReadTimeout:         conf.StringDuration(10 * time.Second),
+		WriteTimeout:        conf.StringDuration(10 * time.Second),
+		WriteQueueSize:      512,
+		RTSPAddress:         "",
+		RunOnConnect:        "",
+		RunOnConnectRestart: false,
+		RunOnDisconnect:     "",
+		ExternalCmdPool:     nil,
+		PathManager:         pathManager,
+		Parent:              test.NilLogger,
+	}
+	err = s.Initialize()
+	require.NoError(t, err)
+	defer s.Close()
+
+	u, err := url.Parse("rtmp://127.0.0.1:1935/teststream?user=myuser&pass=mypass")
+	require.NoError(t, err)
+
+	nconn, err := net.Dial("tcp", u.Host)
+	require.NoError(t, err)
+	defer nconn.Close()
+
+	conn, err := rtmp.NewClientConn(nconn, u, false)
+	require.NoError(t, err)
+
+	r, err := rtmp.NewReader(conn)
+	require.NoError(t, err)
+
+	videoTrack, audioTrack := r.Tracks()
+	require.Equal(t, test.FormatH265, videoTrack)
+	require.Equal(t, &format.Opus{
+		PayloadTyp: 96,
+		IsStereo:   true,
+	}, audioTrack)
+
+	stream.WriteUnit(desc.Medias[0], desc.Medias[0].Formats[0], &unit.H265{
+		Base: unit.Base{
+			NTP: time.Time{},
+		},
+		AU: [][]byte{
+			{0x26, 0x01, 0xaf}, // IDR
+		},
+	})
+
+	r.OnDataH265(func(_ time.Duration, au [][]byte) {
+		require.Equal(t, [][]byte{
+			test.FormatH265.VPS,
+			test.FormatH265.SPS,
+			test.FormatH265.PPS,
+			{0x26, 0x01, 0xaf},
+		}, au)
+	})
+
+	err = r.Read()
+	require.NoError(t, err)
+}
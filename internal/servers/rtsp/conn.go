@@ -74,7 +74,7 @@ func (c *conn) initialize() {
 
 // Log implements logger.Writer.
 func (c *conn) Log(level logger.Level, format string, args ...interface{}) {
-	c.parent.Log(level, "[conn %v] "+format, append([]interface{}{c.rconn.NetConn().RemoteAddr()}, args...)...)
+	c.parent.Log(level, "%v"+format, append([]interface{}{logger.Conn(c.uuid, c.rconn.NetConn().RemoteAddr())}, args...)...)
 }
 
 // Conn returns the RTSP connection.
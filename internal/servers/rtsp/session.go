@@ -1,7 +1,6 @@
 package rtsp
 
 import (
-	"encoding/hex"
 	"errors"
 	"fmt"
 	"net"
@@ -68,8 +67,7 @@ func (s *session) remoteAddr() net.Addr {
 
 // Log implements logger.Writer.
 func (s *session) Log(level logger.Level, format string, args ...interface{}) {
-	id := hex.EncodeToString(s.uuid[:4])
-	s.parent.Log(level, "[session %s] "+format, append([]interface{}{id}, args...)...)
+	s.parent.Log(level, "%v"+format, append([]interface{}{logger.Session(s.uuid, s.remoteAddr().String())}, args...)...)
 }
 
 // onClose is called by rtspServer.
@@ -99,7 +99,7 @@ func (c *conn) Close() {
 
 // Log implements logger.Writer.
 func (c *conn) Log(level logger.Level, format string, args ...interface{}) {
-	c.parent.Log(level, "[conn %v] "+format, append([]interface{}{c.connReq.RemoteAddr()}, args...)...)
+	c.parent.Log(level, "%v"+format, append([]interface{}{logger.Conn(c.uuid, c.connReq.RemoteAddr())}, args...)...)
 }
 
 func (c *conn) ip() net.IP {
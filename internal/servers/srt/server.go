@@ -137,7 +137,7 @@ func (s *Server) Initialize() error {
 
 // Log implements logger.Writer.
 func (s *Server) Log(level logger.Level, format string, args ...interface{}) {
-	s.Parent.Log(level, "[SRT] "+format, args...)
+	s.Parent.Log(level, "%v"+format, append([]interface{}{logger.Component("SRT")}, args...)...)
 }
 
 // Close closes the server.
@@ -31,7 +31,6 @@ import (
 
 const (
 	webrtcTurnSecretExpiration = 24 * 3600 * time.Second
-	webrtcPayloadMaxSize       = 1188 // 1200 - 12 (RTP header)
 )
 
 // ErrSessionNotFound is returned when a session is not found.
@@ -305,7 +304,7 @@ func (s *Server) Initialize() error {
 
 // Log implements logger.Writer.
 func (s *Server) Log(level logger.Level, format string, args ...interface{}) {
-	s.Parent.Log(level, "[WebRTC] "+format, args...)
+	s.Parent.Log(level, "%v"+format, append([]interface{}{logger.Component("WebRTC")}, args...)...)
 }
 
 // Close closes the server.
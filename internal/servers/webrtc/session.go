@@ -2,7 +2,6 @@ package webrtc
 
 import (
 	"context"
-	"encoding/hex"
 	"errors"
 	"fmt"
 	"net"
@@ -11,11 +10,6 @@ import (
 	"time"
 
 	"github.com/bluenviron/gortsplib/v4/pkg/description"
-	"github.com/bluenviron/gortsplib/v4/pkg/format"
-	"github.com/bluenviron/gortsplib/v4/pkg/format/rtpav1"
-	"github.com/bluenviron/gortsplib/v4/pkg/format/rtph264"
-	"github.com/bluenviron/gortsplib/v4/pkg/format/rtpvp8"
-	"github.com/bluenviron/gortsplib/v4/pkg/format/rtpvp9"
 	"github.com/bluenviron/gortsplib/v4/pkg/rtptime"
 	"github.com/google/uuid"
 	"github.com/pion/sdp/v3"
@@ -28,247 +22,8 @@ import (
 	"github.com/bluenviron/mediamtx/internal/hooks"
 	"github.com/bluenviron/mediamtx/internal/logger"
 	"github.com/bluenviron/mediamtx/internal/protocols/webrtc"
-	"github.com/bluenviron/mediamtx/internal/stream"
-	"github.com/bluenviron/mediamtx/internal/unit"
 )
 
-var errNoSupportedCodecs = errors.New(
-	"the stream doesn't contain any supported codec, which are currently AV1, VP9, VP8, H264, Opus, G722, G711")
-
-type setupStreamFunc func(*webrtc.OutgoingTrack) error
-
-func findVideoTrack(
-	stream *stream.Stream,
-	writer *asyncwriter.Writer,
-) (format.Format, setupStreamFunc) {
-	var av1Format *format.AV1
-	media := stream.Desc().FindFormat(&av1Format)
-
-	if av1Format != nil {
-		return av1Format, func(track *webrtc.OutgoingTrack) error {
-			encoder := &rtpav1.Encoder{
-				PayloadType:    105,
-				PayloadMaxSize: webrtcPayloadMaxSize,
-			}
-			err := encoder.Init()
-			if err != nil {
-				return err
-			}
-
-			stream.AddReader(writer, media, av1Format, func(u unit.Unit) error {
-				tunit := u.(*unit.AV1)
-
-				if tunit.TU == nil {
-					return nil
-				}
-
-				packets, err := encoder.Encode(tunit.TU)
-				if err != nil {
-					return nil //nolint:nilerr
-				}
-
-				for _, pkt := range packets {
-					pkt.Timestamp += tunit.RTPPackets[0].Timestamp
-					track.WriteRTP(pkt) //nolint:errcheck
-				}
-
-				return nil
-			})
-
-			return nil
-		}
-	}
-
-	var vp9Format *format.VP9
-	media = stream.Desc().FindFormat(&vp9Format)
-
-	if vp9Format != nil {
-		return vp9Format, func(track *webrtc.OutgoingTrack) error {
-			encoder := &rtpvp9.Encoder{
-				PayloadType:    96,
-				PayloadMaxSize: webrtcPayloadMaxSize,
-			}
-			err := encoder.Init()
-			if err != nil {
-				return err
-			}
-
-			stream.AddReader(writer, media, vp9Format, func(u unit.Unit) error {
-				tunit := u.(*unit.VP9)
-
-				if tunit.Frame == nil {
-					return nil
-				}
-
-				packets, err := encoder.Encode(tunit.Frame)
-				if err != nil {
-					return nil //nolint:nilerr
-				}
-
-				for _, pkt := range packets {
-					pkt.Timestamp += tunit.RTPPackets[0].Timestamp
-					track.WriteRTP(pkt) //nolint:errcheck
-				}
-
-				return nil
-			})
-
-			return nil
-		}
-	}
-
-	var vp8Format *format.VP8
-	media = stream.Desc().FindFormat(&vp8Format)
-
-	if vp8Format != nil {
-		return vp8Format, func(track *webrtc.OutgoingTrack) error {
-			encoder := &rtpvp8.Encoder{
-				PayloadType:    96,
-				PayloadMaxSize: webrtcPayloadMaxSize,
-			}
-			err := encoder.Init()
-			if err != nil {
-				return err
-			}
-
-			stream.AddReader(writer, media, vp8Format, func(u unit.Unit) error {
-				tunit := u.(*unit.VP8)
-
-				if tunit.Frame == nil {
-					return nil
-				}
-
-				packets, err := encoder.Encode(tunit.Frame)
-				if err != nil {
-					return nil //nolint:nilerr
-				}
-
-				for _, pkt := range packets {
-					pkt.Timestamp += tunit.RTPPackets[0].Timestamp
-					track.WriteRTP(pkt) //nolint:errcheck
-				}
-
-				return nil
-			})
-
-			return nil
-		}
-	}
-
-	var h264Format *format.H264
-	media = stream.Desc().FindFormat(&h264Format)
-
-	if h264Format != nil {
-		return h264Format, func(track *webrtc.OutgoingTrack) error {
-			encoder := &rtph264.Encoder{
-				PayloadType:    96,
-				PayloadMaxSize: webrtcPayloadMaxSize,
-			}
-			err := encoder.Init()
-			if err != nil {
-				return err
-			}
-
-			firstReceived := false
-			var lastPTS time.Duration
-
-			stream.AddReader(writer, media, h264Format, func(u unit.Unit) error {
-				tunit := u.(*unit.H264)
-
-				if tunit.AU == nil {
-					return nil
-				}
-
-				if !firstReceived {
-					firstReceived = true
-				} else if tunit.PTS < lastPTS {
-					return fmt.Errorf("WebRTC doesn't support H264 streams with B-frames")
-				}
-				lastPTS = tunit.PTS
-
-				packets, err := encoder.Encode(tunit.AU)
-				if err != nil {
-					return nil //nolint:nilerr
-				}
-
-				for _, pkt := range packets {
-					pkt.Timestamp += tunit.RTPPackets[0].Timestamp
-					track.WriteRTP(pkt) //nolint:errcheck
-				}
-
-				return nil
-			})
-
-			return nil
-		}
-	}
-
-	return nil, nil
-}
-
-func findAudioTrack(
-	stream *stream.Stream,
-	writer *asyncwriter.Writer,
-) (format.Format, setupStreamFunc) {
-	var opusFormat *format.Opus
-	media := stream.Desc().FindFormat(&opusFormat)
-
-	if opusFormat != nil {
-		return opusFormat, func(track *webrtc.OutgoingTrack) error {
-			stream.AddReader(writer, media, opusFormat, func(u unit.Unit) error {
-				for _, pkt := range u.GetRTPPackets() {
-					track.WriteRTP(pkt) //nolint:errcheck
-				}
-
-				return nil
-			})
-			return nil
-		}
-	}
-
-	var g722Format *format.G722
-	media = stream.Desc().FindFormat(&g722Format)
-
-	if g722Format != nil {
-		return g722Format, func(track *webrtc.OutgoingTrack) error {
-			stream.AddReader(writer, media, g722Format, func(u unit.Unit) error {
-				for _, pkt := range u.GetRTPPackets() {
-					track.WriteRTP(pkt) //nolint:errcheck
-				}
-
-				return nil
-			})
-			return nil
-		}
-	}
-
-	var g711Format *format.G711
-	media = stream.Desc().FindFormat(&g711Format)
-
-	if g711Format != nil {
-		return g711Format, func(track *webrtc.OutgoingTrack) error {
-			if g711Format.SampleRate != 8000 {
-				return fmt.Errorf("unsupported G711 sample rate")
-			}
-
-			if g711Format.ChannelCount != 1 {
-				return fmt.Errorf("unsupported G711 channel count")
-			}
-
-			stream.AddReader(writer, media, g711Format, func(u unit.Unit) error {
-				for _, pkt := range u.GetRTPPackets() {
-					track.WriteRTP(pkt) //nolint:errcheck
-				}
-
-				return nil
-			})
-			return nil
-		}
-	}
-
-	return nil, nil
-}
-
 func whipOffer(body []byte) *pwebrtc.SessionDescription {
 	return &pwebrtc.SessionDescription{
 		Type: pwebrtc.SDPTypeOffer,
@@ -317,8 +72,7 @@ func (s *session) initialize() {
 
 // Log implements logger.Writer.
 func (s *session) Log(level logger.Level, format string, args ...interface{}) {
-	id := hex.EncodeToString(s.uuid[:4])
-	s.parent.Log(level, "[session %v] "+format, append([]interface{}{id}, args...)...)
+	s.parent.Log(level, "%v"+format, append([]interface{}{logger.Session(s.uuid, s.req.remoteAddr)}, args...)...)
 }
 
 func (s *session) Close() {
@@ -547,11 +301,11 @@ func (s *session) runRead() (int, error) {
 
 	writer := asyncwriter.New(s.writeQueueSize, s)
 
-	videoTrack, videoSetup := findVideoTrack(stream, writer)
-	audioTrack, audioSetup := findAudioTrack(stream, writer)
+	videoTrack, videoSetup := webrtc.FindVideoTrack(stream, writer)
+	audioTrack, audioSetup := webrtc.FindAudioTrack(stream, writer)
 
 	if videoTrack == nil && audioTrack == nil {
-		return http.StatusBadRequest, errNoSupportedCodecs
+		return http.StatusBadRequest, webrtc.ErrNoSupportedCodecs
 	}
 
 	tracks, err := pc.SetupOutgoingTracks(videoTrack, audioTrack)
@@ -0,0 +1,125 @@
+package file
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"time"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	"github.com/bluenviron/gortsplib/v4/pkg/format"
+
+	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/bluenviron/mediamtx/internal/unit"
+)
+
+// mpegtsSyncByte is the first byte of every MPEG-TS packet.
+const mpegtsSyncByte = 0x47
+
+// fileReader reads the units contained into a media file, in decoding order.
+type fileReader interface {
+	close()
+	medias() []*description.Media
+
+	// read calls onUnit for every unit of the file, together with its DTS,
+	// then returns the duration of the file.
+	read(onUnit func(*description.Media, time.Duration, unit.Unit) error) (time.Duration, error)
+}
+
+// openFile opens a MPEG-TS or MP4 file, depending on its content.
+func openFile(fpath string, decodeErrLogger logger.Writer) (fileReader, error) {
+	f, err := os.Open(fpath)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 1)
+	_, err = io.ReadFull(f, buf)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("unable to read %s: %w", fpath, err)
+	}
+
+	_, err = f.Seek(0, io.SeekStart)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	var r fileReader
+
+	if buf[0] == mpegtsSyncByte {
+		r, err = newReaderMPEGTS(f, decodeErrLogger)
+	} else {
+		r, err = newReaderMP4(f)
+	}
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("unable to read %s: %w", fpath, err)
+	}
+
+	return r, nil
+}
+
+// paramsAreCompatible compares two codec parameters.
+// A missing parameter is sent in-band, therefore it is compatible with any other.
+func paramsAreCompatible(a []byte, b []byte) bool {
+	return a == nil || b == nil || bytes.Equal(a, b)
+}
+
+// formatsAreCompatible checks whether units of a format can be published
+// with the description of another format.
+func formatsAreCompatible(first format.Format, cur format.Format) bool {
+	if reflect.TypeOf(cur) != reflect.TypeOf(first) || cur.ClockRate() != first.ClockRate() {
+		return false
+	}
+
+	switch first := first.(type) {
+	case *format.H265:
+		cur := cur.(*format.H265)
+		return paramsAreCompatible(first.VPS, cur.VPS) &&
+			paramsAreCompatible(first.SPS, cur.SPS) &&
+			paramsAreCompatible(first.PPS, cur.PPS)
+
+	case *format.H264:
+		cur := cur.(*format.H264)
+		return paramsAreCompatible(first.SPS, cur.SPS) &&
+			paramsAreCompatible(first.PPS, cur.PPS)
+
+	case *format.MPEG4Video:
+		return paramsAreCompatible(first.Config, cur.(*format.MPEG4Video).Config)
+
+	case *format.Opus:
+		return first.IsStereo == cur.(*format.Opus).IsStereo
+
+	case *format.MPEG4Audio:
+		return reflect.DeepEqual(first.Config, cur.(*format.MPEG4Audio).Config)
+
+	case *format.AC3:
+		return first.ChannelCount == cur.(*format.AC3).ChannelCount
+
+	case *format.LPCM:
+		cur := cur.(*format.LPCM)
+		return first.BitDepth == cur.BitDepth && first.ChannelCount == cur.ChannelCount
+	}
+
+	return true
+}
+
+// mediasAreCompatible checks whether medias of a file can be published
+// with the medias of the first file of the playlist.
+func mediasAreCompatible(first []*description.Media, cur []*description.Media) bool {
+	if len(first) != len(cur) {
+		return false
+	}
+
+	for i, medi := range cur {
+		if !formatsAreCompatible(first[i].Formats[0], medi.Formats[0]) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,620 @@
+package file
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/abema/go-mp4"
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	"github.com/bluenviron/gortsplib/v4/pkg/format"
+	"github.com/bluenviron/mediacommon/pkg/codecs/av1"
+	"github.com/bluenviron/mediacommon/pkg/codecs/h264"
+	"github.com/bluenviron/mediacommon/pkg/formats/fmp4"
+
+	"github.com/bluenviron/mediamtx/internal/protocols/mp4reader"
+	"github.com/bluenviron/mediamtx/internal/unit"
+)
+
+// prependParams adds parameters to a random access AU, if they are not present yet.
+// Parameters are detected by comparing NALU headers.
+func prependParams(au [][]byte, params ...[]byte) [][]byte {
+	for _, nalu := range au {
+		for _, p := range params {
+			if len(nalu) > 0 && len(p) > 0 && nalu[0] == p[0] {
+				return au
+			}
+		}
+	}
+
+	return append(append([][]byte(nil), params...), au...)
+}
+
+func swapEndianness(samples []byte, bitDepth int) []byte {
+	n := bitDepth / 8
+	ret := make([]byte, len(samples))
+
+	for i := 0; i+n <= len(samples); i += n {
+		for j := 0; j < n; j++ {
+			ret[i+j] = samples[i+n-1-j]
+		}
+	}
+
+	return ret
+}
+
+type mp4Track struct {
+	id        int
+	timeScale uint32
+	media     *description.Media
+	newUnit   func(payload []byte, isNonSyncSample bool) (unit.Unit, error)
+
+	samples    []*mp4reader.Sample
+	nextSample int
+}
+
+func (t *mp4Track) initialize(codec fmp4.Codec) error {
+	switch codec := codec.(type) {
+	case *fmp4.CodecAV1:
+		t.media = &description.Media{
+			Type: description.MediaTypeVideo,
+			Formats: []format.Format{&format.AV1{
+				PayloadTyp: 96,
+			}},
+		}
+
+		t.newUnit = func(payload []byte, _ bool) (unit.Unit, error) {
+			tu, err := av1.BitstreamUnmarshal(payload, true)
+			if err != nil {
+				return nil, err
+			}
+
+			return &unit.AV1{TU: tu}, nil
+		}
+
+	case *fmp4.CodecVP9:
+		t.media = &description.Media{
+			Type: description.MediaTypeVideo,
+			Formats: []format.Format{&format.VP9{
+				PayloadTyp: 96,
+			}},
+		}
+
+		t.newUnit = func(payload []byte, _ bool) (unit.Unit, error) {
+			return &unit.VP9{Frame: payload}, nil
+		}
+
+	case *fmp4.CodecH265:
+		t.media = &description.Media{
+			Type: description.MediaTypeVideo,
+			Formats: []format.Format{&format.H265{
+				PayloadTyp: 96,
+				VPS:        codec.VPS,
+				SPS:        codec.SPS,
+				PPS:        codec.PPS,
+			}},
+		}
+
+		t.newUnit = func(payload []byte, isNonSyncSample bool) (unit.Unit, error) {
+			au, err := h264.AVCCUnmarshal(payload)
+			if err != nil {
+				return nil, err
+			}
+
+			// parameters are stored in the header, while they are needed
+			// in-band in order to switch between files with different parameters.
+			if !isNonSyncSample {
+				au = prependParams(au, codec.VPS, codec.SPS, codec.PPS)
+			}
+
+			return &unit.H265{AU: au}, nil
+		}
+
+	case *fmp4.CodecH264:
+		t.media = &description.Media{
+			Type: description.MediaTypeVideo,
+			Formats: []format.Format{&format.H264{
+				PayloadTyp:        96,
+				PacketizationMode: 1,
+				SPS:               codec.SPS,
+				PPS:               codec.PPS,
+			}},
+		}
+
+		t.newUnit = func(payload []byte, isNonSyncSample bool) (unit.Unit, error) {
+			au, err := h264.AVCCUnmarshal(payload)
+			if err != nil {
+				return nil, err
+			}
+
+			if !isNonSyncSample {
+				au = prependParams(au, codec.SPS, codec.PPS)
+			}
+
+			return &unit.H264{AU: au}, nil
+		}
+
+	case *fmp4.CodecMPEG4Video:
+		t.media = &description.Media{
+			Type: description.MediaTypeVideo,
+			Formats: []format.Format{&format.MPEG4Video{
+				PayloadTyp:     96,
+				ProfileLevelID: 1,
+				Config:         codec.Config,
+			}},
+		}
+
+		t.newUnit = func(payload []byte, _ bool) (unit.Unit, error) {
+			return &unit.MPEG4Video{Frame: payload}, nil
+		}
+
+	case *fmp4.CodecMPEG1Video:
+		t.media = &description.Media{
+			Type:    description.MediaTypeVideo,
+			Formats: []format.Format{&format.MPEG1Video{}},
+		}
+
+		t.newUnit = func(payload []byte, _ bool) (unit.Unit, error) {
+			return &unit.MPEG1Video{Frame: payload}, nil
+		}
+
+	case *fmp4.CodecMJPEG:
+		t.media = &description.Media{
+			Type:    description.MediaTypeVideo,
+			Formats: []format.Format{&format.MJPEG{}},
+		}
+
+		t.newUnit = func(payload []byte, _ bool) (unit.Unit, error) {
+			return &unit.MJPEG{Frame: payload}, nil
+		}
+
+	case *fmp4.CodecOpus:
+		t.media = &description.Media{
+			Type: description.MediaTypeAudio,
+			Formats: []format.Format{&format.Opus{
+				PayloadTyp: 96,
+				IsStereo:   (codec.ChannelCount >= 2),
+			}},
+		}
+
+		t.newUnit = func(payload []byte, _ bool) (unit.Unit, error) {
+			return &unit.Opus{Packets: [][]byte{payload}}, nil
+		}
+
+	case *fmp4.CodecMPEG4Audio:
+		t.media = &description.Media{
+			Type: description.MediaTypeAudio,
+			Formats: []format.Format{&format.MPEG4Audio{
+				PayloadTyp:       96,
+				SizeLength:       13,
+				IndexLength:      3,
+				IndexDeltaLength: 3,
+				Config:           &codec.Config,
+			}},
+		}
+
+		t.newUnit = func(payload []byte, _ bool) (unit.Unit, error) {
+			return &unit.MPEG4Audio{AUs: [][]byte{payload}}, nil
+		}
+
+	case *fmp4.CodecMPEG1Audio:
+		t.media = &description.Media{
+			Type:    description.MediaTypeAudio,
+			Formats: []format.Format{&format.MPEG1Audio{}},
+		}
+
+		t.newUnit = func(payload []byte, _ bool) (unit.Unit, error) {
+			return &unit.MPEG1Audio{Frames: [][]byte{payload}}, nil
+		}
+
+	case *fmp4.CodecAC3:
+		t.media = &description.Media{
+			Type: description.MediaTypeAudio,
+			Formats: []format.Format{&format.AC3{
+				PayloadTyp:   96,
+				SampleRate:   codec.SampleRate,
+				ChannelCount: codec.ChannelCount,
+			}},
+		}
+
+		t.newUnit = func(payload []byte, _ bool) (unit.Unit, error) {
+			return &unit.AC3{Frames: [][]byte{payload}}, nil
+		}
+
+	case *fmp4.CodecLPCM:
+		t.media = &description.Media{
+			Type: description.MediaTypeAudio,
+			Formats: []format.Format{&format.LPCM{
+				PayloadTyp:   96,
+				BitDepth:     codec.BitDepth,
+				SampleRate:   codec.SampleRate,
+				ChannelCount: codec.ChannelCount,
+			}},
+		}
+
+		t.newUnit = func(payload []byte, _ bool) (unit.Unit, error) {
+			// LPCM is always big-endian in RTP
+			if codec.LittleEndian {
+				payload = swapEndianness(payload, codec.BitDepth)
+			}
+
+			return &unit.LPCM{Samples: payload}, nil
+		}
+
+	default:
+		return fmt.Errorf("unsupported codec: %T", codec)
+	}
+
+	return nil
+}
+
+func (t *mp4Track) fillSamples(
+	stts *mp4.Stts,
+	ctts *mp4.Ctts,
+	stss *mp4.Stss,
+	stsz *mp4.Stsz,
+	stsc *mp4.Stsc,
+	chunkOffsets []uint64,
+) error {
+	if stts == nil || stsz == nil || stsc == nil || chunkOffsets == nil {
+		return fmt.Errorf("track %d: sample table is incomplete", t.id)
+	}
+
+	t.samples = make([]*mp4reader.Sample, stsz.SampleCount)
+
+	for i := range t.samples {
+		t.samples[i] = &mp4reader.Sample{
+			IsNonSyncSample: stss != nil,
+		}
+
+		if stsz.SampleSize != 0 {
+			t.samples[i].Size = stsz.SampleSize
+		} else {
+			if i >= len(stsz.EntrySize) {
+				return fmt.Errorf("track %d: invalid stsz", t.id)
+			}
+			t.samples[i].Size = stsz.EntrySize[i]
+		}
+	}
+
+	// decoding timestamps and durations
+	i := 0
+	var dts int64
+	for _, e := range stts.Entries {
+		for j := uint32(0); j < e.SampleCount && i < len(t.samples); j++ {
+			t.samples[i].DTS = dts
+			t.samples[i].Duration = e.SampleDelta
+			dts += int64(e.SampleDelta)
+			i++
+		}
+	}
+
+	// presentation timestamps
+	if ctts != nil {
+		i = 0
+		for j, e := range ctts.Entries {
+			offset := int32(ctts.GetSampleOffset(j))
+			for k := uint32(0); k < e.SampleCount && i < len(t.samples); k++ {
+				t.samples[i].PTSOffset = offset
+				i++
+			}
+		}
+	}
+
+	// sync samples
+	if stss != nil {
+		for _, n := range stss.SampleNumber {
+			if n >= 1 && int(n) <= len(t.samples) {
+				t.samples[n-1].IsNonSyncSample = false
+			}
+		}
+	}
+
+	// positions
+	i = 0
+	for j, e := range stsc.Entries {
+		lastChunk := uint32(len(chunkOffsets))
+		if j < (len(stsc.Entries) - 1) {
+			lastChunk = stsc.Entries[j+1].FirstChunk - 1
+		}
+
+		for chunk := e.FirstChunk; chunk <= lastChunk; chunk++ {
+			if chunk < 1 || int(chunk) > len(chunkOffsets) {
+				return fmt.Errorf("track %d: invalid stsc", t.id)
+			}
+
+			offset := chunkOffsets[chunk-1]
+
+			for k := uint32(0); k < e.SamplesPerChunk && i < len(t.samples); k++ {
+				t.samples[i].Offset = offset
+				offset += uint64(t.samples[i].Size)
+				i++
+			}
+		}
+	}
+
+	if i != len(t.samples) {
+		return fmt.Errorf("track %d: invalid stsc", t.id)
+	}
+
+	return nil
+}
+
+type readerMP4 struct {
+	f        *os.File
+	tracks   []*mp4Track
+	startDTS time.Duration
+}
+
+func newReaderMP4(f *os.File) (*readerMP4, error) {
+	mr := &readerMP4{
+		f: f,
+	}
+
+	var moov *mp4.BoxInfo
+	fragmented := false
+
+	_, err := mp4.ReadBoxStructure(f, func(h *mp4.ReadHandle) (interface{}, error) {
+		switch h.BoxInfo.Type.String() {
+		case "moov":
+			bi := h.BoxInfo
+			moov = &bi
+
+		case "moof":
+			fragmented = true
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if moov == nil {
+		return nil, fmt.Errorf("moov box not found")
+	}
+
+	moovReader := io.NewSectionReader(f, int64(moov.Offset), int64(moov.Size))
+
+	var init fmp4.Init
+	err = init.Unmarshal(moovReader)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, it := range init.Tracks {
+		t := &mp4Track{
+			id:        it.ID,
+			timeScale: it.TimeScale,
+		}
+
+		err = t.initialize(it.Codec)
+		if err != nil {
+			return nil, err
+		}
+
+		mr.tracks = append(mr.tracks, t)
+	}
+
+	if fragmented {
+		err = mr.readTrackRuns()
+	} else {
+		_, err = moovReader.Seek(0, io.SeekStart)
+		if err != nil {
+			return nil, err
+		}
+
+		err = mr.readSampleTables(moovReader)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// timestamps start from the first sample.
+	startSet := false
+	for _, t := range mr.tracks {
+		if len(t.samples) != 0 {
+			dts := mp4reader.DurationMp4ToGo(t.samples[0].DTS, t.timeScale)
+			if !startSet || dts < mr.startDTS {
+				mr.startDTS = dts
+				startSet = true
+			}
+		}
+	}
+
+	return mr, nil
+}
+
+func (mr *readerMP4) findTrack(id int) *mp4Track {
+	for _, t := range mr.tracks {
+		if t.id == id {
+			return t
+		}
+	}
+	return nil
+}
+
+func (mr *readerMP4) readTrackRuns() error {
+	_, err := mr.f.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	return mp4reader.ReadTrackRuns(mr.f, func(run *mp4reader.TrackRun) error {
+		// tracks with unsupported codecs are skipped.
+		t := mr.findTrack(run.TrackID)
+		if t != nil {
+			t.samples = append(t.samples, run.Samples...)
+		}
+		return nil
+	})
+}
+
+func (mr *readerMP4) readSampleTables(r io.ReadSeeker) error {
+	var curTrack *mp4Track
+	var stts *mp4.Stts
+	var ctts *mp4.Ctts
+	var stss *mp4.Stss
+	var stsz *mp4.Stsz
+	var stsc *mp4.Stsc
+	var chunkOffsets []uint64
+
+	flush := func() error {
+		if curTrack == nil {
+			return nil
+		}
+
+		err := curTrack.fillSamples(stts, ctts, stss, stsz, stsc, chunkOffsets)
+		curTrack = nil
+		return err
+	}
+
+	_, err := mp4.ReadBoxStructure(r, func(h *mp4.ReadHandle) (interface{}, error) {
+		switch h.BoxInfo.Type.String() {
+		case "moov", "mdia", "minf", "stbl":
+			return h.Expand()
+
+		case "trak":
+			err := flush()
+			if err != nil {
+				return nil, err
+			}
+
+			stts, ctts, stss, stsz, stsc, chunkOffsets = nil, nil, nil, nil, nil, nil
+			return h.Expand()
+
+		case "tkhd":
+			box, _, err := h.ReadPayload()
+			if err != nil {
+				return nil, err
+			}
+
+			// tracks with unsupported codecs are skipped.
+			curTrack = mr.findTrack(int(box.(*mp4.Tkhd).TrackID))
+
+		case "stts", "ctts", "stss", "stsz", "stsc", "stco", "co64":
+			box, _, err := h.ReadPayload()
+			if err != nil {
+				return nil, err
+			}
+
+			switch box := box.(type) {
+			case *mp4.Stts:
+				stts = box
+			case *mp4.Ctts:
+				ctts = box
+			case *mp4.Stss:
+				stss = box
+			case *mp4.Stsz:
+				stsz = box
+			case *mp4.Stsc:
+				stsc = box
+			case *mp4.Stco:
+				chunkOffsets = make([]uint64, len(box.ChunkOffset))
+				for i, v := range box.ChunkOffset {
+					chunkOffsets[i] = uint64(v)
+				}
+			case *mp4.Co64:
+				chunkOffsets = box.ChunkOffset
+			}
+		}
+
+		return nil, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = flush()
+	if err != nil {
+		return err
+	}
+
+	for _, t := range mr.tracks {
+		if t.samples == nil {
+			return fmt.Errorf("track %d: sample table not found", t.id)
+		}
+	}
+
+	return nil
+}
+
+func (mr *readerMP4) close() {
+	mr.f.Close()
+}
+
+func (mr *readerMP4) medias() []*description.Media {
+	ret := make([]*description.Media, len(mr.tracks))
+	for i, t := range mr.tracks {
+		ret[i] = t.media
+	}
+	return ret
+}
+
+func (mr *readerMP4) writeSample(
+	t *mp4Track,
+	sa *mp4reader.Sample,
+	onUnit func(*description.Media, time.Duration, unit.Unit) error,
+) error {
+	payload := make([]byte, sa.Size)
+	_, err := mr.f.ReadAt(payload, int64(sa.Offset))
+	if err != nil {
+		return err
+	}
+
+	u, err := t.newUnit(payload, sa.IsNonSyncSample)
+	if err != nil {
+		return err
+	}
+
+	dts := mp4reader.DurationMp4ToGo(sa.DTS, t.timeScale) - mr.startDTS
+	u.SetPTS(mp4reader.DurationMp4ToGo(sa.DTS+int64(sa.PTSOffset), t.timeScale) - mr.startDTS)
+
+	return onUnit(t.media, dts, u)
+}
+
+func (mr *readerMP4) read(onUnit func(*description.Media, time.Duration, unit.Unit) error) (time.Duration, error) {
+	var duration time.Duration
+
+	for _, t := range mr.tracks {
+		t.nextSample = 0
+
+		if len(t.samples) != 0 {
+			last := t.samples[len(t.samples)-1]
+			end := mp4reader.DurationMp4ToGo(last.DTS+int64(last.Duration), t.timeScale) - mr.startDTS
+			if end > duration {
+				duration = end
+			}
+		}
+	}
+
+	for {
+		// pick the sample with the lowest DTS among all tracks.
+		var nextTrack *mp4Track
+		var nextDTS time.Duration
+
+		for _, t := range mr.tracks {
+			if t.nextSample < len(t.samples) {
+				dts := mp4reader.DurationMp4ToGo(t.samples[t.nextSample].DTS, t.timeScale)
+				if nextTrack == nil || dts < nextDTS {
+					nextTrack = t
+					nextDTS = dts
+				}
+			}
+		}
+
+		if nextTrack == nil {
+			break
+		}
+
+		sa := nextTrack.samples[nextTrack.nextSample]
+		nextTrack.nextSample++
+
+		err := mr.writeSample(nextTrack, sa, onUnit)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return duration, nil
+}
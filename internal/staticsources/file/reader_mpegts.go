@@ -0,0 +1,132 @@
+package file
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	mcmpegts "github.com/bluenviron/mediacommon/pkg/formats/mpegts"
+
+	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/bluenviron/mediamtx/internal/protocols/mpegts"
+	"github.com/bluenviron/mediamtx/internal/unit"
+)
+
+// eofReader keeps track of whether the end of the file has been reached.
+type eofReader struct {
+	r   io.Reader
+	eof bool
+}
+
+func (r *eofReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if errors.Is(err, io.EOF) {
+		r.eof = true
+	}
+	return n, err
+}
+
+// mpegtsTrackTiming is used to estimate the duration of a MPEG-TS track,
+// since MPEG-TS doesn't store sample durations.
+type mpegtsTrackTiming struct {
+	maxPTS        time.Duration
+	frameDuration time.Duration
+	ok            bool
+}
+
+func (t *mpegtsTrackTiming) update(pts time.Duration) {
+	if !t.ok {
+		t.maxPTS = pts
+		t.ok = true
+		return
+	}
+
+	if pts > t.maxPTS {
+		// the smallest increment is the duration of a single frame,
+		// even in case of B-frames.
+		d := pts - t.maxPTS
+		if t.frameDuration == 0 || d < t.frameDuration {
+			t.frameDuration = d
+		}
+		t.maxPTS = pts
+	}
+}
+
+type readerMPEGTS struct {
+	f       *os.File
+	er      *eofReader
+	r       *mcmpegts.Reader
+	ms      []*description.Media
+	timings map[*description.Media]*mpegtsTrackTiming
+	onUnit  func(*description.Media, time.Duration, unit.Unit) error
+}
+
+func newReaderMPEGTS(f *os.File, decodeErrLogger logger.Writer) (*readerMPEGTS, error) {
+	tr := &readerMPEGTS{
+		f:  f,
+		er: &eofReader{r: f},
+	}
+
+	var err error
+	tr.r, err = mcmpegts.NewReader(bufio.NewReader(tr.er))
+	if err != nil {
+		return nil, err
+	}
+
+	tr.r.OnDecodeError(func(err error) {
+		decodeErrLogger.Log(logger.Warn, err.Error())
+	})
+
+	tr.ms, err = mpegts.ToUnits(tr.r, func(medi *description.Media, dts time.Duration, u unit.Unit) error {
+		tr.timings[medi].update(u.GetPTS())
+		return tr.onUnit(medi, dts, u)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tr.timings = make(map[*description.Media]*mpegtsTrackTiming)
+	for _, medi := range tr.ms {
+		tr.timings[medi] = &mpegtsTrackTiming{}
+	}
+
+	return tr, nil
+}
+
+func (tr *readerMPEGTS) close() {
+	tr.f.Close()
+}
+
+func (tr *readerMPEGTS) medias() []*description.Media {
+	return tr.ms
+}
+
+func (tr *readerMPEGTS) read(onUnit func(*description.Media, time.Duration, unit.Unit) error) (time.Duration, error) {
+	tr.onUnit = onUnit
+
+	for {
+		err := tr.r.Read()
+		if err != nil {
+			if tr.er.eof {
+				break
+			}
+			return 0, err
+		}
+	}
+
+	var duration time.Duration
+
+	for _, t := range tr.timings {
+		if t.ok {
+			end := t.maxPTS + t.frameDuration
+			if end > duration {
+				duration = end
+			}
+		}
+	}
+
+	return duration, nil
+}
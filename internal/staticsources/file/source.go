@@ -0,0 +1,229 @@
+// Package file contains the file static source.
+package file
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+
+	"github.com/bluenviron/mediamtx/internal/defs"
+	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/bluenviron/mediamtx/internal/stream"
+	"github.com/bluenviron/mediamtx/internal/unit"
+)
+
+// isPlaylist checks whether a file is a playlist, by its extension.
+func isPlaylist(fpath string) bool {
+	switch strings.ToLower(filepath.Ext(fpath)) {
+	case ".m3u", ".m3u8", ".txt":
+		return true
+	}
+	return false
+}
+
+// readPlaylist reads a playlist, that contains a media file per line.
+// Empty lines and lines starting with '#' are ignored.
+// Relative paths are relative to the directory of the playlist.
+func readPlaylist(fpath string) ([]string, error) {
+	f, err := os.Open(fpath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var ret []string
+	dir := filepath.Dir(fpath)
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if !filepath.IsAbs(line) {
+			line = filepath.Join(dir, line)
+		}
+
+		ret = append(ret, line)
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ret) == 0 {
+		return nil, fmt.Errorf("playlist %s is empty", fpath)
+	}
+
+	return ret, nil
+}
+
+// Source is a file static source.
+type Source struct {
+	ResolvedSource string
+	Parent         defs.StaticSourceParent
+}
+
+// Log implements logger.Writer.
+func (s *Source) Log(level logger.Level, format string, args ...interface{}) {
+	s.Parent.Log(level, "%v"+format, append([]interface{}{logger.Component("file source")}, args...)...)
+}
+
+func (s *Source) files() ([]string, error) {
+	fpath := s.ResolvedSource[len("file://"):]
+
+	if isPlaylist(fpath) {
+		return readPlaylist(fpath)
+	}
+
+	return []string{fpath}, nil
+}
+
+// Run implements StaticSource.
+func (s *Source) Run(params defs.StaticSourceRunParams) error {
+	s.Log(logger.Debug, "opening")
+
+	files, err := s.files()
+	if err != nil {
+		return err
+	}
+
+	decodeErrLogger := logger.NewLimitedLogger(s)
+
+	r, err := openFile(files[0], decodeErrLogger)
+	if err != nil {
+		return err
+	}
+
+	medias := r.medias()
+
+	res := s.Parent.SetReady(defs.PathSourceStaticSetReadyReq{
+		Desc:               &description.Session{Medias: medias},
+		GenerateRTPPackets: true,
+	})
+	if res.Err != nil {
+		r.close()
+		return res.Err
+	}
+
+	defer s.Parent.SetNotReady(defs.PathSourceStaticSetNotReadyReq{})
+
+	ctx, ctxCancel := context.WithCancel(params.Context)
+	defer ctxCancel()
+
+	// sourceFileLoop can be changed without restarting the source.
+	loop := &atomic.Bool{}
+	loop.Store(params.Conf.SourceFileLoop)
+
+	readerErr := make(chan error)
+	go func() {
+		readerErr <- s.runReader(ctx, r, files, loop, medias, res.Stream, decodeErrLogger)
+	}()
+
+	for {
+		select {
+		case err := <-readerErr:
+			return err
+
+		case cnf := <-params.ReloadConf:
+			loop.Store(cnf.SourceFileLoop)
+
+		case <-params.Context.Done():
+			ctxCancel()
+			<-readerErr
+			return fmt.Errorf("terminated")
+		}
+	}
+}
+
+func (s *Source) runReader(
+	ctx context.Context,
+	r fileReader,
+	files []string,
+	loop *atomic.Bool,
+	medias []*description.Media,
+	stream *stream.Stream,
+	decodeErrLogger logger.Writer,
+) error {
+	// units are published in real time, starting from now.
+	start := time.Now()
+
+	// timestamps of every file are shifted by the duration of previous files,
+	// in order to obtain a continuous timeline.
+	var offset time.Duration
+
+	for i := 0; ; i++ {
+		if r == nil {
+			var err error
+			r, err = openFile(files[i%len(files)], decodeErrLogger)
+			if err != nil {
+				return err
+			}
+
+			if !mediasAreCompatible(medias, r.medias()) {
+				r.close()
+				return fmt.Errorf("tracks of %s are different from the ones of %s", files[i%len(files)], files[0])
+			}
+		}
+
+		// medias of current file are mapped to the ones of the stream.
+		mediaMap := make(map[*description.Media]*description.Media)
+		for j, medi := range r.medias() {
+			mediaMap[medi] = medias[j]
+		}
+
+		duration, err := r.read(func(medi *description.Media, dts time.Duration, u unit.Unit) error {
+			// units are paced by their DTS, that is monotonic even in case of B-frames.
+			wait := time.Until(start.Add(dts + offset))
+			if wait > 0 {
+				t := time.NewTimer(wait)
+				select {
+				case <-t.C:
+				case <-ctx.Done():
+					t.Stop()
+					return fmt.Errorf("terminated")
+				}
+			}
+
+			pts := u.GetPTS() + offset
+			u.SetPTS(pts)
+			u.SetNTP(start.Add(pts))
+
+			smedi := mediaMap[medi]
+			stream.WriteUnit(smedi, smedi.Formats[0], u)
+			return nil
+		})
+		r.close()
+		r = nil
+		if err != nil {
+			return err
+		}
+
+		if duration <= 0 {
+			return fmt.Errorf("%s doesn't contain any sample", files[i%len(files)])
+		}
+
+		offset += duration
+
+		if !loop.Load() && i%len(files) == (len(files)-1) {
+			return fmt.Errorf("end of playlist reached")
+		}
+	}
+}
+
+// APISourceDescribe implements StaticSource.
+func (*Source) APISourceDescribe() defs.APIPathSourceOrReader {
+	return defs.APIPathSourceOrReader{
+		Type: "fileSource",
+		ID:   "",
+	}
+}
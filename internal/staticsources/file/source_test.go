@@ -0,0 +1,459 @@
+package file
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	"github.com/bluenviron/gortsplib/v4/pkg/format"
+	"github.com/bluenviron/mediacommon/pkg/codecs/h264"
+	"github.com/bluenviron/mediacommon/pkg/codecs/mpeg4audio"
+	"github.com/bluenviron/mediacommon/pkg/formats/fmp4"
+	"github.com/bluenviron/mediacommon/pkg/formats/fmp4/seekablebuffer"
+	mcmpegts "github.com/bluenviron/mediacommon/pkg/formats/mpegts"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bluenviron/mediamtx/internal/asyncwriter"
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/defs"
+	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/bluenviron/mediamtx/internal/playback/mp4"
+	"github.com/bluenviron/mediamtx/internal/stream"
+	"github.com/bluenviron/mediamtx/internal/test"
+	"github.com/bluenviron/mediamtx/internal/unit"
+)
+
+var testAUs = [][][]byte{
+	{
+		test.FormatH264.SPS,
+		test.FormatH264.PPS,
+		{5, 1}, // IDR
+	},
+	{{1, 2}},
+	{{1, 3}},
+}
+
+const testFrameDuration = 100 * time.Millisecond
+
+func writeTestMPEGTS(t *testing.T, fpath string) {
+	f, err := os.Create(fpath)
+	require.NoError(t, err)
+	defer f.Close()
+
+	track := &mcmpegts.Track{
+		Codec: &mcmpegts.CodecH264{},
+	}
+
+	bw := bufio.NewWriter(f)
+	w := mcmpegts.NewWriter(bw, []*mcmpegts.Track{track})
+
+	for i, au := range testAUs {
+		ts := int64(i) * 90000 * int64(testFrameDuration) / int64(time.Second)
+		err = w.WriteH26x(track, ts, ts, h264.IDRPresent(au), au)
+		require.NoError(t, err)
+	}
+
+	err = bw.Flush()
+	require.NoError(t, err)
+}
+
+func avccMarshal(t *testing.T, au [][]byte) []byte {
+	byts, err := h264.AVCCMarshal(au)
+	require.NoError(t, err)
+	return byts
+}
+
+func writeTestFMP4(t *testing.T, fpath string) {
+	var buf seekablebuffer.Buffer
+
+	init := fmp4.Init{
+		Tracks: []*fmp4.InitTrack{{
+			ID:        1,
+			TimeScale: 90000,
+			Codec: &fmp4.CodecH264{
+				SPS: test.FormatH264.SPS,
+				PPS: test.FormatH264.PPS,
+			},
+		}},
+	}
+	err := init.Marshal(&buf)
+	require.NoError(t, err)
+
+	for i, au := range testAUs {
+		part := fmp4.Part{
+			SequenceNumber: uint32(i),
+			Tracks: []*fmp4.PartTrack{{
+				ID:       1,
+				BaseTime: uint64(i) * 9000,
+				Samples: []*fmp4.PartSample{{
+					Duration:        9000,
+					IsNonSyncSample: !h264.IDRPresent(au),
+					Payload:         avccMarshal(t, au[len(au)-1:]),
+				}},
+			}},
+		}
+		err = part.Marshal(&buf)
+		require.NoError(t, err)
+	}
+
+	err = os.WriteFile(fpath, buf.Bytes(), 0o644)
+	require.NoError(t, err)
+}
+
+func writeTestMP4(t *testing.T, fpath string) {
+	track := &mp4.Track{
+		ID:        1,
+		TimeScale: 90000,
+		Codec: &fmp4.CodecH264{
+			SPS: test.FormatH264.SPS,
+			PPS: test.FormatH264.PPS,
+		},
+	}
+
+	for _, au := range testAUs {
+		payload := avccMarshal(t, au[len(au)-1:])
+		track.Samples = append(track.Samples, &mp4.Sample{
+			Duration:        9000,
+			IsNonSyncSample: !h264.IDRPresent(au),
+			PayloadSize:     uint32(len(payload)),
+			GetPayload: func() ([]byte, error) {
+				return payload, nil
+			},
+		})
+	}
+
+	f, err := os.Create(fpath)
+	require.NoError(t, err)
+	defer f.Close()
+
+	err = (&mp4.Presentation{Tracks: []*mp4.Track{track}}).Marshal(f)
+	require.NoError(t, err)
+}
+
+// testParent is a static source parent that collects all units of the first media.
+type testParent struct {
+	stream *stream.Stream
+	writer *asyncwriter.Writer
+	units  chan unit.Unit
+}
+
+func (*testParent) Log(_ logger.Level, _ string, _ ...interface{}) {
+}
+
+func (p *testParent) SetReady(req defs.PathSourceStaticSetReadyReq) defs.PathSourceStaticSetReadyRes {
+	p.stream, _ = stream.New(
+		1460,
+		req.Desc,
+		req.GenerateRTPPackets,
+		p,
+	)
+
+	p.writer = asyncwriter.New(2048, p)
+
+	p.stream.AddReader(p.writer, req.Desc.Medias[0], req.Desc.Medias[0].Formats[0], func(u unit.Unit) error {
+		p.units <- u
+		return nil
+	})
+	p.writer.Start()
+
+	return defs.PathSourceStaticSetReadyRes{
+		Stream: p.stream,
+	}
+}
+
+func (p *testParent) SetNotReady(_ defs.PathSourceStaticSetNotReadyReq) {
+	p.writer.Stop()
+	p.stream.Close()
+}
+
+func TestReadPlaylist(t *testing.T) {
+	dir := t.TempDir()
+
+	fpath := filepath.Join(dir, "list.m3u")
+	err := os.WriteFile(fpath, []byte("#EXTM3U\n"+
+		"first.mp4\n"+
+		"\n"+
+		"/media/second.ts\n"), 0o644)
+	require.NoError(t, err)
+
+	files, err := readPlaylist(fpath)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		filepath.Join(dir, "first.mp4"),
+		"/media/second.ts",
+	}, files)
+}
+
+func TestSource(t *testing.T) {
+	for _, ca := range []string{
+		"mpegts",
+		"fmp4",
+		"mp4",
+		"playlist",
+	} {
+		t.Run(ca, func(t *testing.T) {
+			dir := t.TempDir()
+			var fpath string
+
+			switch ca {
+			case "mpegts":
+				fpath = filepath.Join(dir, "file.ts")
+				writeTestMPEGTS(t, fpath)
+
+			case "fmp4":
+				fpath = filepath.Join(dir, "file.mp4")
+				writeTestFMP4(t, fpath)
+
+			case "mp4":
+				fpath = filepath.Join(dir, "file.mp4")
+				writeTestMP4(t, fpath)
+
+			case "playlist":
+				writeTestMPEGTS(t, filepath.Join(dir, "first.ts"))
+				writeTestMP4(t, filepath.Join(dir, "second.mp4"))
+
+				fpath = filepath.Join(dir, "list.txt")
+				err := os.WriteFile(fpath, []byte("first.ts\nsecond.mp4\n"), 0o644)
+				require.NoError(t, err)
+			}
+
+			p := &testParent{
+				units: make(chan unit.Unit, 100),
+			}
+
+			source := &Source{
+				ResolvedSource: "file://" + fpath,
+				Parent:         p,
+			}
+
+			done := make(chan error)
+			ctx, ctxCancel := context.WithCancel(context.Background())
+
+			go func() {
+				done <- source.Run(defs.StaticSourceRunParams{
+					Context: ctx,
+					Conf:    &conf.Path{SourceFileLoop: true},
+				})
+			}()
+
+			defer func() {
+				ctxCancel()
+				<-done
+			}()
+
+			// timestamps must be continuous between different iterations of the loop.
+			for i := 0; i < 2*len(testAUs); i++ {
+				u := <-p.units
+				require.Equal(t, time.Duration(i)*testFrameDuration, u.GetPTS())
+				require.Equal(t, testAUs[i%len(testAUs)][len(testAUs[i%len(testAUs)])-1],
+					u.(*unit.H264).AU[len(u.(*unit.H264).AU)-1])
+			}
+		})
+	}
+}
+
+func TestSourceNoLoop(t *testing.T) {
+	fpath := filepath.Join(t.TempDir(), "file.ts")
+	writeTestMPEGTS(t, fpath)
+
+	p := &testParent{
+		units: make(chan unit.Unit, 100),
+	}
+
+	source := &Source{
+		ResolvedSource: "file://" + fpath,
+		Parent:         p,
+	}
+
+	err := source.Run(defs.StaticSourceRunParams{
+		Context: context.Background(),
+		Conf:    &conf.Path{SourceFileLoop: false},
+	})
+	require.EqualError(t, err, "end of playlist reached")
+}
+
+func TestSourceBFrames(t *testing.T) {
+	var buf seekablebuffer.Buffer
+
+	init := fmp4.Init{
+		Tracks: []*fmp4.InitTrack{{
+			ID:        1,
+			TimeScale: 90000,
+			Codec: &fmp4.CodecH264{
+				SPS: test.FormatH264.SPS,
+				PPS: test.FormatH264.PPS,
+			},
+		}},
+	}
+	err := init.Marshal(&buf)
+	require.NoError(t, err)
+
+	// I, P, B frames in decoding order.
+	part := fmp4.Part{
+		Tracks: []*fmp4.PartTrack{{
+			ID: 1,
+			Samples: []*fmp4.PartSample{
+				{
+					Duration:  9000,
+					PTSOffset: 9000,
+					Payload:   avccMarshal(t, [][]byte{{5, 1}}),
+				},
+				{
+					Duration:        9000,
+					PTSOffset:       18000,
+					IsNonSyncSample: true,
+					Payload:         avccMarshal(t, [][]byte{{1, 2}}),
+				},
+				{
+					Duration:        9000,
+					PTSOffset:       0,
+					IsNonSyncSample: true,
+					Payload:         avccMarshal(t, [][]byte{{1, 3}}),
+				},
+			},
+		}},
+	}
+	err = part.Marshal(&buf)
+	require.NoError(t, err)
+
+	fpath := filepath.Join(t.TempDir(), "file.mp4")
+	err = os.WriteFile(fpath, buf.Bytes(), 0o644)
+	require.NoError(t, err)
+
+	p := &testParent{
+		units: make(chan unit.Unit, 100),
+	}
+
+	source := &Source{
+		ResolvedSource: "file://" + fpath,
+		Parent:         p,
+	}
+
+	done := make(chan error)
+	ctx, ctxCancel := context.WithCancel(context.Background())
+
+	go func() {
+		done <- source.Run(defs.StaticSourceRunParams{
+			Context: ctx,
+			Conf:    &conf.Path{SourceFileLoop: true},
+		})
+	}()
+
+	defer func() {
+		ctxCancel()
+		<-done
+	}()
+
+	var pts []time.Duration
+	var received []time.Time
+
+	for i := 0; i < 3; i++ {
+		u := <-p.units
+		pts = append(pts, u.GetPTS())
+		received = append(received, time.Now())
+	}
+
+	require.Equal(t, []time.Duration{
+		1 * testFrameDuration,
+		3 * testFrameDuration,
+		2 * testFrameDuration,
+	}, pts)
+
+	// units are paced by DTS, therefore the B-frame is not sent
+	// together with the frame that precedes it.
+	require.Greater(t, received[2].Sub(received[1]), testFrameDuration/2)
+}
+
+func TestMediasAreCompatible(t *testing.T) {
+	video := &description.Media{
+		Type:    description.MediaTypeVideo,
+		Formats: []format.Format{&format.H264{}},
+	}
+	audio := &description.Media{
+		Type:    description.MediaTypeAudio,
+		Formats: []format.Format{&format.Opus{}},
+	}
+
+	require.True(t, mediasAreCompatible([]*description.Media{video, audio}, []*description.Media{video, audio}))
+	require.False(t, mediasAreCompatible([]*description.Media{video, audio}, []*description.Media{audio, video}))
+	require.False(t, mediasAreCompatible([]*description.Media{video}, []*description.Media{video, audio}))
+
+	// parameters sent in-band are compatible with any other.
+	videoWithParams := &description.Media{
+		Type: description.MediaTypeVideo,
+		Formats: []format.Format{&format.H264{
+			SPS: test.FormatH264.SPS,
+			PPS: test.FormatH264.PPS,
+		}},
+	}
+	require.True(t, mediasAreCompatible([]*description.Media{video}, []*description.Media{videoWithParams}))
+
+	otherVideoWithParams := &description.Media{
+		Type: description.MediaTypeVideo,
+		Formats: []format.Format{&format.H264{
+			SPS: []byte{
+				0x67, 0x64, 0x00, 0x28, 0xac, 0xd9, 0x40, 0x78,
+				0x02, 0x27, 0xe5, 0x84, 0x00, 0x00, 0x03, 0x00,
+				0x04, 0x00, 0x00, 0x03, 0x00, 0xf0, 0x3c, 0x60,
+				0xc6, 0x58,
+			},
+			PPS: test.FormatH264.PPS,
+		}},
+	}
+	require.False(t, mediasAreCompatible([]*description.Media{videoWithParams},
+		[]*description.Media{otherVideoWithParams}))
+
+	aac := func(sampleRate int) *description.Media {
+		return &description.Media{
+			Type: description.MediaTypeAudio,
+			Formats: []format.Format{&format.MPEG4Audio{
+				Config: &mpeg4audio.Config{
+					Type:         2,
+					SampleRate:   sampleRate,
+					ChannelCount: 2,
+				},
+			}},
+		}
+	}
+	require.True(t, mediasAreCompatible([]*description.Media{aac(44100)}, []*description.Media{aac(44100)}))
+	require.False(t, mediasAreCompatible([]*description.Media{aac(44100)}, []*description.Media{aac(48000)}))
+}
+
+func TestSourceReloadLoop(t *testing.T) {
+	fpath := filepath.Join(t.TempDir(), "file.ts")
+	writeTestMPEGTS(t, fpath)
+
+	p := &testParent{
+		units: make(chan unit.Unit, 100),
+	}
+
+	source := &Source{
+		ResolvedSource: "file://" + fpath,
+		Parent:         p,
+	}
+
+	reloadConf := make(chan *conf.Path)
+	done := make(chan error)
+
+	go func() {
+		done <- source.Run(defs.StaticSourceRunParams{
+			Context:    context.Background(),
+			Conf:       &conf.Path{SourceFileLoop: true},
+			ReloadConf: reloadConf,
+		})
+	}()
+
+	// wait for the second iteration of the loop.
+	for i := 0; i < len(testAUs)+1; i++ {
+		<-p.units
+	}
+
+	reloadConf <- &conf.Path{SourceFileLoop: false}
+
+	err := <-done
+	require.EqualError(t, err, "end of playlist reached")
+}
@@ -27,7 +27,7 @@ type Source struct {
 
 // Log implements logger.Writer.
 func (s *Source) Log(level logger.Level, format string, args ...interface{}) {
-	s.Parent.Log(level, "[HLS source] "+format, args...)
+	s.Parent.Log(level, "%v"+format, append([]interface{}{logger.Component("HLS source")}, args...)...)
 }
 
 // Run implements StaticSource.
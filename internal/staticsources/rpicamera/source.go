@@ -73,7 +73,7 @@ type Source struct {
 
 // Log implements logger.Writer.
 func (s *Source) Log(level logger.Level, format string, args ...interface{}) {
-	s.Parent.Log(level, "[RPI Camera source] "+format, args...)
+	s.Parent.Log(level, "%v"+format, append([]interface{}{logger.Component("RPI Camera source")}, args...)...)
 }
 
 // Run implements StaticSource.
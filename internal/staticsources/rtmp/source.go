@@ -31,7 +31,7 @@ type Source struct {
 
 // Log implements logger.Writer.
 func (s *Source) Log(level logger.Level, format string, args ...interface{}) {
-	s.Parent.Log(level, "[RTMP source] "+format, args...)
+	s.Parent.Log(level, "%v"+format, append([]interface{}{logger.Component("RTMP source")}, args...)...)
 }
 
 // Run implements StaticSource.
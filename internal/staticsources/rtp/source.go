@@ -0,0 +1,468 @@
+// Package rtp contains the RTP/SDP static source.
+package rtp
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	"github.com/bluenviron/gortsplib/v4/pkg/format"
+	"github.com/bluenviron/gortsplib/v4/pkg/multicast"
+	"github.com/bluenviron/gortsplib/v4/pkg/rtcpreceiver"
+	"github.com/bluenviron/gortsplib/v4/pkg/rtptime"
+	"github.com/bluenviron/gortsplib/v4/pkg/sdp"
+	"github.com/pion/rtcp"
+	prtp "github.com/pion/rtp"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/defs"
+	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/bluenviron/mediamtx/internal/restrictnetwork"
+	"github.com/bluenviron/mediamtx/internal/stream"
+)
+
+const (
+	// same size as GStreamer's rtspsrc
+	udpKernelReadBufferSize = 0x80000
+
+	// maximum size of a UDP datagram
+	maxPacketSize = 0xFFFF
+
+	receiverReportPeriod = 5 * time.Second
+)
+
+type packetConn interface {
+	net.PacketConn
+	SetReadBuffer(int) error
+}
+
+func listenUDP(address string, isMulticast bool) (packetConn, error) {
+	var pc packetConn
+
+	if isMulticast {
+		var err error
+		pc, err = multicast.NewMultiConn(address, true, net.ListenPacket)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		tmp, err := net.ListenPacket(restrictnetwork.Restrict("udp", address))
+		if err != nil {
+			return nil, err
+		}
+		pc = tmp.(*net.UDPConn)
+	}
+
+	err := pc.SetReadBuffer(udpKernelReadBufferSize)
+	if err != nil {
+		pc.Close()
+		return nil, err
+	}
+
+	return pc, nil
+}
+
+// mediaEndpoint contains the addresses where the RTP and RTCP packets of a media are received.
+type mediaEndpoint struct {
+	rtpAddress  string
+	rtcpAddress string
+	multicast   bool
+}
+
+func mediaEndpoints(sd *sdp.SessionDescription) ([]mediaEndpoint, error) {
+	ret := make([]mediaEndpoint, len(sd.MediaDescriptions))
+
+	for i, md := range sd.MediaDescriptions {
+		ci := md.ConnectionInformation
+		if ci == nil {
+			ci = sd.ConnectionInformation
+		}
+
+		var ip net.IP
+		if ci != nil && ci.Address != nil {
+			// remove the TTL and the number of addresses, if present.
+			ip = net.ParseIP(strings.SplitN(ci.Address.Address, "/", 2)[0])
+			if ip == nil {
+				return nil, fmt.Errorf("invalid connection address: %s", ci.Address.Address)
+			}
+		}
+
+		rtpPort := md.MediaName.Port.Value
+		if rtpPort <= 0 || rtpPort > 65535 {
+			return nil, fmt.Errorf("media %d has an invalid port: %d", i+1, rtpPort)
+		}
+
+		rtcpPort := rtpPort + 1
+
+		if v, ok := md.Attribute("rtcp"); ok {
+			tmp, err := strconv.ParseUint(strings.SplitN(v, " ", 2)[0], 10, 16)
+			if err != nil {
+				return nil, fmt.Errorf("invalid RTCP attribute: %s", v)
+			}
+			rtcpPort = int(tmp)
+		}
+
+		// unicast streams are received on every interface.
+		if ip != nil && ip.IsMulticast() {
+			ret[i] = mediaEndpoint{
+				rtpAddress:  net.JoinHostPort(ip.String(), strconv.FormatInt(int64(rtpPort), 10)),
+				rtcpAddress: net.JoinHostPort(ip.String(), strconv.FormatInt(int64(rtcpPort), 10)),
+				multicast:   true,
+			}
+		} else {
+			ret[i] = mediaEndpoint{
+				rtpAddress:  ":" + strconv.FormatInt(int64(rtpPort), 10),
+				rtcpAddress: ":" + strconv.FormatInt(int64(rtcpPort), 10),
+			}
+		}
+	}
+
+	return ret, nil
+}
+
+// sourceFormat contains the RTCP receiver of a format, that depends on its clock rate.
+type sourceFormat struct {
+	format          format.Format
+	writePacketRTCP func(rtcp.Packet)
+
+	mutex        sync.RWMutex
+	rtcpReceiver *rtcpreceiver.RTCPReceiver
+}
+
+func (f *sourceFormat) initialize() error {
+	_, err := f.resetRTCPReceiver()
+	return err
+}
+
+func (f *sourceFormat) close() {
+	f.rtcpReceiver.Close()
+}
+
+// resetRTCPReceiver replaces the RTCP receiver with a new one,
+// in order to accept packets from a different sender.
+func (f *sourceFormat) resetRTCPReceiver() (*rtcpreceiver.RTCPReceiver, error) {
+	rr, err := rtcpreceiver.New(
+		f.format.ClockRate(),
+		nil,
+		receiverReportPeriod,
+		nil,
+		f.writePacketRTCP,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mutex.Lock()
+	prev := f.rtcpReceiver
+	f.rtcpReceiver = rr
+	f.mutex.Unlock()
+
+	if prev != nil {
+		prev.Close()
+	}
+
+	return rr, nil
+}
+
+func (f *sourceFormat) receiver() *rtcpreceiver.RTCPReceiver {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	return f.rtcpReceiver
+}
+
+type sourceMedia struct {
+	media       *description.Media
+	endpoint    mediaEndpoint
+	readTimeout time.Duration
+	timeDecoder *rtptime.GlobalDecoder
+
+	rtpConn    packetConn
+	rtcpConn   packetConn
+	formats    map[uint8]*sourceFormat
+	mutex      sync.Mutex
+	rtcpSender net.Addr
+}
+
+func (m *sourceMedia) initialize() error {
+	var err error
+	m.rtpConn, err = listenUDP(m.endpoint.rtpAddress, m.endpoint.multicast)
+	if err != nil {
+		return err
+	}
+
+	m.rtcpConn, err = listenUDP(m.endpoint.rtcpAddress, m.endpoint.multicast)
+	if err != nil {
+		m.rtpConn.Close()
+		return err
+	}
+
+	m.formats = make(map[uint8]*sourceFormat)
+
+	for _, forma := range m.media.Formats {
+		f := &sourceFormat{
+			format:          forma,
+			writePacketRTCP: m.writePacketRTCP,
+		}
+		err = f.initialize()
+		if err != nil {
+			m.close()
+			return err
+		}
+		m.formats[forma.PayloadType()] = f
+	}
+
+	return nil
+}
+
+func (m *sourceMedia) close() {
+	m.rtpConn.Close()
+	m.rtcpConn.Close()
+
+	for _, f := range m.formats {
+		f.close()
+	}
+}
+
+// writePacketRTCP sends receiver reports to the sender, once its address is known.
+func (m *sourceMedia) writePacketRTCP(pkt rtcp.Packet) {
+	m.mutex.Lock()
+	addr := m.rtcpSender
+	m.mutex.Unlock()
+
+	if addr == nil || m.endpoint.multicast {
+		return
+	}
+
+	byts, err := pkt.Marshal()
+	if err != nil {
+		return
+	}
+
+	m.rtcpConn.WriteTo(byts, addr) //nolint:errcheck
+}
+
+func (m *sourceMedia) runRTP(stream *stream.Stream, decodeErrLogger logger.Writer) error {
+	buf := make([]byte, maxPacketSize)
+
+	for {
+		m.rtpConn.SetReadDeadline(time.Now().Add(m.readTimeout))
+		n, _, err := m.rtpConn.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+
+		// packets are read by the stream asynchronously, therefore a copy is needed.
+		byts := make([]byte, n)
+		copy(byts, buf[:n])
+
+		var pkt prtp.Packet
+		err = pkt.Unmarshal(byts)
+		if err != nil {
+			decodeErrLogger.Log(logger.Warn, err.Error())
+			continue
+		}
+
+		f, ok := m.formats[pkt.PayloadType]
+		if !ok {
+			decodeErrLogger.Log(logger.Warn, "received RTP packet with unknown payload type: %d", pkt.PayloadType)
+			continue
+		}
+
+		now := time.Now()
+
+		rr := f.receiver()
+
+		err = rr.ProcessPacket(&pkt, now, f.format.PTSEqualsDTS(&pkt))
+		if err != nil {
+			// the SSRC has changed, that means that the sender has been restarted.
+			decodeErrLogger.Log(logger.Warn, "%v, resetting receiver", err)
+
+			rr, err = f.resetRTCPReceiver()
+			if err != nil {
+				return err
+			}
+
+			rr.ProcessPacket(&pkt, now, f.format.PTSEqualsDTS(&pkt)) //nolint:errcheck
+		}
+
+		pts, ok := m.timeDecoder.Decode(f.format, &pkt)
+		if !ok {
+			continue
+		}
+
+		// use the absolute timestamp provided by RTCP sender reports, when available.
+		ntp, ok := rr.PacketNTP(pkt.Timestamp)
+		if !ok {
+			ntp = now
+		}
+
+		stream.WriteRTPPacket(m.media, f.format, &pkt, ntp, pts)
+	}
+}
+
+func (m *sourceMedia) runRTCP(decodeErrLogger logger.Writer) error {
+	buf := make([]byte, maxPacketSize)
+
+	for {
+		n, addr, err := m.rtcpConn.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+
+		pkts, err := rtcp.Unmarshal(buf[:n])
+		if err != nil {
+			decodeErrLogger.Log(logger.Warn, err.Error())
+			continue
+		}
+
+		m.mutex.Lock()
+		m.rtcpSender = addr
+		m.mutex.Unlock()
+
+		for _, pkt := range pkts {
+			if sr, ok := pkt.(*rtcp.SenderReport); ok {
+				now := time.Now()
+
+				// sender reports are routed to formats by SSRC.
+				// Formats that didn't receive any packet yet accept any sender report.
+				for _, f := range m.formats {
+					rr := f.receiver()
+					if ssrc, ok := rr.SenderSSRC(); !ok || ssrc == sr.SSRC {
+						rr.ProcessSenderReport(sr, now)
+					}
+				}
+			}
+		}
+	}
+}
+
+// Source is a RTP/SDP static source.
+type Source struct {
+	ResolvedSource string
+	ReadTimeout    conf.StringDuration
+	Parent         defs.StaticSourceParent
+}
+
+// Log implements logger.Writer.
+func (s *Source) Log(level logger.Level, format string, args ...interface{}) {
+	s.Parent.Log(level, "%v"+format, append([]interface{}{logger.Component("RTP source")}, args...)...)
+}
+
+func (s *Source) loadSDP(pconf *conf.Path) ([]byte, error) {
+	fpath := s.ResolvedSource[len("sdp://"):]
+
+	// an empty path means that the SDP is provided inline.
+	if fpath == "" {
+		return []byte(pconf.SourceSDP), nil
+	}
+
+	return os.ReadFile(fpath)
+}
+
+// Run implements StaticSource.
+func (s *Source) Run(params defs.StaticSourceRunParams) error {
+	s.Log(logger.Debug, "connecting")
+
+	byts, err := s.loadSDP(params.Conf)
+	if err != nil {
+		return err
+	}
+
+	var sd sdp.SessionDescription
+	err = sd.Unmarshal(byts)
+	if err != nil {
+		return fmt.Errorf("invalid SDP: %w", err)
+	}
+
+	var desc description.Session
+	err = desc.Unmarshal(&sd)
+	if err != nil {
+		return fmt.Errorf("invalid SDP: %w", err)
+	}
+
+	endpoints, err := mediaEndpoints(&sd)
+	if err != nil {
+		return err
+	}
+
+	timeDecoder := rtptime.NewGlobalDecoder()
+
+	medias := make([]*sourceMedia, 0, len(desc.Medias))
+
+	closeMedias := func() {
+		for _, m := range medias {
+			m.close()
+		}
+	}
+
+	for i, medi := range desc.Medias {
+		m := &sourceMedia{
+			media:       medi,
+			endpoint:    endpoints[i],
+			readTimeout: time.Duration(s.ReadTimeout),
+			timeDecoder: timeDecoder,
+		}
+		err = m.initialize()
+		if err != nil {
+			closeMedias()
+			return err
+		}
+		medias = append(medias, m)
+	}
+
+	res := s.Parent.SetReady(defs.PathSourceStaticSetReadyReq{
+		Desc:               &desc,
+		GenerateRTPPackets: false,
+	})
+	if res.Err != nil {
+		closeMedias()
+		return res.Err
+	}
+
+	defer s.Parent.SetNotReady(defs.PathSourceStaticSetNotReadyReq{})
+
+	decodeErrLogger := logger.NewLimitedLogger(s)
+
+	readErr := make(chan error, 2*len(medias))
+	var wg sync.WaitGroup
+
+	for _, m := range medias {
+		cm := m
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+			readErr <- cm.runRTP(res.Stream, decodeErrLogger)
+		}()
+
+		go func() {
+			defer wg.Done()
+			readErr <- cm.runRTCP(decodeErrLogger)
+		}()
+	}
+
+	select {
+	case err = <-readErr:
+
+	case <-params.Context.Done():
+		err = fmt.Errorf("terminated")
+	}
+
+	closeMedias()
+	wg.Wait()
+
+	return err
+}
+
+// APISourceDescribe implements StaticSource.
+func (*Source) APISourceDescribe() defs.APIPathSourceOrReader {
+	return defs.APIPathSourceOrReader{
+		Type: "rtpSource",
+		ID:   "",
+	}
+}
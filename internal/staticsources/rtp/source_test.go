@@ -0,0 +1,198 @@
+package rtp
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/sdp"
+	"github.com/pion/rtcp"
+	prtp "github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/defs"
+	"github.com/bluenviron/mediamtx/internal/test"
+	"github.com/bluenviron/mediamtx/internal/unit"
+)
+
+const testSDP = "v=0\r\n" +
+	"o=- 0 0 IN IP4 127.0.0.1\r\n" +
+	"s=Stream\r\n" +
+	"c=IN IP4 127.0.0.1\r\n" +
+	"t=0 0\r\n" +
+	"m=video 9004 RTP/AVP 96\r\n" +
+	"a=rtpmap:96 H264/90000\r\n" +
+	"a=fmtp:96 packetization-mode=1\r\n"
+
+func ntpTimeGoToRTCP(v time.Time) uint64 {
+	s := uint64(v.UnixNano()) + 2208988800*1000000000
+	return (s/1000000000)<<32 | (s % 1000000000)
+}
+
+func TestMediaEndpoints(t *testing.T) {
+	var sd sdp.SessionDescription
+	err := sd.Unmarshal([]byte("v=0\r\n" +
+		"o=- 0 0 IN IP4 127.0.0.1\r\n" +
+		"s=Stream\r\n" +
+		"c=IN IP4 239.0.0.1/32\r\n" +
+		"t=0 0\r\n" +
+		"m=video 5004 RTP/AVP 96\r\n" +
+		"a=rtpmap:96 H264/90000\r\n" +
+		"m=audio 5006 RTP/AVP 97\r\n" +
+		"c=IN IP4 127.0.0.1\r\n" +
+		"a=rtpmap:97 opus/48000/2\r\n" +
+		"a=rtcp:5010\r\n"))
+	require.NoError(t, err)
+
+	endpoints, err := mediaEndpoints(&sd)
+	require.NoError(t, err)
+	require.Equal(t, []mediaEndpoint{
+		{
+			rtpAddress:  "239.0.0.1:5004",
+			rtcpAddress: "239.0.0.1:5005",
+			multicast:   true,
+		},
+		{
+			rtpAddress:  ":5006",
+			rtcpAddress: ":5010",
+		},
+	}, endpoints)
+}
+
+func TestSource(t *testing.T) {
+	for _, ca := range []string{
+		"file",
+		"inline",
+	} {
+		t.Run(ca, func(t *testing.T) {
+			var source string
+			pconf := &conf.Path{}
+
+			if ca == "file" {
+				fpath := filepath.Join(t.TempDir(), "stream.sdp")
+				err := os.WriteFile(fpath, []byte(testSDP), 0o644)
+				require.NoError(t, err)
+				source = "sdp://" + fpath
+			} else {
+				source = "sdp://"
+				pconf.SourceSDP = testSDP
+			}
+
+			te := test.NewSourceTester(
+				func(p defs.StaticSourceParent) defs.StaticSource {
+					return &Source{
+						ResolvedSource: source,
+						ReadTimeout:    conf.StringDuration(10 * time.Second),
+						Parent:         p,
+					}
+				},
+				pconf,
+			)
+			defer te.Close()
+
+			time.Sleep(50 * time.Millisecond)
+
+			ntp := time.Date(2018, 5, 20, 8, 17, 15, 0, time.UTC)
+
+			rtcpConn, err := net.Dial("udp", "127.0.0.1:9005")
+			require.NoError(t, err)
+			defer rtcpConn.Close()
+
+			byts, err := (&rtcp.SenderReport{
+				SSRC:    753621,
+				NTPTime: ntpTimeGoToRTCP(ntp),
+				RTPTime: 54352,
+			}).Marshal()
+			require.NoError(t, err)
+
+			_, err = rtcpConn.Write(byts)
+			require.NoError(t, err)
+
+			time.Sleep(50 * time.Millisecond)
+
+			rtpConn, err := net.Dial("udp", "127.0.0.1:9004")
+			require.NoError(t, err)
+			defer rtpConn.Close()
+
+			byts, err = (&prtp.Packet{
+				Header: prtp.Header{
+					Version:        2,
+					Marker:         true,
+					PayloadType:    96,
+					SequenceNumber: 946,
+					Timestamp:      54352 + 90000,
+					SSRC:           753621,
+				},
+				Payload: []byte{5, 1, 2, 3}, // IDR
+			}).Marshal()
+			require.NoError(t, err)
+
+			_, err = rtpConn.Write(byts)
+			require.NoError(t, err)
+
+			u := <-te.Unit
+			require.Equal(t, [][]byte{{5, 1, 2, 3}}, u.(*unit.H264).AU)
+			require.Equal(t, ntp.Add(1*time.Second), u.GetNTP().UTC())
+		})
+	}
+}
+
+func TestSourceSSRCChange(t *testing.T) {
+	pconf := &conf.Path{SourceSDP: testSDP}
+
+	te := test.NewSourceTester(
+		func(p defs.StaticSourceParent) defs.StaticSource {
+			return &Source{
+				ResolvedSource: "sdp://",
+				ReadTimeout:    conf.StringDuration(10 * time.Second),
+				Parent:         p,
+			}
+		},
+		pconf,
+	)
+	defer te.Close()
+
+	time.Sleep(50 * time.Millisecond)
+
+	rtpConn, err := net.Dial("udp", "127.0.0.1:9004")
+	require.NoError(t, err)
+	defer rtpConn.Close()
+
+	for _, pkt := range []*prtp.Packet{
+		{
+			Header: prtp.Header{
+				Version:        2,
+				Marker:         false,
+				PayloadType:    96,
+				SequenceNumber: 946,
+				Timestamp:      54352,
+				SSRC:           753621,
+			},
+			Payload: []byte{1, 1, 2, 3}, // non-IDR
+		},
+		{
+			// the sender has been restarted with a different SSRC.
+			Header: prtp.Header{
+				Version:        2,
+				Marker:         true,
+				PayloadType:    96,
+				SequenceNumber: 123,
+				Timestamp:      54352,
+				SSRC:           96342362,
+			},
+			Payload: []byte{5, 4, 5, 6}, // IDR
+		},
+	} {
+		byts, err := pkt.Marshal()
+		require.NoError(t, err)
+
+		_, err = rtpConn.Write(byts)
+		require.NoError(t, err)
+	}
+
+	u := <-te.Unit
+	require.Equal(t, [][]byte{{5, 4, 5, 6}}, u.(*unit.H264).AU)
+}
@@ -24,7 +24,7 @@ type Source struct {
 
 // Log implements logger.Writer.
 func (s *Source) Log(level logger.Level, format string, args ...interface{}) {
-	s.Parent.Log(level, "[SRT source] "+format, args...)
+	s.Parent.Log(level, "%v"+format, append([]interface{}{logger.Component("SRT source")}, args...)...)
 }
 
 // Run implements StaticSource.
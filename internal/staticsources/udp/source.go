@@ -52,7 +52,7 @@ type Source struct {
 
 // Log implements logger.Writer.
 func (s *Source) Log(level logger.Level, format string, args ...interface{}) {
-	s.Parent.Log(level, "[UDP source] "+format, args...)
+	s.Parent.Log(level, "%v"+format, append([]interface{}{logger.Component("UDP source")}, args...)...)
 }
 
 // Run implements StaticSource.
@@ -93,6 +93,20 @@ func (s *Stream) BytesSent() uint64 {
 	return bytesSent
 }
 
+// Stats returns statistics of all formats, in the order of the description.
+func (s *Stream) Stats() []FormatStats {
+	var ret []FormatStats
+
+	for _, medi := range s.desc.Medias {
+		sm := s.smedias[medi]
+		for _, forma := range medi.Formats {
+			ret = append(ret, sm.formats[forma].stats.get())
+		}
+	}
+
+	return ret
+}
+
 // RTSPStream returns the RTSP stream.
 func (s *Stream) RTSPStream(server *gortsplib.Server) *gortsplib.ServerStream {
 	s.mutex.Lock()
@@ -25,12 +25,14 @@ func unitSize(u unit.Unit) uint64 {
 type streamFormat struct {
 	decodeErrLogger logger.Writer
 	proc            formatprocessor.Processor
+	stats           *formatStats
 	readers         map[*asyncwriter.Writer]ReadFunc
 }
 
 func newStreamFormat(
 	udpMaxPayloadSize int,
 	forma format.Format,
+	isVideo bool,
 	generateRTPPackets bool,
 	decodeErrLogger logger.Writer,
 ) (*streamFormat, error) {
@@ -42,6 +44,7 @@ func newStreamFormat(
 	sf := &streamFormat{
 		decodeErrLogger: decodeErrLogger,
 		proc:            proc,
+		stats:           newFormatStats(forma, isVideo),
 		readers:         make(map[*asyncwriter.Writer]ReadFunc),
 	}
 
@@ -73,6 +76,8 @@ func (sf *streamFormat) writeRTPPacket(
 	ntp time.Time,
 	pts time.Duration,
 ) {
+	sf.stats.onRTPPacket(pkt)
+
 	hasNonRTSPReaders := len(sf.readers) > 0
 
 	u, err := sf.proc.ProcessRTPPacket(pkt, ntp, pts, hasNonRTSPReaders)
@@ -89,6 +94,8 @@ func (sf *streamFormat) writeUnitInner(s *Stream, medi *description.Media, u uni
 
 	atomic.AddUint64(s.bytesReceived, size)
 
+	sf.stats.onUnit(u, size)
+
 	if s.rtspStream != nil {
 		for _, pkt := range u.GetRTPPackets() {
 			s.rtspStream.WritePacketRTPWithNTP(medi, pkt, u.GetNTP()) //nolint:errcheck
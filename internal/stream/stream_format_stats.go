@@ -0,0 +1,351 @@
+package stream
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/format"
+	"github.com/bluenviron/mediacommon/pkg/codecs/h264"
+	"github.com/bluenviron/mediacommon/pkg/codecs/h265"
+	"github.com/pion/rtp"
+
+	"github.com/bluenviron/mediamtx/internal/unit"
+)
+
+const (
+	// statistics are computed on windows of this duration.
+	statsWindow = 1 * time.Second
+
+	// timestamp differences above this value are considered discontinuities.
+	statsDiscontinuityThreshold = 5 * time.Second
+)
+
+// FormatStats contains statistics of a format.
+// Values that are not available are set to zero.
+type FormatStats struct {
+	Codec                    string
+	Bitrate                  float64
+	FrameRate                float64
+	KeyFrameInterval         time.Duration
+	Width                    int
+	Height                   int
+	TimestampDiscontinuities uint64
+	RTPPacketsReceived       uint64
+	RTPPacketsLost           uint64
+	Jitter                   time.Duration
+	LastUnit                 time.Time
+}
+
+// unitFrame returns whether the unit contains a decoded frame and whether the frame is a key frame.
+func unitFrame(u unit.Unit) (bool, bool) {
+	switch tu := u.(type) {
+	case *unit.H264:
+		if tu.AU == nil {
+			return false, false
+		}
+		return true, h264.IDRPresent(tu.AU)
+
+	case *unit.H265:
+		if tu.AU == nil {
+			return false, false
+		}
+		return true, h265.IsRandomAccess(tu.AU)
+
+	case *unit.AV1:
+		return tu.TU != nil, false
+
+	case *unit.VP8:
+		return tu.Frame != nil, false
+
+	case *unit.VP9:
+		return tu.Frame != nil, false
+
+	case *unit.MJPEG:
+		return tu.Frame != nil, false
+
+	case *unit.MPEG1Video:
+		return tu.Frame != nil, false
+
+	case *unit.MPEG4Video:
+		return tu.Frame != nil, false
+	}
+
+	return false, false
+}
+
+func h264IsIDR(typ h264.NALUType) bool {
+	return typ == h264.NALUTypeIDR
+}
+
+func h265IsRandomAccess(typ h265.NALUType) bool {
+	return typ >= h265.NALUType_BLA_W_LP && typ <= h265.NALUType_CRA_NUT
+}
+
+// rtpContainsKeyFrame checks whether a RTP packet contains a key frame,
+// without decoding the access unit.
+func rtpContainsKeyFrame(forma format.Format, pkt *rtp.Packet) bool {
+	pl := pkt.Payload
+
+	switch forma.(type) {
+	case *format.H264:
+		if len(pl) < 1 {
+			return false
+		}
+
+		switch typ := h264.NALUType(pl[0] & 0x1F); typ {
+		case h264.NALUTypeSTAPA:
+			pl = pl[1:]
+			for len(pl) >= 3 {
+				size := int(pl[0])<<8 | int(pl[1])
+				if h264IsIDR(h264.NALUType(pl[2] & 0x1F)) {
+					return true
+				}
+				if len(pl) < 2+size {
+					return false
+				}
+				pl = pl[2+size:]
+			}
+			return false
+
+		case h264.NALUTypeFUA:
+			return len(pl) >= 2 && (pl[1]&0x80) != 0 && h264IsIDR(h264.NALUType(pl[1]&0x1F))
+
+		default:
+			return h264IsIDR(typ)
+		}
+
+	case *format.H265:
+		if len(pl) < 2 {
+			return false
+		}
+
+		switch typ := h265.NALUType((pl[0] >> 1) & 0x3F); typ {
+		case h265.NALUType_AggregationUnit:
+			pl = pl[2:]
+			for len(pl) >= 3 {
+				size := int(pl[0])<<8 | int(pl[1])
+				if h265IsRandomAccess(h265.NALUType((pl[2] >> 1) & 0x3F)) {
+					return true
+				}
+				if len(pl) < 2+size {
+					return false
+				}
+				pl = pl[2+size:]
+			}
+			return false
+
+		case h265.NALUType_FragmentationUnit:
+			return len(pl) >= 3 && (pl[2]&0x80) != 0 && h265IsRandomAccess(h265.NALUType(pl[2]&0x3F))
+
+		default:
+			return h265IsRandomAccess(typ)
+		}
+	}
+
+	return false
+}
+
+func formatResolution(forma format.Format) (int, int) {
+	switch forma := forma.(type) {
+	case *format.H264:
+		sps, _ := forma.SafeParams()
+		if sps == nil {
+			return 0, 0
+		}
+
+		var s h264.SPS
+		err := s.Unmarshal(sps)
+		if err != nil {
+			return 0, 0
+		}
+		return s.Width(), s.Height()
+
+	case *format.H265:
+		_, sps, _ := forma.SafeParams()
+		if sps == nil {
+			return 0, 0
+		}
+
+		var s h265.SPS
+		err := s.Unmarshal(sps)
+		if err != nil {
+			return 0, 0
+		}
+		return s.Width(), s.Height()
+	}
+
+	return 0, 0
+}
+
+type formatStats struct {
+	forma   format.Format
+	isVideo bool
+
+	mutex              sync.Mutex
+	windowStart        time.Time
+	windowBytes        uint64
+	windowFrames       uint64
+	bitrate            float64
+	frameRate          float64
+	lastKeyFramePTS    *time.Duration
+	keyFrameInterval   time.Duration
+	lastPTS            *time.Duration
+	discontinuities    uint64
+	lastSequenceNumber *uint16
+	rtpPacketsReceived uint64
+	rtpPacketsLost     uint64
+	jitter             float64
+	lastArrival        time.Time
+	lastRTPTimestamp   uint32
+	lastUnit           time.Time
+	pendingKeyFrame    bool
+}
+
+func newFormatStats(forma format.Format, isVideo bool) *formatStats {
+	return &formatStats{
+		forma:       forma,
+		isVideo:     isVideo,
+		windowStart: time.Now(),
+	}
+}
+
+// rollWindow must be called with the mutex locked.
+func (st *formatStats) rollWindow(now time.Time) {
+	elapsed := now.Sub(st.windowStart)
+	if elapsed < statsWindow {
+		return
+	}
+
+	st.bitrate = float64(st.windowBytes*8) / elapsed.Seconds()
+	st.frameRate = float64(st.windowFrames) / elapsed.Seconds()
+	st.windowStart = now
+	st.windowBytes = 0
+	st.windowFrames = 0
+}
+
+func (st *formatStats) onRTPPacket(pkt *rtp.Packet) {
+	now := time.Now()
+
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+
+	st.rtpPacketsReceived++
+
+	if st.lastSequenceNumber != nil {
+		diff := pkt.SequenceNumber - *st.lastSequenceNumber
+
+		// ignore duplicate and reordered packets
+		if diff == 0 || diff >= 0x8000 {
+			return
+		}
+
+		st.rtpPacketsLost += uint64(diff - 1)
+	}
+
+	v := pkt.SequenceNumber
+	st.lastSequenceNumber = &v
+
+	// interarrival jitter, as described in RFC 3550, section 6.4.1.
+	// it is computed on packets whose timestamp is monotonic only.
+	if st.forma.ClockRate() > 0 && st.forma.PTSEqualsDTS(pkt) {
+		if !st.lastArrival.IsZero() {
+			d := now.Sub(st.lastArrival).Seconds()*float64(st.forma.ClockRate()) -
+				float64(int32(pkt.Timestamp-st.lastRTPTimestamp))
+			if d < 0 {
+				d = -d
+			}
+			st.jitter += (d - st.jitter) / 16
+		}
+
+		st.lastArrival = now
+		st.lastRTPTimestamp = pkt.Timestamp
+	}
+}
+
+func (st *formatStats) onUnit(u unit.Unit, size uint64) {
+	now := time.Now()
+
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+
+	st.rollWindow(now)
+
+	st.windowBytes += size
+	st.lastUnit = now
+
+	pts := u.GetPTS()
+
+	if st.lastPTS != nil {
+		diff := pts - *st.lastPTS
+		if diff > statsDiscontinuityThreshold || diff < -statsDiscontinuityThreshold {
+			st.discontinuities++
+
+			// key frame interval is not valid anymore
+			st.lastKeyFramePTS = nil
+		}
+	}
+	st.lastPTS = &pts
+
+	if !st.isVideo {
+		return
+	}
+
+	isFrame, isKeyFrame := unitFrame(u)
+
+	// when units are not decoded, frames are detected through RTP packets.
+	if !isFrame {
+		for _, pkt := range u.GetRTPPackets() {
+			if rtpContainsKeyFrame(st.forma, pkt) {
+				st.pendingKeyFrame = true
+			}
+
+			if pkt.Marker {
+				isFrame = true
+				isKeyFrame = st.pendingKeyFrame
+			}
+		}
+	}
+
+	if !isFrame {
+		return
+	}
+
+	st.windowFrames++
+	st.pendingKeyFrame = false
+
+	if isKeyFrame {
+		if st.lastKeyFramePTS != nil {
+			st.keyFrameInterval = pts - *st.lastKeyFramePTS
+		}
+		st.lastKeyFramePTS = &pts
+	}
+}
+
+func (st *formatStats) get() FormatStats {
+	width, height := formatResolution(st.forma)
+
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+
+	// when units are not received anymore, bitrate and frame rate drop to zero.
+	st.rollWindow(time.Now())
+
+	var jitter time.Duration
+	if clockRate := st.forma.ClockRate(); clockRate > 0 {
+		jitter = time.Duration(st.jitter * float64(time.Second) / float64(clockRate))
+	}
+
+	return FormatStats{
+		Codec:                    st.forma.Codec(),
+		Bitrate:                  st.bitrate,
+		FrameRate:                st.frameRate,
+		KeyFrameInterval:         st.keyFrameInterval,
+		Width:                    width,
+		Height:                   height,
+		TimestampDiscontinuities: st.discontinuities,
+		RTPPacketsReceived:       st.rtpPacketsReceived,
+		RTPPacketsLost:           st.rtpPacketsLost,
+		Jitter:                   jitter,
+		LastUnit:                 st.lastUnit,
+	}
+}
@@ -0,0 +1,75 @@
+package stream
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	"github.com/bluenviron/gortsplib/v4/pkg/format"
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bluenviron/mediamtx/internal/logger"
+)
+
+type nilLogger struct{}
+
+func (nilLogger) Log(logger.Level, string, ...interface{}) {
+}
+
+func TestStats(t *testing.T) {
+	medi := &description.Media{
+		Type: description.MediaTypeVideo,
+		Formats: []format.Format{&format.H264{
+			PayloadTyp: 96,
+			SPS: []byte{ // 1920x1080 baseline
+				0x67, 0x42, 0xc0, 0x28, 0xd9, 0x00, 0x78, 0x02,
+				0x27, 0xe5, 0x84, 0x00, 0x00, 0x03, 0x00, 0x04,
+				0x00, 0x00, 0x03, 0x00, 0xf0, 0x3c, 0x60, 0xc9, 0x20,
+			},
+			PPS:               []byte{0x08, 0x06, 0x07, 0x08},
+			PacketizationMode: 1,
+		}},
+	}
+
+	s, err := New(1472, &description.Session{Medias: []*description.Media{medi}}, false, nilLogger{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	for i, ca := range []struct {
+		seq     uint16
+		pts     time.Duration
+		payload []byte
+	}{
+		{100, 0, []byte{0x05, 1}},                      // IDR
+		{101, 500 * time.Millisecond, []byte{0x01, 2}}, // non-IDR
+		{103, 1 * time.Second, []byte{0x05, 3}},        // IDR, a packet is lost
+		{104, 20 * time.Second, []byte{0x1c, 0x85, 4}}, // FU-A IDR start, discontinuity
+	} {
+		s.WriteRTPPacket(medi, medi.Formats[0], &rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				Marker:         i != 3,
+				PayloadType:    96,
+				SequenceNumber: ca.seq,
+				Timestamp:      uint32(ca.pts * 90000 / time.Second),
+				SSRC:           563423,
+			},
+			Payload: ca.payload,
+		}, time.Now(), ca.pts)
+	}
+
+	stats := s.Stats()
+	require.Equal(t, 1, len(stats))
+
+	st := stats[0]
+	require.Equal(t, "H264", st.Codec)
+	require.Equal(t, 1920, st.Width)
+	require.Equal(t, 1080, st.Height)
+	require.Equal(t, 1*time.Second, st.KeyFrameInterval)
+	require.Equal(t, uint64(1), st.TimestampDiscontinuities)
+	require.Equal(t, uint64(4), st.RTPPacketsReceived)
+	require.Equal(t, uint64(1), st.RTPPacketsLost)
+	require.Greater(t, st.Jitter, time.Duration(0))
+	require.False(t, st.LastUnit.IsZero())
+}
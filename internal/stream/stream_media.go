@@ -22,7 +22,12 @@ func newStreamMedia(udpMaxPayloadSize int,
 
 	for _, forma := range medi.Formats {
 		var err error
-		sm.formats[forma], err = newStreamFormat(udpMaxPayloadSize, forma, generateRTPPackets, decodeErrLogger)
+		sm.formats[forma], err = newStreamFormat(
+			udpMaxPayloadSize,
+			forma,
+			medi.Type == description.MediaTypeVideo,
+			generateRTPPackets,
+			decodeErrLogger)
 		if err != nil {
 			return nil, err
 		}
@@ -12,6 +12,10 @@ func (m *AuthManager) Authenticate(req *auth.Request) error {
 	return m.Func(req)
 }
 
+// FlushCache implements auth.Manager.
+func (m *AuthManager) FlushCache() {
+}
+
 // NilAuthManager is an auth manager that accepts everything.
 var NilAuthManager = &AuthManager{
 	Func: func(_ *auth.Request) error {
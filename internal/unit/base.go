@@ -23,7 +23,17 @@ func (u *Base) GetNTP() time.Time {
 	return u.NTP
 }
 
+// SetNTP implements Unit.
+func (u *Base) SetNTP(v time.Time) {
+	u.NTP = v
+}
+
 // GetPTS implements Unit.
 func (u *Base) GetPTS() time.Duration {
 	return u.PTS
 }
+
+// SetPTS implements Unit.
+func (u *Base) SetPTS(v time.Duration) {
+	u.PTS = v
+}
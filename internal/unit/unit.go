@@ -15,6 +15,12 @@ type Unit interface {
 	// returns the NTP timestamp of the unit.
 	GetNTP() time.Time
 
+	// sets the NTP timestamp of the unit.
+	SetNTP(time.Time)
+
 	// returns the PTS of the unit.
 	GetPTS() time.Duration
+
+	// sets the PTS of the unit.
+	SetPTS(time.Duration)
 }